@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"os/signal"
+	"sync/atomic"
 
 	"github.com/projectdiscovery/dnsx/internal/runner"
 	"github.com/projectdiscovery/gologger"
@@ -17,13 +18,18 @@ func main() {
 		gologger.Fatal().Msgf("Could not create runner: %s\n", err)
 	}
 
-	// Setup graceful exits
+	// Setup graceful exits: the in-flight queries are canceled and the runner is closed once the workers returned
+	var interrupted atomic.Bool
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	go func() {
 		for range c {
+			if interrupted.Swap(true) {
+				// a second CTRL+C doesn't wait for the workers
+				os.Exit(1)
+			}
 			gologger.Info().Msgf("CTRL+C pressed: Exiting\n")
-			dnsxRunner.Close()
+			// the position is saved before the canceled workers drain the remaining targets
 			if options.ShouldSaveResume() {
 				gologger.Info().Msgf("Creating resume file: %s\n", runner.DefaultResumeFile)
 				err := dnsxRunner.SaveResumeConfig()
@@ -31,11 +37,16 @@ func main() {
 					gologger.Error().Msgf("Couldn't create resume file: %s\n", err)
 				}
 			}
-			os.Exit(1)
+			dnsxRunner.Cancel()
 		}
 	}()
 
-	// nolint:errcheck
-	dnsxRunner.Run()
+	err = dnsxRunner.Run()
 	dnsxRunner.Close()
+	if interrupted.Load() {
+		os.Exit(1)
+	}
+	if err != nil {
+		gologger.Fatal().Msgf("Could not run dnsx: %s\n", err)
+	}
 }
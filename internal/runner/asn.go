@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	asnmap "github.com/projectdiscovery/asnmap/libs"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/mapcidr"
+	"github.com/projectdiscovery/mapcidr/asn"
+)
+
+// asnPrefixFilter restricts the prefixes expanded from an ASN input
+type asnPrefixFilter struct {
+	sizes map[int]struct{}
+	cidrs []*net.IPNet
+}
+
+// parseAsnPrefixFilter parses a comma separated list of prefix sizes (eg. /24) and cidrs (eg. 10.0.0.0/8)
+func parseAsnPrefixFilter(value string) (*asnPrefixFilter, error) {
+	filter := &asnPrefixFilter{sizes: make(map[int]struct{})}
+	for _, item := range strings.Split(value, Comma) {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			continue
+		case strings.HasPrefix(item, "/"):
+			size, err := strconv.Atoi(strings.TrimPrefix(item, "/"))
+			if err != nil || size < 0 || size > 128 {
+				return nil, errors.Errorf("invalid asn prefix size: %s", item)
+			}
+			filter.sizes[size] = struct{}{}
+		default:
+			_, cidr, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, errors.Errorf("invalid asn prefix cidr: %s", item)
+			}
+			filter.cidrs = append(filter.cidrs, cidr)
+		}
+	}
+	if len(filter.sizes) == 0 && len(filter.cidrs) == 0 {
+		return nil, errors.New("empty asn prefix filter")
+	}
+	return filter, nil
+}
+
+// apply returns the portion of the prefixes allowed by the filter, without the ranges overlapping: an asn often
+// announces a range with more specific prefixes within it, and several filter cidrs can fall in one prefix
+func (f *asnPrefixFilter) apply(prefixes []*net.IPNet) []*net.IPNet {
+	return dedupeCIDRs(f.filter(prefixes))
+}
+
+// filter returns the prefixes of the allowed sizes, cut down to the filter cidrs
+func (f *asnPrefixFilter) filter(prefixes []*net.IPNet) []*net.IPNet {
+	var filtered []*net.IPNet
+	for _, prefix := range prefixes {
+		if len(f.sizes) > 0 {
+			size, _ := prefix.Mask.Size()
+			if _, ok := f.sizes[size]; !ok {
+				continue
+			}
+		}
+		if len(f.cidrs) == 0 {
+			filtered = append(filtered, prefix)
+			continue
+		}
+		prefixSize, _ := prefix.Mask.Size()
+		for _, cidr := range f.cidrs {
+			cidrSize, _ := cidr.Mask.Size()
+			// the prefix is fully contained within the filter
+			if cidr.Contains(prefix.IP) && cidrSize <= prefixSize {
+				filtered = append(filtered, prefix)
+				break
+			}
+			// the filter is a subset of the prefix
+			if prefix.Contains(cidr.IP) && prefixSize < cidrSize {
+				filtered = append(filtered, cidr)
+			}
+		}
+	}
+	return filtered
+}
+
+// dedupeCIDRs drops the cidrs contained in another one of the list, keeping the first of the identical ones
+func dedupeCIDRs(cidrs []*net.IPNet) []*net.IPNet {
+	var deduped []*net.IPNet
+	for i, cidr := range cidrs {
+		if !containedInOther(cidrs, i) {
+			deduped = append(deduped, cidr)
+		}
+	}
+	return deduped
+}
+
+// containedInOther reports whether the cidr at index i is within a wider cidr of the list or repeats an earlier one
+func containedInOther(cidrs []*net.IPNet, i int) bool {
+	size, bits := cidrs[i].Mask.Size()
+	for j, other := range cidrs {
+		if j == i {
+			continue
+		}
+		otherSize, otherBits := other.Mask.Size()
+		if otherBits != bits || !other.Contains(cidrs[i].IP) {
+			continue
+		}
+		if otherSize < size || (otherSize == size && j < i) {
+			return true
+		}
+	}
+	return false
+}
+
+// asnIPAddressesAsStream returns the ip addresses of the asn prefixes allowed by the configured filter
+func (r *Runner) asnIPAddressesAsStream(value string) (chan string, error) {
+	if r.options.asnPrefixFilter == nil {
+		return asn.GetIPAddressesAsStream(value)
+	}
+
+	prefixes, err := asn.GetCIDRsForASNNum(value)
+	if err != nil {
+		return nil, err
+	}
+	prefixes = r.options.asnPrefixFilter.apply(prefixes)
+
+	ret := make(chan string)
+	go func() {
+		defer close(ret)
+		for _, prefix := range prefixes {
+			ips, err := mapcidr.IPAddressesAsStream(prefix.String())
+			if err != nil {
+				gologger.Warning().Msgf("Could not expand asn prefix %s: %s\n", prefix, err)
+				continue
+			}
+			for ip := range ips {
+				ret <- ip
+			}
+		}
+	}()
+	return ret, nil
+}
+
+// lookupASN returns the autonomous system of the first ip found in the asn database, nil if none is
+func lookupASN(ips []string) *dnsx.AsnResponse {
+	for _, ip := range ips {
+		if results, err := asnmap.DefaultClient.GetData(ip); err == nil && len(results) > 0 {
+			return asnResponse(results)
+		}
+	}
+	return nil
+}
+
+// asnResponse returns the autonomous system of the first result with the ranges of all the results
+func asnResponse(results []*asnmap.Response) *dnsx.AsnResponse {
+	cidrs, _ := asnmap.GetCIDR(results)
+	response := &dnsx.AsnResponse{
+		AsNumber:  fmt.Sprintf("AS%v", results[0].ASN),
+		AsName:    results[0].Org,
+		AsCountry: results[0].Country,
+	}
+	for _, cidr := range cidrs {
+		response.AsRange = append(response.AsRange, cidr.String())
+	}
+	return response
+}
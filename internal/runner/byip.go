@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/hmap/store/hybrid"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// ipIndexEntry is the json form of the hosts pointing to an address
+type ipIndexEntry struct {
+	IP    string   `json:"ip"`
+	Hosts []string `json:"hosts"`
+}
+
+// ipIndex maps the resolved addresses to the hosts pointing to them, in memory or on disk for large scans
+type ipIndex struct {
+	hosts *hybrid.HybridMap
+	mutex sync.Mutex
+}
+
+func newIPIndex(disk bool) (*ipIndex, error) {
+	options := hybrid.DefaultMemoryOptions
+	if disk {
+		options = hybrid.DefaultDiskOptions
+	}
+	hosts, err := hybrid.New(options)
+	if err != nil {
+		return nil, err
+	}
+	return &ipIndex{hosts: hosts}, nil
+}
+
+// add records the host as pointing to the addresses
+func (i *ipIndex) add(host string, ips ...string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for _, ip := range ips {
+		var hosts []string
+		if data, ok := i.hosts.Get(ip); ok {
+			hosts = strings.Split(string(data), "\n")
+		}
+		if sliceutil.Contains(hosts, host) {
+			continue
+		}
+		hosts = append(hosts, host)
+		_ = i.hosts.Set(ip, []byte(strings.Join(hosts, "\n")))
+	}
+}
+
+// scan calls fn with each address and its sorted hosts, ipv4 addresses first in numerical order
+func (i *ipIndex) scan(fn func(ip string, hosts []string)) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	var addrs []netip.Addr
+	i.hosts.Scan(func(k, _ []byte) error {
+		if addr, err := netip.ParseAddr(string(k)); err == nil {
+			addrs = append(addrs, addr)
+		}
+		return nil
+	})
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Less(addrs[j])
+	})
+	for _, addr := range addrs {
+		data, ok := i.hosts.Get(addr.String())
+		if !ok {
+			continue
+		}
+		hosts := strings.Split(string(data), "\n")
+		sort.Strings(hosts)
+		fn(addr.String(), hosts)
+	}
+}
+
+func (i *ipIndex) close() {
+	i.hosts.Close()
+}
+
+// outputIPIndex writes each resolved address with the hosts pointing to it
+func (r *Runner) outputIPIndex() {
+	r.startOutputWorker()
+	r.ipIndex.scan(func(ip string, hosts []string) {
+		if r.options.JSON {
+			data, _ := json.Marshal(ipIndexEntry{IP: ip, Hosts: hosts})
+			r.outputchan <- string(data)
+			return
+		}
+		r.outputchan <- fmt.Sprintf("%s [%s]", ip, strings.Join(hosts, ","))
+	})
+	close(r.outputchan)
+	r.wgoutputworker.Wait()
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"strings"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/pkg/errors"
+)
+
+// colorScheme defines the colors used for the record type label and the records of each type
+type colorScheme struct {
+	label   aurora.Color
+	record  aurora.Color
+	records map[string]aurora.Color
+}
+
+var colorNames = map[string]aurora.Color{
+	"black":          aurora.BlackFg,
+	"red":            aurora.RedFg,
+	"green":          aurora.GreenFg,
+	"yellow":         aurora.YellowFg,
+	"blue":           aurora.BlueFg,
+	"magenta":        aurora.MagentaFg,
+	"cyan":           aurora.CyanFg,
+	"white":          aurora.WhiteFg,
+	"bright-black":   aurora.BrightFg | aurora.BlackFg,
+	"bright-red":     aurora.BrightFg | aurora.RedFg,
+	"bright-green":   aurora.BrightFg | aurora.GreenFg,
+	"bright-yellow":  aurora.BrightFg | aurora.YellowFg,
+	"bright-blue":    aurora.BrightFg | aurora.BlueFg,
+	"bright-magenta": aurora.BrightFg | aurora.MagentaFg,
+	"bright-cyan":    aurora.BrightFg | aurora.CyanFg,
+	"bright-white":   aurora.BrightFg | aurora.WhiteFg,
+}
+
+// colorSchemePresets contains the built-in color schemes
+var colorSchemePresets = map[string]colorScheme{
+	"default": {label: aurora.MagentaFg, record: aurora.GreenFg},
+	// blue and orange/yellow are distinguishable with the most common color vision deficiencies
+	"colorblind": {label: aurora.BrightFg | aurora.BlueFg, record: aurora.BrightFg | aurora.YellowFg},
+}
+
+var defaultColorScheme = colorSchemePresets["default"]
+
+// parseColorScheme parses a preset name optionally followed by type=color overrides (eg. colorblind,mx=cyan,type=white)
+func parseColorScheme(value string) (*colorScheme, error) {
+	scheme := defaultColorScheme
+	scheme.records = make(map[string]aurora.Color)
+	for i, item := range strings.Split(value, Comma) {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		key, colorName, isOverride := strings.Cut(item, "=")
+		if !isOverride {
+			preset, ok := colorSchemePresets[key]
+			if !ok || i > 0 {
+				return nil, errors.Errorf("invalid color scheme preset: %s", key)
+			}
+			scheme.label, scheme.record = preset.label, preset.record
+			continue
+		}
+		color, ok := colorNames[colorName]
+		if !ok {
+			return nil, errors.Errorf("invalid color: %s", colorName)
+		}
+		if key == "type" {
+			scheme.label = color
+		} else {
+			scheme.records[strings.ToUpper(key)] = color
+		}
+	}
+	return &scheme, nil
+}
+
+func (r *Runner) colorizeType(queryType string) aurora.Value {
+	scheme := r.options.colorScheme
+	if scheme == nil {
+		scheme = &defaultColorScheme
+	}
+	return r.aurora.Colorize(queryType, scheme.label)
+}
+
+func (r *Runner) colorizeRecord(queryType, record string) aurora.Value {
+	scheme := r.options.colorScheme
+	if scheme == nil {
+		scheme = &defaultColorScheme
+	}
+	if color, ok := scheme.records[queryType]; ok {
+		return r.aurora.Colorize(record, color)
+	}
+	return r.aurora.Colorize(record, scheme.record)
+}
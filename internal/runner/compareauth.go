@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/projectdiscovery/gologger"
+)
+
+// outputAuthComparison displays the records of the host returned by the recursive resolvers next to
+// the ones returned by the authoritative servers of its zone. The hosts without authoritative servers
+// are reported as unknown, and the ones whose servers could not be found or queried as errors
+func (r *Runner) outputAuthComparison(domain string, dnsData *dnsx.ResponseData) {
+	questionTypes := r.questionTypesFor(domain)
+	var comparison *dnsx.AuthoritativeComparison
+	if zone, err := r.authServers.Find(domain); err != nil {
+		gologger.Verbose().Msgf("%s: could not find the authoritative servers: %s\n", domain, err)
+		comparison = &dnsx.AuthoritativeComparison{Status: dnsx.AuthStatusError, Error: err.Error(), Recursive: dnsData.Records(questionTypes)}
+	} else {
+		if comparison, err = r.dnsx.CompareAuthoritative(domain, zone, dnsData, questionTypes); err != nil {
+			gologger.Verbose().Msgf("%s: could not query the authoritative servers of %s: %s\n", domain, zone.Name, err)
+		}
+	}
+	dnsData.CompareAuth = comparison
+	if r.options.JSON {
+		r.outputStructured(dnsData)
+		return
+	}
+	recursive := "[recursive: " + strings.Join(comparison.Recursive, ",") + "]"
+	switch comparison.Status {
+	case dnsx.AuthStatusUnknown:
+		r.outputchan <- fmt.Sprintf("%s [%s] %s [no authoritative servers]", domain, r.aurora.Yellow("UNKNOWN"), recursive)
+	case dnsx.AuthStatusError:
+		r.outputchan <- fmt.Sprintf("%s [%s] %s [error: %s]", domain, r.aurora.Red("ERROR"), recursive, comparison.Error)
+	default:
+		status := r.aurora.Green("MATCH").String()
+		if comparison.Status == dnsx.AuthStatusDiff {
+			status = r.aurora.Red("DIFF").String()
+		}
+		r.outputchan <- fmt.Sprintf("%s [%s] %s [authoritative: %s]", domain, status, recursive, strings.Join(comparison.Authoritative, ","))
+	}
+}
@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+)
+
+// confirmations returns the responses of the other resolvers queried to confirm the records of the
+// host, reusing the agreement response. Hosts pinned to a resolver are never confirmed
+func (r *Runner) confirmations(domain, resolver string, resolvers []string, agreementData *retryabledns.DNSData) []*retryabledns.DNSData {
+	if resolver != "" {
+		return nil
+	}
+	var confirmations []*retryabledns.DNSData
+	if agreementData != nil {
+		confirmations = append(confirmations, agreementData)
+		resolvers = append(resolvers, agreementData.Resolver...)
+	}
+	for len(confirmations) < r.options.ConfidenceMin-1 {
+		data, _ := r.dnsx.QueryMultipleExcluding(domain, resolvers)
+		if data == nil || len(data.Resolver) == 0 {
+			break
+		}
+		confirmations = append(confirmations, data)
+		resolvers = append(resolvers, data.Resolver...)
+	}
+	return confirmations
+}
+
+// confidenceAnnotation returns the colored marker of the confidence level
+func (r *Runner) confidenceAnnotation(level string) string {
+	switch level {
+	case dnsx.ConfidenceHigh:
+		return fmt.Sprintf(" [%s]", r.aurora.Green(level))
+	case dnsx.ConfidenceMedium:
+		return fmt.Sprintf(" [%s]", r.aurora.Yellow(level))
+	case dnsx.ConfidenceLow:
+		return fmt.Sprintf(" [%s]", r.aurora.Red(level))
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+)
+
+// deferredBacklog is the number of resolved hosts queued per resolve worker while the deferred lookups are busy
+const deferredBacklog = 10
+
+// deferredResponse is a resolved response waiting for its slow lookups (trace, whois)
+type deferredResponse struct {
+	domain  string
+	dnsData dnsx.ResponseData
+}
+
+// deferredThreads returns the number of workers running the slow lookups, 0 when none is requested. The whois
+// lookups are rate limited on their own, so they keep the concurrency of the resolution without tracing
+func (r *Runner) deferredThreads() int {
+	switch {
+	case r.options.Trace:
+		return r.options.TraceThreads
+	case r.whoisLookup != nil:
+		return r.options.Threads
+	}
+	return 0
+}
+
+// startDeferredWorkers starts the pool running the slow lookups of the resolved hosts apart from the resolve
+// workers. The queue lets the resolve workers run ahead of the lookups, up to the backlog of each worker
+func (r *Runner) startDeferredWorkers() {
+	threads := r.deferredThreads()
+	if threads == 0 {
+		return
+	}
+	r.deferredchan = make(chan deferredResponse, r.options.Threads*deferredBacklog)
+	for i := 0; i < threads; i++ {
+		r.wgdeferredworkers.Add(1)
+		go r.deferredWorker()
+	}
+}
+
+func (r *Runner) deferredWorker() {
+	defer r.wgdeferredworkers.Done()
+	for deferred := range r.deferredchan {
+		dnsData := deferred.dnsData
+		if r.ctx.Err() != nil {
+			continue
+		}
+		if r.options.Trace {
+			r.addTrace(deferred.domain, &dnsData)
+		}
+		r.processResponse(deferred.domain, &dnsData)
+	}
+}
+
+// waitWorkers waits for the resolve workers and then for the pending deferred lookups
+func (r *Runner) waitWorkers() {
+	r.wgresolveworkers.Wait()
+	if r.deferredchan != nil {
+		close(r.deferredchan)
+		r.wgdeferredworkers.Wait()
+		r.deferredchan = nil
+	}
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// delayRange is the range of the random pause taken by each worker before a query
+type delayRange struct {
+	min time.Duration
+	max time.Duration
+}
+
+// parseDelayRange parses a single delay or a range of delays (eg. 200ms, 100-500ms, 1s-2s). A bound
+// without unit takes the unit of the upper bound, bare numbers are seconds
+func parseDelayRange(value string) (*delayRange, error) {
+	bounds := strings.SplitN(value, "-", 2)
+	unit := strings.TrimLeft(bounds[len(bounds)-1], "0123456789.")
+	if unit == "" {
+		unit = "s"
+	}
+	var durations []time.Duration
+	for _, bound := range bounds {
+		bound = strings.TrimSpace(bound)
+		if strings.Trim(bound, "0123456789.") == "" {
+			bound += unit
+		}
+		duration, err := time.ParseDuration(bound)
+		if err != nil || duration < 0 {
+			return nil, errors.Errorf("invalid delay %q (eg. 200ms, 100-500ms)", value)
+		}
+		durations = append(durations, duration)
+	}
+	delay := &delayRange{min: durations[0], max: durations[len(durations)-1]}
+	if delay.min > delay.max {
+		return nil, errors.Errorf("invalid delay %q, the lower bound is greater than the upper bound", value)
+	}
+	return delay, nil
+}
+
+// next returns a random delay within the range
+func (d *delayRange) next() time.Duration {
+	if d.max == d.min {
+		return d.min
+	}
+	return d.min + time.Duration(rand.Int63n(int64(d.max-d.min)+1))
+}
+
+// sleepDelay pauses the worker for a random delay, returning early once the run is canceled
+func (r *Runner) sleepDelay() {
+	if r.options.delay == nil {
+		return
+	}
+	timer := time.NewTimer(r.options.delay.next())
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.ctx.Done():
+	}
+}
@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// DefaultDotMaxEdges is the default maximum number of edges of the dot graph
+const DefaultDotMaxEdges = 10000
+
+// kinds of the dot graph edges
+const (
+	dotEdgeCNAME = "CNAME"
+	dotEdgeNS    = "NS"
+)
+
+// dotEdge is a cname or ns pointer between two names
+type dotEdge struct {
+	from string
+	to   string
+	kind string
+}
+
+// dotGraph accumulates the cname and ns relationships to render them as a graphviz graph
+type dotGraph struct {
+	path     string
+	maxEdges int
+	edges    []dotEdge
+	seen     map[dotEdge]struct{}
+	dropped  int
+	mutex    sync.Mutex
+}
+
+func newDotGraph(path string, maxEdges int) *dotGraph {
+	return &dotGraph{path: path, maxEdges: maxEdges, seen: make(map[dotEdge]struct{})}
+}
+
+// add records the cname chain and the nameservers of the host
+func (g *dotGraph) add(host string, cnames, nameservers []string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	from := host
+	for _, cname := range cnames {
+		g.addEdge(dotEdge{from: from, to: cname, kind: dotEdgeCNAME})
+		from = cname
+	}
+	for _, nameserver := range nameservers {
+		g.addEdge(dotEdge{from: host, to: nameserver, kind: dotEdgeNS})
+	}
+}
+
+func (g *dotGraph) addEdge(edge dotEdge) {
+	edge.from, edge.to = normalizeDotName(edge.from), normalizeDotName(edge.to)
+	if _, ok := g.seen[edge]; ok {
+		return
+	}
+	// the graph is bounded so that huge scans still render
+	if g.maxEdges > 0 && len(g.edges) >= g.maxEdges {
+		g.dropped++
+		return
+	}
+	g.seen[edge] = struct{}{}
+	g.edges = append(g.edges, edge)
+}
+
+// write renders the graph to the dot file
+func (g *dotGraph) write() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	file, err := os.Create(g.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "digraph dnsx {")
+	fmt.Fprintln(w, "\trankdir=LR;")
+	for _, edge := range g.edges {
+		style := ""
+		if edge.kind == dotEdgeNS {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(w, "\t%s -> %s [label=%q%s];\n", quoteDotID(edge.from), quoteDotID(edge.to), edge.kind, style)
+	}
+	fmt.Fprintln(w, "}")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if g.dropped > 0 {
+		gologger.Warning().Msgf("Dot graph limited to %d edges, %d edges dropped\n", g.maxEdges, g.dropped)
+	}
+	return nil
+}
+
+func normalizeDotName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// quoteDotID returns the name as a double quoted dot identifier
+func quoteDotID(name string) string {
+	name = strings.ReplaceAll(name, `\`, `\\`)
+	return `"` + strings.ReplaceAll(name, `"`, `\"`) + `"`
+}
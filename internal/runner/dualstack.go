@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+)
+
+// reverseChecks checks the reverse dns of the addresses, each address once however many hosts share it
+type reverseChecks struct {
+	check   func(ip string) dnsx.ReverseCheck
+	entries map[string]*reverseCheckEntry
+	mutex   sync.Mutex
+}
+
+// reverseCheckEntry is an address checked by the first host resolving to it, the others waiting for it
+type reverseCheckEntry struct {
+	once  sync.Once
+	check dnsx.ReverseCheck
+}
+
+func newReverseChecks(check func(ip string) dnsx.ReverseCheck) *reverseChecks {
+	return &reverseChecks{check: check, entries: make(map[string]*reverseCheckEntry)}
+}
+
+// lookup returns the reverse checks of the addresses
+func (c *reverseChecks) lookup(ips []string) []dnsx.ReverseCheck {
+	checks := make([]dnsx.ReverseCheck, 0, len(ips))
+	for _, ip := range ips {
+		c.mutex.Lock()
+		entry, ok := c.entries[ip]
+		if !ok {
+			entry = &reverseCheckEntry{}
+			c.entries[ip] = entry
+		}
+		c.mutex.Unlock()
+
+		entry.once.Do(func() { entry.check = c.check(ip) })
+		checks = append(checks, entry.check)
+	}
+	return checks
+}
+
+// dualStack returns the reverse dns report of the A and AAAA addresses of the host, nil if it has none
+func (c *reverseChecks) dualStack(ipv4, ipv6 []string) *dnsx.DualStack {
+	if len(ipv4) == 0 && len(ipv6) == 0 {
+		return nil
+	}
+	return dnsx.NewDualStack(c.lookup(ipv4), c.lookup(ipv6))
+}
+
+// outputDualStack writes a line per address of each family with its ptr names and fcrdns status, or a missing
+// line for the family without address
+func (r *Runner) outputDualStack(domain string, dualStack *dnsx.DualStack) {
+	families := []struct {
+		label     string
+		queryType string
+		checks    []dnsx.ReverseCheck
+	}{
+		{"ipv4", "A", dualStack.IPv4},
+		{"ipv6", "AAAA", dualStack.IPv6},
+	}
+	domain = r.displayName(domain)
+	for _, family := range families {
+		if len(family.checks) == 0 {
+			r.outputRecordLine(family.queryType, fmt.Sprintf("%s [%s] [%s] [%s]", domain, r.aurora.Cyan("dualstack"), family.label, r.aurora.Yellow("missing")))
+			continue
+		}
+		for _, check := range family.checks {
+			status := r.aurora.Green("fcrdns")
+			switch {
+			case len(check.PTR) == 0:
+				status = r.aurora.Red("no-ptr")
+			case !check.Confirmed:
+				status = r.aurora.Red("fcrdns-fail")
+			}
+			line := fmt.Sprintf("%s [%s] [%s] %s", domain, r.aurora.Cyan("dualstack"), family.label, check.IP)
+			if len(check.PTR) > 0 {
+				line += fmt.Sprintf(" [ptr: %s]", strings.Join(check.PTR, ","))
+			}
+			r.outputRecordLine(family.queryType, fmt.Sprintf("%s [%s]", line, status))
+		}
+	}
+}
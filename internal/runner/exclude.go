@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// resolverExclusions contains the resolver ips and networks that must never be used
+type resolverExclusions struct {
+	ips      map[string]struct{}
+	networks []*net.IPNet
+}
+
+// loadResolverExclusions reads the exclusions from a file or a comma separated list
+func loadResolverExclusions(value string) (*resolverExclusions, error) {
+	var items []string
+	if fileutil.FileExists(value) {
+		lines, err := linesInFile(value)
+		if err != nil {
+			return nil, err
+		}
+		items = lines
+	} else {
+		items = strings.Split(value, Comma)
+	}
+
+	exclusions := &resolverExclusions{ips: make(map[string]struct{})}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			_, network, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, errors.Errorf("invalid resolver exclusion: %s", item)
+			}
+			exclusions.networks = append(exclusions.networks, network)
+			continue
+		}
+		if ip := net.ParseIP(item); ip != nil {
+			item = ip.String()
+		}
+		exclusions.ips[item] = struct{}{}
+	}
+	return exclusions, nil
+}
+
+// Contains checks if the resolver host matches any exclusion
+func (e *resolverExclusions) Contains(host string) bool {
+	ip := net.ParseIP(host)
+	if ip != nil {
+		host = ip.String()
+	}
+	if _, ok := e.ips[host]; ok {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, network := range e.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the resolvers not matching any exclusion
+func (e *resolverExclusions) filter(resolvers []string) []string {
+	var filtered []string
+	for _, resolver := range resolvers {
+		if !e.Contains(resolverHost(resolver)) {
+			filtered = append(filtered, resolver)
+		}
+	}
+	return filtered
+}
+
+// resolverHost extracts the host from a resolver in the [protocol:]host[:port] or quic://host[:port] form
+func resolverHost(resolver string) string {
+	if dnsx.IsDoQResolver(resolver) {
+		resolver = resolver[len("quic://"):]
+	}
+	if len(resolver) > 4 && resolver[3] == ':' {
+		switch resolver[:3] {
+		case "doh":
+			u, err := url.Parse(resolver[4:])
+			if err != nil {
+				return ""
+			}
+			return u.Hostname()
+		case "udp", "tcp", "dot":
+			resolver = resolver[4:]
+		}
+	}
+	if host, _, err := net.SplitHostPort(resolver); err == nil {
+		return host
+	}
+	return resolver
+}
+
+// isEncryptedResolver checks if the resolver uses doh or dot
+func isEncryptedResolver(resolver string) bool {
+	return strings.HasPrefix(resolver, "doh:") || strings.HasPrefix(resolver, "dot:") || dnsx.IsDoQResolver(resolver)
+}
@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	// DefaultExecFilterThreads is the default number of filter processes running at the same time
+	DefaultExecFilterThreads = 4
+	// DefaultExecFilterTimeout is the default time a filter process gets to transform a record
+	DefaultExecFilterTimeout = 10 * time.Second
+)
+
+// execFilter pipes the json records through an external command kept running as line oriented coprocesses,
+// each reading a record per line on its stdin and answering with the transformed record on a line of its
+// stdout. A pool of processes is started on demand, the workers waiting for an idle one, and a process which
+// exits, times out or answers garbage is killed and started again for the next record
+type execFilter struct {
+	command string
+	timeout time.Duration
+	// processes holds the idle processes, nil for the slots whose process is not running
+	processes chan *filterProcess
+	// failures counts the records kept unchanged as the command failed on them
+	failures atomic.Uint64
+}
+
+func newExecFilter(command string, concurrency int, timeout time.Duration) *execFilter {
+	f := &execFilter{command: command, timeout: timeout, processes: make(chan *filterProcess, concurrency)}
+	for i := 0; i < concurrency; i++ {
+		f.processes <- nil
+	}
+	return f
+}
+
+// apply returns the record transformed by the command, false when the command dropped it by answering an
+// empty line. The record is kept unchanged when the command fails, times out or writes invalid json
+func (f *execFilter) apply(record string) (string, bool) {
+	transformed, err := f.run(record)
+	if err != nil {
+		f.failures.Add(1)
+		gologger.Verbose().Msgf("exec-filter failed, record kept unchanged: %s\n", err)
+		return record, true
+	}
+	return transformed, transformed != ""
+}
+
+// run sends the record to an idle process and returns its answer compacted to a single json line
+func (f *execFilter) run(record string) (string, error) {
+	process := <-f.processes
+	defer func() { f.processes <- process }()
+
+	// a process which exited since its last record is started again
+	if process != nil && process.ended() {
+		process.kill()
+		process = nil
+	}
+	if process == nil {
+		var err error
+		if process, err = startFilterProcess(f.command); err != nil {
+			return "", err
+		}
+	}
+	output, err := process.transform(record, f.timeout)
+	if err != nil {
+		// the process is in an unknown state, a new one handles the next record
+		process.kill()
+		process = nil
+		return "", err
+	}
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return "", nil
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, output); err != nil {
+		return "", errors.Wrap(err, "invalid json output")
+	}
+	return compacted.String(), nil
+}
+
+// close ends the processes once they answered the records sent, killing those still running after the timeout
+func (f *execFilter) close() {
+	for i := 0; i < cap(f.processes); i++ {
+		if process := <-f.processes; process != nil {
+			process.close(f.timeout)
+		}
+	}
+}
+
+// filterProcess is a running filter command, its stdout lines being read in the background until eof
+type filterProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan []byte
+	eof    chan struct{}
+	exited chan struct{}
+}
+
+func startFilterProcess(command string) (*filterProcess, error) {
+	cmd := shellCommand(command)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	// the children of the shell may outlive it when killed and keep the output open
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &filterProcess{cmd: cmd, stdin: stdin, lines: make(chan []byte, 1), eof: make(chan struct{}), exited: make(chan struct{})}
+	go func() {
+		defer close(p.eof)
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			select {
+			case p.lines <- line:
+			case <-p.exited:
+				return
+			}
+		}
+	}()
+	return p, nil
+}
+
+// transform writes the record on a line and waits for the answer of the process
+func (p *filterProcess) transform(record string, timeout time.Duration) ([]byte, error) {
+	if _, err := io.WriteString(p.stdin, record+"\n"); err != nil {
+		return nil, errors.Wrap(err, "could not write record")
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case line := <-p.lines:
+		return line, nil
+	case <-p.eof:
+		// the answer may come right before the end of the output
+		select {
+		case line := <-p.lines:
+			return line, nil
+		default:
+			return nil, errors.New("process exited")
+		}
+	case <-timer.C:
+		return nil, errors.Errorf("timed out after %s", timeout)
+	}
+}
+
+// ended reports whether the process closed its output, having exited
+func (p *filterProcess) ended() bool {
+	select {
+	case <-p.eof:
+		return true
+	default:
+		return false
+	}
+}
+
+// kill ends the process right away
+func (p *filterProcess) kill() {
+	close(p.exited)
+	_ = p.stdin.Close()
+	_ = p.cmd.Process.Kill()
+	_ = p.cmd.Wait()
+}
+
+// close lets the process exit on the end of its input, killing it after the timeout
+func (p *filterProcess) close(timeout time.Duration) {
+	_ = p.stdin.Close()
+	done := make(chan struct{})
+	go func() {
+		_ = p.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		_ = p.cmd.Process.Kill()
+		<-done
+	}
+	close(p.exited)
+}
+
+// shellCommand runs the command line through the shell of the platform
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+)
+
+// expectations holds the record values expected for each input host
+type expectations struct {
+	values map[string][]string
+	mutex  sync.RWMutex
+}
+
+func newExpectations() *expectations {
+	return &expectations{values: make(map[string][]string)}
+}
+
+// set records the values expected for the host
+func (e *expectations) set(host string, values []string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.values[expectationKey(host)] = values
+}
+
+// get returns the values expected for the host
+func (e *expectations) get(host string) []string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return e.values[expectationKey(host)]
+}
+
+func expectationKey(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// splitExpectation splits an input line into the target and the values expected for it, separated by
+// whitespaces or commas (eg. example.com 93.184.216.34,2606:2800:220:1::)
+func splitExpectation(line string) (string, []string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	var values []string
+	for _, field := range fields[1:] {
+		for _, value := range strings.Split(field, ",") {
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return fields[0], values
+}
+
+// setExpectations records the values expected for the hosts in expect mode
+func (r *Runner) setExpectations(hosts []string, values []string) {
+	if r.expectations == nil {
+		return
+	}
+	for _, host := range hosts {
+		r.expectations.set(host, values)
+	}
+}
+
+// outputExpectation reports whether the records of the host match the expected values
+func (r *Runner) outputExpectation(domain string, dnsData *dnsx.ResponseData) {
+	dnsData.CheckExpectation(r.expectations.get(domain), r.questionTypesFor(domain))
+	if !dnsData.Expect.Pass {
+		r.expectFailures.Add(1)
+	}
+	if r.options.JSON {
+		r.outputStructured(dnsData)
+		return
+	}
+	if dnsData.Expect.Pass {
+		r.outputchan <- fmt.Sprintf("%s [%s]", domain, r.aurora.Green("PASS"))
+		return
+	}
+	r.outputchan <- fmt.Sprintf("%s [%s] [expected: %s] [actual: %s]", domain, r.aurora.Red("FAIL"), strings.Join(dnsData.Expect.Expected, ","), strings.Join(dnsData.Expect.Actual, ","))
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxLineSize is the default maximum size of an input line
+const DefaultMaxLineSize = 10 * 1024 * 1024
+
+// lineReader streams the lines of an input, stopping with an error on lines longer than the maximum size. The
+// input is closed once read, or by Close when the lines are not all consumed
+type lineReader struct {
+	lines chan string
+	err   error
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newLineReader(reader io.Reader, maxLineSize int) *lineReader {
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+	l := &lineReader{lines: make(chan string), done: make(chan struct{})}
+	go func() {
+		defer close(l.lines)
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+		scanner := bufio.NewScanner(reader)
+		// the larger of the capacity and the maximum bounds the lines
+		scanner.Buffer(make([]byte, 0, min(maxLineSize, 4096)), maxLineSize)
+		for scanner.Scan() {
+			select {
+			case l.lines <- scanner.Text():
+			case <-l.done:
+				return
+			}
+		}
+		l.err = scanner.Err()
+		if errors.Is(l.err, bufio.ErrTooLong) {
+			l.err = errors.Errorf("input line longer than %d bytes (use -max-line-size to increase the limit)", maxLineSize)
+		}
+	}()
+	return l
+}
+
+// newFileLineReader opens the file and streams its lines
+func newFileLineReader(filename string, maxLineSize int) (*lineReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return newLineReader(f, maxLineSize), nil
+}
+
+// Err returns the read error once all the lines have been consumed
+func (l *lineReader) Err() error {
+	return l.err
+}
+
+// Close stops the reading of the lines not consumed yet and closes the input
+func (l *lineReader) Close() {
+	l.once.Do(func() { close(l.done) })
+}
@@ -0,0 +1,272 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/hmap/store/hybrid"
+	fileutil "github.com/projectdiscovery/utils/file"
+	folderutil "github.com/projectdiscovery/utils/folder"
+)
+
+// DefaultMonitorInterval is the default time between the start of two monitor cycles
+const DefaultMonitorInterval = time.Hour
+
+// DefaultMonitorStateFile is the file keeping the records seen in monitor mode from one run to the next, in the
+// dnsx config directory rather than the working directory of the run
+var DefaultMonitorStateFile = filepath.Join(folderutil.AppConfigDirOrDefault(".", "dnsx"), "monitor.jsonl")
+
+// changes of the records reported in monitor mode
+const (
+	monitorAdded     = "added"
+	monitorRemoved   = "removed"
+	monitorUnchanged = "unchanged"
+)
+
+// monitorRecord is a record of a host with the times it was first and last seen, as kept in the state file
+type monitorRecord struct {
+	Host      string    `json:"host"`
+	Type      string    `json:"type"`
+	Value     string    `json:"value"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+func (m *monitorRecord) key() string {
+	return monitorTypeKey(m.Host, m.Type) + "\x00" + m.Value
+}
+
+func monitorTypeKey(host, questionType string) string {
+	return host + "\x00" + questionType
+}
+
+// monitorChange is a record that appeared or disappeared during a monitor cycle
+type monitorChange struct {
+	monitorRecord
+	Change string `json:"change"`
+}
+
+// monitor tracks the records of the hosts across the cycles of the monitor mode
+type monitor struct {
+	records    *hybrid.HybridMap
+	cycleStart time.Time
+
+	// answered holds the types queried for the hosts that answered during the cycle
+	mutex    sync.Mutex
+	answered map[string]struct{}
+
+	closeOnce sync.Once
+}
+
+// newMonitor returns a monitor starting from the records of the state file, if any
+func newMonitor(statePath string) (*monitor, error) {
+	records, err := hybrid.New(hybrid.DefaultDiskOptions)
+	if err != nil {
+		return nil, err
+	}
+	m := &monitor{records: records, answered: make(map[string]struct{})}
+	if statePath == "" || !fileutil.FileExists(statePath) {
+		return m, nil
+	}
+	if err := m.load(statePath); err != nil {
+		records.Close()
+		return nil, errors.Wrap(err, "could not load monitor state")
+	}
+	return m, nil
+}
+
+// beginCycle starts a cycle, the records not seen again in the cycle being removed at its end
+func (m *monitor) beginCycle() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.cycleStart = time.Now()
+	m.answered = make(map[string]struct{})
+}
+
+// observe records the current records of the queried types of the host and returns the ones never seen before,
+// and the others as well when all is set
+func (m *monitor) observe(host string, questionTypes []uint16, records []monitorRecord, seen time.Time, all bool) []monitorChange {
+	m.mutex.Lock()
+	for _, questionType := range questionTypes {
+		m.answered[monitorTypeKey(host, dns.TypeToString[questionType])] = struct{}{}
+	}
+	m.mutex.Unlock()
+
+	var changes []monitorChange
+	for _, record := range records {
+		change := monitorUnchanged
+		record.FirstSeen, record.LastSeen = seen, seen
+		if data, ok := m.records.Get(record.key()); ok {
+			var stored monitorRecord
+			if json.Unmarshal(data, &stored) == nil {
+				record.FirstSeen = stored.FirstSeen
+			}
+		} else {
+			change = monitorAdded
+		}
+		data, _ := json.Marshal(record)
+		_ = m.records.Set(record.key(), data)
+		if change == monitorAdded || all {
+			changes = append(changes, monitorChange{monitorRecord: record, Change: change})
+		}
+	}
+	return changes
+}
+
+// endCycle drops and returns the records of the hosts answering during the cycle that were not seen again, the
+// records of the hosts left without response and of the types no longer queried being kept
+func (m *monitor) endCycle() []monitorChange {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var changes []monitorChange
+	m.records.Scan(func(_, v []byte) error {
+		var record monitorRecord
+		if json.Unmarshal(v, &record) != nil || !record.LastSeen.Before(m.cycleStart) {
+			return nil
+		}
+		if _, ok := m.answered[monitorTypeKey(record.Host, record.Type)]; ok {
+			changes = append(changes, monitorChange{monitorRecord: record, Change: monitorRemoved})
+		}
+		return nil
+	})
+	for _, change := range changes {
+		_ = m.records.Del(change.key())
+	}
+	return changes
+}
+
+// load reads the records of the state file, one json record per line
+func (m *monitor) load(statePath string) error {
+	file, err := os.Open(statePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record monitorRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return err
+		}
+		_ = m.records.Set(record.key(), scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+// save writes the records to the state file, replaced once complete. It is called at the end of the completed
+// cycles only, an interrupted cycle having updated the records of part of the hosts
+func (m *monitor) save(statePath string) error {
+	if err := os.MkdirAll(filepath.Dir(statePath), 0700); err != nil {
+		return err
+	}
+	tmpPath := statePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	m.records.Scan(func(_, v []byte) error {
+		_, _ = w.Write(v)
+		return w.WriteByte('\n')
+	})
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, statePath)
+}
+
+// close releases the records, only once as an interrupted run is closed by both the signal handler and the end
+// of the run
+func (m *monitor) close() {
+	m.closeOnce.Do(func() {
+		m.records.Close()
+	})
+}
+
+// monitorRecords returns the records of the queried types in the response
+func monitorRecords(host string, questionTypes []uint16, dnsData *dnsx.ResponseData) []monitorRecord {
+	var records []monitorRecord
+	for _, questionType := range questionTypes {
+		for _, value := range dnsData.Records([]uint16{questionType}) {
+			records = append(records, monitorRecord{Host: host, Type: dns.TypeToString[questionType], Value: strings.ToLower(value)})
+		}
+	}
+	return records
+}
+
+// outputMonitorChanges writes the changes as json lines or as host [change] [type] value lines
+func (r *Runner) outputMonitorChanges(changes []monitorChange) {
+	for _, change := range changes {
+		if r.options.JSON {
+			data, _ := json.Marshal(change)
+			r.outputchan <- string(data)
+			continue
+		}
+		label := r.aurora.Cyan(change.Change)
+		switch change.Change {
+		case monitorAdded:
+			label = r.aurora.Green(change.Change)
+		case monitorRemoved:
+			label = r.aurora.Red(change.Change)
+		}
+		r.outputchan <- fmt.Sprintf("%s [%s] [%s] %s", r.displayName(change.Host), label, r.colorizeType(change.Type), change.Value)
+	}
+}
+
+// runMonitor repeats the scan of the prepared targets every interval until the run is canceled, outputting the
+// records appearing and disappearing in each cycle
+func (r *Runner) runMonitor() error {
+	if err := r.prepareInput(); err != nil {
+		return err
+	}
+	for cycle := 1; ; cycle++ {
+		r.monitor.beginCycle()
+		if cycle > 1 {
+			r.workerchan = make(chan string, workerchanSize(r.options))
+		}
+		r.startWorkers()
+		r.waitWorkers()
+		// an interrupted cycle would report the records of the hosts not queried yet as removed, and its partial
+		// state is not saved, the state file keeping the last completed cycle
+		interrupted := r.ctx.Err() != nil
+		if !interrupted {
+			r.outputMonitorChanges(r.monitor.endCycle())
+		}
+		close(r.outputchan)
+		r.wgoutputworker.Wait()
+		if interrupted {
+			return nil
+		}
+		if r.options.MonitorState != "" {
+			if err := r.monitor.save(r.options.MonitorState); err != nil {
+				gologger.Error().Msgf("Could not write monitor state %s: %s\n", r.options.MonitorState, err)
+			}
+		}
+
+		next := r.monitor.cycleStart.Add(r.options.MonitorInterval)
+		gologger.Info().Msgf("Monitor cycle %d completed, next cycle at %s\n", cycle, next.Format(time.RFC3339))
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
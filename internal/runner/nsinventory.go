@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+)
+
+// nsInventory resolves the nameservers of the domains, each nameserver once however many domains share it
+type nsInventory struct {
+	resolve func(name string) dnsx.Nameserver
+	entries map[string]*nsInventoryEntry
+	mutex   sync.Mutex
+}
+
+// nsInventoryEntry is a nameserver resolved by the first domain returning it, the others waiting for it
+type nsInventoryEntry struct {
+	once       sync.Once
+	nameserver dnsx.Nameserver
+}
+
+func newNSInventory(resolve func(name string) dnsx.Nameserver) *nsInventory {
+	return &nsInventory{resolve: resolve, entries: make(map[string]*nsInventoryEntry)}
+}
+
+// lookup returns the resolved nameservers, deduplicated and in name order
+func (i *nsInventory) lookup(nameservers []string) []dnsx.Nameserver {
+	names := make(map[string]struct{})
+	for _, nameserver := range nameservers {
+		names[dnsx.NormalizeNameserver(nameserver)] = struct{}{}
+	}
+	inventory := make([]dnsx.Nameserver, 0, len(names))
+	for name := range names {
+		i.mutex.Lock()
+		entry, ok := i.entries[name]
+		if !ok {
+			entry = &nsInventoryEntry{}
+			i.entries[name] = entry
+		}
+		i.mutex.Unlock()
+
+		entry.once.Do(func() { entry.nameserver = i.resolve(name) })
+		inventory = append(inventory, entry.nameserver)
+	}
+	sort.Slice(inventory, func(a, b int) bool { return inventory[a].Name < inventory[b].Name })
+	return inventory
+}
@@ -1,12 +1,18 @@
 package runner
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
 	"github.com/projectdiscovery/goconfig"
 	"github.com/projectdiscovery/goflags"
 	"github.com/projectdiscovery/gologger"
@@ -18,7 +24,10 @@ import (
 )
 
 const (
-	DefaultResumeFile = "resume.cfg"
+	DefaultResumeFile   = "resume.cfg"
+	defaultDoHUserAgent = "dnsx/" + version
+	idnDisplayUnicode   = "unicode"
+	idnDisplayBoth      = "both"
 )
 
 var PDCPApiKey string
@@ -30,11 +39,15 @@ type Options struct {
 	WordList           string
 	Threads            int
 	RateLimit          int
+	Delay              string
+	delay              *delayRange
 	Retries            int
 	OutputFormat       string
 	OutputFile         string
+	CompressOutput     bool
 	Raw                bool
 	Silent             bool
+	Quiet              bool
 	Verbose            bool
 	Version            bool
 	NoColor            bool
@@ -53,19 +66,36 @@ type Options struct {
 	AXFR               bool
 	JSON               bool
 	OmitRaw            bool
+	JSONCompact        bool
+	ExecFilter         string
+	ExecFilterThreads  int
+	ExecFilterTimeout  time.Duration
 	Trace              bool
 	TraceMaxRecursion  int
+	TraceThreads       int
 	WildcardThreshold  int
 	WildcardDomain     string
 	ShowStatistics     bool
 	rcodes             map[int]struct{}
 	RCode              string
 	hasRCodes          bool
+	ServFail           bool
+	Refused            bool
+	NXDomain           bool
 	Resume             bool
 	resumeCfg          *ResumeCfg
 	HostsFile          bool
 	Stream             bool
+	LowMemory          bool
 	CAA                bool
+	TLSA               bool
+	DNSKEY             bool
+	DS                 bool
+	HTTPS              bool
+	SVCB               bool
+	NAPTR              bool
+	SSHFP              bool
+	DNAME              bool
 	QueryAll           bool
 	ExcludeType        []string
 	OutputCDN          bool
@@ -73,6 +103,110 @@ type Options struct {
 	HealthCheck        bool
 	DisableUpdateCheck bool
 	PdcpAuth           string
+	AsnPrefixFilter    string
+	asnPrefixFilter    *asnPrefixFilter
+	ListTargets        bool
+	CNAMEChain         bool
+	QueryTimeout       time.Duration
+	TimeoutEscalation  bool
+	MaxQueryTimeout    time.Duration
+	AnnotateBogon      bool
+	Offline            bool
+	MaxAnswers         int
+	OutputSocket       string
+	OutputSinks        goflags.StringSlice
+	ColorScheme        string
+	colorScheme        *colorScheme
+	RetryNoData        bool
+	ExcludeResolvers   string
+	ResolversOut       string
+	AsnSummary         bool
+	Probe              bool
+	Expect             bool
+	CompareAuth        bool
+	Confidence         bool
+	ConfidenceMin      int
+	ConfidenceRetries  int
+	RequireAgreement   bool
+	DetectNXHijack     bool
+	SizeStats          bool
+	ResolverStats      bool
+	EDNSVersion        int
+	Padding            int
+	NSID               bool
+	BootstrapResolver  string
+	MsgPack            bool
+	DetectSpoof        bool
+	QueryType          []string
+	OutputOrder        string
+	FlagMultiASN       bool
+	Whois              bool
+	WhoisRateLimit     int
+	Prime              bool
+	RetryFile          string
+	DoHUserAgent       string
+	DoTInsecure        bool
+	SOAHealth          bool
+	WildcardTCP        bool
+	WildcardCacheDir   string
+	WildcardCacheTTL   time.Duration
+	WildcardReprobe    bool
+	Hierarchy          bool
+	TypePriority       []string
+	NSSummary          bool
+	ExternalDeps       bool
+	NSSummaryResolve   bool
+	NSInventory        bool
+	DualStackCheck     bool
+	MaxLineSize        goflags.Size
+	ShowCoverage       bool
+	CollapseCIDR       bool
+	CollapseCIDRApprox bool
+	CollapseRR         bool
+	CollapseRRCIDR     bool
+	CollapseRRMin      int
+	ByIP               bool
+	ByIPDisk           bool
+	ResponseHash       bool
+	HashSummary        bool
+	MinDNSSECAlgo      string
+	minDNSSECAlgo      uint8
+	WeakDNSSECOnly     bool
+	ECH                bool
+	ECHOnly            bool
+	TCPRetryRcodes     string
+	tcpRetryRcodes     map[int]struct{}
+	tcpRetryNoData     bool
+	Monitor            bool
+	MonitorInterval    time.Duration
+	MonitorState       string
+	MonitorAll         bool
+	AllowedRanges      string
+	QueryLog           string
+	ExtractWords       string
+	QueryIDMode        string
+	QueryID            int
+	NoCompression      bool
+	ESBulk             bool
+	ESIndex            string
+	esAction           string
+	Prefix             goflags.StringSlice
+	Suffix             goflags.StringSlice
+	AlsoWWW            bool
+	SRVService         goflags.StringSlice
+	SRVServiceFile     string
+	srvNames           []srvName
+	TLSAPorts          goflags.StringSlice
+	tlsaPorts          []srvEndpoint
+	IDNDisplay         string
+	QueryName          bool
+	SkipRegex          string
+	skipRegex          *regexp.Regexp
+	DotFile            string
+	DotMaxEdges        int
+	TargetConfig       string
+	ShowCounts         bool
+	ResolverHash       bool
 }
 
 // ShouldLoadResume resume file
@@ -95,22 +229,39 @@ func ParseOptions() *Options {
 		flagSet.StringVarP(&options.Hosts, "list", "l", "", "list of sub(domains)/hosts to resolve (file or stdin)"),
 		flagSet.StringVarP(&options.Domains, "domain", "d", "", "list of domain to bruteforce (file or comma separated or stdin)"),
 		flagSet.StringVarP(&options.WordList, "wordlist", "w", "", "list of words to bruteforce (file or comma separated or stdin)"),
+		flagSet.StringVarP(&options.AsnPrefixFilter, "asn-prefix-filter", "apf", "", "restrict asn expansion to prefix sizes or cidrs (eg. -apf /24,10.0.0.0/8)"),
+		flagSet.SizeVarP(&options.MaxLineSize, "max-line-size", "mls", "10mb", "maximum size of an input line (eg. 64kb, 100mb)"),
+		flagSet.StringVarP(&options.SkipRegex, "skip-regex", "sr", "", "regex of the input hosts to skip without querying (eg. -sr '^(localhost|.*\\.cdn\\.internal)$')"),
+		flagSet.StringSliceVar(&options.Prefix, "prefix", nil, "prefix prepended to every input host, one host per value (eg. -prefix api.,dev.)", goflags.CommaSeparatedStringSliceOptions),
+		flagSet.StringSliceVar(&options.Suffix, "suffix", nil, "suffix appended to every input host, one host per value (eg. -suffix .internal.example.com)", goflags.CommaSeparatedStringSliceOptions),
+		flagSet.BoolVarP(&options.AlsoWWW, "also-www", "aw", false, "also query www.host for each registrable domain and the registrable domain for each www host"),
+		flagSet.StringSliceVarP(&options.SRVService, "srv-service", "srvs", nil, "query the srv records of the services for each input host (eg. -srvs ldap,kerberos,sip queries _ldap._tcp.host, ...) (implies -srv)", goflags.CommaSeparatedStringSliceOptions),
+		flagSet.StringVarP(&options.SRVServiceFile, "srv-service-file", "srvsf", "", "file extending the built-in srv services, one service per line with its endpoints (eg. ldap 389/tcp)"),
+		flagSet.StringSliceVarP(&options.TLSAPorts, "tlsa-ports", "tlsap", nil, "query the tlsa records of the ports for each input host as port[/proto] or default for the common dane ports (eg. -tlsap default,8443 queries _443._tcp.host, ...) (implies -tlsa)", goflags.CommaSeparatedStringSliceOptions),
 	)
 
 	queries := goflags.AllowdTypes{
-		"none":  goflags.EnumVariable(0),
-		"a":     goflags.EnumVariable(1),
-		"aaaa":  goflags.EnumVariable(2),
-		"cname": goflags.EnumVariable(3),
-		"ns":    goflags.EnumVariable(4),
-		"txt":   goflags.EnumVariable(5),
-		"srv":   goflags.EnumVariable(6),
-		"ptr":   goflags.EnumVariable(7),
-		"mx":    goflags.EnumVariable(8),
-		"soa":   goflags.EnumVariable(9),
-		"axfr":  goflags.EnumVariable(10),
-		"caa":   goflags.EnumVariable(11),
-		"any":   goflags.EnumVariable(12),
+		"none":   goflags.EnumVariable(0),
+		"a":      goflags.EnumVariable(1),
+		"aaaa":   goflags.EnumVariable(2),
+		"cname":  goflags.EnumVariable(3),
+		"ns":     goflags.EnumVariable(4),
+		"txt":    goflags.EnumVariable(5),
+		"srv":    goflags.EnumVariable(6),
+		"ptr":    goflags.EnumVariable(7),
+		"mx":     goflags.EnumVariable(8),
+		"soa":    goflags.EnumVariable(9),
+		"axfr":   goflags.EnumVariable(10),
+		"caa":    goflags.EnumVariable(11),
+		"any":    goflags.EnumVariable(12),
+		"dnskey": goflags.EnumVariable(13),
+		"ds":     goflags.EnumVariable(14),
+		"tlsa":   goflags.EnumVariable(15),
+		"https":  goflags.EnumVariable(16),
+		"svcb":   goflags.EnumVariable(17),
+		"naptr":  goflags.EnumVariable(18),
+		"sshfp":  goflags.EnumVariable(19),
+		"dname":  goflags.EnumVariable(20),
 	}
 
 	flagSet.CreateGroup("query", "Query",
@@ -126,24 +277,77 @@ func ParseOptions() *Options {
 		flagSet.BoolVar(&options.ANY, "any", false, "query ANY record"),
 		flagSet.BoolVar(&options.AXFR, "axfr", false, "query AXFR"),
 		flagSet.BoolVar(&options.CAA, "caa", false, "query CAA record"),
-		flagSet.BoolVarP(&options.QueryAll, "recon", "all", false, "query all the dns records (a,aaaa,cname,ns,txt,srv,ptr,mx,soa,axfr,caa)"),
-		flagSet.EnumSliceVarP(&options.ExcludeType, "exclude-type", "e", []goflags.EnumVariable{0}, "dns query type to exclude (a,aaaa,cname,ns,txt,srv,ptr,mx,soa,axfr,caa)", queries),
+		flagSet.BoolVar(&options.TLSA, "tlsa", false, "query TLSA record"),
+		flagSet.BoolVar(&options.DNSKEY, "dnskey", false, "query DNSKEY record"),
+		flagSet.BoolVar(&options.DS, "ds", false, "query DS record"),
+		flagSet.BoolVar(&options.HTTPS, "https", false, "query HTTPS record"),
+		flagSet.BoolVar(&options.SVCB, "svcb", false, "query SVCB record"),
+		flagSet.BoolVar(&options.NAPTR, "naptr", false, "query NAPTR record"),
+		flagSet.BoolVar(&options.SSHFP, "sshfp", false, "query SSHFP record"),
+		flagSet.BoolVar(&options.DNAME, "dname", false, "query DNAME record"),
+		flagSet.BoolVarP(&options.QueryAll, "recon", "all", false, "query all the dns records (a,aaaa,cname,ns,txt,srv,ptr,mx,soa,axfr,caa,dnskey,ds,tlsa,https,svcb,naptr,sshfp,dname)"),
+		flagSet.EnumSliceVarP(&options.QueryType, "type", "qtype", []goflags.EnumVariable{0}, "dns query types to query (a,aaaa,cname,ns,txt,srv,ptr,mx,soa,axfr,caa,any,dnskey,ds,tlsa,https,svcb,naptr,sshfp,dname)", queries),
+		flagSet.EnumSliceVarP(&options.TypePriority, "type-priority", "tp", []goflags.EnumVariable{0}, "order in which the query types are sent for each host, unlisted types follow (eg. -tp a,cname)", queries),
+		flagSet.EnumSliceVarP(&options.ExcludeType, "exclude-type", "e", []goflags.EnumVariable{0}, "dns query type to exclude (a,aaaa,cname,ns,txt,srv,ptr,mx,soa,axfr,caa,dnskey,ds,tlsa,https,svcb,naptr,sshfp,dname)", queries),
 	)
 
 	flagSet.CreateGroup("filter", "Filter",
 		flagSet.BoolVarP(&options.Response, "resp", "re", false, "display dns response"),
 		flagSet.BoolVarP(&options.ResponseOnly, "resp-only", "ro", false, "display dns response only"),
 		flagSet.StringVarP(&options.RCode, "rcode", "rc", "", "filter result by dns status code (eg. -rcode noerror,servfail,refused)"),
+		flagSet.BoolVar(&options.ServFail, "servfail", false, "filter result by servfail status code (same as -rcode servfail)"),
+		flagSet.BoolVar(&options.Refused, "refused", false, "filter result by refused status code (same as -rcode refused)"),
+		flagSet.BoolVar(&options.NXDomain, "nxdomain", false, "filter result by nxdomain status code (same as -rcode nxdomain)"),
+		flagSet.BoolVarP(&options.CNAMEChain, "cname-chain", "cc", false, "display the whole cname chain in a single response line"),
+		flagSet.BoolVarP(&options.RequireAgreement, "require-agreement", "ra", false, "display only records returned by at least two distinct resolvers"),
+		flagSet.BoolVarP(&options.DetectNXHijack, "detect-nxhijack", "dnh", false, "probe each resolver with a non-existent name and drop the answers of the resolvers redirecting nxdomain (eg. to ad servers)"),
+		flagSet.BoolVarP(&options.ShowCoverage, "show-coverage", "sc", false, "display how many of the queried types returned records for each host (eg. 3/5)"),
+		flagSet.BoolVarP(&options.CollapseRR, "collapse-rr", "crr", false, "display the large a/aaaa round-robin sets as their count and first addresses in the response output (json keeps all the records)"),
+		flagSet.BoolVarP(&options.CollapseRRCIDR, "collapse-rr-cidr", "crrc", false, "display the collapsed round-robin sets as their count and covering cidr blocks (implies -collapse-rr)"),
+		flagSet.IntVarP(&options.CollapseRRMin, "collapse-rr-threshold", "crrt", DefaultCollapseRRThreshold, "number of a/aaaa records from which a round-robin set is collapsed"),
+		flagSet.BoolVarP(&options.ShowCounts, "show-counts", "sco", false, "display a summary line with the number of records per queried type for each host (eg. host [A:3] [MX:2])"),
+		flagSet.BoolVarP(&options.QueryName, "query-name", "qn", false, "display the name actually queried for each host, after the input transforms and the in-addr.arpa/ip6.arpa name of the ips (always in json output)"),
 	)
 
 	flagSet.CreateGroup("probe", "Probe",
 		flagSet.BoolVar(&options.OutputCDN, "cdn", false, "display cdn name"),
 		flagSet.BoolVar(&options.ASN, "asn", false, "display host asn information"),
+		flagSet.BoolVarP(&options.AsnSummary, "asn-summary", "as", false, "display the number of hosts per asn at the end of the run (implies -asn)"),
+		flagSet.BoolVarP(&options.NSSummary, "ns-summary", "nss", false, "display the discovered nameservers with the number of hosts they serve at the end of the run (implies -ns)"),
+		flagSet.BoolVarP(&options.NSSummaryResolve, "ns-summary-resolve", "nssr", false, "resolve the ip addresses of the nameservers in the summary (implies -ns-summary)"),
+		flagSet.BoolVarP(&options.ExternalDeps, "external-deps", "xd", false, "display the cname targets outside the registrable domains of the input with the number of hosts depending on them at the end of the run (implies -cname)"),
+		flagSet.BoolVarP(&options.NSInventory, "ns-inventory", "nsi", false, "resolve the nameservers of each domain to their ip addresses, and asn with -asn (implies -ns)"),
+		flagSet.BoolVarP(&options.DualStackCheck, "dualstack-check", "dsc", false, "check the ptr of each a/aaaa address and whether it resolves back (fcrdns), flagging the ipv4/ipv6 inconsistencies (implies -a -aaaa)"),
+		flagSet.BoolVarP(&options.FlagMultiASN, "flag-multi-asn", "fma", false, "flag hosts whose a/aaaa records span multiple asns (implies -asn)"),
+		flagSet.BoolVar(&options.Whois, "whois", false, "display the whois netname, organization and country of the resolved ips (cached per ip)"),
+		flagSet.IntVarP(&options.WhoisRateLimit, "whois-rate-limit", "wrl", DefaultWhoisRateLimit, "number of whois queries per minute"),
+		flagSet.BoolVarP(&options.AnnotateBogon, "annotate-bogon", "ab", false, "flag a/aaaa records in private or bogon ranges"),
+		flagSet.BoolVarP(&options.Confidence, "confidence", "conf", false, "annotate each record with a confidence level (high/medium/low) from the resolvers returning it and the retries"),
+		flagSet.IntVarP(&options.ConfidenceMin, "confidence-resolvers", "cr", dnsx.DefaultConfidenceResolvers, "number of distinct resolvers that must return a record for a medium or high confidence"),
+		flagSet.IntVarP(&options.ConfidenceRetries, "confidence-max-retries", "cmr", dnsx.DefaultConfidenceMaxRetries, "number of retries above which the records get a low confidence"),
+		flagSet.BoolVarP(&options.DetectSpoof, "detect-spoof", "dsp", false, "flag responses showing spoofing indicators (0x20 case mismatch, unexpected source, late answers) on the udp queries"),
+		flagSet.BoolVarP(&options.SOAHealth, "soa-health", "sh", false, "flag zones with risky soa timers (short expire, refresh not shorter than expire, etc.) (implies -soa)"),
+		flagSet.StringVarP(&options.MinDNSSECAlgo, "min-dnssec-algo", "mda", "", "flag zones whose dnskey/rrsig records use a dnssec algorithm below the given one, number or name (eg. 8, RSASHA256)"),
+		flagSet.BoolVarP(&options.WeakDNSSECOnly, "weak-dnssec-only", "wdo", false, "display only the hosts signed with an algorithm below -min-dnssec-algo"),
+		flagSet.BoolVar(&options.ECH, "ech", false, "query the https records of each host and display the encrypted client hello configs they advertise"),
+		flagSet.BoolVarP(&options.ECHOnly, "ech-only", "eco", false, "display only the hosts advertising an encrypted client hello config (implies -ech)"),
+		flagSet.BoolVar(&options.Probe, "probe", false, "display only whether each host resolves (true/false) for any queried type"),
+		flagSet.BoolVarP(&options.CompareAuth, "compare-auth", "ca", false, "query each host on the authoritative servers of its zone as well and display both views with the differences (MATCH/DIFF/UNKNOWN/ERROR)"),
+		flagSet.BoolVarP(&options.Expect, "expect", "ex", false, "read the expected record values after each input host (eg. example.com 93.184.216.34) and display PASS/FAIL, exiting with an error on any mismatch"),
+		flagSet.StringVarP(&options.AllowedRanges, "allowed-ranges", "ar", "", "file mapping domains to the cidrs their a/aaaa records must fall in (eg. example.com 192.0.2.0/24), flagging the others and exiting with an error"),
+		flagSet.BoolVarP(&options.CollapseCIDR, "collapse-cidr", "ccidr", false, "display the resolved a/aaaa addresses as the minimal set of covering cidr blocks at the end of the run"),
+		flagSet.BoolVarP(&options.CollapseCIDRApprox, "collapse-cidr-approx", "ccidra", false, "collapse the resolved addresses into the smallest cidr block covering each ip family (implies -collapse-cidr)"),
+		flagSet.BoolVarP(&options.ByIP, "by-ip", "bip", false, "display each resolved a/aaaa address with the hosts pointing to it at the end of the run"),
+		flagSet.BoolVarP(&options.ByIPDisk, "by-ip-disk", "bipd", false, "keep the by-ip index on disk to bound the memory of large scans (implies -by-ip)"),
+		flagSet.BoolVarP(&options.ResponseHash, "response-hash", "rhash", false, "display a hash of the raw response to spot the hosts sharing an identical answer (sinkholes, parking)"),
+		flagSet.BoolVarP(&options.HashSummary, "response-hash-summary", "rhsum", false, "display the response hashes shared by several hosts with their number of hosts at the end of the run (implies -response-hash)"),
 	)
 
 	flagSet.CreateGroup("rate-limit", "Rate-limit",
 		flagSet.IntVarP(&options.Threads, "threads", "t", 100, "number of concurrent threads to use"),
+		flagSet.IntVarP(&options.TraceThreads, "trace-threads", "tt", DefaultTraceThreads, "number of concurrent traces, run apart from the resolution threads"),
 		flagSet.IntVarP(&options.RateLimit, "rate-limit", "rl", -1, "number of dns request/second to make (disabled as default)"),
+		flagSet.StringVar(&options.Delay, "delay", "", "random pause taken by each thread before every host query, single value or range (eg. 200ms, 100-500ms)"),
 	)
 
 	flagSet.CreateGroup("update", "Update",
@@ -153,34 +357,90 @@ func ParseOptions() *Options {
 
 	flagSet.CreateGroup("output", "Output",
 		flagSet.StringVarP(&options.OutputFile, "output", "o", "", "file to write output"),
+		flagSet.BoolVarP(&options.CompressOutput, "compress-output", "co", false, "gzip the output file (implied by a .gz output file)"),
+		flagSet.StringVarP(&options.OutputSocket, "output-socket", "os", "", "stream output to a unix socket or tcp address (eg. unix:///tmp/dnsx.sock, tcp://127.0.0.1:9000)"),
+		flagSet.StringSliceVarP(&options.OutputSinks, "output-sink", "osk", nil, "additional file receiving the jsonl of the responses matching an optional filter, can be repeated (eg. -osk all.jsonl -osk nx.jsonl:rcode=nxdomain)", goflags.StringSliceOptions),
 		flagSet.BoolVarP(&options.JSON, "json", "j", false, "write output in JSONL(ines) format"),
 		flagSet.BoolVarP(&options.OmitRaw, "or", "omit-raw", false, "omit raw dns response from jsonl output"),
+		flagSet.BoolVarP(&options.JSONCompact, "json-compact", "jc", false, "write the jsonl records without their null and empty fields (the records no longer share a fixed set of keys)"),
+		flagSet.StringVarP(&options.ExecFilter, "exec-filter", "ef", "", "line oriented shell command transforming the jsonl records, answering each record line of its stdin with a line of its stdout (empty to drop it) (implies -json)"),
+		flagSet.IntVarP(&options.ExecFilterThreads, "exec-filter-threads", "eft", DefaultExecFilterThreads, "number of exec-filter processes kept running"),
+		flagSet.DurationVarP(&options.ExecFilterTimeout, "exec-filter-timeout", "efto", DefaultExecFilterTimeout, "time an exec-filter process gets to answer a record before it is kept unchanged"),
+		flagSet.BoolVarP(&options.Hierarchy, "hierarchy", "hy", false, "add the apex, parent domain and depth of each host to the jsonl output"),
+		flagSet.BoolVarP(&options.MsgPack, "msgpack", "mp", false, "write output as length prefixed MessagePack records"),
+		flagSet.BoolVarP(&options.ESBulk, "es-bulk", "esb", false, "write output as elasticsearch/opensearch bulk ndjson, an index action line before each jsonl record"),
+		flagSet.StringVarP(&options.ESIndex, "es-index", "esi", "dnsx", "index of the es-bulk actions"),
+		flagSet.StringVarP(&options.IDNDisplay, "idn-display", "idn", "", "display punycode names decoded to unicode in text output, instead of (unicode) or alongside (both) the ace form"),
+		flagSet.StringVarP(&options.OutputOrder, "output-order", "oo", outputOrderHost, "order of the text output, interleaved per host or grouped per record type (host,type) - type buffers the whole output in memory"),
+		flagSet.BoolVarP(&options.ListTargets, "list-targets", "lt", false, "display the prepared list of targets without querying"),
+		flagSet.StringVarP(&options.ExtractWords, "extract-words", "ew", "", "display the unique labels of the resolved subdomains as a wordlist at the end of the run, the leftmost label or all the labels below the registrable domain (leftmost,all)"),
+		flagSet.StringVarP(&options.RetryFile, "retry-file", "rf", "", "file to write the hosts that errored (timeout, servfail, refused) for a later retry run"),
+		flagSet.StringVarP(&options.QueryLog, "query-log", "ql", "", "file to write a json line for every query attempt (host, type, resolver, attempt, rcode, latency, error)"),
+		flagSet.StringVar(&options.DotFile, "dot", "", "file to write the discovered cname chains and ns relationships as a graphviz dot graph"),
+		flagSet.IntVarP(&options.DotMaxEdges, "dot-max-edges", "dme", DefaultDotMaxEdges, "maximum number of edges of the dot graph (0 for unlimited)"),
 	)
 
 	flagSet.CreateGroup("debug", "Debug",
 		flagSet.BoolVarP(&options.HealthCheck, "health-check", "hc", false, "run diagnostic check up"),
+		flagSet.BoolVar(&options.Prime, "prime", false, "send the priming query to the root servers and compare the root hints with the known list"),
 		flagSet.BoolVar(&options.Silent, "silent", false, "display only results in the output"),
+		flagSet.BoolVarP(&options.Quiet, "quiet", "q", false, "display only results and errors, suppressing the banner, the informational messages and the stats"),
 		flagSet.BoolVarP(&options.Verbose, "verbose", "v", false, "display verbose output"),
 		flagSet.BoolVarP(&options.Raw, "debug", "raw", false, "display raw dns response"),
 		flagSet.BoolVar(&options.ShowStatistics, "stats", false, "display stats of the running scan"),
+		flagSet.BoolVarP(&options.SizeStats, "size-stats", "ss", false, "display request and response wire sizes at the end of the run"),
+		flagSet.BoolVarP(&options.ResolverStats, "resolver-stats", "rst", false, "display the queries handled, the answers and the failures returned by each resolver at the end of the run"),
 		flagSet.BoolVar(&options.Version, "version", false, "display version of dnsx"),
 		flagSet.BoolVarP(&options.NoColor, "no-color", "nc", false, "disable color in output"),
+		flagSet.StringVarP(&options.ColorScheme, "color-scheme", "cs", "", "output color scheme (default,colorblind) with optional overrides (eg. -cs colorblind,mx=cyan,type=white)"),
 	)
 
 	flagSet.CreateGroup("optimization", "Optimization",
 		flagSet.IntVar(&options.Retries, "retry", 2, "number of dns attempts to make (must be at least 1)"),
+		flagSet.IntVarP(&options.MaxAnswers, "max-answers", "ma", dnsx.DefaultMaxAnswers, "maximum number of records kept per record type in a response (applied after the full response is parsed)"),
+		flagSet.BoolVarP(&options.RetryNoData, "retry-nodata", "rnd", false, "query again with a different resolver on empty noerror responses"),
+		flagSet.StringVarP(&options.TCPRetryRcodes, "tcp-retry-rcodes", "trr", "", "query again over tcp the hosts answered with one of these rcodes, nodata for empty noerror responses (eg. servfail,nodata)"),
+		flagSet.IntVarP(&options.EDNSVersion, "edns-version", "ev", 0, "edns version to advertise in the queries (BADVERS responses report the supported version)"),
+		flagSet.IntVar(&options.Padding, "padding", 0, "pad the queries with the edns padding option to a multiple of the block size, against traffic analysis over dot/doh (eg. 128)"),
+		flagSet.BoolVar(&options.NSID, "nsid", false, "request the identifier of the servers with the edns nsid option, displayed with the records"),
+		flagSet.DurationVarP(&options.QueryTimeout, "query-timeout", "qt", 0, "timeout for each dns attempt (eg. 2s, 500ms - bare numbers are seconds)"),
+		flagSet.BoolVarP(&options.TimeoutEscalation, "timeout-escalation", "te", false, "double the query timeout at each retry (eg. 1s, 2s, 4s), up to -max-query-timeout"),
+		flagSet.DurationVarP(&options.MaxQueryTimeout, "max-query-timeout", "mqt", dnsx.DefaultMaxTimeout, "maximum timeout of a dns attempt with -timeout-escalation"),
 		flagSet.BoolVarP(&options.HostsFile, "hostsfile", "hf", false, "use system host file"),
+		flagSet.BoolVar(&options.Offline, "offline", false, "answer exclusively from the system host file without network queries"),
 		flagSet.BoolVar(&options.Trace, "trace", false, "perform dns tracing"),
 		flagSet.IntVar(&options.TraceMaxRecursion, "trace-max-recursion", math.MaxInt16, "Max recursion for dns trace"),
 		flagSet.BoolVar(&options.Resume, "resume", false, "resume existing scan"),
 		flagSet.BoolVar(&options.Stream, "stream", false, "stream mode (wordlist, wildcard, stats and stop/resume will be disabled)"),
+		flagSet.BoolVarP(&options.LowMemory, "low-memory", "lm", false, "generate the targets while resolving instead of storing them all first (no duplicate removal, approximate stats, resume disabled)"),
+	)
+
+	flagSet.CreateGroup("monitor", "Monitor",
+		flagSet.BoolVarP(&options.Monitor, "monitor", "mon", false, "query the targets again every interval and display only the records added or removed since the previous cycle"),
+		flagSet.DurationVarP(&options.MonitorInterval, "interval", "mi", DefaultMonitorInterval, "time between the start of two monitor cycles"),
+		flagSet.StringVarP(&options.MonitorState, "monitor-state", "mst", DefaultMonitorStateFile, "file keeping the records with their first and last seen times between runs (empty to disable)"),
+		flagSet.BoolVarP(&options.MonitorAll, "monitor-all", "mall", false, "display the unchanged records of each monitor cycle as well"),
 	)
 
 	flagSet.CreateGroup("configs", "Configurations",
 		flagSet.DynamicVar(&options.PdcpAuth, "auth", "true", "configure ProjectDiscovery Cloud Platform (PDCP) api key"),
 		flagSet.StringVarP(&options.Resolvers, "resolver", "r", "", "list of resolvers to use (file or comma separated)"),
+		flagSet.StringVarP(&options.BootstrapResolver, "bootstrap-resolver", "br", "", "resolver ip used to resolve the doh/dot/doq server hostnames (default system resolver)"),
+		flagSet.StringVarP(&options.DoHUserAgent, "doh-user-agent", "dua", defaultDoHUserAgent, "user agent of the doh requests (empty to omit the header)"),
+		flagSet.BoolVarP(&options.DoTInsecure, "dot-insecure", "doti", false, "skip the verification of the certificates of the dot resolvers (self-signed internal resolvers)"),
+		flagSet.StringVarP(&options.ExcludeResolvers, "exclude-resolvers", "er", "", "list of resolver ips or cidrs that must never be used (file or comma separated)"),
+		flagSet.StringVarP(&options.ResolversOut, "resolvers-out", "rout", "", "file to write the resolvers actually used, once prepared and after each reload"),
+		flagSet.BoolVarP(&options.ResolverHash, "resolver-hash", "rh", false, "send each host to the resolver picked by hashing its name, the same host always hitting the same resolver"),
+		flagSet.StringVarP(&options.QueryIDMode, "query-id-mode", "qim", dnsx.QueryIDRandom, "generation of the query message ids (random,fixed,sequential) - the non-random modes are meant for lab testing only"),
+		flagSet.IntVarP(&options.QueryID, "query-id", "qid", 0, "message id of the queries in fixed mode, first id in sequential mode (0-65535)"),
+		flagSet.BoolVarP(&options.NoCompression, "no-compression", "ncp", false, "send the queries without name compression and report the compression of the responses (-v) to test middleboxes"),
+		flagSet.StringVarP(&options.TargetConfig, "target-config", "tc", "", "yaml/json file mapping target patterns to query types, resolvers and recursion"),
 		flagSet.IntVarP(&options.WildcardThreshold, "wildcard-threshold", "wt", 5, "wildcard filter threshold"),
 		flagSet.StringVarP(&options.WildcardDomain, "wildcard-domain", "wd", "", "domain name for wildcard filtering (other flags will be ignored - only json output is supported)"),
+		flagSet.BoolVarP(&options.WildcardTCP, "wildcard-tcp", "wtcp", false, "send the wildcard filtering queries over tcp to avoid udp rate limits"),
+		flagSet.StringVarP(&options.WildcardCacheDir, "wildcard-cache-dir", "wcd", "", "directory keeping the wildcard probe results of the parent domains from one run to the next"),
+		flagSet.DurationVarP(&options.WildcardCacheTTL, "wildcard-cache-ttl", "wct", 0, "age after which the cached wildcard probe results are probed again (0 keeps them)"),
+		flagSet.BoolVarP(&options.WildcardReprobe, "wildcard-reprobe", "wrp", false, "probe the parent domains again, ignoring the cached wildcard results"),
 	)
 
 	_ = flagSet.Parse()
@@ -192,6 +452,80 @@ func ParseOptions() *Options {
 
 	options.configureQueryOptions()
 
+	if options.JSONCompact {
+		options.JSON = true
+	}
+
+	if options.ExecFilter != "" {
+		if options.MsgPack {
+			gologger.Fatal().Msgf("exec-filter can't be used with msgpack output")
+		}
+		if options.ExecFilterThreads < 1 {
+			gologger.Fatal().Msgf("exec-filter-threads must be at least 1")
+		}
+		if options.ExecFilterTimeout <= 0 {
+			gologger.Fatal().Msgf("exec-filter-timeout must be positive")
+		}
+		options.JSON = true
+	}
+
+	// messagepack records carry the same fields as the json output
+	if options.MsgPack {
+		options.JSON = true
+	}
+
+	// the bulk documents are the json records
+	if options.ESBulk {
+		if options.MsgPack {
+			gologger.Fatal().Msgf("es-bulk can't be used with msgpack output")
+		}
+		options.JSON = true
+	}
+	if err := options.configureESBulk(); err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
+	if options.AsnSummary || options.FlagMultiASN {
+		options.ASN = true
+	}
+
+	if options.SOAHealth {
+		options.SOA = true
+	}
+
+	if options.NSSummaryResolve {
+		options.NSSummary = true
+	}
+
+	if options.CollapseCIDRApprox {
+		options.CollapseCIDR = true
+	}
+	if options.CollapseRRCIDR {
+		options.CollapseRR = true
+	}
+	if options.CollapseRR && options.CollapseRRMin < 2 {
+		gologger.Fatal().Msgf("collapse-rr-threshold must be at least 2")
+	}
+	if options.ByIPDisk {
+		options.ByIP = true
+	}
+	if options.ECHOnly {
+		options.ECH = true
+	}
+	if options.NSSummary || options.NSInventory {
+		options.NS = true
+	}
+	if options.ExternalDeps {
+		options.CNAME = true
+	}
+	if options.DualStackCheck {
+		options.A = true
+		options.AAAA = true
+	}
+	if options.HashSummary {
+		options.ResponseHash = true
+	}
+
 	// Read the inputs and configure the logging
 	options.configureOutput()
 
@@ -205,6 +539,46 @@ func ParseOptions() *Options {
 		gologger.Fatal().Msgf("%s\n", err)
 	}
 
+	err = options.configureAsnPrefixFilter()
+	if err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
+	err = options.configureColorScheme()
+	if err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
+	err = options.configureSkipRegex()
+	if err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
+	err = options.configureDelay()
+	if err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
+	err = options.configureSRVServices()
+	if err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
+	err = options.configureTLSAPorts()
+	if err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
+	err = options.configureTCPRetryRcodes()
+	if err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
+	err = options.configureMinDNSSECAlgo()
+	if err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+
 	// api key hierarchy: cli flag > env var > .pdcp/credential file
 	if options.PdcpAuth == "true" {
 		AuthWithPDCP()
@@ -247,10 +621,38 @@ func (options *Options) validateOptions() {
 		gologger.Fatal().Msgf("resp and resp-only can't be used at the same time")
 	}
 
+	if options.Whois && options.WhoisRateLimit < 1 {
+		gologger.Fatal().Msgf("whois-rate-limit must be at least 1")
+	}
+
+	if options.Quiet && options.Verbose {
+		gologger.Fatal().Msgf("quiet and verbose can't be used at the same time")
+	}
+
 	if options.Retries == 0 {
 		gologger.Fatal().Msgf("retries must be at least 1")
 	}
 
+	if options.MaxAnswers < 1 {
+		gologger.Fatal().Msgf("max-answers must be at least 1")
+	}
+
+	if options.EDNSVersion < 0 || options.EDNSVersion > math.MaxUint8 {
+		gologger.Fatal().Msgf("edns-version must be between 0 and %d", math.MaxUint8)
+	}
+
+	if options.Padding < 0 || options.Padding > math.MaxUint16 {
+		gologger.Fatal().Msgf("padding must be between 0 and %d", math.MaxUint16)
+	}
+
+	if options.QueryTimeout < 0 {
+		gologger.Fatal().Msgf("query-timeout can't be negative")
+	}
+
+	if options.TimeoutEscalation && options.MaxQueryTimeout < options.QueryTimeout {
+		gologger.Fatal().Msgf("max-query-timeout can't be lower than query-timeout")
+	}
+
 	wordListPresent := options.WordList != ""
 	domainsPresent := options.Domains != ""
 	hostsPresent := options.Hosts != ""
@@ -274,6 +676,252 @@ func (options *Options) validateOptions() {
 		gologger.Fatal().Msgf("stdin can be set for one flag")
 	}
 
+	switch options.OutputOrder {
+	case outputOrderHost:
+	case outputOrderType:
+		if options.JSON {
+			gologger.Fatal().Msgf("output-order type not supported with json output")
+		}
+	default:
+		gologger.Fatal().Msgf("invalid output-order %s (host,type)", options.OutputOrder)
+	}
+
+	switch options.ExtractWords {
+	case "":
+	case extractWordsLeftmost, extractWordsAll:
+		if options.JSON || options.MsgPack {
+			gologger.Fatal().Msgf("extract-words outputs plain words and can't be used with json or msgpack output")
+		}
+		if options.WildcardDomain != "" {
+			gologger.Fatal().Msgf("extract-words can't be used with wildcard filtering")
+		}
+		if options.Probe || options.Expect || options.CompareAuth {
+			gologger.Fatal().Msgf("extract-words can't be used with probe, expect or compare-auth")
+		}
+		if options.CollapseCIDR || options.ByIP {
+			gologger.Fatal().Msgf("extract-words can't be used with collapse-cidr or by-ip")
+		}
+	default:
+		gologger.Fatal().Msgf("invalid extract-words %s (leftmost,all)", options.ExtractWords)
+	}
+
+	if options.Monitor {
+		if options.MonitorInterval <= 0 {
+			gologger.Fatal().Msgf("interval must be positive")
+		}
+		if options.MsgPack {
+			gologger.Fatal().Msgf("monitor can't be used with msgpack output")
+		}
+		if options.Stream || options.LowMemory {
+			gologger.Fatal().Msgf("monitor can't be used with stream or low-memory")
+		}
+		if options.Resume || options.ListTargets || options.Prime {
+			gologger.Fatal().Msgf("monitor can't be used with resume, list-targets or prime")
+		}
+		if options.WildcardDomain != "" || options.Trace || options.ShowStatistics {
+			gologger.Fatal().Msgf("monitor can't be used with wildcard filtering, trace or stats")
+		}
+		if options.Probe || options.Expect || options.CompareAuth {
+			gologger.Fatal().Msgf("monitor can't be used with probe, expect or compare-auth")
+		}
+		if options.CollapseCIDR || options.ByIP || options.ExtractWords != "" {
+			gologger.Fatal().Msgf("monitor can't be used with collapse-cidr, by-ip or extract-words")
+		}
+		if options.OutputOrder == outputOrderType {
+			gologger.Fatal().Msgf("monitor can't be used with the type output order")
+		}
+	}
+
+	switch options.QueryIDMode {
+	case dnsx.QueryIDRandom, dnsx.QueryIDFixed, dnsx.QueryIDSequential:
+	default:
+		gologger.Fatal().Msgf("invalid query-id-mode %s (random,fixed,sequential)", options.QueryIDMode)
+	}
+	if options.QueryID < 0 || options.QueryID > math.MaxUint16 {
+		gologger.Fatal().Msgf("query-id must be between 0 and %d", math.MaxUint16)
+	}
+
+	if options.Trace && options.TraceThreads < 1 {
+		gologger.Fatal().Msgf("trace-threads must be at least 1")
+	}
+
+	switch options.IDNDisplay {
+	case "", idnDisplayUnicode, idnDisplayBoth:
+	default:
+		gologger.Fatal().Msgf("invalid idn-display %s (unicode,both)", options.IDNDisplay)
+	}
+
+	if options.MsgPack {
+		if options.WildcardDomain != "" {
+			gologger.Fatal().Msgf("msgpack can't be used with wildcard filtering")
+		}
+		if options.ListTargets {
+			gologger.Fatal().Msgf("msgpack can't be used with list-targets")
+		}
+	}
+
+	if options.Prime {
+		if options.Offline {
+			gologger.Fatal().Msgf("prime not supported in offline mode")
+		}
+		if options.MsgPack {
+			gologger.Fatal().Msgf("msgpack can't be used with prime")
+		}
+	}
+
+	if options.Hierarchy && !options.JSON {
+		gologger.Fatal().Msgf("hierarchy is only supported with json output")
+	}
+
+	if (options.WildcardCacheDir != "" || options.WildcardCacheTTL != 0 || options.WildcardReprobe) && options.WildcardDomain == "" {
+		gologger.Fatal().Msgf("wildcard-cache-dir, wildcard-cache-ttl and wildcard-reprobe require wildcard-domain")
+	}
+	if options.WildcardCacheTTL < 0 {
+		gologger.Fatal().Msgf("wildcard-cache-ttl can't be negative")
+	}
+
+	if options.Probe && options.WildcardDomain != "" {
+		gologger.Fatal().Msgf("probe can't be used with wildcard filtering")
+	}
+
+	if options.Confidence && options.ConfidenceMin < 1 {
+		gologger.Fatal().Msgf("confidence-resolvers must be at least 1")
+	}
+
+	if options.Confidence && options.ConfidenceRetries < 0 {
+		gologger.Fatal().Msgf("confidence-max-retries can't be negative")
+	}
+
+	if options.WeakDNSSECOnly && options.MinDNSSECAlgo == "" {
+		gologger.Fatal().Msgf("weak-dnssec-only requires min-dnssec-algo")
+	}
+
+	if options.CompareAuth {
+		if options.WildcardDomain != "" {
+			gologger.Fatal().Msgf("compare-auth can't be used with wildcard filtering")
+		}
+		if options.Probe || options.Expect {
+			gologger.Fatal().Msgf("compare-auth can't be used with probe or expect")
+		}
+		if options.CollapseCIDR || options.ByIP {
+			gologger.Fatal().Msgf("compare-auth can't be used with collapse-cidr or by-ip")
+		}
+	}
+
+	if options.Expect {
+		if options.WordList != "" {
+			gologger.Fatal().Msgf("expect can't be used with a wordlist")
+		}
+		if options.WildcardDomain != "" {
+			gologger.Fatal().Msgf("expect can't be used with wildcard filtering")
+		}
+		if options.Probe {
+			gologger.Fatal().Msgf("expect can't be used with probe")
+		}
+		if options.CollapseCIDR || options.ByIP {
+			gologger.Fatal().Msgf("expect can't be used with collapse-cidr or by-ip")
+		}
+	}
+
+	if options.ByIP {
+		if options.MsgPack {
+			gologger.Fatal().Msgf("by-ip can't be used with msgpack output")
+		}
+		if options.CollapseCIDR {
+			gologger.Fatal().Msgf("by-ip can't be used with collapse-cidr")
+		}
+		if options.WildcardDomain != "" {
+			gologger.Fatal().Msgf("by-ip can't be used with wildcard filtering")
+		}
+		if options.Probe {
+			gologger.Fatal().Msgf("by-ip can't be used with probe")
+		}
+	}
+
+	if options.CollapseCIDR {
+		if options.JSON || options.MsgPack {
+			gologger.Fatal().Msgf("collapse-cidr outputs plain cidr blocks and can't be used with json or msgpack output")
+		}
+		if options.WildcardDomain != "" {
+			gologger.Fatal().Msgf("collapse-cidr can't be used with wildcard filtering")
+		}
+		if options.Probe {
+			gologger.Fatal().Msgf("collapse-cidr can't be used with probe")
+		}
+	}
+
+	if options.Offline {
+		if options.Trace {
+			gologger.Fatal().Msgf("trace not supported in offline mode")
+		}
+		if options.AXFR {
+			gologger.Fatal().Msgf("axfr not supported in offline mode")
+		}
+		if options.OutputCDN {
+			gologger.Fatal().Msgf("cdn not supported in offline mode")
+		}
+		if options.ASN {
+			gologger.Fatal().Msgf("asn not supported in offline mode")
+		}
+		if options.Whois {
+			gologger.Fatal().Msgf("whois not supported in offline mode")
+		}
+		if options.CompareAuth {
+			gologger.Fatal().Msgf("compare-auth not supported in offline mode")
+		}
+		if options.Confidence {
+			gologger.Fatal().Msgf("confidence not supported in offline mode")
+		}
+		if options.DetectNXHijack {
+			gologger.Fatal().Msgf("detect-nxhijack not supported in offline mode")
+		}
+		if options.WildcardDomain != "" {
+			gologger.Fatal().Msgf("wildcard not supported in offline mode")
+		}
+		if options.RequireAgreement {
+			gologger.Fatal().Msgf("require-agreement not supported in offline mode")
+		}
+		if options.DetectSpoof {
+			gologger.Fatal().Msgf("detect-spoof not supported in offline mode")
+		}
+		if options.RetryFile != "" {
+			gologger.Fatal().Msgf("retry-file not supported in offline mode")
+		}
+		if options.NSSummary {
+			gologger.Fatal().Msgf("ns-summary not supported in offline mode")
+		}
+		if options.NSInventory {
+			gologger.Fatal().Msgf("ns-inventory not supported in offline mode")
+		}
+		if options.DualStackCheck {
+			gologger.Fatal().Msgf("dualstack-check not supported in offline mode")
+		}
+		if options.ResponseHash {
+			gologger.Fatal().Msgf("response-hash not supported in offline mode")
+		}
+		if options.ECH {
+			gologger.Fatal().Msgf("ech not supported in offline mode")
+		}
+		if options.TCPRetryRcodes != "" {
+			gologger.Fatal().Msgf("tcp-retry-rcodes not supported in offline mode")
+		}
+		if options.Monitor {
+			gologger.Fatal().Msgf("monitor not supported in offline mode")
+		}
+		if options.MinDNSSECAlgo != "" {
+			gologger.Fatal().Msgf("min-dnssec-algo not supported in offline mode")
+		}
+		if options.ResolverStats {
+			gologger.Fatal().Msgf("resolver-stats not supported in offline mode")
+		}
+		if options.QueryLog != "" {
+			gologger.Fatal().Msgf("query-log not supported in offline mode")
+		}
+		if options.TargetConfig != "" {
+			gologger.Fatal().Msgf("target-config not supported in offline mode")
+		}
+	}
+
 	if options.Stream {
 		if wordListPresent {
 			gologger.Fatal().Msgf("wordlist not supported in stream mode")
@@ -290,6 +938,21 @@ func (options *Options) validateOptions() {
 		if options.ShowStatistics {
 			gologger.Fatal().Msgf("stats not supported in stream mode")
 		}
+		if options.ListTargets {
+			gologger.Fatal().Msgf("list-targets not supported in stream mode")
+		}
+		if options.LowMemory {
+			gologger.Fatal().Msgf("low-memory not supported in stream mode")
+		}
+	}
+
+	if options.LowMemory {
+		if options.Resume {
+			gologger.Fatal().Msgf("resume not supported in low-memory mode")
+		}
+		if options.ListTargets {
+			gologger.Fatal().Msgf("list-targets not supported in low-memory mode")
+		}
 	}
 }
 
@@ -303,6 +966,11 @@ func (options *Options) configureOutput() {
 	if options.Verbose {
 		gologger.DefaultLogger.SetMaxLevel(levels.LevelVerbose)
 	}
+	// only the errors are kept on stderr, the stats are written there as well
+	if options.Quiet {
+		gologger.DefaultLogger.SetMaxLevel(levels.LevelError)
+		options.ShowStatistics = false
+	}
 	if options.Silent {
 		gologger.DefaultLogger.SetMaxLevel(levels.LevelSilent)
 	}
@@ -364,8 +1032,17 @@ func (options *Options) configureRcodes() error {
 
 		options.rcodes[rc] = struct{}{}
 	}
+	if options.ServFail {
+		options.rcodes[dns.RcodeServerFailure] = struct{}{}
+	}
+	if options.Refused {
+		options.rcodes[dns.RcodeRefused] = struct{}{}
+	}
+	if options.NXDomain {
+		options.rcodes[dns.RcodeNameError] = struct{}{}
+	}
 
-	options.hasRCodes = options.RCode != ""
+	options.hasRCodes = len(options.rcodes) > 0
 	return nil
 }
 
@@ -378,20 +1055,164 @@ func (options *Options) configureResume() error {
 	return nil
 }
 
+func (options *Options) configureAsnPrefixFilter() error {
+	if options.AsnPrefixFilter == "" {
+		return nil
+	}
+	filter, err := parseAsnPrefixFilter(options.AsnPrefixFilter)
+	if err != nil {
+		return err
+	}
+	options.asnPrefixFilter = filter
+	return nil
+}
+
+func (options *Options) configureSkipRegex() error {
+	if options.SkipRegex == "" {
+		return nil
+	}
+	skipRegex, err := regexp.Compile(options.SkipRegex)
+	if err != nil {
+		return errors.New("invalid skip-regex: " + err.Error())
+	}
+	options.skipRegex = skipRegex
+	return nil
+}
+
+func (options *Options) configureSRVServices() error {
+	if len(options.SRVService) == 0 {
+		return nil
+	}
+	services, err := loadSRVServices(options.SRVServiceFile)
+	if err != nil {
+		return err
+	}
+	options.srvNames, err = srvNamesFor(services, options.SRVService)
+	if err != nil {
+		return err
+	}
+	options.SRV = true
+	return nil
+}
+
+func (options *Options) configureTLSAPorts() error {
+	if len(options.TLSAPorts) == 0 {
+		return nil
+	}
+	if len(options.SRVService) > 0 {
+		return errors.New("tlsa-ports can't be used with srv-service")
+	}
+	var err error
+	options.tlsaPorts, err = parseTLSAPorts(options.TLSAPorts)
+	if err != nil {
+		return err
+	}
+	options.TLSA = true
+	return nil
+}
+
+func (options *Options) configureMinDNSSECAlgo() error {
+	if options.MinDNSSECAlgo == "" {
+		return nil
+	}
+	algorithm, err := dnsx.ParseDNSSECAlgorithm(options.MinDNSSECAlgo)
+	if err != nil {
+		return err
+	}
+	options.minDNSSECAlgo = algorithm
+	return nil
+}
+
+// configureTCPRetryRcodes parses the response codes retried over tcp, nodata standing for the empty noerror responses
+func (options *Options) configureTCPRetryRcodes() error {
+	if options.TCPRetryRcodes == "" {
+		return nil
+	}
+	options.tcpRetryRcodes = make(map[int]struct{})
+	for _, item := range strings.Split(options.TCPRetryRcodes, Comma) {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if item == "NODATA" {
+			options.tcpRetryNoData = true
+			continue
+		}
+		rcode, ok := dns.StringToRcode[item]
+		if !ok {
+			return fmt.Errorf("invalid tcp-retry-rcodes value: %s", item)
+		}
+		options.tcpRetryRcodes[rcode] = struct{}{}
+	}
+	return nil
+}
+
+// configureESBulk prepares the action line written before each bulk document
+func (options *Options) configureESBulk() error {
+	if !options.ESBulk {
+		return nil
+	}
+	// elasticsearch rejects the index names with uppercase letters or any of these characters
+	if options.ESIndex == "" || options.ESIndex != strings.ToLower(options.ESIndex) || strings.ContainsAny(options.ESIndex, ` ",*\\/<>?|#`) {
+		return fmt.Errorf("invalid es-index %q (lowercase name without spaces or ,\"*\\/<>?|#)", options.ESIndex)
+	}
+	action, err := json.Marshal(map[string]map[string]string{"index": {"_index": options.ESIndex}})
+	if err != nil {
+		return err
+	}
+	options.esAction = string(action)
+	return nil
+}
+
+func (options *Options) configureDelay() error {
+	if options.Delay == "" {
+		return nil
+	}
+	delay, err := parseDelayRange(options.Delay)
+	if err != nil {
+		return err
+	}
+	options.delay = delay
+	return nil
+}
+
+func (options *Options) configureColorScheme() error {
+	if options.ColorScheme == "" {
+		return nil
+	}
+	scheme, err := parseColorScheme(options.ColorScheme)
+	if err != nil {
+		return err
+	}
+	options.colorScheme = scheme
+	return nil
+}
+
 func (options *Options) configureQueryOptions() {
 	queryMap := map[string]*bool{
-		"a":     &options.A,
-		"aaaa":  &options.AAAA,
-		"cname": &options.CNAME,
-		"ns":    &options.NS,
-		"txt":   &options.TXT,
-		"srv":   &options.SRV,
-		"ptr":   &options.PTR,
-		"mx":    &options.MX,
-		"soa":   &options.SOA,
-		"axfr":  &options.AXFR,
-		"caa":   &options.CAA,
-		"any":   &options.ANY,
+		"a":      &options.A,
+		"aaaa":   &options.AAAA,
+		"cname":  &options.CNAME,
+		"ns":     &options.NS,
+		"txt":    &options.TXT,
+		"srv":    &options.SRV,
+		"ptr":    &options.PTR,
+		"mx":     &options.MX,
+		"soa":    &options.SOA,
+		"axfr":   &options.AXFR,
+		"caa":    &options.CAA,
+		"any":    &options.ANY,
+		"dnskey": &options.DNSKEY,
+		"ds":     &options.DS,
+		"tlsa":   &options.TLSA,
+		"https":  &options.HTTPS,
+		"svcb":   &options.SVCB,
+		"naptr":  &options.NAPTR,
+		"sshfp":  &options.SSHFP,
+		"dname":  &options.DNAME,
+	}
+
+	for _, qt := range options.QueryType {
+		if val, ok := queryMap[qt]; ok {
+			*val = true
+		}
 	}
 
 	if options.QueryAll {
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	outputOrderHost = "host"
+	outputOrderType = "type"
+)
+
+// typeOrderedOutput buffers the output lines of each record type until the end of the run
+type typeOrderedOutput struct {
+	lines map[string][]string
+	mutex sync.Mutex
+}
+
+func newTypeOrderedOutput() *typeOrderedOutput {
+	return &typeOrderedOutput{lines: make(map[string][]string)}
+}
+
+func (o *typeOrderedOutput) add(queryType, line string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.lines[queryType] = append(o.lines[queryType], line)
+}
+
+// flush writes the buffered lines grouped by record type, ordered by type code
+func (o *typeOrderedOutput) flush(outputchan chan string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	queryTypes := make([]string, 0, len(o.lines))
+	for queryType := range o.lines {
+		queryTypes = append(queryTypes, queryType)
+	}
+	sort.Slice(queryTypes, func(i, j int) bool {
+		return dns.StringToType[queryTypes[i]] < dns.StringToType[queryTypes[j]]
+	})
+	for _, queryType := range queryTypes {
+		for _, line := range o.lines[queryType] {
+			outputchan <- line
+		}
+	}
+	o.lines = make(map[string][]string)
+}
+
+// outputRecordLine writes the line right away or buffers it when the output is grouped by type
+func (r *Runner) outputRecordLine(queryType, line string) {
+	if r.typeOrderedOutput != nil {
+		r.typeOrderedOutput.add(queryType, line)
+		return
+	}
+	r.outputchan <- line
+}
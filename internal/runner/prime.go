@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/projectdiscovery/gologger"
+)
+
+// primingResult is the json representation of a root server priming response
+type primingResult struct {
+	Host        string   `json:"host"`
+	IP          string   `json:"ip"`
+	Latency     string   `json:"latency,omitempty"`
+	Hints       []string `json:"hints,omitempty"`
+	Missing     []string `json:"missing,omitempty"`
+	Unknown     []string `json:"unknown,omitempty"`
+	UnknownGlue []string `json:"unknown_glue,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// runPrime queries the root servers with the priming query and outputs each response
+func (r *Runner) runPrime() {
+	r.startOutputWorker()
+	for _, response := range r.dnsx.Prime() {
+		if r.options.JSON {
+			r.outputPrimingJSON(response)
+		} else {
+			r.outputPriming(response)
+		}
+	}
+	close(r.outputchan)
+	r.wgoutputworker.Wait()
+}
+
+func (r *Runner) outputPriming(response *dnsx.PrimingResponse) {
+	server := fmt.Sprintf("%s [%s]", response.Server.Host, response.Server.IPv4)
+	if response.Err != nil {
+		r.outputchan <- fmt.Sprintf("%s [%s: %s]", server, r.aurora.Red("error"), response.Err)
+		return
+	}
+
+	var details []string
+	if len(response.Missing) > 0 {
+		details = append(details, fmt.Sprintf("[%s: %s]", r.aurora.Yellow("missing"), strings.Join(response.Missing, ",")))
+	}
+	if len(response.Unknown) > 0 {
+		details = append(details, fmt.Sprintf("[%s: %s]", r.aurora.Yellow("unknown"), strings.Join(response.Unknown, ",")))
+	}
+	if len(response.UnknownGlue) > 0 {
+		details = append(details, fmt.Sprintf("[%s: %s]", r.aurora.Yellow("unknown-glue"), strings.Join(response.UnknownGlue, ",")))
+	}
+	status := r.aurora.Green("ok").String()
+	if len(details) > 0 {
+		status = r.aurora.Red("mismatch").String()
+	}
+	line := fmt.Sprintf("%s [%s] [%d hints] [%s]", server, response.Latency.Round(time.Millisecond), len(response.Hints), status)
+	if len(details) > 0 {
+		line += " " + strings.Join(details, " ")
+	}
+	r.outputchan <- line
+}
+
+func (r *Runner) outputPrimingJSON(response *dnsx.PrimingResponse) {
+	result := primingResult{
+		Host:        response.Server.Host,
+		IP:          response.Server.IPv4,
+		Hints:       response.Hints,
+		Missing:     response.Missing,
+		Unknown:     response.Unknown,
+		UnknownGlue: response.UnknownGlue,
+	}
+	if response.Err != nil {
+		result.Error = response.Err.Error()
+	} else {
+		result.Latency = response.Latency.String()
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		gologger.Error().Msgf("Could not marshal priming response for %s: %s\n", response.Server.Host, err)
+		return
+	}
+	r.outputchan <- string(data)
+}
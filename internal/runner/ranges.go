@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// allowedRanges maps the domains to the networks their a/aaaa records must fall in, a *.example.com pattern
+// covering the subdomains of example.com
+type allowedRanges struct {
+	domains   map[string][]*net.IPNet
+	wildcards map[string][]*net.IPNet
+}
+
+// loadAllowedRanges reads the mapping file, one domain per line followed by its allowed cidrs or ips separated by
+// whitespaces or commas (eg. example.com 192.0.2.0/24,2001:db8::/32)
+func loadAllowedRanges(path string) (*allowedRanges, error) {
+	lines, err := linesInFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ranges := &allowedRanges{domains: make(map[string][]*net.IPNet), wildcards: make(map[string][]*net.IPNet)}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domain, values := splitExpectation(line)
+		if len(values) == 0 {
+			return nil, errors.Errorf("no allowed range for %s", domain)
+		}
+		var networks []*net.IPNet
+		for _, value := range values {
+			network, err := parseAllowedRange(value)
+			if err != nil {
+				return nil, err
+			}
+			networks = append(networks, network)
+		}
+		domain = expectationKey(domain)
+		if pattern := strings.TrimPrefix(domain, "*."); pattern != domain {
+			ranges.wildcards[pattern] = append(ranges.wildcards[pattern], networks...)
+		} else {
+			ranges.domains[domain] = append(ranges.domains[domain], networks...)
+		}
+	}
+	return ranges, nil
+}
+
+// parseAllowedRange parses a cidr, or an ip standing for its single address network
+func parseAllowedRange(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.Errorf("invalid allowed range: %s", value)
+		}
+		return network, nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, errors.Errorf("invalid allowed range: %s", value)
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return &net.IPNet{IP: ipv4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// get returns the networks allowed for the host, nil if it is not covered by the mapping. The host's own entry
+// takes precedence over the patterns, the closest parent pattern applying otherwise.
+func (a *allowedRanges) get(host string) []*net.IPNet {
+	host = expectationKey(host)
+	if networks, ok := a.domains[host]; ok {
+		return networks
+	}
+	for parent := host; strings.Contains(parent, "."); {
+		parent = parent[strings.Index(parent, ".")+1:]
+		if networks, ok := a.wildcards[parent]; ok {
+			return networks
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// resolversFromFile reads the resolvers listed in the file
+func resolversFromFile(filename string) ([]string, error) {
+	lines, err := linesInFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var resolvers []string
+	for _, line := range lines {
+		if line = normalize(line); line != "" {
+			resolvers = append(resolvers, prepareResolver(line))
+		}
+	}
+	return resolvers, nil
+}
+
+// resolversToFile writes the resolvers to the file, one per line
+func resolversToFile(filename string, resolvers []string) error {
+	data := strings.Join(resolvers, "\n") + "\n"
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		return errors.Wrapf(err, "could not write resolvers to %s", filename)
+	}
+	return nil
+}
+
+// watchResolvers reloads the resolvers file on SIGHUP
+func (r *Runner) watchResolvers() {
+	if !fileutil.FileExists(r.options.Resolvers) {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			if err := r.reloadResolvers(); err != nil {
+				gologger.Error().Msgf("Could not reload resolvers, keeping the current ones: %s\n", err)
+			}
+		}
+	}()
+}
+
+// reloadResolvers swaps the active resolvers with the ones of the resolvers file
+func (r *Runner) reloadResolvers() error {
+	resolvers, err := resolversFromFile(r.options.Resolvers)
+	if err != nil {
+		return err
+	}
+	if r.excludedResolvers != nil {
+		resolvers = r.excludedResolvers.filter(resolvers)
+	}
+	if len(resolvers) == 0 {
+		return errors.Errorf("no usable resolver in %s", r.options.Resolvers)
+	}
+	if err := r.dnsx.SetResolvers(resolvers); err != nil {
+		return err
+	}
+	if r.options.ResolversOut != "" {
+		if err := resolversToFile(r.options.ResolversOut, resolvers); err != nil {
+			gologger.Warning().Msgf("%s\n", err)
+		}
+	}
+	gologger.Info().Msgf("Reloaded %d resolvers from %s\n", len(resolvers), r.options.Resolvers)
+	return nil
+}
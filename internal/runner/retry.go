@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/gologger"
+)
+
+// categories of the errored hosts written to the retry file
+const (
+	retryCategoryTimeout  = "timeout"
+	retryCategoryError    = "error"
+	retryCategoryServfail = "servfail"
+	retryCategoryRefused  = "refused"
+)
+
+// retryCategoryMarker separates the target from its error category in the retry file
+const retryCategoryMarker = " #"
+
+// retryWriter writes the deduplicated errored hosts to the retry file
+type retryWriter struct {
+	path  string
+	file  *os.File
+	seen  map[string]struct{}
+	mutex sync.Mutex
+}
+
+func newRetryWriter(path string) *retryWriter {
+	return &retryWriter{path: path, seen: make(map[string]struct{})}
+}
+
+// add writes the target followed by the error category as a comment
+func (w *retryWriter) add(target, category string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.seen[target]; ok {
+		return
+	}
+	w.seen[target] = struct{}{}
+
+	// the file is created on the first failure so that it can also be the input of the run
+	if w.file == nil {
+		file, err := os.Create(w.path)
+		if err != nil {
+			gologger.Error().Msgf("Could not create retry file %s: %s\n", w.path, err)
+			return
+		}
+		w.file = file
+	}
+	if _, err := fmt.Fprintf(w.file, "%s%s %s\n", target, retryCategoryMarker, category); err != nil {
+		gologger.Error().Msgf("Could not write to retry file %s: %s\n", w.path, err)
+	}
+}
+
+// Close closes the retry file, removing the one left by a previous run when no host errored
+func (w *retryWriter) Close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file != nil {
+		w.file.Close()
+		return
+	}
+	if err := os.Remove(w.path); err == nil {
+		gologger.Info().Msgf("No host errored, removed retry file %s\n", w.path)
+	} else if !os.IsNotExist(err) {
+		gologger.Error().Msgf("Could not remove retry file %s: %s\n", w.path, err)
+	}
+}
+
+// stripRetryCategory drops the error category written after the targets of the retry file, the other lines
+// being returned unchanged
+func stripRetryCategory(line string) string {
+	idx := strings.LastIndex(line, retryCategoryMarker)
+	if idx < 0 {
+		return line
+	}
+	switch strings.TrimSpace(line[idx+len(retryCategoryMarker):]) {
+	case retryCategoryTimeout, retryCategoryError, retryCategoryServfail, retryCategoryRefused:
+		return line[:idx]
+	}
+	return line
+}
+
+// queryErrorCategory returns the retry category of a failed query
+func queryErrorCategory(err error) string {
+	// none of the attempts got a response
+	if err == nil {
+		return retryCategoryTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return retryCategoryTimeout
+	}
+	return retryCategoryError
+}
+
+// responseCodeCategory returns the retry category of an errored response code
+func responseCodeCategory(rcode int) string {
+	switch rcode {
+	case dns.RcodeServerFailure:
+		return retryCategoryServfail
+	case dns.RcodeRefused:
+		return retryCategoryRefused
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultCollapseRRThreshold is the default number of addresses from which a round-robin set is collapsed
+	DefaultCollapseRRThreshold = 10
+	// collapseRRSample is the number of addresses displayed along the count of a collapsed set
+	collapseRRSample = 3
+)
+
+// collapseRoundRobin summarizes a large set of addresses as their count followed by the first addresses, or
+// by the cidr blocks covering them
+func collapseRoundRobin(ips []string, cidr bool) string {
+	if cidr {
+		collapser := newCidrCollapser()
+		collapser.add(ips...)
+		ipv4, ipv6 := collapser.collapse(false)
+		var blocks []string
+		for _, network := range append(ipv4, ipv6...) {
+			blocks = append(blocks, network.String())
+		}
+		return fmt.Sprintf("%d records: %s", len(ips), strings.Join(blocks, ","))
+	}
+	if len(ips) <= collapseRRSample {
+		return fmt.Sprintf("%d records: %s", len(ips), strings.Join(ips, ","))
+	}
+	return fmt.Sprintf("%d records: %s,...", len(ips), strings.Join(ips[:collapseRRSample], ","))
+}
@@ -2,12 +2,14 @@ package runner
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/logrusorgru/aurora"
@@ -30,44 +32,114 @@ import (
 
 // Runner is a client for running the enumeration process.
 type Runner struct {
-	options             *Options
-	dnsx                *dnsx.DNSX
-	wgoutputworker      *sync.WaitGroup
-	wgresolveworkers    *sync.WaitGroup
-	wgwildcardworker    *sync.WaitGroup
-	workerchan          chan string
-	outputchan          chan string
-	wildcardworkerchan  chan string
-	wildcards           map[string]struct{}
-	wildcardsmutex      sync.RWMutex
-	wildcardscache      map[string][]string
-	wildcardscachemutex sync.Mutex
-	limiter             *ratelimit.Limiter
-	hm                  *hybrid.HybridMap
-	stats               clistats.StatisticsClient
-	tmpStdinFile        string
-	aurora              aurora.Aurora
-}
-
-func New(options *Options) (*Runner, error) {
+	options            *Options
+	dnsx               *dnsx.DNSX
+	wgoutputworker     *sync.WaitGroup
+	wgresolveworkers   *sync.WaitGroup
+	wgdeferredworkers  *sync.WaitGroup
+	wgwildcardworker   *sync.WaitGroup
+	workerchan         chan string
+	deferredchan       chan deferredResponse
+	outputchan         chan string
+	wildcardworkerchan chan string
+	wildcards          map[string]struct{}
+	wildcardsmutex     sync.RWMutex
+	wildcardProbeCache *wildcardCache
+	limiter            *ratelimit.Limiter
+	ctx                context.Context
+	cancel             context.CancelFunc
+	hm                 *hybrid.HybridMap
+	stats              clistats.StatisticsClient
+	tmpStdinFile       string
+	aurora             aurora.Aurora
+	socketWriter       *socketWriter
+	outputSinks        *outputSinks
+	asnSummary         *asnSummary
+	whoisLookup        *whoisLookup
+	excludedResolvers  *resolverExclusions
+	typeOrderedOutput  *typeOrderedOutput
+	retryWriter        *retryWriter
+	nsSummary          *nsSummary
+	nsInventory        *nsInventory
+	reverseChecks      *reverseChecks
+	execFilter         *execFilter
+	monitor            *monitor
+	hashSummary        *hashSummary
+	externalDeps       *externalDeps
+	cidrCollapser      *cidrCollapser
+	ipIndex            *ipIndex
+	skippedHosts       atomic.Uint64
+	expectations       *expectations
+	authServers        *dnsx.AuthoritativeServers
+	expectFailures     atomic.Uint64
+	allowedRanges      *allowedRanges
+	rangeViolations    atomic.Uint64
+	dotGraph           *dotGraph
+	targetConfig       *targetConfig
+	nxHijack           *dnsx.NXHijackSignature
+	inputErr           error
+	queryLogFile       *os.File
+	wordExtractor      *wordExtractor
+}
+
+// workerchanSize returns the number of targets buffered for the workers, the generation running ahead of the
+// workers by at most one target per thread in low memory mode
+func workerchanSize(options *Options) int {
+	if options.LowMemory {
+		return options.Threads
+	}
+	return 0
+}
+
+func New(options *Options) (_ *Runner, err error) {
 	retryabledns.CheckInternalIPs = true
 
+	// the resources opened so far are released, in reverse order, when a later step fails
+	var closers []func()
+	defer func() {
+		if err != nil {
+			for i := len(closers) - 1; i >= 0; i-- {
+				closers[i]()
+			}
+		}
+	}()
+
 	dnsxOptions := dnsx.DefaultOptions
 	dnsxOptions.MaxRetries = options.Retries
+	dnsxOptions.Timeout = options.QueryTimeout
+	dnsxOptions.TimeoutEscalation = options.TimeoutEscalation
+	dnsxOptions.MaxTimeout = options.MaxQueryTimeout
 	dnsxOptions.TraceMaxRecursion = options.TraceMaxRecursion
 	dnsxOptions.Hostsfile = options.HostsFile
 	dnsxOptions.OutputCDN = options.OutputCDN
+	dnsxOptions.Offline = options.Offline
+	dnsxOptions.EDNSVersion = uint8(options.EDNSVersion)
+	dnsxOptions.Padding = options.Padding
+	dnsxOptions.NSID = options.NSID
+	dnsxOptions.ResolverHash = options.ResolverHash
+	if options.SizeStats {
+		dnsxOptions.SizeStats = dnsx.NewSizeStats()
+	}
+	if options.ResolverStats {
+		dnsxOptions.ResolverStats = dnsx.NewResolverStats()
+	}
+	var queryLogFile *os.File
+	if options.QueryLog != "" {
+		if queryLogFile, err = os.Create(options.QueryLog); err != nil {
+			return nil, errors.Wrap(err, "could not create query log")
+		}
+		closers = append(closers, func() { queryLogFile.Close() })
+		dnsxOptions.QueryLog = dnsx.NewQueryLog(queryLogFile)
+	}
 	if options.Resolvers != "" {
 		dnsxOptions.BaseResolvers = []string{}
 		// If it's a file load resolvers from it
 		if fileutil.FileExists(options.Resolvers) {
-			rs, err := linesInFile(options.Resolvers)
+			rs, err := resolversFromFile(options.Resolvers)
 			if err != nil {
-				gologger.Fatal().Msgf("%s\n", err)
-			}
-			for _, rr := range rs {
-				dnsxOptions.BaseResolvers = append(dnsxOptions.BaseResolvers, prepareResolver(rr))
+				return nil, errors.Wrap(err, "could not read resolvers")
 			}
+			dnsxOptions.BaseResolvers = rs
 		} else {
 			// otherwise gets comma separated ones
 			for _, rr := range strings.Split(options.Resolvers, ",") {
@@ -75,6 +147,36 @@ func New(options *Options) (*Runner, error) {
 			}
 		}
 	}
+	var excludedResolvers *resolverExclusions
+	if options.ExcludeResolvers != "" {
+		excludedResolvers, err = loadResolverExclusions(options.ExcludeResolvers)
+		if err != nil {
+			return nil, err
+		}
+		baseResolvers := excludedResolvers.filter(dnsxOptions.BaseResolvers)
+		gologger.Info().Msgf("Excluded %d resolvers\n", len(dnsxOptions.BaseResolvers)-len(baseResolvers))
+		if len(baseResolvers) == 0 {
+			return nil, errors.New("all resolvers have been excluded")
+		}
+		dnsxOptions.BaseResolvers = baseResolvers
+	}
+
+	dnsxOptions.BootstrapResolver = options.BootstrapResolver
+	dnsxOptions.QueryIDMode = options.QueryIDMode
+	dnsxOptions.QueryID = uint16(options.QueryID)
+	if options.QueryIDMode != dnsx.QueryIDRandom {
+		gologger.Warning().Msgf("Query ids are %s instead of random: the responses can be spoofed by guessing them, use this mode for lab testing only\n", options.QueryIDMode)
+	}
+	dnsxOptions.DoHUserAgent = &options.DoHUserAgent
+	dnsxOptions.NoCompression = options.NoCompression
+	dnsxOptions.DoTInsecure = options.DoTInsecure
+	if options.BootstrapResolver == "" {
+		for _, resolver := range dnsxOptions.BaseResolvers {
+			if isEncryptedResolver(resolver) && !iputil.IsIP(resolverHost(resolver)) {
+				gologger.Verbose().Msgf("Resolving %s with the system resolver, use -bootstrap-resolver to override\n", resolverHost(resolver))
+			}
+		}
+	}
 
 	var questionTypes []uint16
 	if options.A {
@@ -110,29 +212,72 @@ func New(options *Options) (*Runner, error) {
 	if options.CAA {
 		questionTypes = append(questionTypes, dns.TypeCAA)
 	}
+	if options.TLSA {
+		questionTypes = append(questionTypes, dns.TypeTLSA)
+	}
+	if options.DNSKEY {
+		questionTypes = append(questionTypes, dns.TypeDNSKEY)
+	}
+	if options.DS {
+		questionTypes = append(questionTypes, dns.TypeDS)
+	}
+	if options.HTTPS {
+		questionTypes = append(questionTypes, dns.TypeHTTPS)
+	}
+	if options.SVCB {
+		questionTypes = append(questionTypes, dns.TypeSVCB)
+	}
+	if options.NAPTR {
+		questionTypes = append(questionTypes, dns.TypeNAPTR)
+	}
+	if options.SSHFP {
+		questionTypes = append(questionTypes, dns.TypeSSHFP)
+	}
+	if options.DNAME {
+		questionTypes = append(questionTypes, dns.TypeDNAME)
+	}
 
 	// If no option is specified or wildcard filter has been requested use query type A
 	if len(questionTypes) == 0 || options.WildcardDomain != "" {
 		options.A = true
 		questionTypes = append(questionTypes, dns.TypeA)
 	}
-	dnsxOptions.QuestionTypes = questionTypes
+	dnsxOptions.QuestionTypes = prioritizeQuestionTypes(questionTypes, options.TypePriority)
 	dnsxOptions.QueryAll = options.QueryAll
 
 	dnsX, err := dnsx.New(dnsxOptions)
 	if err != nil {
 		return nil, err
 	}
+	closers = append(closers, dnsX.Close)
 
-	limiter := ratelimit.NewUnlimited(context.Background())
-	if options.RateLimit > 0 {
-		limiter = ratelimit.New(context.Background(), uint(options.RateLimit), time.Second)
+	if options.ResolversOut != "" {
+		if err := resolversToFile(options.ResolversOut, dnsxOptions.BaseResolvers); err != nil {
+			return nil, err
+		}
+	}
+
+	var nxHijack *dnsx.NXHijackSignature
+	if options.DetectNXHijack {
+		nxHijack = dnsX.ProbeNXHijack()
+		for resolver, ips := range nxHijack.Resolvers {
+			gologger.Warning().Msgf("Resolver %s redirects NXDOMAIN to %s, its answers with these addresses are dropped\n", resolver, strings.Join(ips, ","))
+		}
+	}
+
+	var targetConfig *targetConfig
+	if options.TargetConfig != "" {
+		targetConfig, err = loadTargetConfig(options.TargetConfig, dnsX)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	hm, err := hybrid.New(hybrid.DefaultDiskOptions)
 	if err != nil {
 		return nil, err
 	}
+	closers = append(closers, func() { hm.Close() })
 
 	var stats clistats.StatisticsClient
 	if options.ShowStatistics {
@@ -146,53 +291,269 @@ func New(options *Options) (*Runner, error) {
 		options.NoColor = true
 	}
 
+	var socketWriter *socketWriter
+	if options.OutputSocket != "" {
+		socketWriter, err = newSocketWriter(options.OutputSocket)
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, func() { socketWriter.Close() })
+	}
+
+	var outputSinks *outputSinks
+	if len(options.OutputSinks) > 0 {
+		outputSinks, err = newOutputSinks(options.OutputSinks)
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, outputSinks.close)
+	}
+
+	var typeOrderedOutput *typeOrderedOutput
+	if options.OutputOrder == outputOrderType {
+		typeOrderedOutput = newTypeOrderedOutput()
+	}
+
+	var asnSummary *asnSummary
+	if options.AsnSummary {
+		asnSummary = newAsnSummary()
+	}
+
+	var nsSummary *nsSummary
+	if options.NSSummary {
+		nsSummary = newNsSummary()
+	}
+
+	var nsInventory *nsInventory
+	if options.NSInventory {
+		nsInventory = newNSInventory(func(name string) dnsx.Nameserver {
+			nameserver, err := dnsX.ResolveNameserver(name)
+			if err != nil {
+				gologger.Verbose().Msgf("%s: could not resolve nameserver: %s\n", nameserver.Name, err)
+			}
+			if options.ASN {
+				nameserver.ASN = lookupASN(nameserver.IPs)
+			}
+			return *nameserver
+		})
+	}
+
+	var reverseChecks *reverseChecks
+	if options.DualStackCheck {
+		reverseChecks = newReverseChecks(dnsX.CheckReverse)
+	}
+
+	var execFilter *execFilter
+	if options.ExecFilter != "" {
+		execFilter = newExecFilter(options.ExecFilter, options.ExecFilterThreads, options.ExecFilterTimeout)
+	}
+
+	var allowedRanges *allowedRanges
+	if options.AllowedRanges != "" {
+		allowedRanges, err = loadAllowedRanges(options.AllowedRanges)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load allowed ranges")
+		}
+	}
+
+	var monitor *monitor
+	if options.Monitor {
+		monitor, err = newMonitor(options.MonitorState)
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, monitor.close)
+	}
+
+	var externalDeps *externalDeps
+	if options.ExternalDeps {
+		externalDeps = newExternalDeps()
+	}
+
+	var hashSummary *hashSummary
+	if options.HashSummary {
+		hashSummary = newHashSummary()
+	}
+
+	var cidrCollapser *cidrCollapser
+	if options.CollapseCIDR {
+		cidrCollapser = newCidrCollapser()
+	}
+
+	var expectations *expectations
+	if options.Expect {
+		expectations = newExpectations()
+	}
+
+	var authServers *dnsx.AuthoritativeServers
+	if options.CompareAuth {
+		authServers = dnsX.NewAuthoritativeServers()
+	}
+
+	var wordExtractor *wordExtractor
+	if options.ExtractWords != "" {
+		wordExtractor = newWordExtractor(options.ExtractWords)
+	}
+
+	var ipIndex *ipIndex
+	if options.ByIP {
+		ipIndex, err = newIPIndex(options.ByIPDisk)
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, ipIndex.close)
+	}
+
+	var dotGraph *dotGraph
+	if options.DotFile != "" {
+		dotGraph = newDotGraph(options.DotFile, options.DotMaxEdges)
+	}
+
+	var retryWriter *retryWriter
+	if options.RetryFile != "" {
+		retryWriter = newRetryWriter(options.RetryFile)
+	}
+
+	// root context of the run, canceling it aborts the in-flight queries
+	ctx, cancel := context.WithCancel(context.Background())
+	closers = append(closers, cancel)
+
+	limiter := ratelimit.NewUnlimited(ctx)
+	if options.RateLimit > 0 {
+		limiter = ratelimit.New(ctx, uint(options.RateLimit), time.Second)
+	}
+
+	wildcardProbeCache, err := newWildcardCache(options.WildcardCacheDir, options.WildcardCacheTTL, options.WildcardReprobe)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open wildcard cache")
+	}
+	closers = append(closers, wildcardProbeCache.close)
+
+	var whoisLookup *whoisLookup
+	if options.Whois {
+		whoisLookup, err = newWhoisLookup(ctx, options.WhoisRateLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	r := Runner{
 		options:            options,
 		dnsx:               dnsX,
 		wgoutputworker:     &sync.WaitGroup{},
 		wgresolveworkers:   &sync.WaitGroup{},
+		wgdeferredworkers:  &sync.WaitGroup{},
 		wgwildcardworker:   &sync.WaitGroup{},
-		workerchan:         make(chan string),
+		workerchan:         make(chan string, workerchanSize(options)),
 		wildcardworkerchan: make(chan string),
 		wildcards:          make(map[string]struct{}),
-		wildcardscache:     make(map[string][]string),
+		wildcardProbeCache: wildcardProbeCache,
 		limiter:            limiter,
+		ctx:                ctx,
+		cancel:             cancel,
 		hm:                 hm,
 		stats:              stats,
 		aurora:             aurora.NewAurora(!options.NoColor),
+		socketWriter:       socketWriter,
+		outputSinks:        outputSinks,
+		asnSummary:         asnSummary,
+		whoisLookup:        whoisLookup,
+		excludedResolvers:  excludedResolvers,
+		typeOrderedOutput:  typeOrderedOutput,
+		retryWriter:        retryWriter,
+		queryLogFile:       queryLogFile,
+		nsSummary:          nsSummary,
+		nsInventory:        nsInventory,
+		reverseChecks:      reverseChecks,
+		execFilter:         execFilter,
+		monitor:            monitor,
+		allowedRanges:      allowedRanges,
+		hashSummary:        hashSummary,
+		externalDeps:       externalDeps,
+		cidrCollapser:      cidrCollapser,
+		ipIndex:            ipIndex,
+		wordExtractor:      wordExtractor,
+		expectations:       expectations,
+		authServers:        authServers,
+		dotGraph:           dotGraph,
+		targetConfig:       targetConfig,
+		nxHijack:           nxHijack,
 	}
+	r.watchResolvers()
 
 	return &r, nil
 }
 
 func (r *Runner) InputWorkerStream() {
-	var sc *bufio.Scanner
+	defer close(r.workerchan)
+
+	var reader io.Reader
 	// attempt to load list from file
 	if fileutil.FileExists(r.options.Hosts) {
-		f, _ := os.Open(r.options.Hosts)
-		sc = bufio.NewScanner(f)
+		f, err := os.Open(r.options.Hosts)
+		if err != nil {
+			r.inputErr = err
+			return
+		}
+		reader = f
 	} else if fileutil.HasStdin() {
-		sc = bufio.NewScanner(os.Stdin)
+		reader = os.Stdin
+	} else {
+		r.inputErr = errors.New("hosts file or stdin not provided")
+		return
 	}
 
-	for sc.Scan() {
-		item := strings.TrimSpace(sc.Text())
+	input := newLineReader(reader, int(r.options.MaxLineSize))
+	defer input.Close()
+	for line := range input.lines {
+		line = normalize(stripRetryCategory(line))
+		// in expect mode the target is followed by the expected values
+		var expected []string
+		if r.expectations != nil {
+			line, expected = splitExpectation(line)
+		}
+		item, resolver := splitTargetResolver(line)
+		if item == "" {
+			continue
+		}
 		switch {
 		case iputil.IsCIDR(item):
 			hostsC, _ := mapcidr.IPAddressesAsStream(item)
 			for host := range hostsC {
-				r.workerchan <- host
+				if !r.skipHost(host) {
+					r.workerchan <- joinTargetResolver(host, resolver)
+				}
 			}
 		case asn.IsASN(item):
-			hostsC, _ := asn.GetIPAddressesAsStream(item)
+			hostsC, err := r.asnIPAddressesAsStream(item)
+			if err != nil {
+				gologger.Warning().Msgf("Could not expand asn %s: %s\n", item, err)
+				continue
+			}
 			for host := range hostsC {
-				r.workerchan <- host
+				if !r.skipHost(host) {
+					r.workerchan <- joinTargetResolver(host, resolver)
+				}
 			}
 		default:
-			r.workerchan <- item
+			hosts := affixHosts([]string{item}, r.options.Prefix, r.options.Suffix)
+			hosts = srvHosts(hosts, r.options.srvNames)
+			hosts = tlsaHosts(hosts, r.options.tlsaPorts)
+			r.setExpectations(hosts, expected)
+			if r.options.AlsoWWW {
+				hosts = pairWWW(hosts)
+			}
+			for _, host := range hosts {
+				if !r.skipHost(host) {
+					r.workerchan <- joinTargetResolver(host, resolver)
+				}
+			}
 		}
 	}
-	close(r.workerchan)
+	r.inputErr = input.Err()
+	if skipped := r.skippedHosts.Load(); skipped > 0 {
+		gologger.Verbose().Msgf("Skipped %d hosts matching the skip regex\n", skipped)
+	}
 }
 
 func (r *Runner) InputWorker() {
@@ -214,10 +575,18 @@ func (r *Runner) InputWorker() {
 	close(r.workerchan)
 }
 
+// InputWorkerLowMemory generates the targets while they are resolved, the generation blocking as long as the
+// workers are busy
+func (r *Runner) InputWorkerLowMemory() {
+	r.inputErr = r.prepareInput()
+	close(r.workerchan)
+}
+
 func (r *Runner) prepareInput() error {
 	var (
 		dataDomains chan string
 		sc          chan string
+		input       *lineReader
 		err         error
 	)
 
@@ -253,24 +622,42 @@ func (r *Runner) prepareInput() error {
 	if sc == nil {
 		// attempt to load list from file
 		if fileutil.FileExists(r.options.Hosts) {
-			f, err := fileutil.ReadFile(r.options.Hosts)
+			input, err = newFileLineReader(r.options.Hosts, int(r.options.MaxLineSize))
 			if err != nil {
 				return err
 			}
-			sc = f
+			sc = input.lines
 		} else if argumentHasStdin(r.options.Hosts) || hasStdin {
-			sc, err = fileutil.ReadFile(r.tmpStdinFile)
+			input, err = newFileLineReader(r.tmpStdinFile, int(r.options.MaxLineSize))
 			if err != nil {
 				return err
 			}
+			sc = input.lines
 		} else {
 			return errors.New("hosts file or stdin not provided")
 		}
+		// the early returns leave lines unread
+		defer input.Close()
+	}
+
+	// in low memory mode the hosts are counted as they are generated
+	if r.options.LowMemory && r.options.ShowStatistics {
+		r.startStats(0)
 	}
 
 	numHosts := 0
-	for item := range sc {
-		item := normalize(item)
+	for line := range sc {
+		line = normalize(stripRetryCategory(line))
+		// in expect mode the target is followed by the expected values
+		var expected []string
+		if r.expectations != nil {
+			line, expected = splitExpectation(line)
+		}
+		// the optional @resolver suffix overrides the resolvers pool for the target
+		item, resolver := splitTargetResolver(line)
+		if item == "" {
+			continue
+		}
 		var hosts []string
 		switch {
 		case strings.Contains(item, "FUZZ"):
@@ -282,7 +669,13 @@ func (r *Runner) prepareInput() error {
 				subdomain := strings.ReplaceAll(item, "FUZZ", r)
 				hosts = append(hosts, subdomain)
 			}
-			numHosts += r.addHostsToHMapFromList(hosts)
+			hosts = affixHosts(hosts, r.options.Prefix, r.options.Suffix)
+			hosts = srvHosts(hosts, r.options.srvNames)
+			hosts = tlsaHosts(hosts, r.options.tlsaPorts)
+			if r.options.AlsoWWW {
+				hosts = pairWWW(hosts)
+			}
+			numHosts += r.addHostsToHMapFromList(hosts, resolver)
 		case r.options.WordList != "":
 			// prepare wordlist
 			prefixes, err := r.preProcessArgument(r.options.WordList)
@@ -294,44 +687,117 @@ func (r *Runner) prepareInput() error {
 				subdomain := strings.TrimSpace(prefix) + "." + item
 				hosts = append(hosts, subdomain)
 			}
-			numHosts += r.addHostsToHMapFromList(hosts)
+			hosts = affixHosts(hosts, r.options.Prefix, r.options.Suffix)
+			hosts = srvHosts(hosts, r.options.srvNames)
+			hosts = tlsaHosts(hosts, r.options.tlsaPorts)
+			if r.options.AlsoWWW {
+				hosts = pairWWW(hosts)
+			}
+			numHosts += r.addHostsToHMapFromList(hosts, resolver)
 		case iputil.IsCIDR(item):
 			hostC, err := mapcidr.IPAddressesAsStream(item)
 			if err != nil {
 				return err
 			}
-			numHosts += r.addHostsToHMapFromChan(hostC)
+			numHosts += r.addHostsToHMapFromChan(hostC, resolver)
 		case asn.IsASN(item):
-			hostC, err := asn.GetIPAddressesAsStream(item)
+			hostC, err := r.asnIPAddressesAsStream(item)
 			if err != nil {
 				return err
 			}
-			numHosts += r.addHostsToHMapFromChan(hostC)
+			numHosts += r.addHostsToHMapFromChan(hostC, resolver)
 		default:
-			hosts = []string{item}
-			numHosts += r.addHostsToHMapFromList(hosts)
+			hosts = affixHosts([]string{item}, r.options.Prefix, r.options.Suffix)
+			hosts = srvHosts(hosts, r.options.srvNames)
+			hosts = tlsaHosts(hosts, r.options.tlsaPorts)
+			r.setExpectations(hosts, expected)
+			if r.options.AlsoWWW {
+				hosts = pairWWW(hosts)
+			}
+			numHosts += r.addHostsToHMapFromList(hosts, resolver)
 		}
 	}
-	if r.options.ShowStatistics {
-		r.stats.AddStatic("hosts", numHosts)
-		r.stats.AddStatic("startedAt", time.Now())
-		r.stats.AddCounter("requests", 0)
-		r.stats.AddCounter("total", uint64(numHosts*len(r.dnsx.Options.QuestionTypes)))
-		r.stats.AddDynamic("summary", makePrintCallback())
-		// nolint:errcheck
-		r.stats.Start()
-		r.stats.GetStatResponse(time.Second*5, func(s string, err error) error {
-			if err != nil && r.options.Verbose {
-				gologger.Error().Msgf("Could not read statistics: %s\n", err)
-			}
-			return nil
-		})
+	if input != nil {
+		if err := input.Err(); err != nil {
+			return err
+		}
+	}
+	if skipped := r.skippedHosts.Load(); skipped > 0 {
+		gologger.Verbose().Msgf("Skipped %d hosts matching the skip regex\n", skipped)
+	}
+	if r.options.ShowStatistics && !r.options.LowMemory {
+		r.startStats(numHosts)
 	}
 	return nil
 }
 
-func (r *Runner) addHostsToHMapFromList(hosts []string) (numHosts int) {
+// startStats starts the statistics of the scan, the hosts being counted as they are generated in low memory mode
+func (r *Runner) startStats(numHosts int) {
+	if r.options.LowMemory {
+		r.stats.AddCounter("hosts", 0)
+	} else {
+		r.stats.AddStatic("hosts", numHosts)
+	}
+	r.stats.AddStatic("startedAt", time.Now())
+	r.stats.AddCounter("requests", 0)
+	r.stats.AddCounter("total", uint64(numHosts*len(r.dnsx.Options.QuestionTypes)))
+	r.stats.AddDynamic("summary", makePrintCallback())
+	// nolint:errcheck
+	r.stats.Start()
+	r.stats.GetStatResponse(time.Second*5, func(s string, err error) error {
+		if err != nil && r.options.Verbose {
+			gologger.Error().Msgf("Could not read statistics: %s\n", err)
+		}
+		return nil
+	})
+}
+
+// targetGroup returns the target config group matching the domain, if any
+func (r *Runner) targetGroup(domain string) *targetGroup {
+	if r.targetConfig == nil {
+		return nil
+	}
+	return r.targetConfig.match(domain)
+}
+
+// questionTypesFor returns the question types sent for the domain
+func (r *Runner) questionTypesFor(domain string) []uint16 {
+	if group := r.targetGroup(domain); group != nil {
+		return r.dnsx.QuestionTypesWithProfile(domain, group.profile)
+	}
+	return r.dnsx.QuestionTypesFor(domain)
+}
+
+// skipHost returns true if the host matches the skip regex
+func (r *Runner) skipHost(host string) bool {
+	if r.options.skipRegex == nil || !r.options.skipRegex.MatchString(host) {
+		return false
+	}
+	r.skippedHosts.Add(1)
+	return true
+}
+
+// sendHost hands the target straight to the workers in low memory mode, without duplicate removal
+func (r *Runner) sendHost(host string) {
+	if r.stats != nil {
+		r.stats.IncrementCounter("hosts", 1)
+		r.stats.IncrementCounter("total", len(r.dnsx.Options.QuestionTypes))
+		r.stats.IncrementCounter("requests", len(r.dnsx.Options.QuestionTypes))
+	}
+	r.workerchan <- host
+}
+
+func (r *Runner) addHostsToHMapFromList(hosts []string, resolver string) (numHosts int) {
 	for _, host := range hosts {
+		if r.skipHost(host) {
+			continue
+		}
+		host = joinTargetResolver(host, resolver)
+		if r.options.LowMemory {
+			numHosts++
+			r.sendHost(host)
+			continue
+		}
 		// Used just to get the exact number of targets
 		if _, ok := r.hm.Get(host); ok {
 			continue
@@ -343,8 +809,17 @@ func (r *Runner) addHostsToHMapFromList(hosts []string) (numHosts int) {
 	return
 }
 
-func (r *Runner) addHostsToHMapFromChan(hosts chan string) (numHosts int) {
+func (r *Runner) addHostsToHMapFromChan(hosts chan string, resolver string) (numHosts int) {
 	for host := range hosts {
+		if r.skipHost(host) {
+			continue
+		}
+		host = joinTargetResolver(host, resolver)
+		if r.options.LowMemory {
+			numHosts++
+			r.sendHost(host)
+			continue
+		}
 		// Used just to get the exact number of targets
 		if _, ok := r.hm.Get(host); ok {
 			continue
@@ -389,6 +864,9 @@ func makePrintCallback() func(stats clistats.StatisticsClient) interface{} {
 		builder.WriteRune(']')
 
 		hosts, _ := stats.GetStatic("hosts")
+		if count, ok := stats.GetCounter("hosts"); ok {
+			hosts = count
+		}
 		builder.WriteString(" | Hosts: ")
 		builder.WriteString(clistats.String(hosts))
 
@@ -426,17 +904,97 @@ func (r *Runner) SaveResumeConfig() error {
 }
 
 func (r *Runner) Run() error {
+	if r.options.Prime {
+		r.runPrime()
+		return nil
+	}
+	if r.monitor != nil {
+		return r.runMonitor()
+	}
+
+	var err error
 	if r.options.Stream {
-		return r.runStream()
+		err = r.runStream()
+	} else {
+		err = r.run()
+	}
+	if err != nil {
+		return err
 	}
 
-	return r.run()
+	if r.cidrCollapser != nil {
+		r.outputCollapsedCIDRs()
+	}
+	if r.ipIndex != nil {
+		r.outputIPIndex()
+	}
+	if r.wordExtractor != nil {
+		r.outputWords()
+	}
+	if r.dotGraph != nil {
+		if err := r.dotGraph.write(); err != nil {
+			gologger.Error().Msgf("Could not write dot graph %s: %s\n", r.options.DotFile, err)
+		}
+	}
+	if r.asnSummary != nil {
+		r.asnSummary.print()
+	}
+	if r.nsSummary != nil {
+		var lookup func(string) ([]string, error)
+		if r.options.NSSummaryResolve {
+			lookup = r.dnsx.Lookup
+		}
+		r.nsSummary.print(lookup)
+	}
+	if r.hashSummary != nil {
+		r.hashSummary.print()
+	}
+	if r.externalDeps != nil {
+		r.externalDeps.print()
+	}
+	if r.dnsx.Options.SizeStats != nil {
+		printSizeStats(r.dnsx.Options.SizeStats)
+	}
+	if r.dnsx.Options.ResolverStats != nil {
+		printResolverStats(r.dnsx.Options.ResolverStats)
+	}
+	if r.execFilter != nil {
+		if failures := r.execFilter.failures.Load(); failures > 0 {
+			gologger.Warning().Msgf("exec-filter failed on %d records, written unchanged (-v for the errors)\n", failures)
+		}
+	}
+	if r.options.QueryIDMode != dnsx.QueryIDRandom {
+		if mismatches := r.dnsx.IDMismatches(); mismatches > 0 {
+			gologger.Warning().Msgf("%d responses echoed a query id different from the sent one\n", mismatches)
+		} else {
+			gologger.Info().Msgf("No response echoed a query id different from the sent one\n")
+		}
+	}
+	if failures := r.expectFailures.Load(); failures > 0 {
+		return errors.Errorf("%d hosts did not match the expected records", failures)
+	}
+	if violations := r.rangeViolations.Load(); violations > 0 {
+		return errors.Errorf("%d hosts resolved outside their allowed ranges", violations)
+	}
+	return nil
 }
 
 func (r *Runner) run() error {
-	err := r.prepareInput()
-	if err != nil {
-		return err
+	var err error
+	// in low memory mode the input is prepared by the input worker
+	if !r.options.LowMemory {
+		err = r.prepareInput()
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.options.ListTargets {
+		r.listTargets()
+		if r.stats != nil {
+			return r.stats.Stop()
+		}
+		return nil
 	}
 
 	// if resume is enabled inform the user
@@ -446,7 +1004,10 @@ func (r *Runner) run() error {
 
 	r.startWorkers()
 
-	r.wgresolveworkers.Wait()
+	r.waitWorkers()
+	if r.typeOrderedOutput != nil {
+		r.typeOrderedOutput.flush(r.outputchan)
+	}
 	if r.stats != nil {
 		err = r.stats.Stop()
 		if err != nil {
@@ -456,11 +1017,16 @@ func (r *Runner) run() error {
 
 	close(r.outputchan)
 	r.wgoutputworker.Wait()
+	if r.inputErr != nil {
+		return r.inputErr
+	}
 
 	if r.options.WildcardDomain != "" {
 		gologger.Print().Msgf("Starting to filter wildcard subdomains\n")
 		ipDomain := make(map[string]map[string]struct{})
 		listIPs := []string{}
+		// hosts answering only with cname records can't be compared by ip
+		var cnameOnlyHosts []string
 		// prepare in memory structure similarly to shuffledns
 		r.hm.Scan(func(k, v []byte) error {
 			var dnsdata retryabledns.DNSData
@@ -470,6 +1036,9 @@ func (r *Runner) run() error {
 				return nil
 			}
 
+			if r.options.CNAME && len(dnsdata.A) == 0 && len(dnsdata.CNAME) > 0 {
+				cnameOnlyHosts = append(cnameOnlyHosts, string(k))
+			}
 			for _, a := range dnsdata.A {
 				_, ok := ipDomain[a]
 				if !ok {
@@ -532,6 +1101,9 @@ func (r *Runner) run() error {
 				}
 			}
 		}
+		for _, host := range cnameOnlyHosts {
+			_ = r.lookupAndOutput(host)
+		}
 		close(r.outputchan)
 		// waiting output worker
 		r.wgoutputworker.Wait()
@@ -541,6 +1113,37 @@ func (r *Runner) run() error {
 	return nil
 }
 
+// outputCollapsedCIDRs outputs the blocks covering the resolved addresses, ipv4 first
+func (r *Runner) outputCollapsedCIDRs() {
+	r.startOutputWorker()
+	ipv4, ipv6 := r.cidrCollapser.collapse(r.options.CollapseCIDRApprox)
+	for _, network := range append(ipv4, ipv6...) {
+		r.outputchan <- network.String()
+	}
+	close(r.outputchan)
+	r.wgoutputworker.Wait()
+}
+
+// listTargets outputs the prepared targets without querying them
+func (r *Runner) listTargets() {
+	r.startOutputWorker()
+	seen := make(map[string]struct{})
+	r.hm.Scan(func(k, _ []byte) error {
+		target, resolver := splitTargetResolver(string(k))
+		if isURL(target) {
+			target = extractDomain(target)
+		}
+		target = joinTargetResolver(target, resolver)
+		if _, ok := seen[target]; !ok {
+			seen[target] = struct{}{}
+			r.outputchan <- target
+		}
+		return nil
+	})
+	close(r.outputchan)
+	r.wgoutputworker.Wait()
+}
+
 func (r *Runner) lookupAndOutput(host string) error {
 	if r.options.JSON {
 		if data, ok := r.hm.Get(host); ok {
@@ -565,12 +1168,15 @@ func (r *Runner) lookupAndOutput(host string) error {
 func (r *Runner) runStream() error {
 	r.startWorkers()
 
-	r.wgresolveworkers.Wait()
+	r.waitWorkers()
+	if r.typeOrderedOutput != nil {
+		r.typeOrderedOutput.flush(r.outputchan)
+	}
 
 	close(r.outputchan)
 	r.wgoutputworker.Wait()
 
-	return nil
+	return r.inputErr
 }
 
 func (r *Runner) HandleOutput() {
@@ -589,13 +1195,37 @@ func (r *Runner) HandleOutput() {
 		}
 		defer foutput.Close()
 		w = bufio.NewWriter(foutput)
+		if r.options.CompressOutput || strings.HasSuffix(r.options.OutputFile, ".gz") {
+			// each output session appends a gzip member, the buffer being flushed before the member is closed
+			gw := gzip.NewWriter(foutput)
+			defer gw.Close()
+			w = bufio.NewWriter(gw)
+		}
 		defer w.Flush()
 	}
 	for item := range r.outputchan {
+		// messagepack records are length prefixed and written as they are
+		if r.options.MsgPack {
+			if foutput != nil {
+				_, _ = w.WriteString(item)
+			}
+			if r.socketWriter != nil {
+				r.socketWriter.WriteString(item)
+			}
+			_, _ = os.Stdout.WriteString(item)
+			continue
+		}
+		// each bulk document follows its action line
+		if r.options.ESBulk {
+			item = r.options.esAction + "\n" + item
+		}
 		if foutput != nil {
 			// uses a buffer to write to file
 			_, _ = w.WriteString(item + "\n")
 		}
+		if r.socketWriter != nil {
+			r.socketWriter.WriteString(item + "\n")
+		}
 		// writes sequentially to stdout
 		gologger.Silent().Msgf("%s\n", item)
 	}
@@ -609,13 +1239,17 @@ func (r *Runner) startOutputWorker() {
 }
 
 func (r *Runner) startWorkers() {
-	if r.options.Stream {
+	switch {
+	case r.options.Stream:
 		go r.InputWorkerStream()
-	} else {
+	case r.options.LowMemory:
+		go r.InputWorkerLowMemory()
+	default:
 		go r.InputWorker()
 	}
 
 	r.startOutputWorker()
+	r.startDeferredWorkers()
 	// resolve workers
 	for i := 0; i < r.options.Threads; i++ {
 		r.wgresolveworkers.Add(1)
@@ -625,174 +1259,684 @@ func (r *Runner) startWorkers() {
 
 func (r *Runner) worker() {
 	defer r.wgresolveworkers.Done()
-	for domain := range r.workerchan {
-		if isURL(domain) {
-			domain = extractDomain(domain)
-		}
-		r.limiter.Take()
-		dnsData := dnsx.ResponseData{}
-		// Ignoring errors as partial results are still good
-		dnsData.DNSData, _ = r.dnsx.QueryMultiple(domain)
-		// Just skipping nil responses (in case of critical errors)
-		if dnsData.DNSData == nil {
+	for target := range r.workerchan {
+		// the targets left once canceled are drained without being queried
+		if r.ctx.Err() != nil {
 			continue
 		}
+		r.resolveTarget(target)
+	}
+}
 
-		if dnsData.Host == "" || dnsData.Timestamp.IsZero() {
-			continue
+// resolveTarget queries the target, runs the checks and follow-up queries enabled on its response and outputs it
+func (r *Runner) resolveTarget(target string) {
+	domain, resolver := splitTargetResolver(target)
+	if isURL(domain) {
+		domain = extractDomain(domain)
+	}
+	if resolver != "" {
+		resolver = prepareResolver(resolver)
+		if r.excludedResolvers != nil && r.excludedResolvers.Contains(resolverHost(resolver)) {
+			gologger.Warning().Msgf("%s: skipping excluded resolver %s\n", domain, resolver)
+			return
 		}
+	}
+	r.sleepDelay()
+	r.limiter.Take()
+	// the name actually queried once the input transforms are applied
+	dnsData := dnsx.ResponseData{QueryName: dnsx.QueryName(domain)}
+	// the attempts of the queries and of their follow-ups (empty answer, tcp) are counted apart, every
+	// attempt of a follow-up being a retry
+	attempts, followUps := dnsx.NewAttemptCounter(), dnsx.NewAttemptCounter()
+	ctx, followUpCtx := attempts.Context(r.ctx), followUps.Context(r.ctx)
+	// the responses of the queries themselves are checked for spoofing
+	var spoofDetector *dnsx.SpoofDetector
+	if r.options.DetectSpoof {
+		spoofDetector = dnsx.NewSpoofDetector()
+		ctx = spoofDetector.Context(ctx)
+	}
+	var err error
+	dnsData.DNSData, err = r.queryTarget(ctx, domain, resolver)
+	if r.ctx.Err() != nil {
+		return
+	}
+	// Just skipping nil responses (in case of critical errors)
+	if dnsData.DNSData == nil || dnsData.Host == "" || dnsData.Timestamp.IsZero() {
+		r.outputFailure(domain, resolver, err, &dnsData)
+		return
+	}
 
-		// results from hosts file are always returned
-		if !dnsData.HostsFile {
-			// skip responses not having the expected response code
-			if len(r.options.rcodes) > 0 {
-				if _, ok := r.options.rcodes[dnsData.StatusCodeRaw]; !ok {
-					continue
-				}
-			}
+	resolvers := r.retryResponse(followUpCtx, domain, resolver, &dnsData)
+	dnsData.Retries = attempts.Retries() + followUps.Attempts()
+	// the hash is taken from the response as received, before any record is dropped
+	if r.options.ResponseHash {
+		dnsData.HashResponse()
+	}
+
+	if r.nxHijack != nil {
+		dnsData.RemoveHijacked(r.nxHijack)
+		if len(dnsData.NXHijackIPs) > 0 {
+			gologger.Verbose().Msgf("%s: dropped nxdomain hijacking answers %s\n", domain, strings.Join(dnsData.NXHijackIPs, ","))
 		}
+	}
+
+	r.checkAgreement(followUpCtx, domain, resolver, resolvers, &dnsData)
+	r.checkResponse(followUpCtx, domain, resolver, &dnsData, spoofDetector)
 
-		if !r.options.Raw {
-			dnsData.Raw = ""
+	if r.retryWriter != nil && !dnsData.HostsFile {
+		if category := responseCodeCategory(dnsData.StatusCodeRaw); category != "" {
+			r.retryWriter.add(joinTargetResolver(domain, resolver), category)
 		}
+	}
 
-		if r.options.Trace {
-			dnsData.TraceData, _ = r.dnsx.Trace(domain)
-			if dnsData.TraceData != nil {
-				for _, data := range dnsData.TraceData.DNSData {
-					if r.options.Raw && data.RawResp != nil {
-						rawRespString := data.RawResp.String()
-						data.Raw = rawRespString
-						// join the whole chain in raw field
-						dnsData.Raw += fmt.Sprintln(rawRespString)
-					}
-					data.RawResp = nil
-				}
+	if !r.options.Raw {
+		dnsData.Raw = ""
+	}
+
+	if r.externalDeps != nil {
+		r.externalDeps.add(domain, dnsData.CNAME)
+	}
+	if !r.keepResponse(domain, &dnsData) {
+		return
+	}
+	r.outputResponse(domain, &dnsData)
+}
+
+// queryTarget sends the queries of the host to the resolver of the target, to the resolvers of its target config
+// group or to the pool, the hosts file answering them in offline mode
+func (r *Runner) queryTarget(ctx context.Context, domain, resolver string) (*retryabledns.DNSData, error) {
+	if r.options.Offline {
+		return r.dnsx.QueryHostsFile(domain)
+	} else if resolver != "" {
+		return r.dnsx.QueryMultipleWithResolverContext(ctx, domain, resolver)
+	} else if group := r.targetGroup(domain); group != nil {
+		return r.dnsx.QueryMultipleWithProfileContext(ctx, domain, group.profile)
+	}
+	// Ignoring errors as partial results are still good
+	return r.dnsx.QueryMultipleContext(ctx, domain)
+}
+
+// outputFailure reports the host whose queries got no response
+func (r *Runner) outputFailure(domain, resolver string, err error, dnsData *dnsx.ResponseData) {
+	if r.retryWriter != nil {
+		r.retryWriter.add(joinTargetResolver(domain, resolver), queryErrorCategory(err))
+	}
+	// in probe mode failed hosts are reported as not resolving
+	if r.options.Probe {
+		dnsData.DNSData = &retryabledns.DNSData{Host: domain, Timestamp: time.Now()}
+		r.outputProbe(domain, dnsData)
+	}
+	// and in expect mode as not matching
+	if r.expectations != nil {
+		dnsData.DNSData = &retryabledns.DNSData{Host: domain, Timestamp: time.Now()}
+		r.outputExpectation(domain, dnsData)
+	}
+}
+
+// retryResponse queries the host again when its response is empty or incomplete, keeping the better response. It
+// returns the resolvers that answered the host, excluded from the agreement query
+func (r *Runner) retryResponse(ctx context.Context, domain, resolver string, dnsData *dnsx.ResponseData) []string {
+	resolvers := dnsData.Resolver
+	// hosts with a resolver override are never queried against the pool
+	if r.options.RetryNoData && resolver == "" && dnsData.IsNoData() {
+		gologger.Verbose().Msgf("%s: empty answer from %s, retrying with a different resolver\n", domain, strings.Join(dnsData.Resolver, ","))
+		if retryData, _ := r.dnsx.QueryMultipleExcludingContext(ctx, domain, dnsData.Resolver); retryData != nil && retryData.Host != "" && !retryData.Timestamp.IsZero() {
+			dnsData.DNSData = retryData
+			resolvers = append(resolvers, retryData.Resolver...)
+		}
+	}
+	// some servers only return the complete answers over tcp, the resolver that answered is asked again
+	if r.retryOverTCP(domain, resolver, dnsData) {
+		udpResolver := dnsData.Resolver[len(dnsData.Resolver)-1]
+		gologger.Verbose().Msgf("%s: %s response from %s, retrying over tcp\n", domain, dnsData.StatusCode, udpResolver)
+		if tcpData, _ := r.dnsx.QueryMultipleTCPWithResolverContext(ctx, domain, udpResolver); tcpData != nil && tcpData.Host != "" && !tcpData.Timestamp.IsZero() {
+			resolvers = append(resolvers, tcpData.Resolver...)
+			if betterResponse(tcpData, dnsData.DNSData) {
+				dnsData.DNSData = tcpData
 			}
 		}
+	}
+	return resolvers
+}
 
-		if r.options.AXFR {
-			hasAxfrData := false
-			axfrData, _ := r.dnsx.AXFR(domain)
-			if axfrData != nil {
-				dnsData.AXFRData = axfrData
-				hasAxfrData = len(axfrData.DNSData) > 0
-			}
+// checkAgreement keeps the records also returned by a resolver that hasn't been queried yet and annotates their
+// confidence
+func (r *Runner) checkAgreement(ctx context.Context, domain, resolver string, resolvers []string, dnsData *dnsx.ResponseData) {
+	// the resolver whose response is kept, before the agreement adds its own
+	var answeredBy string
+	if len(dnsData.Resolver) > 0 {
+		answeredBy = dnsData.Resolver[len(dnsData.Resolver)-1]
+	}
+	var agreementData *retryabledns.DNSData
+	if r.options.RequireAgreement && resolver == "" && !dnsData.HostsFile {
+		var err error
+		// a failed query says nothing about the records, which are kept as they are
+		if agreementData, err = r.dnsx.QueryMultipleExcludingContext(ctx, domain, resolvers); err != nil {
+			gologger.Verbose().Msgf("%s: could not query the agreement resolvers: %s\n", domain, err)
+			agreementData = nil
+		} else {
+			dnsData.KeepAgreedRecords(agreementData)
+		}
+	}
+	if r.options.Confidence && !dnsData.HostsFile {
+		dnsData.ComputeConfidence(answeredBy, r.confirmations(domain, resolver, resolvers, agreementData), r.questionTypesFor(domain), r.options.ConfidenceMin, r.options.ConfidenceRetries)
+	}
+}
 
-			// if the query type is only AFXR then output only if we have results (ref: https://github.com/projectdiscovery/dnsx/issues/230#issuecomment-1256659249)
-			if len(r.dnsx.Options.QuestionTypes) == 1 && !hasAxfrData && !r.options.JSON {
-				continue
+// checkResponse parses the response and runs the checks enabled on it, the follow-up queries of the checks being
+// sent to the resolvers of the host
+func (r *Runner) checkResponse(ctx context.Context, domain, resolver string, dnsData *dnsx.ResponseData, spoofDetector *dnsx.SpoofDetector) {
+	if r.allowedRanges != nil {
+		if allowed := r.allowedRanges.get(domain); allowed != nil {
+			dnsData.CheckAllowedRanges(allowed)
+			if len(dnsData.OutOfRange) > 0 {
+				r.rangeViolations.Add(1)
 			}
 		}
-		// add flags for cdn
-		if r.options.OutputCDN {
-			dnsData.IsCDNIP, dnsData.CDNName, _ = r.dnsx.CdnCheck(domain)
+	}
+
+	// the section counts are taken before the answers are truncated
+	dnsData.ParseRawResp()
+	if dnsData.LimitAnswers(r.options.MaxAnswers) {
+		gologger.Verbose().Msgf("%s: response truncated to %d records per type\n", domain, r.options.MaxAnswers)
+	}
+	dnsData.OrderCNAMEChain()
+	if r.options.SOAHealth {
+		dnsData.CheckSOAHealth()
+	}
+	// the edns fields are in the json output, only the unusual ones are logged: an extended rcode other than
+	// BADVERS (reported below) or an udp size below the minimum of 512 bytes
+	if edns := dnsData.EDNS; edns != nil && ((edns.ExtendedRcode != 0 && dnsData.SupportedEDNSVersion == nil) || edns.UDPSize < dns.MinMsgSize) {
+		gologger.Verbose().Msgf("%s: unusual edns %s\n", domain, edns)
+	}
+	if r.options.NoCompression && dnsData.RawResp != nil {
+		compression := "without"
+		if dnsData.RawResp.Compress {
+			compression = "with"
 		}
-		if r.options.ASN {
-			results := []*asnmap.Response{}
-			ips := dnsData.A
-			if ips == nil {
-				ips, _ = r.dnsx.Lookup(domain)
-			}
-			for _, ip := range ips {
-				if data, err := asnmap.DefaultClient.GetData(ip); err == nil {
-					results = append(results, data...)
-				}
-			}
-			if iputil.IsIP(domain) {
-				if data, err := asnmap.DefaultClient.GetData(domain); err == nil {
-					results = append(results, data...)
-				}
+		gologger.Verbose().Msgf("%s: uncompressed query answered %s name compression\n", domain, compression)
+	}
+	for _, ede := range dnsData.EDE {
+		gologger.Verbose().Msgf("%s: extended dns error %s\n", domain, ede)
+	}
+	if spoofDetector != nil {
+		dnsData.Anomalies = spoofDetector.Anomalies()
+		if len(dnsData.Anomalies) > 0 {
+			gologger.Warning().Msgf("%s: possible spoofed response (%s)\n", domain, strings.Join(dnsData.Anomalies, ","))
+		}
+	}
+	var profile *dnsx.QueryProfile
+	if group := r.targetGroup(domain); resolver == "" && group != nil {
+		profile = group.profile
+	}
+	if r.options.MinDNSSECAlgo != "" && !iputil.IsIP(domain) {
+		if msg, err := r.dnsx.QueryDNSSECContext(ctx, dnsData.QueryName, resolver, profile); err == nil {
+			dnsData.CheckDNSSECAlgorithms(msg, r.options.minDNSSECAlgo)
+		}
+	}
+	if r.options.ECH && !iputil.IsIP(domain) {
+		// the HTTPS records queried with the other types are not asked again
+		if sliceutil.Contains(r.questionTypesFor(domain), dns.TypeHTTPS) {
+			dnsData.ParseECHRecords()
+		} else if msg, err := r.dnsx.QueryHTTPSContext(ctx, dnsData.QueryName, resolver, profile); err == nil {
+			dnsData.ParseECH(msg)
+		}
+	}
+	if r.options.Padding > 0 && r.options.Verbose {
+		var sizes []string
+		for _, questionType := range r.questionTypesFor(domain) {
+			sizes = append(sizes, fmt.Sprintf("%s:%d", dns.TypeToString[questionType], r.dnsx.QuerySize(dnsData.QueryName, questionType)))
+		}
+		gologger.Verbose().Msgf("%s: queries padded to %s bytes\n", domain, strings.Join(sizes, ","))
+	}
+	if dnsData.SupportedEDNSVersion != nil {
+		gologger.Verbose().Msgf("%s: edns version %d not supported (BADVERS), highest supported version is %d\n", domain, r.options.EDNSVersion, *dnsData.SupportedEDNSVersion)
+	}
+}
+
+// keepResponse returns false if the response is filtered out by the response code or the dnssec and ech filters
+func (r *Runner) keepResponse(domain string, dnsData *dnsx.ResponseData) bool {
+	// results from hosts file are always returned
+	if !dnsData.HostsFile {
+		// skip responses not having the expected response code
+		if len(r.options.rcodes) > 0 {
+			if _, ok := r.options.rcodes[dnsData.StatusCodeRaw]; !ok {
+				return false
 			}
-			if len(results) > 0 {
-				cidrs, _ := asnmap.GetCIDR(results)
-				dnsData.ASN = &dnsx.AsnResponse{
-					AsNumber:  fmt.Sprintf("AS%v", results[0].ASN),
-					AsName:    results[0].Org,
-					AsCountry: results[0].Country,
-				}
-				for _, cidr := range cidrs {
-					dnsData.ASN.AsRange = append(dnsData.ASN.AsRange, cidr.String())
-				}
+		}
+	}
+
+	if r.nsSummary != nil && len(dnsData.NS) > 0 {
+		r.nsSummary.add(domain, dnsData.NS)
+	}
+	if r.options.WeakDNSSECOnly && len(dnsData.WeakDNSSEC) == 0 {
+		return false
+	}
+	if r.options.ECHOnly && len(dnsData.ECH) == 0 {
+		return false
+	}
+	return true
+}
+
+// outputResponse hands the response to the monitor, the summaries written at the end of the run or the output
+// mode of the run, the other responses being written as they come
+func (r *Runner) outputResponse(domain string, dnsData *dnsx.ResponseData) {
+	// the records are compared with the ones of the previous cycles, the transient failures leaving them as they are
+	if r.monitor != nil {
+		if dnsData.StatusCodeRaw == dns.RcodeSuccess || dnsData.StatusCodeRaw == dns.RcodeNameError {
+			questionTypes := r.questionTypesFor(domain)
+			records := monitorRecords(domain, questionTypes, dnsData)
+			r.outputMonitorChanges(r.monitor.observe(domain, questionTypes, records, dnsData.Timestamp, r.options.MonitorAll))
+		}
+		return
+	}
+
+	if r.hashSummary != nil && dnsData.ResponseHash != "" {
+		r.hashSummary.add(domain, dnsData.ResponseHash)
+	}
+	if r.dotGraph != nil {
+		r.dotGraph.add(domain, dnsData.CNAME, dnsData.NS)
+	}
+
+	switch {
+	// the addresses are output as cidr blocks at the end of the run
+	case r.cidrCollapser != nil:
+		r.cidrCollapser.add(dnsData.A...)
+		r.cidrCollapser.add(dnsData.AAAA...)
+	// the hosts are output grouped by address at the end of the run
+	case r.ipIndex != nil:
+		r.ipIndex.add(domain, dnsData.A...)
+		r.ipIndex.add(domain, dnsData.AAAA...)
+	// the labels of the resolved hosts are output as a wordlist at the end of the run
+	case r.wordExtractor != nil:
+		if dnsData.HasRecords() {
+			r.wordExtractor.add(domain)
+		}
+	case r.options.Probe:
+		r.outputProbe(domain, dnsData)
+	case r.expectations != nil:
+		r.outputExpectation(domain, dnsData)
+	case r.authServers != nil:
+		r.outputAuthComparison(domain, dnsData)
+	case r.deferredchan != nil:
+		r.deferredchan <- deferredResponse{domain: domain, dnsData: *dnsData}
+	default:
+		r.processResponse(domain, dnsData)
+	}
+}
+
+// retryOverTCP returns true if the response of the host is to be queried again over tcp, hosts with a resolver
+// override or a target config group being never queried against the pool
+func (r *Runner) retryOverTCP(domain, resolver string, dnsData *dnsx.ResponseData) bool {
+	if r.options.tcpRetryRcodes == nil || resolver != "" || dnsData.HostsFile || len(dnsData.Resolver) == 0 || r.targetGroup(domain) != nil {
+		return false
+	}
+	if _, ok := r.options.tcpRetryRcodes[dnsData.StatusCodeRaw]; ok {
+		return true
+	}
+	return r.options.tcpRetryNoData && dnsData.IsNoData()
+}
+
+// betterResponse returns true if the response is a NOERROR one replacing a failed response, or one with more
+// records than the current NOERROR response
+func betterResponse(response, current *retryabledns.DNSData) bool {
+	if response.StatusCodeRaw != dns.RcodeSuccess {
+		return false
+	}
+	if current.StatusCodeRaw != dns.RcodeSuccess {
+		return true
+	}
+	return len(response.AllRecords) > len(current.AllRecords)
+}
+
+// processResponse enriches the response with the lookups requested by the options and outputs it
+func (r *Runner) processResponse(domain string, dnsData *dnsx.ResponseData) {
+	if r.options.AXFR {
+		hasAxfrData := false
+		axfrData, _ := r.dnsx.AXFRContext(r.ctx, domain)
+		if axfrData != nil {
+			dnsData.AXFRData = axfrData
+			hasAxfrData = len(axfrData.DNSData) > 0
+		}
+
+		// if the query type is only AFXR then output only if we have results (ref: https://github.com/projectdiscovery/dnsx/issues/230#issuecomment-1256659249)
+		if len(r.dnsx.Options.QuestionTypes) == 1 && !hasAxfrData && !r.options.JSON {
+			return
+		}
+	}
+	// add flags for cdn
+	if r.options.OutputCDN {
+		dnsData.IsCDNIP, dnsData.CDNName, _ = r.dnsx.CdnCheck(domain)
+		// the address hints of the HTTPS and SVCB records point to the cdn as well
+		if !dnsData.IsCDNIP {
+			if hints := dnsData.HintIPs(); len(hints) > 0 {
+				dnsData.IsCDNIP, dnsData.CDNName, _ = r.dnsx.CdnCheckIPs(hints)
 			}
 		}
-		// if wildcard filtering just store the data
-		if r.options.WildcardDomain != "" {
-			_ = r.storeDNSData(dnsData.DNSData)
-			continue
+	}
+	if r.options.ASN {
+		results := []*asnmap.Response{}
+		ips := dnsData.A
+		if ips == nil {
+			ips, _ = r.dnsx.Lookup(domain)
+		}
+		if r.options.FlagMultiASN {
+			ips = sliceutil.Merge(ips, dnsData.AAAA)
 		}
-		if r.options.JSON {
-			var marshalOptions []dnsx.MarshalOption
-			if r.options.OmitRaw {
-				marshalOptions = append(marshalOptions, dnsx.WithoutAllRecords())
+		for _, ip := range ips {
+			if data, err := asnmap.DefaultClient.GetData(ip); err == nil {
+				results = append(results, data...)
 			}
-			jsons, _ := dnsData.JSON(marshalOptions...)
-			r.outputchan <- jsons
-			continue
 		}
-		if r.options.Raw {
-			r.outputchan <- dnsData.Raw
-			continue
+		if r.options.FlagMultiASN {
+			// only the asns of the resolved records are compared
+			for _, result := range results {
+				dnsData.ASNs = append(dnsData.ASNs, fmt.Sprintf("AS%v", result.ASN))
+			}
+			dnsData.ASNs = sliceutil.Dedupe(dnsData.ASNs)
+			dnsData.MultiASN = len(dnsData.ASNs) > 1
 		}
-		if r.options.hasRCodes {
-			r.outputResponseCode(domain, dnsData.StatusCodeRaw)
-			continue
+		if iputil.IsIP(domain) {
+			if data, err := asnmap.DefaultClient.GetData(domain); err == nil {
+				results = append(results, data...)
+			}
 		}
-		if r.options.A {
-			r.outputRecordType(domain, dnsData.A, "A", dnsData.CDNName, dnsData.ASN)
+		if len(results) > 0 {
+			dnsData.ASN = asnResponse(results)
+			if r.asnSummary != nil {
+				r.asnSummary.add(domain, dnsData.ASN)
+			}
 		}
-		if r.options.AAAA {
-			r.outputRecordType(domain, dnsData.AAAA, "AAAA", dnsData.CDNName, dnsData.ASN)
+	}
+	if r.nsInventory != nil && len(dnsData.NS) > 0 {
+		dnsData.NSInventory = r.nsInventory.lookup(dnsData.NS)
+	}
+	if r.reverseChecks != nil {
+		dnsData.DualStack = r.reverseChecks.dualStack(dnsData.A, dnsData.AAAA)
+	}
+	if r.whoisLookup != nil {
+		for _, ip := range sliceutil.Merge(dnsData.A, dnsData.AAAA) {
+			if whois := r.whoisLookup.lookup(ip); whois != nil {
+				dnsData.Whois = append(dnsData.Whois, *whois)
+			}
 		}
-		if r.options.CNAME {
-			r.outputRecordType(domain, dnsData.CNAME, "CNAME", dnsData.CDNName, dnsData.ASN)
+	}
+	if r.options.AnnotateBogon {
+		dnsData.AnnotateBogons()
+	}
+	if r.options.Hierarchy {
+		dnsData.ParseHierarchy()
+	}
+	if r.options.ShowCoverage {
+		dnsData.ComputeCoverage(r.questionTypesFor(domain))
+	}
+	if srvName := r.srvNameOf(domain); srvName != nil {
+		dnsData.SRVService = srvName.service
+	}
+	dnsData.TLSAPort = r.tlsaPortOf(domain)
+	// the structured output writes the sinks along with its own record, the other outputs before the wildcard
+	// filtering and the output modes
+	if !r.options.JSON || r.options.WildcardDomain != "" {
+		r.writeSinks(dnsData, "")
+	}
+	// if wildcard filtering just store the data
+	if r.options.WildcardDomain != "" {
+		_ = r.storeDNSData(dnsData.DNSData)
+		return
+	}
+	if r.options.JSON {
+		r.outputStructured(dnsData)
+		return
+	}
+	if r.options.Raw {
+		r.outputchan <- dnsData.Raw
+		return
+	}
+	if r.options.hasRCodes {
+		if dnsData.SupportedEDNSVersion != nil {
+			// BADVERS shares its value with BADSIG
+			r.outputchan <- domain + " [" + dnsx.RcodeBadVersName + "]"
+		} else {
+			r.outputResponseCode(domain, dnsData.StatusCodeRaw)
 		}
-		if r.options.PTR {
-			r.outputRecordType(domain, dnsData.PTR, "PTR", dnsData.CDNName, dnsData.ASN)
+		return
+	}
+	// the hosts of a target config group output the types of the group
+	group := r.targetGroup(domain)
+	outputType := func(questionType uint16, enabled bool) bool {
+		if group != nil && len(group.profile.QuestionTypes) > 0 {
+			return sliceutil.Contains(group.profile.QuestionTypes, questionType)
 		}
-		if r.options.MX {
-			r.outputRecordType(domain, dnsData.MX, "MX", dnsData.CDNName, dnsData.ASN)
+		return enabled
+	}
+	if r.options.ShowCounts {
+		r.outputCounts(domain, dnsData)
+	}
+	if outputType(dns.TypeA, r.options.A) {
+		r.outputRecordType(domain, dnsData.A, "A", dnsData)
+	}
+	if outputType(dns.TypeAAAA, r.options.AAAA) {
+		r.outputRecordType(domain, dnsData.AAAA, "AAAA", dnsData)
+	}
+	if outputType(dns.TypeCNAME, r.options.CNAME) {
+		if r.options.CNAMEChain && len(dnsData.CNAME) > 1 {
+			r.outputRecordType(domain, []string{strings.Join(dnsData.CNAME, " -> ")}, "CNAME", dnsData)
+		} else {
+			r.outputRecordType(domain, dnsData.CNAME, "CNAME", dnsData)
 		}
-		if r.options.NS {
-			r.outputRecordType(domain, dnsData.NS, "NS", dnsData.CDNName, dnsData.ASN)
+		if len(dnsData.CNAMELoop) > 0 {
+			r.outputRecordLine("CNAME", fmt.Sprintf("%s [%s] %s", domain, r.aurora.Yellow("cname-loop"), strings.Join(dnsData.CNAMELoop, " -> ")))
+		} else if dnsData.CNAMETooDeep {
+			r.outputRecordLine("CNAME", fmt.Sprintf("%s [%s] %d records", domain, r.aurora.Yellow("cname-too-deep"), len(dnsData.CNAME)))
 		}
-		if r.options.SOA {
-			r.outputRecordType(domain, sliceutil.Dedupe(dnsData.GetSOARecords()), "SOA", dnsData.CDNName, dnsData.ASN)
+	}
+	if outputType(dns.TypePTR, r.options.PTR) {
+		r.outputRecordType(domain, dnsData.PTR, "PTR", dnsData)
+	}
+	if outputType(dns.TypeMX, r.options.MX) {
+		r.outputRecordType(domain, dnsData.MX, "MX", dnsData)
+	}
+	if outputType(dns.TypeNS, r.options.NS) {
+		r.outputRecordType(domain, dnsData.NS, "NS", dnsData)
+		for _, nameserver := range dnsData.NSInventory {
+			r.outputRecordLine("NS", fmt.Sprintf("%s [%s] %s", domain, r.aurora.Cyan("ns-inventory"), nameserver.String()))
 		}
-		if r.options.ANY {
-			allParsedRecords := sliceutil.Merge(
-				dnsData.A,
-				dnsData.AAAA,
-				dnsData.CNAME,
-				dnsData.MX,
-				dnsData.PTR,
-				sliceutil.Dedupe(dnsData.GetSOARecords()),
-				dnsData.NS,
-				dnsData.TXT,
-				dnsData.SRV,
-				dnsData.CAA,
-			)
-			r.outputRecordType(domain, allParsedRecords, "ANY", dnsData.CDNName, dnsData.ASN)
+	}
+	if outputType(dns.TypeSOA, r.options.SOA) {
+		r.outputRecordType(domain, sliceutil.Dedupe(dnsData.GetSOARecords()), "SOA", dnsData)
+		for _, warning := range dnsData.SOAWarnings {
+			r.outputRecordLine("SOA", fmt.Sprintf("%s [%s] [%s] %s", domain, r.aurora.Yellow("soa-warning"), warning.Zone, warning.Warning))
 		}
-		if r.options.TXT {
-			r.outputRecordType(domain, dnsData.TXT, "TXT", dnsData.CDNName, dnsData.ASN)
+	}
+	if outputType(dns.TypeANY, r.options.ANY) {
+		allParsedRecords := sliceutil.Merge(
+			dnsData.A,
+			dnsData.AAAA,
+			dnsData.CNAME,
+			dnsData.MX,
+			dnsData.PTR,
+			sliceutil.Dedupe(dnsData.GetSOARecords()),
+			dnsData.NS,
+			dnsData.TXT,
+			dnsData.SRV,
+			dnsData.CAA,
+			dnsData.TLSA,
+			dnsData.Records([]uint16{dns.TypeDNSKEY}),
+			dnsData.Records([]uint16{dns.TypeDS}),
+			dnsData.Records([]uint16{dns.TypeHTTPS, dns.TypeSVCB}),
+			dnsData.Records([]uint16{dns.TypeNAPTR}),
+			dnsData.Records([]uint16{dns.TypeSSHFP}),
+			dnsData.Records([]uint16{dns.TypeDNAME}),
+		)
+		r.outputRecordType(domain, allParsedRecords, "ANY", dnsData)
+	}
+	if outputType(dns.TypeTXT, r.options.TXT) {
+		r.outputRecordType(domain, dnsData.TXT, "TXT", dnsData)
+	}
+	if outputType(dns.TypeSRV, r.options.SRV) {
+		r.outputRecordType(domain, dnsData.SRV, "SRV", dnsData)
+	}
+	if outputType(dns.TypeCAA, r.options.CAA) {
+		r.outputRecordType(domain, dnsData.CAA, "CAA", dnsData)
+	}
+	if outputType(dns.TypeTLSA, r.options.TLSA) {
+		r.outputRecordType(domain, dnsData.TLSA, "TLSA", dnsData)
+	}
+	if outputType(dns.TypeDNSKEY, r.options.DNSKEY) {
+		r.outputRecordType(domain, dnsData.DNSKEY, "DNSKEY", dnsData)
+	}
+	if outputType(dns.TypeDS, r.options.DS) {
+		r.outputRecordType(domain, dnsData.DS, "DS", dnsData)
+	}
+	if outputType(dns.TypeHTTPS, r.options.HTTPS) {
+		r.outputRecordType(domain, dnsData.HTTPS, "HTTPS", dnsData)
+	}
+	if outputType(dns.TypeSVCB, r.options.SVCB) {
+		r.outputRecordType(domain, dnsData.SVCB, "SVCB", dnsData)
+	}
+	if outputType(dns.TypeNAPTR, r.options.NAPTR) {
+		r.outputRecordType(domain, dnsData.NAPTR, "NAPTR", dnsData)
+	}
+	if outputType(dns.TypeSSHFP, r.options.SSHFP) {
+		r.outputRecordType(domain, dnsData.SSHFP, "SSHFP", dnsData)
+	}
+	if outputType(dns.TypeDNAME, r.options.DNAME) {
+		r.outputRecordType(domain, dnsData.DNAME, "DNAME", dnsData)
+	}
+	for _, algorithm := range dnsData.WeakDNSSEC {
+		r.outputRecordLine("DNSKEY", fmt.Sprintf("%s [%s] %s", domain, r.aurora.Yellow("weak-dnssec"), algorithm))
+	}
+	for _, ip := range dnsData.OutOfRange {
+		queryType := "A"
+		if iputil.IsIPv6(ip) {
+			queryType = "AAAA"
 		}
-		if r.options.SRV {
-			r.outputRecordType(domain, dnsData.SRV, "SRV", dnsData.CDNName, dnsData.ASN)
+		r.outputRecordLine(queryType, fmt.Sprintf("%s [%s] [%s] %s", domain, r.aurora.Red("out-of-range"), r.colorizeType(queryType), ip))
+	}
+	for _, config := range dnsData.ECH {
+		r.outputRecordLine(config.Record, fmt.Sprintf("%s [%s] %s", domain, r.aurora.Cyan("ech"), config))
+	}
+	if dnsData.DualStack != nil {
+		r.outputDualStack(domain, dnsData.DualStack)
+	}
+	for _, glueless := range dnsData.TraceGlueless {
+		r.outputRecordLine("NS", fmt.Sprintf("%s [%s] %s", domain, r.aurora.Yellow("glueless"), glueless))
+	}
+}
+
+// outputStructured writes the response as a json line or a length prefixed messagepack record
+func (r *Runner) outputStructured(dnsData *dnsx.ResponseData) {
+	marshalOptions := r.marshalOptions()
+	if r.options.ESBulk {
+		marshalOptions = append(marshalOptions, dnsx.WithBulkTimestamp())
+	}
+	if r.options.MsgPack {
+		r.writeSinks(dnsData, "")
+		record, err := dnsData.MsgPack(marshalOptions...)
+		if err != nil {
+			gologger.Warning().Msgf("%s: could not encode messagepack record: %s\n", dnsData.Host, err)
+			return
 		}
-		if r.options.CAA {
-			r.outputRecordType(domain, dnsData.CAA, "CAA", dnsData.CDNName, dnsData.ASN)
+		r.outputchan <- string(record)
+		return
+	}
+	jsons, _ := dnsData.JSON(marshalOptions...)
+	// the bulk timestamp aside, the sinks get the same record
+	if r.options.ESBulk {
+		r.writeSinks(dnsData, "")
+	} else {
+		r.writeSinks(dnsData, jsons)
+	}
+	if r.execFilter != nil {
+		var ok bool
+		if jsons, ok = r.execFilter.apply(jsons); !ok {
+			return
 		}
 	}
+	r.outputchan <- jsons
 }
 
-func (r *Runner) outputRecordType(domain string, items interface{}, queryType, cdnName string, asn *dnsx.AsnResponse) {
+// writeSinks writes the enriched response to the output sinks it matches, line being its json record when
+// already encoded
+func (r *Runner) writeSinks(dnsData *dnsx.ResponseData, line string) {
+	if r.outputSinks != nil {
+		r.outputSinks.write(dnsData, line, r.marshalOptions()...)
+	}
+}
+
+func (r *Runner) marshalOptions() []dnsx.MarshalOption {
+	var marshalOptions []dnsx.MarshalOption
+	if r.options.OmitRaw {
+		marshalOptions = append(marshalOptions, dnsx.WithoutAllRecords())
+	}
+	if r.options.JSONCompact {
+		marshalOptions = append(marshalOptions, dnsx.WithCompactJSON())
+	}
+	return marshalOptions
+}
+
+// outputCounts writes a summary line with the number of records of each queried type
+func (r *Runner) outputCounts(domain string, dnsData *dnsx.ResponseData) {
+	var builder strings.Builder
+	builder.WriteString(domain)
+	for _, questionType := range r.questionTypesFor(domain) {
+		fmt.Fprintf(&builder, " [%s:%d]", r.colorizeType(dns.TypeToString[questionType]), dnsData.RecordCount(questionType))
+	}
+	r.outputchan <- builder.String()
+}
+
+// outputProbe reports whether the host returned any record for the queried types
+func (r *Runner) outputProbe(domain string, dnsData *dnsx.ResponseData) {
+	live := dnsData.HasRecords()
+	if r.options.JSON {
+		dnsData.Live = &live
+		r.outputStructured(dnsData)
+		return
+	}
+	if live {
+		r.outputchan <- fmt.Sprintf("%s [%s]", domain, r.aurora.Green("true"))
+	} else {
+		r.outputchan <- fmt.Sprintf("%s [%s]", domain, r.aurora.Red("false"))
+	}
+}
+
+func (r *Runner) outputRecordType(domain string, items interface{}, queryType string, dnsData *dnsx.ResponseData) {
 	var details string
-	if cdnName != "" {
-		details = fmt.Sprintf(" [%s]", cdnName)
+	if dnsData.CDNName != "" {
+		details = fmt.Sprintf(" [%s]", dnsData.CDNName)
+	}
+	if dnsData.ASN != nil {
+		details = fmt.Sprintf("%s %s", details, dnsData.ASN.String())
+	}
+	if dnsData.MultiASN {
+		details = fmt.Sprintf("%s [%s: %s]", details, r.aurora.Yellow("multi-asn"), strings.Join(dnsData.ASNs, ","))
+	}
+	for _, whois := range dnsData.Whois {
+		details = fmt.Sprintf("%s %s", details, whois.String())
 	}
-	if asn != nil {
-		details = fmt.Sprintf("%s %s", details, asn.String())
+	if dnsData.Coverage != "" {
+		details = fmt.Sprintf("%s [%s]", details, dnsData.Coverage)
+	}
+	if srvName := r.srvNameOf(domain); srvName != nil {
+		details = fmt.Sprintf("%s [%s: %s]", details, r.aurora.Cyan("service"), srvName.label())
+	}
+	if port := r.tlsaPortOf(domain); port != "" {
+		details = fmt.Sprintf("%s [%s: %s]", details, r.aurora.Cyan("port"), port)
+	}
+	if r.options.QueryName && dnsData.QueryName != "" {
+		details = fmt.Sprintf("%s [%s: %s]", details, r.aurora.Cyan("query"), dnsData.QueryName)
+	}
+	if dnsData.ResponseHash != "" {
+		details = fmt.Sprintf("%s [%s: %s]", details, r.aurora.Cyan("hash"), dnsData.ResponseHash)
+	}
+	if r.options.NSID && dnsData.EDNS != nil && dnsData.EDNS.NSID != "" {
+		details = fmt.Sprintf("%s [%s: %s]", details, r.aurora.Cyan("nsid"), dnsData.EDNS.NSID)
+	}
+	// the names below a DNAME owner resolve under its target
+	if queryType != "DNAME" {
+		for _, redirection := range dnsData.Redirections() {
+			details = fmt.Sprintf("%s [%s: %s]", details, r.aurora.Cyan("dname"), redirection)
+		}
 	}
 	var records []string
 
@@ -803,22 +1947,110 @@ func (r *Runner) outputRecordType(domain string, items interface{}, queryType, c
 		for _, item := range items {
 			records = append(records, item.NS, item.Mbox)
 		}
+	case []dnsx.DNSKEY:
+		for _, item := range items {
+			records = append(records, item.String())
+		}
+	case []dnsx.DS:
+		for _, item := range items {
+			records = append(records, item.String())
+		}
+	case []dnsx.ServiceBinding:
+		for _, item := range items {
+			records = append(records, item.String())
+		}
+	case []dnsx.NAPTR:
+		for _, item := range items {
+			// the response only output lists the next names to query, to be fed back to dnsx
+			if r.options.ResponseOnly {
+				if item.Replacement != "." {
+					records = append(records, item.Replacement)
+				}
+				continue
+			}
+			records = append(records, item.String())
+		}
+		records = sliceutil.Dedupe(records)
+	case []dnsx.SSHFP:
+		for _, item := range items {
+			records = append(records, item.String())
+		}
+	case []dnsx.DNAME:
+		for _, item := range items {
+			records = append(records, item.Target)
+		}
+	}
+	// json keeps all the records, only the response lines of the large round-robin sets are collapsed, with the
+	// annotations of the whole set
+	var (
+		collapsed           bool
+		collapsedAnnotation string
+	)
+	if r.options.CollapseRR && (r.options.Response || r.options.ResponseOnly) && (queryType == "A" || queryType == "AAAA") && len(records) >= r.options.CollapseRRMin {
+		collapsed = true
+		collapsedAnnotation = r.setAnnotation(queryType, records, dnsData)
+		records = []string{collapseRoundRobin(records, r.options.CollapseRRCIDR)}
 	}
 
+	domain = r.displayName(domain)
 	for _, item := range records {
-		item := strings.ToLower(item)
+		annotation := collapsedAnnotation
+		if !collapsed {
+			annotation = r.confidenceAnnotation(dnsData.RecordConfidence(item))
+			item = r.displayName(strings.ToLower(item))
+			annotation = r.bogonAnnotation(queryType, item) + annotation
+		}
 		if r.options.ResponseOnly {
-			r.outputchan <- fmt.Sprintf("%s%s", item, details)
+			r.outputRecordLine(queryType, fmt.Sprintf("%s%s%s", item, annotation, details))
 		} else if r.options.Response {
-			r.outputchan <- fmt.Sprintf("%s [%s] [%s] %s", domain, r.aurora.Magenta(queryType), r.aurora.Green(item).String(), details)
+			r.outputRecordLine(queryType, fmt.Sprintf("%s [%s] [%s]%s %s", domain, r.colorizeType(queryType), r.colorizeRecord(queryType, item).String(), annotation, details))
 		} else {
 			// just prints out the domain if it has a record type and exit
-			r.outputchan <- fmt.Sprintf("%s%s", domain, details)
+			r.outputRecordLine(queryType, fmt.Sprintf("%s%s%s", domain, r.setAnnotation(queryType, records, dnsData), details))
 			break
 		}
 	}
 }
 
+// setAnnotation returns the annotations of a set of records displayed on a single line: the bogon types found
+// among them and their lowest confidence
+func (r *Runner) setAnnotation(queryType string, records []string, dnsData *dnsx.ResponseData) string {
+	var annotation string
+	seen := make(map[string]struct{})
+	for _, record := range records {
+		bogonAnnotation := r.bogonAnnotation(queryType, record)
+		if _, ok := seen[bogonAnnotation]; ok || bogonAnnotation == "" {
+			continue
+		}
+		seen[bogonAnnotation] = struct{}{}
+		annotation += bogonAnnotation
+	}
+	return annotation + r.confidenceAnnotation(dnsData.LowestConfidence(records))
+}
+
+// displayName returns the name with its punycode labels decoded according to the idn display mode
+func (r *Runner) displayName(name string) string {
+	if r.options.IDNDisplay == "" {
+		return name
+	}
+	decoded := dnsx.DecodePunycode(name)
+	if r.options.IDNDisplay == idnDisplayBoth && decoded != name {
+		return fmt.Sprintf("%s (%s)", decoded, name)
+	}
+	return decoded
+}
+
+// bogonAnnotation returns the private/bogon marker for address records
+func (r *Runner) bogonAnnotation(queryType, item string) string {
+	if !r.options.AnnotateBogon || (queryType != "A" && queryType != "AAAA") {
+		return ""
+	}
+	if bogonType := dnsx.BogonType(item); bogonType != "" {
+		return fmt.Sprintf(" [%s]", r.aurora.Yellow(bogonType))
+	}
+	return ""
+}
+
 func (r *Runner) outputResponseCode(domain string, responsecode int) {
 	responseCodeExt, ok := dns.RcodeToString[responsecode]
 	if ok {
@@ -827,16 +2059,59 @@ func (r *Runner) outputResponseCode(domain string, responsecode int) {
 }
 
 func (r *Runner) storeDNSData(dnsdata *retryabledns.DNSData) error {
-	data, err := dnsdata.Marshal()
+	data, err := marshalStoredDNSData(dnsdata)
 	if err != nil {
 		return err
 	}
 	return r.hm.Set(dnsdata.Host, data)
 }
 
+// marshalStoredDNSData encodes the response for the hybrid map without its parsed message, which can't be gob
+// encoded, its content being kept in the raw field
+func marshalStoredDNSData(dnsdata *retryabledns.DNSData) ([]byte, error) {
+	stored := *dnsdata
+	stored.RawResp = nil
+	return stored.Marshal()
+}
+
+// Cancel aborts the in-flight queries, the remaining targets are drained without being queried
+func (r *Runner) Cancel() {
+	r.cancel()
+}
+
 // Close running instance
 func (r *Runner) Close() {
+	r.cancel()
+	r.dnsx.Close()
 	r.hm.Close()
+	r.wildcardProbeCache.close()
+	if r.ipIndex != nil {
+		r.ipIndex.close()
+	}
+	if r.whoisLookup != nil {
+		r.whoisLookup.close()
+	}
+	if r.retryWriter != nil {
+		r.retryWriter.Close()
+	}
+	if r.socketWriter != nil {
+		r.socketWriter.Close()
+	}
+	if r.outputSinks != nil {
+		r.outputSinks.close()
+	}
+	if r.execFilter != nil {
+		r.execFilter.close()
+	}
+	if r.monitor != nil {
+		r.monitor.close()
+	}
+	if r.queryLogFile != nil {
+		if err := r.dnsx.Options.QueryLog.Close(); err != nil {
+			gologger.Error().Msgf("Could not write query log %s: %s\n", r.options.QueryLog, err)
+		}
+		r.queryLogFile.Close()
+	}
 }
 
 func (r *Runner) wildcardWorker() {
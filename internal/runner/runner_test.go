@@ -1,11 +1,26 @@
 package runner
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/logrusorgru/aurora"
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
 	"github.com/projectdiscovery/hmap/store/hybrid"
+	"github.com/projectdiscovery/retryabledns"
+	fileutil "github.com/projectdiscovery/utils/file"
 	"github.com/stretchr/testify/require"
 )
 
@@ -124,6 +139,56 @@ func TestRunner_fileInput_prepareInput(t *testing.T) {
 	require.ElementsMatch(t, expected, got, "could not match expected output")
 }
 
+func TestRunner_affixInput_prepareInput(t *testing.T) {
+	options := &Options{
+		Domains:  "example.com",
+		WordList: "www,mail",
+		Prefix:   []string{"", "dev-"},
+		Suffix:   []string{".internal"},
+	}
+	hm, err := hybrid.New(hybrid.DefaultDiskOptions)
+	require.Nil(t, err, "could not create hybrid map")
+	r := Runner{
+		options: options,
+		hm:      hm,
+	}
+	// call the prepareInput
+	err = r.prepareInput()
+	require.Nil(t, err, "failed to prepare input")
+	expected := []string{"www.example.com.internal", "mail.example.com.internal", "dev-www.example.com.internal", "dev-mail.example.com.internal"}
+	got := []string{}
+	r.hm.Scan(func(k, v []byte) error {
+		got = append(got, string(k))
+		return nil
+	})
+	require.ElementsMatch(t, expected, got, "could not match expected output")
+}
+
+func TestRunner_skipRegex_prepareInput(t *testing.T) {
+	options := &Options{
+		Hosts:     "tests/file_input.txt",
+		SkipRegex: `^example\.`,
+	}
+	require.Nil(t, options.configureSkipRegex(), "could not compile skip regex")
+	hm, err := hybrid.New(hybrid.DefaultDiskOptions)
+	require.Nil(t, err, "could not create hybrid map")
+	r := Runner{
+		options: options,
+		hm:      hm,
+	}
+	// call the prepareInput
+	err = r.prepareInput()
+	require.Nil(t, err, "failed to prepare input")
+	expected := []string{"one.one.one.one"}
+	got := []string{}
+	r.hm.Scan(func(k, v []byte) error {
+		got = append(got, string(k))
+		return nil
+	})
+	require.ElementsMatch(t, expected, got, "could not match expected output")
+	require.Equal(t, uint64(1), r.skippedHosts.Load(), "could not match skipped hosts")
+}
+
 func TestRunner_InputWorkerStream(t *testing.T) {
 	options := &Options{
 		Hosts: "tests/stream_input.txt",
@@ -144,3 +209,1204 @@ func TestRunner_InputWorkerStream(t *testing.T) {
 	expected = append(expected, strings.Split(strings.ReplaceAll(string(fileContent), "\r\n", "\n"), "\n")...)
 	require.ElementsMatch(t, expected, got, "could not match expected output")
 }
+
+func TestRunner_InputWorkerLowMemory(t *testing.T) {
+	options := &Options{
+		Domains:   "example.com,example.com",
+		WordList:  "www,mail",
+		LowMemory: true,
+		Threads:   1,
+	}
+	hm, err := hybrid.New(hybrid.DefaultDiskOptions)
+	require.Nil(t, err, "could not create hybrid map")
+	r := Runner{
+		options:    options,
+		hm:         hm,
+		workerchan: make(chan string, workerchanSize(options)),
+	}
+	go r.InputWorkerLowMemory()
+	var got []string
+	for c := range r.workerchan {
+		got = append(got, c)
+	}
+	require.Nil(t, r.inputErr, "failed to prepare input")
+	// the targets are not deduplicated nor stored
+	expected := []string{"www.example.com", "mail.example.com", "www.example.com", "mail.example.com"}
+	require.ElementsMatch(t, expected, got, "could not match expected output")
+	stored := 0
+	r.hm.Scan(func(k, v []byte) error {
+		stored++
+		return nil
+	})
+	require.Zero(t, stored, "targets stored in low memory mode")
+}
+
+func TestRunner_resolverOverride_prepareInput(t *testing.T) {
+	options := &Options{
+		Domains:  "internal.corp@10.0.0.53",
+		WordList: "jenkins,beta",
+	}
+	hm, err := hybrid.New(hybrid.DefaultDiskOptions)
+	require.Nil(t, err, "could not create hybrid map")
+	r := Runner{
+		options: options,
+		hm:      hm,
+	}
+	// call the prepareInput
+	err = r.prepareInput()
+	require.Nil(t, err, "failed to prepare input")
+	expected := []string{"jenkins.internal.corp@10.0.0.53", "beta.internal.corp@10.0.0.53"}
+	got := []string{}
+	r.hm.Scan(func(k, v []byte) error {
+		got = append(got, string(k))
+		return nil
+	})
+	require.ElementsMatch(t, expected, got, "could not match expected output")
+}
+
+func TestRunner_retryFileInput_prepareInput(t *testing.T) {
+	options := &Options{
+		Hosts: "tests/retry_input.txt",
+	}
+	hm, err := hybrid.New(hybrid.DefaultDiskOptions)
+	require.Nil(t, err, "could not create hybrid map")
+	r := Runner{
+		options: options,
+		hm:      hm,
+	}
+	// call the prepareInput
+	err = r.prepareInput()
+	require.Nil(t, err, "failed to prepare input")
+	expected := []string{"sf.example.com", "internal.corp@10.0.0.53:53"}
+	got := []string{}
+	r.hm.Scan(func(k, v []byte) error {
+		got = append(got, string(k))
+		return nil
+	})
+	require.ElementsMatch(t, expected, got, "could not match expected output")
+}
+
+// closeNotifier reports when the reader it wraps is closed
+type closeNotifier struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (c *closeNotifier) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestLineReader(t *testing.T) {
+	input := newLineReader(strings.NewReader("a.example.com\n"+strings.Repeat("b", 64)+"\n"), 32)
+	var lines []string
+	for line := range input.lines {
+		lines = append(lines, line)
+	}
+	require.Equal(t, []string{"a.example.com"}, lines, "could not match lines")
+	require.NotNil(t, input.Err(), "long line accepted")
+
+	// the input is closed when the lines are left unread
+	reader := &closeNotifier{Reader: strings.NewReader("a.example.com\nb.example.com\nc.example.com\n"), closed: make(chan struct{})}
+	input = newLineReader(reader, 0)
+	require.Equal(t, "a.example.com", <-input.lines, "could not match first line")
+	input.Close()
+	select {
+	case <-reader.closed:
+	case <-time.After(time.Second):
+		require.Fail(t, "input not closed")
+	}
+}
+
+func TestStoreDNSData(t *testing.T) {
+	hm, err := hybrid.New(hybrid.DefaultMemoryOptions)
+	require.Nil(t, err, "could not create hybrid map")
+	defer hm.Close()
+	r := Runner{hm: hm}
+
+	msg := &dns.Msg{}
+	msg.SetQuestion("www.example.com.", dns.TypeA)
+	cname, _ := dns.NewRR("www.example.com. 60 IN CNAME edge.example.net.")
+	msg.Answer = append(msg.Answer, cname)
+	dnsData := &retryabledns.DNSData{Host: "www.example.com", CNAME: []string{"edge.example.net"}, Raw: msg.String(), RawResp: msg}
+	require.Nil(t, r.storeDNSData(dnsData), "could not store response with its parsed message")
+	require.NotNil(t, dnsData.RawResp, "parsed message of the response dropped")
+
+	data, ok := hm.Get("www.example.com")
+	require.True(t, ok, "response not stored")
+	var stored retryabledns.DNSData
+	require.Nil(t, stored.Unmarshal(data), "could not decode stored response")
+	require.Equal(t, []string{"edge.example.net"}, stored.CNAME, "could not match stored cname")
+	require.Equal(t, msg.String(), stored.Raw, "raw response not stored")
+}
+
+func TestRetryFile(t *testing.T) {
+	require.Equal(t, "sf.example.com", stripRetryCategory("sf.example.com # servfail"), "category not dropped")
+	require.Equal(t, "a.example.com # note", stripRetryCategory("a.example.com # note"), "other comment dropped")
+	require.Equal(t, "a.example.com", stripRetryCategory("a.example.com"), "could not match line without category")
+
+	path := t.TempDir() + "/retry.txt"
+	writer := newRetryWriter(path)
+	writer.add("sf.example.com", retryCategoryServfail)
+	writer.add("sf.example.com", retryCategoryServfail)
+	writer.Close()
+	data, err := os.ReadFile(path)
+	require.Nil(t, err, "could not read retry file")
+	require.Equal(t, "sf.example.com # servfail\n", string(data), "could not match retry file")
+
+	// a clean run removes the retry file of the previous one
+	newRetryWriter(path).Close()
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "stale retry file kept")
+}
+
+func TestSocketWriter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	address := listener.Addr().String()
+	sw, err := newSocketWriter("tcp://" + address)
+	require.Nil(t, err, "could not connect to output socket")
+	defer sw.Close()
+	server, err := listener.Accept()
+	require.Nil(t, err, "could not accept connection")
+
+	sw.WriteString("a.example.com\n")
+	line, err := bufio.NewReader(server).ReadString('\n')
+	require.Nil(t, err, "could not read output")
+	require.Equal(t, "a.example.com\n", line, "could not match output")
+
+	// a reader not keeping up ends the write at the timeout instead of blocking the output
+	sw.writeTimeout = 100 * time.Millisecond
+	block := strings.Repeat("a", 1<<20)
+	conn, start := sw.conn, time.Now()
+	for i := 0; i < 64 && sw.conn == conn; i++ {
+		sw.WriteString(block)
+	}
+	require.NotEqual(t, conn, sw.conn, "write did not time out")
+	require.Less(t, time.Since(start), 5*time.Second, "write blocked")
+	server.Close()
+	listener.Close()
+
+	// an unreachable socket is dialed again once the reconnection delay is over
+	sw = &socketWriter{network: "tcp", address: address, writeTimeout: socketWriteTimeout}
+	defer sw.Close()
+	sw.WriteString("b.example.com\n")
+	require.Equal(t, socketMinReconnectDelay, sw.reconnectDelay, "could not match reconnection delay")
+	dropped := sw.dropped
+	sw.WriteString("c.example.com\n")
+	require.Equal(t, dropped+1, sw.dropped, "write during the reconnection delay not dropped")
+
+	listener, err = net.Listen("tcp", address)
+	require.Nil(t, err, "could not listen again")
+	defer listener.Close()
+	sw.nextDial = time.Time{}
+	sw.WriteString("d.example.com\n")
+	server, err = listener.Accept()
+	require.Nil(t, err, "could not accept reconnection")
+	defer server.Close()
+	line, err = bufio.NewReader(server).ReadString('\n')
+	require.Nil(t, err, "could not read output after reconnection")
+	require.Equal(t, "d.example.com\n", line, "could not match output after reconnection")
+	require.Equal(t, time.Duration(0), sw.reconnectDelay, "reconnection delay not reset")
+}
+
+func TestPrioritizeQuestionTypes(t *testing.T) {
+	questionTypes := []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeCNAME, dns.TypeMX}
+	got := prioritizeQuestionTypes(questionTypes, []string{"mx", "txt", "cname"})
+	require.Equal(t, []uint16{dns.TypeMX, dns.TypeCNAME, dns.TypeA, dns.TypeAAAA}, got, "could not match expected order")
+	require.Equal(t, questionTypes, prioritizeQuestionTypes(questionTypes, nil), "default order changed")
+}
+
+func TestCidrCollapser(t *testing.T) {
+	collapser := newCidrCollapser()
+	collapser.add("10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.8", "10.0.0.3")
+	collapser.add("2001:db8::1", "2001:db8::2")
+
+	toStrings := func(networks []*net.IPNet) []string {
+		var got []string
+		for _, network := range networks {
+			got = append(got, network.String())
+		}
+		return got
+	}
+	ipv4, ipv6 := collapser.collapse(false)
+	require.ElementsMatch(t, []string{"10.0.0.0/30", "10.0.0.8/32"}, toStrings(ipv4), "could not match exact ipv4 blocks")
+	require.ElementsMatch(t, []string{"2001:db8::1/128", "2001:db8::2/128"}, toStrings(ipv6), "could not match exact ipv6 blocks")
+
+	ipv4, ipv6 = collapser.collapse(true)
+	require.Equal(t, []string{"10.0.0.0/28"}, toStrings(ipv4), "could not match approximated ipv4 block")
+	require.Equal(t, []string{"2001:db8::/126"}, toStrings(ipv6), "could not match approximated ipv6 block")
+}
+
+func TestDotGraph(t *testing.T) {
+	path := t.TempDir() + "/graph.gv"
+	graph := newDotGraph(path, 3)
+	graph.add("www.example.com", []string{"cdn.example.net.", "edge.example.org"}, nil)
+	graph.add("Example.com", nil, []string{"ns1.example.com", "ns2.example.com"})
+	require.Nil(t, graph.write(), "could not write dot graph")
+
+	data, err := os.ReadFile(path)
+	require.Nil(t, err, "could not read dot graph")
+	expected := `digraph dnsx {
+	rankdir=LR;
+	"www.example.com" -> "cdn.example.net" [label="CNAME"];
+	"cdn.example.net" -> "edge.example.org" [label="CNAME"];
+	"example.com" -> "ns1.example.com" [label="NS", style=dashed];
+}
+`
+	require.Equal(t, expected, string(data), "could not match dot graph")
+	require.Equal(t, 1, graph.dropped, "could not match dropped edges")
+}
+
+func TestLoadTargetConfig(t *testing.T) {
+	dnsX, err := dnsx.New(dnsx.DefaultOptions)
+	require.Nil(t, err, "could not create dnsx")
+	config, err := loadTargetConfig("tests/target_config.yaml", dnsX)
+	require.Nil(t, err, "could not load target config")
+
+	group := config.match("API.Corp.Example.com.")
+	require.NotNil(t, group, "host not matched")
+	require.Equal(t, "internal", group.name, "first matching group not used")
+	require.Equal(t, []uint16{dns.TypeA, dns.TypeSRV}, group.profile.QuestionTypes, "could not match types")
+	require.True(t, group.profile.NoRecursion, "recursion not disabled")
+
+	group = config.match("mail1.example.com")
+	require.NotNil(t, group, "host not matched")
+	require.Equal(t, "mail", group.name, "could not match group")
+	require.False(t, group.profile.NoRecursion, "recursion disabled by default")
+	require.Nil(t, config.match("www.example.com"), "unexpected group")
+}
+
+func TestConfigureRcodes(t *testing.T) {
+	options := &Options{RCode: "noerror,servfail", ServFail: true, NXDomain: true}
+	require.Nil(t, options.configureRcodes(), "could not configure rcodes")
+	require.True(t, options.hasRCodes, "rcode filter not enabled")
+	require.Equal(t, map[int]struct{}{dns.RcodeSuccess: {}, dns.RcodeServerFailure: {}, dns.RcodeNameError: {}}, options.rcodes, "could not match rcodes")
+
+	options = &Options{Refused: true}
+	require.Nil(t, options.configureRcodes(), "could not configure rcodes")
+	require.True(t, options.hasRCodes, "rcode filter not enabled")
+	require.Equal(t, map[int]struct{}{dns.RcodeRefused: {}}, options.rcodes, "could not match rcodes")
+
+	options = &Options{}
+	require.Nil(t, options.configureRcodes(), "could not configure rcodes")
+	require.False(t, options.hasRCodes, "rcode filter enabled")
+}
+
+func TestRetryOverTCP(t *testing.T) {
+	options := &Options{TCPRetryRcodes: "servfail,bogus"}
+	require.NotNil(t, options.configureTCPRetryRcodes(), "invalid rcode accepted")
+	options = &Options{TCPRetryRcodes: "ServFail, nodata"}
+	require.Nil(t, options.configureTCPRetryRcodes(), "could not configure tcp retry rcodes")
+
+	r := Runner{options: options}
+	response := func(rcode int, a ...string) *dnsx.ResponseData {
+		return &dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "example.com", StatusCodeRaw: rcode, A: a, Resolver: []string{"192.0.2.53:53"}}}
+	}
+	require.True(t, r.retryOverTCP("example.com", "", response(dns.RcodeServerFailure)), "servfail not retried")
+	require.True(t, r.retryOverTCP("example.com", "", response(dns.RcodeSuccess)), "nodata not retried")
+	require.False(t, r.retryOverTCP("example.com", "", response(dns.RcodeSuccess, "192.0.2.1")), "answer retried")
+	require.False(t, r.retryOverTCP("example.com", "", response(dns.RcodeNameError)), "nxdomain retried")
+	require.False(t, r.retryOverTCP("example.com", "192.0.2.53", response(dns.RcodeServerFailure)), "pinned host retried")
+}
+
+func TestKeepResponse(t *testing.T) {
+	options := &Options{RCode: "noerror"}
+	require.Nil(t, options.configureRcodes(), "could not configure rcodes")
+	r := Runner{options: options, nsSummary: newNsSummary()}
+	response := func(rcode int, hostsFile bool) *dnsx.ResponseData {
+		return &dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "example.com", StatusCodeRaw: rcode, HostsFile: hostsFile, NS: []string{"ns1.example.com"}}}
+	}
+	require.True(t, r.keepResponse("example.com", response(dns.RcodeSuccess, false)), "matching rcode dropped")
+	require.False(t, r.keepResponse("example.org", response(dns.RcodeServerFailure, false)), "other rcode kept")
+	require.True(t, r.keepResponse("example.net", response(dns.RcodeServerFailure, true)), "hosts file response dropped")
+	require.Len(t, r.nsSummary.hosts["ns1.example.com"], 2, "filtered response added to the ns summary")
+
+	r.options.WeakDNSSECOnly = true
+	require.False(t, r.keepResponse("example.com", response(dns.RcodeSuccess, false)), "response without weak dnssec kept")
+}
+
+func TestBetterResponse(t *testing.T) {
+	response := func(rcode int, records ...string) *retryabledns.DNSData {
+		return &retryabledns.DNSData{StatusCodeRaw: rcode, AllRecords: records}
+	}
+	require.True(t, betterResponse(response(dns.RcodeSuccess), response(dns.RcodeServerFailure)), "noerror not better than servfail")
+	require.True(t, betterResponse(response(dns.RcodeSuccess, "a", "b"), response(dns.RcodeSuccess, "a")), "more records not better")
+	require.False(t, betterResponse(response(dns.RcodeSuccess, "a"), response(dns.RcodeSuccess, "a")), "same records better")
+	require.False(t, betterResponse(response(dns.RcodeSuccess), response(dns.RcodeSuccess, "a")), "empty answer better")
+	require.False(t, betterResponse(response(dns.RcodeRefused), response(dns.RcodeServerFailure)), "refused better than servfail")
+}
+
+func TestAllowedRanges(t *testing.T) {
+	filename := t.TempDir() + "/ranges.txt"
+	require.Nil(t, os.WriteFile(filename, []byte("# bank\nExample.com 192.0.2.0/24, 2001:db8::/32\n*.example.com 198.51.100.0/24\n*.dev.example.com 203.0.113.7\n"), 0644), "could not write ranges")
+	ranges, err := loadAllowedRanges(filename)
+	require.Nil(t, err, "could not load allowed ranges")
+
+	networks := func(host string) []string {
+		var items []string
+		for _, network := range ranges.get(host) {
+			items = append(items, network.String())
+		}
+		return items
+	}
+	require.Equal(t, []string{"192.0.2.0/24", "2001:db8::/32"}, networks("example.com."), "could not match domain ranges")
+	require.Equal(t, []string{"198.51.100.0/24"}, networks("www.example.com"), "could not match pattern ranges")
+	require.Equal(t, []string{"203.0.113.7/32"}, networks("api.dev.example.com"), "could not match closest pattern ranges")
+	require.Nil(t, networks("example.org"), "unmapped domain matched")
+
+	require.Nil(t, os.WriteFile(filename, []byte("example.com 192.0.2.0/33\n"), 0644), "could not write ranges")
+	_, err = loadAllowedRanges(filename)
+	require.NotNil(t, err, "invalid range accepted")
+}
+
+func TestParseDelayRange(t *testing.T) {
+	tests := map[string]delayRange{
+		"200ms":     {min: 200 * time.Millisecond, max: 200 * time.Millisecond},
+		"100-500ms": {min: 100 * time.Millisecond, max: 500 * time.Millisecond},
+		"500ms-2s":  {min: 500 * time.Millisecond, max: 2 * time.Second},
+		"1-3":       {min: time.Second, max: 3 * time.Second},
+	}
+	for value, expected := range tests {
+		delay, err := parseDelayRange(value)
+		require.Nil(t, err, "could not parse %s", value)
+		require.Equal(t, expected, *delay, "could not match %s", value)
+		for i := 0; i < 10; i++ {
+			next := delay.next()
+			require.True(t, next >= delay.min && next <= delay.max, "delay %s out of range %s", next, value)
+		}
+	}
+	for _, value := range []string{"", "fast", "500-100ms", "100ms-", "-1s"} {
+		_, err := parseDelayRange(value)
+		require.NotNil(t, err, "invalid delay %q accepted", value)
+	}
+}
+
+func TestWhoisLookup(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	defer listener.Close()
+	var served atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			served.Add(1)
+			_, _ = bufio.NewReader(conn).ReadString('\n')
+			_, _ = conn.Write([]byte("refer: " + listener.Addr().String() + "\nnetname: EXAMPLE-NET\norg-name: Example\ncountry: US\n"))
+			conn.Close()
+		}
+	}()
+
+	whoisLookup, err := newWhoisLookup(context.Background(), 600)
+	require.Nil(t, err, "could not create whois lookup")
+	defer whoisLookup.close()
+	whoisLookup.client.Server = listener.Addr().String()
+
+	expected := &dnsx.WhoisResponse{IP: "192.0.2.1", NetName: "EXAMPLE-NET", Org: "Example", Country: "US"}
+	require.Equal(t, expected, whoisLookup.lookup("192.0.2.1"), "could not match whois")
+	require.Equal(t, expected, whoisLookup.lookup("192.0.2.1"), "could not match cached whois")
+	require.Equal(t, int32(2), served.Load(), "cached ip looked up again")
+
+	// failed lookups are cached as well
+	whoisLookup.client.Server = "127.0.0.1:1"
+	require.Nil(t, whoisLookup.lookup("2001:db8::1"), "unexpected whois")
+	_, ok := whoisLookup.cached("2001:db8::1")
+	require.True(t, ok, "failed lookup not cached")
+}
+
+func TestIPIndex(t *testing.T) {
+	for _, disk := range []bool{false, true} {
+		index, err := newIPIndex(disk)
+		require.Nil(t, err, "could not create ip index")
+		index.add("b.example.com", "10.0.0.2", "10.0.0.10")
+		index.add("a.example.com", "10.0.0.10", "2001:db8::1")
+		index.add("a.example.com", "10.0.0.10")
+
+		var entries []ipIndexEntry
+		index.scan(func(ip string, hosts []string) {
+			entries = append(entries, ipIndexEntry{IP: ip, Hosts: hosts})
+		})
+		index.close()
+		expected := []ipIndexEntry{
+			{IP: "10.0.0.2", Hosts: []string{"b.example.com"}},
+			{IP: "10.0.0.10", Hosts: []string{"a.example.com", "b.example.com"}},
+			{IP: "2001:db8::1", Hosts: []string{"a.example.com"}},
+		}
+		require.Equal(t, expected, entries, "could not match ip index (disk: %v)", disk)
+	}
+}
+
+func TestListTargets(t *testing.T) {
+	dir := t.TempDir()
+	hosts, output := dir+"/hosts.txt", dir+"/targets.txt"
+	require.Nil(t, os.WriteFile(hosts, []byte("https://a.example.com/path\na.example.com\nb.example.com@1.1.1.1\n192.0.2.0/31\n"), 0600), "could not write hosts")
+	hm, err := hybrid.New(hybrid.DefaultDiskOptions)
+	require.Nil(t, err, "could not create hybrid map")
+	defer hm.Close()
+	r := Runner{
+		options:        &Options{Hosts: hosts, OutputFile: output},
+		hm:             hm,
+		wgoutputworker: &sync.WaitGroup{},
+	}
+	require.Nil(t, r.prepareInput(), "failed to prepare input")
+	r.listTargets()
+
+	data, err := os.ReadFile(output)
+	require.Nil(t, err, "could not read targets")
+	// the url is listed as its host, once, and the resolver override is kept
+	require.ElementsMatch(t, []string{"a.example.com", "b.example.com@1.1.1.1", "192.0.2.0", "192.0.2.1"}, strings.Fields(string(data)), "could not match listed targets")
+}
+
+func TestSplitExpectation(t *testing.T) {
+	tests := map[string]struct {
+		target   string
+		expected []string
+	}{
+		"example.com 192.0.2.1":                     {target: "example.com", expected: []string{"192.0.2.1"}},
+		"example.com@1.1.1.1 192.0.2.1,2001:db8::1": {target: "example.com@1.1.1.1", expected: []string{"192.0.2.1", "2001:db8::1"}},
+		"example.com  192.0.2.1   192.0.2.2,":       {target: "example.com", expected: []string{"192.0.2.1", "192.0.2.2"}},
+		"example.com":                               {target: "example.com"},
+		"":                                          {},
+	}
+	for line, test := range tests {
+		target, expected := splitExpectation(line)
+		require.Equal(t, test.target, target, "could not match target of %q", line)
+		require.Equal(t, test.expected, expected, "could not match expected values of %q", line)
+	}
+}
+
+func TestSplitTargetResolver(t *testing.T) {
+	tests := map[string][2]string{
+		"example.com":            {"example.com", ""},
+		"example.com@1.1.1.1:53": {"example.com", "1.1.1.1:53"},
+		"example.com@https://user@doh.example/dns-query":      {"example.com", "https://user@doh.example/dns-query"},
+		"https://user@example.com/path":                       {"https://user@example.com/path", ""},
+		"https://user@example.com/path@https://u@doh.example": {"https://user@example.com/path", "https://u@doh.example"},
+		"@1.1.1.1": {"@1.1.1.1", ""},
+	}
+	for target, expected := range tests {
+		host, resolver := splitTargetResolver(target)
+		require.Equal(t, expected[0], host, "could not match host of %q", target)
+		require.Equal(t, expected[1], resolver, "could not match resolver of %q", target)
+	}
+}
+
+func TestAsnPrefixFilter(t *testing.T) {
+	_, err := parseAsnPrefixFilter("/24,bogus")
+	require.NotNil(t, err, "invalid cidr accepted")
+	_, err = parseAsnPrefixFilter("/129")
+	require.NotNil(t, err, "invalid prefix size accepted")
+
+	cidrs := func(values ...string) []*net.IPNet {
+		var cidrs []*net.IPNet
+		for _, value := range values {
+			_, cidr, err := net.ParseCIDR(value)
+			require.Nil(t, err, "could not parse %s", value)
+			cidrs = append(cidrs, cidr)
+		}
+		return cidrs
+	}
+	strs := func(cidrs []*net.IPNet) []string {
+		var values []string
+		for _, cidr := range cidrs {
+			values = append(values, cidr.String())
+		}
+		return values
+	}
+	// an asn announcing a range and more specific prefixes within it
+	prefixes := cidrs("192.0.2.0/24", "198.51.100.0/23", "198.51.100.0/24", "203.0.113.0/25", "2001:db8::/32")
+
+	filter, err := parseAsnPrefixFilter("/24")
+	require.Nil(t, err, "could not parse sizes")
+	require.Equal(t, []string{"192.0.2.0/24", "198.51.100.0/24"}, strs(filter.apply(prefixes)), "could not match sizes")
+
+	// the filter cidrs within a prefix are kept once, the prefixes within a filter cidr as a whole
+	filter, err = parseAsnPrefixFilter("198.51.100.0/25,198.51.100.0/26,192.0.0.0/16,2001:db8:1::/48")
+	require.Nil(t, err, "could not parse cidrs")
+	require.Equal(t, []string{"192.0.2.0/24", "198.51.100.0/25", "2001:db8:1::/48"}, strs(filter.apply(prefixes)), "could not match cidrs")
+
+	filter, err = parseAsnPrefixFilter("10.0.0.0/8")
+	require.Nil(t, err, "could not parse cidr")
+	require.Empty(t, filter.apply(prefixes), "prefixes outside the filter kept")
+}
+
+func TestPairWWW(t *testing.T) {
+	hosts := []string{"example.com", "www.example.org", "www.example.com", "api.example.net", "www.api.example.net", "https://example.co.uk/path", "192.0.2.1"}
+	expected := []string{"example.com", "www.example.org", "www.example.com", "api.example.net", "www.api.example.net", "https://example.co.uk/path", "192.0.2.1", "example.org", "www.example.co.uk"}
+	require.Equal(t, expected, pairWWW(hosts), "could not match paired hosts")
+}
+
+func TestOutputSinks(t *testing.T) {
+	_, err := parseOutputSink("nx.jsonl:rcode=bogus")
+	require.NotNil(t, err, "invalid rcode accepted")
+	_, err = parseOutputSink("nx.jsonl:port=53")
+	require.NotNil(t, err, "unsupported filter accepted")
+
+	sink, err := parseOutputSink(`C:\results\all.jsonl`)
+	require.Nil(t, err, "could not parse windows path")
+	require.Equal(t, `C:\results\all.jsonl`, sink.path, "windows path split")
+
+	dir := t.TempDir()
+	all, nx, cname := dir+"/all.jsonl", dir+"/nx.jsonl", dir+"/cname.jsonl"
+	sinks, err := newOutputSinks([]string{all, nx + ":rcode=nxdomain|servfail", cname + ":type=cname,rcode=noerror"})
+	require.Nil(t, err, "could not create output sinks")
+	sinks.write(&dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "a.example.com", StatusCodeRaw: dns.RcodeSuccess, A: []string{"192.0.2.1"}}}, "")
+	sinks.write(&dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "b.example.com", StatusCodeRaw: dns.RcodeNameError}}, "")
+	sinks.write(&dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "c.example.com", StatusCodeRaw: dns.RcodeSuccess, CNAME: []string{"a.example.com"}}}, "")
+	sinks.close()
+
+	hosts := func(path string) []string {
+		data, err := os.ReadFile(path)
+		require.Nil(t, err, "could not read output sink")
+		var hosts []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var response dnsx.ResponseData
+			if line != "" && json.Unmarshal([]byte(line), &response) == nil {
+				hosts = append(hosts, response.Host)
+			}
+		}
+		return hosts
+	}
+	require.Equal(t, []string{"a.example.com", "b.example.com", "c.example.com"}, hosts(all), "could not match unfiltered sink")
+	require.Equal(t, []string{"b.example.com"}, hosts(nx), "could not match rcode sink")
+	require.Equal(t, []string{"c.example.com"}, hosts(cname), "could not match type sink")
+}
+
+func TestResolversToFile(t *testing.T) {
+	filename := t.TempDir() + "/resolvers.txt"
+	resolvers := []string{"1.1.1.1:53", "tcp:8.8.8.8:53", "doh:https://dns.example.com/dns-query"}
+	require.Nil(t, resolversToFile(filename, resolvers), "could not write resolvers")
+	read, err := resolversFromFile(filename)
+	require.Nil(t, err, "could not read resolvers")
+	require.Equal(t, resolvers, read, "could not match written resolvers")
+}
+
+func TestHandleOutputGzip(t *testing.T) {
+	filename := t.TempDir() + "/output.txt.gz"
+	r := Runner{options: &Options{OutputFile: filename, Silent: true}, wgoutputworker: &sync.WaitGroup{}}
+	// every output session appends a gzip member to the file
+	for _, line := range []string{"a.example.com", "b.example.com"} {
+		r.startOutputWorker()
+		r.outputchan <- line
+		close(r.outputchan)
+		r.wgoutputworker.Wait()
+	}
+
+	file, err := os.Open(filename)
+	require.Nil(t, err, "could not open output")
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	require.Nil(t, err, "could not read gzip output")
+	data, err := io.ReadAll(reader)
+	require.Nil(t, err, "could not decompress output")
+	require.Equal(t, "a.example.com\nb.example.com\n", string(data), "could not match output")
+}
+
+func TestOutputSinksEnriched(t *testing.T) {
+	filename := t.TempDir() + "/all.jsonl"
+	sinks, err := newOutputSinks([]string{filename})
+	require.Nil(t, err, "could not create output sinks")
+	r := Runner{options: &Options{AnnotateBogon: true, Raw: true}, outputSinks: sinks, outputchan: make(chan string, 1)}
+	r.processResponse("a.example.com", &dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "a.example.com", A: []string{"10.0.0.1"}}})
+	sinks.close()
+
+	data, err := os.ReadFile(filename)
+	require.Nil(t, err, "could not read output sink")
+	var response dnsx.ResponseData
+	require.Nil(t, json.Unmarshal(data, &response), "could not decode sink record")
+	require.True(t, response.Private, "sink record not annotated")
+	require.Equal(t, []string{"10.0.0.1"}, response.PrivateIPs, "could not match private ips")
+}
+
+func TestHandleOutputESBulk(t *testing.T) {
+	options := &Options{ESBulk: true, ESIndex: "Dnsx"}
+	require.NotNil(t, options.configureESBulk(), "uppercase index accepted")
+	options.ESIndex = "dnsx-2026.10"
+	require.Nil(t, options.configureESBulk(), "could not configure es-bulk")
+
+	filename := t.TempDir() + "/output.ndjson"
+	options.OutputFile, options.JSON, options.Silent = filename, true, true
+	r := Runner{options: options, wgoutputworker: &sync.WaitGroup{}}
+	r.startOutputWorker()
+	timestamp := time.Date(2026, 10, 15, 8, 30, 0, 123456789, time.FixedZone("CEST", 2*60*60))
+	r.outputStructured(&dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "a.example.com", A: []string{"192.0.2.1"}, Timestamp: timestamp}})
+	close(r.outputchan)
+	r.wgoutputworker.Wait()
+
+	data, err := os.ReadFile(filename)
+	require.Nil(t, err, "could not read output")
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 2, "could not match bulk lines")
+	require.Equal(t, `{"index":{"_index":"dnsx-2026.10"}}`, lines[0], "could not match action line")
+	var document map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(lines[1]), &document), "could not decode document")
+	require.Equal(t, "a.example.com", document["host"], "could not match host")
+	require.Equal(t, "2026-10-15T06:30:00.123Z", document["@timestamp"], "could not match timestamp")
+}
+
+func TestSRVHosts(t *testing.T) {
+	filename := t.TempDir() + "/services.txt"
+	require.Nil(t, os.WriteFile(filename, []byte("# custom services\nvoip 5070/udp,5071/tcp\nldap 3389/tcp\n"), 0644), "could not write services")
+	services, err := loadSRVServices(filename)
+	require.Nil(t, err, "could not load services")
+	require.Equal(t, []srvEndpoint{{Port: 3389, Proto: "tcp"}}, services["ldap"], "built-in service not replaced")
+	require.Equal(t, []srvEndpoint{{Port: 88, Proto: "tcp"}, {Port: 88, Proto: "udp"}}, services["kerberos"], "built-in service lost")
+
+	srvNames, err := srvNamesFor(services, []string{"kerberos", "_voip", "kerberos"})
+	require.Nil(t, err, "could not match services")
+	hosts := srvHosts([]string{"example.com", "192.0.2.1"}, srvNames)
+	expected := []string{"_kerberos._tcp.example.com", "_kerberos._udp.example.com", "_voip._tcp.example.com", "_voip._udp.example.com", "192.0.2.1"}
+	require.Equal(t, expected, hosts, "could not match srv hosts")
+
+	r := Runner{options: &Options{srvNames: srvNames}}
+	require.Equal(t, "voip 5070/udp", r.srvNameOf("_VOIP._udp.example.com").label(), "could not match service label")
+	require.Nil(t, r.srvNameOf("example.com"), "service of a plain host")
+
+	_, err = srvNamesFor(services, []string{"unknown"})
+	require.NotNil(t, err, "unknown service accepted")
+	_, _, err = parseSRVServiceLine("voip 5070")
+	require.NotNil(t, err, "endpoint without protocol accepted")
+}
+
+func TestTLSAHosts(t *testing.T) {
+	ports, err := parseTLSAPorts([]string{"443", "default", "53/UDP"})
+	require.Nil(t, err, "could not parse tlsa ports")
+	require.Len(t, ports, len(tlsaDefaultPorts)+1, "duplicate port kept")
+	require.Equal(t, srvEndpoint{Port: 53, Proto: "udp"}, ports[len(ports)-1], "could not match udp port")
+
+	hosts := tlsaHosts([]string{"example.com", "192.0.2.1"}, ports[:1])
+	require.Equal(t, []string{"_443._tcp.example.com", "192.0.2.1"}, hosts, "could not match tlsa hosts")
+
+	r := Runner{options: &Options{tlsaPorts: ports}}
+	require.Equal(t, "53/udp", r.tlsaPortOf("_53._UDP.example.com"), "could not match port label")
+	require.Empty(t, r.tlsaPortOf("_8443._tcp.example.com"), "port of an unlisted name")
+
+	_, err = parseTLSAPorts([]string{"443/quic"})
+	require.NotNil(t, err, "unknown protocol accepted")
+	_, err = parseTLSAPorts([]string{"70000"})
+	require.NotNil(t, err, "invalid port accepted")
+}
+
+func TestReverseChecks(t *testing.T) {
+	var checked atomic.Int32
+	checks := newReverseChecks(func(ip string) dnsx.ReverseCheck {
+		checked.Add(1)
+		return dnsx.ReverseCheck{IP: ip, PTR: []string{"host.example.com"}, Confirmed: ip != "2001:db8::2"}
+	})
+	dualStack := checks.dualStack([]string{"192.0.2.1"}, []string{"2001:db8::1", "2001:db8::2"})
+	require.Len(t, dualStack.IPv6, 2, "could not match ipv6 checks")
+	require.Equal(t, []string{"fcrdns-fail 2001:db8::2"}, dualStack.Issues, "could not match issues")
+	checks.dualStack([]string{"192.0.2.1"}, nil)
+	require.Equal(t, int32(3), checked.Load(), "address checked twice")
+	require.Nil(t, checks.dualStack(nil, nil), "report of a host without address")
+}
+
+func TestExecFilter(t *testing.T) {
+	record := `{"host":"a.example.com","a":["192.0.2.1"]}`
+	filter := newExecFilter(`sed -u 's/"a"/"ipv4"/'`, 1, time.Second)
+	defer filter.close()
+	transformed, ok := filter.apply(record)
+	require.True(t, ok, "record dropped")
+	require.Equal(t, `{"host":"a.example.com","ipv4":["192.0.2.1"]}`, transformed, "could not match transformed record")
+
+	// a single process answers every record
+	counting := newExecFilter(`n=0; while read -r line; do n=$((n+1)); echo "{\"n\":$n}"; done`, 1, time.Second)
+	defer counting.close()
+	for i := 1; i <= 3; i++ {
+		transformed, _ = counting.apply(record)
+		require.Equal(t, `{"n":`+strconv.Itoa(i)+`}`, transformed, "could not match record count")
+	}
+
+	dropping := newExecFilter("while read -r line; do echo; done", 1, time.Second)
+	defer dropping.close()
+	_, ok = dropping.apply(record)
+	require.False(t, ok, "record without output kept")
+
+	for _, command := range []string{"exit 3", "while read -r line; do echo invalid; done", "sleep 5"} {
+		filter := newExecFilter(command, 1, 200*time.Millisecond)
+		transformed, ok := filter.apply(record)
+		require.True(t, ok, "record dropped on failure of %s", command)
+		require.Equal(t, record, transformed, "record changed on failure of %s", command)
+		require.Equal(t, uint64(1), filter.failures.Load(), "failure of %s not counted", command)
+		filter.close()
+	}
+
+	// a process exiting after its first record is started again for the next one
+	crashing := newExecFilter(`read -r line; echo "$line"; exit 1`, 1, time.Second)
+	defer crashing.close()
+	for i := 0; i < 3; i++ {
+		transformed, ok = crashing.apply(record)
+		require.True(t, ok, "record dropped")
+		require.Equal(t, record, transformed, "could not match record")
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.Equal(t, uint64(0), crashing.failures.Load(), "restarted process failed")
+}
+
+func TestCollapseRoundRobin(t *testing.T) {
+	ips := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.0", "192.0.2.4"}
+	require.Equal(t, "5 records: 192.0.2.1,192.0.2.2,192.0.2.3,...", collapseRoundRobin(ips, false), "could not match sample")
+	require.Equal(t, "5 records: 192.0.2.0/30,192.0.2.4/32", collapseRoundRobin(ips, true), "could not match cidr blocks")
+
+	// the collapsed line carries the annotations of the whole set, once per type, the private address being past
+	// the sample
+	r := Runner{
+		options:    &Options{Response: true, NoColor: true, AnnotateBogon: true, CollapseRR: true, CollapseRRMin: 5},
+		outputchan: make(chan string, 1),
+		aurora:     aurora.NewAurora(false),
+	}
+	ips = append(ips, "10.0.0.1")
+	r.outputRecordType("example.com", ips, "A", &dnsx.ResponseData{DNSData: &retryabledns.DNSData{A: ips}})
+	require.Equal(t, "example.com [A] [6 records: 192.0.2.1,192.0.2.2,192.0.2.3,...] [bogon] [private] ", <-r.outputchan, "could not match collapsed annotations")
+}
+
+func TestExternalDeps(t *testing.T) {
+	deps := newExternalDeps()
+	deps.add("www.example.com", []string{"d1.cloudfront.net.", "edge.example.com"})
+	deps.add("cdn.example.co.uk", []string{"D2.CloudFront.net"})
+	deps.add("shop.example.co.uk", []string{"shops.myshopify.com"})
+	// the target inside a domain of the input added later is internal
+	deps.add("app.example.com", []string{"lb.example.org"})
+	deps.add("www.example.org", nil)
+
+	external := deps.external()
+	require.Len(t, external, 2, "could not classify the targets")
+	require.Equal(t, "cloudfront.net", external[0].apex)
+	require.Len(t, external[0].hosts, 2)
+	require.Equal(t, []string{"d1.cloudfront.net", "d2.cloudfront.net"}, external[0].targets)
+	require.Equal(t, "myshopify.com", external[1].apex)
+}
+
+func TestPrepareResolver(t *testing.T) {
+	tests := map[string]string{
+		"1.1.1.1":                          "1.1.1.1:53",
+		"tcp:1.1.1.1":                      "tcp:1.1.1.1",
+		"https://dns.google/dns-query":     "doh:https://dns.google/dns-query",
+		" HTTPS://dns.google/dns-query ":   "doh:HTTPS://dns.google/dns-query",
+		"doh:https://dns.google/dns-query": "doh:https://dns.google/dns-query",
+		"quic://dns.adguard-dns.com":       "quic://dns.adguard-dns.com:853",
+		"tls://dns.google":                 "dot:dns.google:853",
+		"tls://1.1.1.1:8853":               "dot:1.1.1.1:8853",
+		"1.1.1.1:853":                      "dot:1.1.1.1:853",
+		"[2606:4700:4700::1111]:853":       "dot:[2606:4700:4700::1111]:853",
+		"dot:1.1.1.1":                      "dot:1.1.1.1",
+		"tcp:1.1.1.1:853":                  "tcp:1.1.1.1:853",
+	}
+	for resolver, expected := range tests {
+		require.Equal(t, expected, prepareResolver(resolver), "could not prepare %s", resolver)
+	}
+}
+
+func TestHashSummary(t *testing.T) {
+	s := newHashSummary()
+	s.add("a.example.com", "parked")
+	s.add("b.example.com", "parked")
+	s.add("b.example.com", "parked")
+	s.add("c.example.com", "parked")
+	s.add("x.example.org", "sinkhole")
+	s.add("y.example.org", "sinkhole")
+	s.add("unique.example.net", "unique")
+	require.Equal(t, []string{"parked", "sinkhole"}, s.shared(), "could not match shared hashes")
+	require.Len(t, s.hosts["parked"], 3, "duplicate host counted")
+}
+
+func TestNSInventory(t *testing.T) {
+	var resolved atomic.Int32
+	inventory := newNSInventory(func(name string) dnsx.Nameserver {
+		resolved.Add(1)
+		return dnsx.Nameserver{Name: name, IPs: []string{"192.0.2.53"}}
+	})
+	// the domains sharing the nameservers are processed concurrently
+	results := make([][]dnsx.Nameserver, 10)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = inventory.lookup([]string{"NS2.example.com.", "ns1.example.com", "ns2.example.com"})
+		}(i)
+	}
+	wg.Wait()
+	for _, nameservers := range results {
+		require.Equal(t, []dnsx.Nameserver{
+			{Name: "ns1.example.com", IPs: []string{"192.0.2.53"}},
+			{Name: "ns2.example.com", IPs: []string{"192.0.2.53"}},
+		}, nameservers, "could not match inventory")
+	}
+	require.Equal(t, int32(2), resolved.Load(), "shared nameservers resolved more than once")
+}
+
+func TestMonitor(t *testing.T) {
+	statePath := t.TempDir() + "/monitor.jsonl"
+	m, err := newMonitor(statePath)
+	require.Nil(t, err, "could not create monitor")
+	record := func(host, value string) monitorRecord {
+		return monitorRecord{Host: host, Type: "A", Value: value}
+	}
+	changes := func(changes []monitorChange) []string {
+		var items []string
+		for _, change := range changes {
+			items = append(items, change.Change+" "+change.Host+" "+change.Value)
+		}
+		return items
+	}
+
+	m.beginCycle()
+	first := time.Now()
+	require.Equal(t, []string{"added a.example.com 192.0.2.1"}, changes(m.observe("a.example.com", []uint16{dns.TypeA}, []monitorRecord{record("a.example.com", "192.0.2.1")}, first, false)), "could not match new record")
+	m.observe("b.example.com", []uint16{dns.TypeA}, []monitorRecord{record("b.example.com", "192.0.2.2")}, first, false)
+	require.Empty(t, m.endCycle(), "records removed in the first cycle")
+
+	// a.example.com moves to another address and b.example.com does not answer
+	second := first.Add(time.Hour)
+	m.cycleStart = second
+	m.answered = make(map[string]struct{})
+	require.Equal(t, []string{"added a.example.com 192.0.2.3"}, changes(m.observe("a.example.com", []uint16{dns.TypeA}, []monitorRecord{record("a.example.com", "192.0.2.3")}, second, false)), "could not match moved record")
+	require.Equal(t, []string{"removed a.example.com 192.0.2.1"}, changes(m.endCycle()), "could not match removed record")
+	require.Nil(t, m.save(statePath), "could not save monitor state")
+	m.close()
+	m.close()
+
+	// the state of the next run keeps the first seen times
+	m, err = newMonitor(statePath)
+	require.Nil(t, err, "could not load monitor state")
+	defer m.close()
+	m.beginCycle()
+	unchanged := m.observe("b.example.com", []uint16{dns.TypeA}, []monitorRecord{record("b.example.com", "192.0.2.2")}, second, true)
+	require.Equal(t, []string{"unchanged b.example.com 192.0.2.2"}, changes(unchanged), "could not match unchanged record")
+	require.True(t, unchanged[0].FirstSeen.Equal(first), "first seen time lost")
+	require.True(t, unchanged[0].LastSeen.Equal(second), "could not match last seen time")
+	// the records of the types no longer queried are kept
+	m.observe("a.example.com", []uint16{dns.TypeAAAA}, nil, time.Now(), false)
+	require.Empty(t, m.endCycle(), "records of a type not queried removed")
+}
+
+func TestRunMonitor(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		if req.Question[0].Qtype == dns.TypeA {
+			rr, _ := dns.NewRR(req.Question[0].Name + " 60 IN A 192.0.2.1")
+			resp.Answer = append(resp.Answer, rr)
+		}
+		_ = w.WriteMsg(resp)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	dir := t.TempDir()
+	hosts := dir + "/hosts.txt"
+	require.Nil(t, os.WriteFile(hosts, []byte("a.example.com\n"), 0600), "could not write hosts")
+	options := &Options{
+		Hosts:           hosts,
+		Resolvers:       conn.LocalAddr().String(),
+		A:               true,
+		Threads:         1,
+		Retries:         1,
+		MaxAnswers:      dnsx.DefaultMaxAnswers,
+		Silent:          true,
+		NoColor:         true,
+		Monitor:         true,
+		MonitorInterval: time.Hour,
+		MonitorState:    dir + "/state/monitor.jsonl",
+	}
+	r, err := New(options)
+	require.Nil(t, err, "could not create runner")
+	defer r.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- r.runMonitor() }()
+	// the state of the completed cycle is saved while waiting for the next one
+	require.Eventually(t, func() bool { return fileutil.FileExists(options.MonitorState) }, 5*time.Second, 10*time.Millisecond, "monitor state not saved")
+	data, err := os.ReadFile(options.MonitorState)
+	require.Nil(t, err, "could not read monitor state")
+	require.Contains(t, string(data), `"value":"192.0.2.1"`, "could not match saved record")
+
+	r.Cancel()
+	select {
+	case err := <-done:
+		require.Nil(t, err, "monitor failed")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "monitor not ended by the cancellation")
+	}
+}
+
+func TestDeferredQueue(t *testing.T) {
+	r := Runner{options: &Options{Threads: 2, TraceThreads: 1}, wgresolveworkers: &sync.WaitGroup{}, wgdeferredworkers: &sync.WaitGroup{}}
+	r.startDeferredWorkers()
+	require.Nil(t, r.deferredchan, "deferred pool started without slow lookups")
+
+	// the canceled run drains the queue without tracing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ctx = ctx
+	r.options.Trace = true
+	r.startDeferredWorkers()
+	require.Equal(t, r.options.Threads*deferredBacklog, cap(r.deferredchan), "resolve workers not queued ahead of the traces")
+	for i := 0; i < 2*r.options.Threads*deferredBacklog; i++ {
+		r.deferredchan <- deferredResponse{domain: "example.com"}
+	}
+	r.waitWorkers()
+	require.Nil(t, r.deferredchan, "deferred queue not released")
+}
+
+func TestCNAMEChainOutput(t *testing.T) {
+	dnsData := &dnsx.ResponseData{DNSData: &retryabledns.DNSData{
+		Host:          "www.example.com",
+		StatusCodeRaw: dns.RcodeSuccess,
+		CNAME:         []string{"cdn.example.net", "edge.example.com"},
+		AllRecords: []string{
+			"edge.example.com.\t300\tIN\tCNAME\tcdn.example.net.",
+			"www.example.com.\t300\tIN\tCNAME\tedge.example.com.",
+		},
+	}}
+	dnsData.OrderCNAMEChain()
+	r := Runner{
+		options:    &Options{Response: true, CNAME: true, CNAMEChain: true, NoColor: true},
+		outputchan: make(chan string, 1),
+		aurora:     aurora.NewAurora(false),
+	}
+	r.processResponse("www.example.com", dnsData)
+	require.Equal(t, "www.example.com [CNAME] [edge.example.com -> cdn.example.net] ", <-r.outputchan, "could not match cname chain")
+}
+
+func TestWordExtractor(t *testing.T) {
+	hosts := []string{"api.dev.example.com", "API.example.com.", "www.example.co.uk", "example.com", "*.dev.example.org", "192.0.2.1"}
+	leftmost := newWordExtractor(extractWordsLeftmost)
+	all := newWordExtractor(extractWordsAll)
+	for _, host := range hosts {
+		leftmost.add(host)
+		all.add(host)
+	}
+	require.Equal(t, []string{"api", "www"}, leftmost.sorted(), "could not match leftmost labels")
+	require.Equal(t, []string{"api", "dev", "www"}, all.sorted(), "could not match all labels")
+}
+
+func TestWildcardCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := newWildcardCache(dir, 0, false)
+	require.Nil(t, err, "could not open wildcard cache")
+	cache.set("example.com", []string{"192.0.2.1"})
+	cache.set("dev.example.com", nil)
+	cache.close()
+
+	// the next run reuses the probes, no answer included
+	cache, err = newWildcardCache(dir, time.Hour, false)
+	require.Nil(t, err, "could not reopen wildcard cache")
+	a, ok := cache.get("example.com")
+	require.True(t, ok, "cached probe not found")
+	require.Equal(t, []string{"192.0.2.1"}, a, "could not match cached probe")
+	_, ok = cache.get("dev.example.com")
+	require.True(t, ok, "cached empty probe not found")
+	_, ok = cache.get("example.org")
+	require.False(t, ok, "probe found for an unknown domain")
+	cache.close()
+
+	// expired or reprobed entries are ignored, the new probes of the run are used
+	for _, reopen := range []func() (*wildcardCache, error){
+		func() (*wildcardCache, error) { return newWildcardCache(dir, time.Nanosecond, false) },
+		func() (*wildcardCache, error) { return newWildcardCache(dir, 0, true) },
+	} {
+		cache, err = reopen()
+		require.Nil(t, err, "could not reopen wildcard cache")
+		_, ok = cache.get("example.com")
+		require.False(t, ok, "stale probe used")
+		cache.set("example.com", []string{"192.0.2.2"})
+		a, _ = cache.get("example.com")
+		require.Equal(t, []string{"192.0.2.2"}, a, "could not match new probe")
+		cache.close()
+	}
+}
+
+func TestParseColorScheme(t *testing.T) {
+	scheme, err := parseColorScheme("colorblind,mx=cyan,type=white")
+	require.Nil(t, err, "could not parse color scheme")
+	require.Equal(t, aurora.WhiteFg, scheme.label, "could not override the type color")
+	require.Equal(t, colorSchemePresets["colorblind"].record, scheme.record, "could not use the preset record color")
+	require.Equal(t, aurora.CyanFg, scheme.records["MX"], "could not override the mx color")
+
+	_, err = parseColorScheme("mx=cyan,colorblind")
+	require.NotNil(t, err, "preset accepted after an override")
+	_, err = parseColorScheme("unknown")
+	require.NotNil(t, err, "unknown preset accepted")
+	_, err = parseColorScheme("a=purple")
+	require.NotNil(t, err, "unknown color accepted")
+
+	r := Runner{options: &Options{colorScheme: scheme}, aurora: aurora.NewAurora(true)}
+	require.Equal(t, aurora.CyanFg, r.colorizeRecord("MX", "mail.example.com").Color(), "could not color the mx record")
+	require.Equal(t, scheme.record, r.colorizeRecord("A", "192.0.2.1").Color(), "could not color the a record")
+	require.Equal(t, aurora.WhiteFg, r.colorizeType("A").Color(), "could not color the type")
+
+	r.options.colorScheme = nil
+	require.Equal(t, aurora.GreenFg, r.colorizeRecord("A", "192.0.2.1").Color(), "could not use the default scheme")
+}
+
+func TestResolverExclusions(t *testing.T) {
+	filename := t.TempDir() + "/exclude.txt"
+	require.Nil(t, os.WriteFile(filename, []byte("# blocked\n1.1.1.1\n192.0.2.0/24\n2001:db8:0::1\ndns.google\n"), 0644), "could not write exclusions")
+	exclusions, err := loadResolverExclusions(filename)
+	require.Nil(t, err, "could not load exclusions")
+
+	resolvers := []string{
+		"1.1.1.1",
+		"udp:1.1.1.1:53",
+		"192.0.2.53:5353",
+		"[2001:db8::1]:53",
+		"doh:https://dns.google/dns-query",
+		"quic://192.0.2.1:853",
+		"8.8.8.8",
+		"dot:9.9.9.9:853",
+	}
+	require.Equal(t, []string{"8.8.8.8", "dot:9.9.9.9:853"}, exclusions.filter(resolvers), "could not filter the excluded resolvers")
+
+	exclusions, err = loadResolverExclusions("8.8.8.8, 10.0.0.0/8")
+	require.Nil(t, err, "could not load exclusions list")
+	require.True(t, exclusions.Contains("10.1.2.3"), "could not match excluded network")
+	require.False(t, exclusions.Contains("1.1.1.1"), "matched resolver not excluded")
+
+	_, err = loadResolverExclusions("10.0.0.0/33")
+	require.NotNil(t, err, "invalid network accepted")
+}
+
+func TestAsnSummary(t *testing.T) {
+	s := newAsnSummary()
+	cloud := &dnsx.AsnResponse{AsNumber: "AS64500", AsName: "cloud", AsCountry: "US"}
+	cdn := &dnsx.AsnResponse{AsNumber: "AS64496", AsName: "cdn", AsCountry: "NL"}
+	hosting := &dnsx.AsnResponse{AsNumber: "AS64511", AsName: "hosting", AsCountry: "DE"}
+	s.add("a.example.com", cloud)
+	s.add("b.example.com", cloud)
+	s.add("b.example.com", cloud)
+	s.add("c.example.com", hosting)
+	s.add("d.example.com", cdn)
+
+	entries := s.sorted()
+	require.Len(t, entries, 3, "could not group hosts by asn")
+	require.Equal(t, "AS64500", entries[0].asn.AsNumber, "could not put the largest asn first")
+	require.Len(t, entries[0].hosts, 2, "duplicate host counted")
+	require.Equal(t, "AS64496", entries[1].asn.AsNumber, "could not order asns with the same hosts by number")
+	require.Equal(t, "AS64511", entries[2].asn.AsNumber, "could not order asns with the same hosts by number")
+}
+
+func TestOutputProbe(t *testing.T) {
+	r := Runner{
+		options:    &Options{Probe: true},
+		outputchan: make(chan string, 1),
+		aurora:     aurora.NewAurora(false),
+	}
+	live := &dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "www.example.com", A: []string{"192.0.2.1"}}}
+	r.outputProbe("www.example.com", live)
+	require.Equal(t, "www.example.com [true]", <-r.outputchan, "could not report the live host")
+
+	mail := &dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "mail.example.com", MX: []string{"mx.example.com"}}}
+	r.outputProbe("mail.example.com", mail)
+	require.Equal(t, "mail.example.com [true]", <-r.outputchan, "could not report the host with mx records")
+
+	failed := &dnsx.ResponseData{DNSData: &retryabledns.DNSData{Host: "nx.example.com"}}
+	r.outputProbe("nx.example.com", failed)
+	require.Equal(t, "nx.example.com [false]", <-r.outputchan, "could not report the host without records")
+
+	r.options.JSON = true
+	r.outputProbe("nx.example.com", failed)
+	var record map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(<-r.outputchan), &record), "could not decode probe record")
+	require.Equal(t, false, record["live"], "could not report the liveness in json")
+}
+
+func TestTypeOrderedOutput(t *testing.T) {
+	options := &Options{QueryType: []string{"a", "mx"}, Response: true, NoColor: true}
+	options.configureQueryOptions()
+	require.True(t, options.A && options.MX, "could not enable the listed types")
+	require.False(t, options.AAAA, "enabled a type not listed")
+
+	r := Runner{
+		options:           options,
+		outputchan:        make(chan string, 4),
+		aurora:            aurora.NewAurora(false),
+		typeOrderedOutput: newTypeOrderedOutput(),
+	}
+	for _, host := range []string{"a.example.com", "b.example.com"} {
+		r.processResponse(host, &dnsx.ResponseData{DNSData: &retryabledns.DNSData{
+			Host:          host,
+			StatusCodeRaw: dns.RcodeSuccess,
+			A:             []string{"192.0.2.1"},
+			MX:            []string{"mx.example.com"},
+		}})
+	}
+	require.Empty(t, r.outputchan, "lines written before the end of the run")
+	r.typeOrderedOutput.flush(r.outputchan)
+	close(r.outputchan)
+	var lines []string
+	for line := range r.outputchan {
+		lines = append(lines, line)
+	}
+	require.Equal(t, []string{
+		"a.example.com [A] [192.0.2.1] ",
+		"b.example.com [A] [192.0.2.1] ",
+		"a.example.com [MX] [mx.example.com] ",
+		"b.example.com [MX] [mx.example.com] ",
+	}, lines, "could not group the lines by type")
+}
+
+func TestNsSummary(t *testing.T) {
+	s := newNsSummary()
+	s.add("a.example.com", []string{"ns2.example.net", "NS1.example.net"})
+	s.add("b.example.com", []string{"ns1.example.net"})
+	s.add("b.example.com", []string{"ns1.example.net"})
+	s.add("c.example.com", []string{"ns3.example.net"})
+	require.Equal(t, []string{"ns1.example.net", "ns2.example.net", "ns3.example.net"}, s.sorted(), "could not order nameservers")
+	require.Len(t, s.hosts["ns1.example.net"], 2, "could not merge nameserver case or duplicate hosts")
+}
+
+func TestReloadResolvers(t *testing.T) {
+	dir := t.TempDir()
+	resolversFile := dir + "/resolvers.txt"
+	require.Nil(t, os.WriteFile(resolversFile, []byte("192.0.2.53:53\n"), 0644), "could not write resolvers")
+	dnsxOptions := dnsx.DefaultOptions
+	dnsxOptions.BaseResolvers = []string{"192.0.2.53:53"}
+	dnsX, err := dnsx.New(dnsxOptions)
+	require.Nil(t, err, "could not create dnsx")
+	exclusions, err := loadResolverExclusions("198.51.100.0/24")
+	require.Nil(t, err, "could not load exclusions")
+	r := Runner{
+		options:           &Options{Resolvers: resolversFile, ResolversOut: dir + "/active.txt"},
+		dnsx:              dnsX,
+		excludedResolvers: exclusions,
+	}
+
+	require.Nil(t, os.WriteFile(resolversFile, []byte("192.0.2.54:53\n198.51.100.53:53\nhttps://dns.example/dns-query\n"), 0644), "could not update resolvers")
+	require.Nil(t, r.reloadResolvers(), "could not reload resolvers")
+	expected := []string{"192.0.2.54:53", "doh:https://dns.example/dns-query"}
+	require.Equal(t, expected, dnsX.Options.BaseResolvers, "could not reload the resolvers without the excluded ones")
+	active, err := resolversFromFile(r.options.ResolversOut)
+	require.Nil(t, err, "could not read active resolvers")
+	require.Equal(t, expected, active, "could not write the reloaded resolvers")
+
+	// a file without usable resolver keeps the current ones
+	require.Nil(t, os.WriteFile(resolversFile, []byte("198.51.100.53:53\n"), 0644), "could not update resolvers")
+	require.NotNil(t, r.reloadResolvers(), "reloaded only excluded resolvers")
+	require.Equal(t, expected, dnsX.Options.BaseResolvers, "resolvers replaced on error")
+}
+
+func TestOutputCounts(t *testing.T) {
+	dnsxOptions := dnsx.DefaultOptions
+	dnsxOptions.QuestionTypes = []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeMX}
+	dnsX, err := dnsx.New(dnsxOptions)
+	require.Nil(t, err, "could not create dnsx")
+	r := Runner{
+		options:    &Options{ShowCounts: true},
+		dnsx:       dnsX,
+		outputchan: make(chan string, 1),
+		aurora:     aurora.NewAurora(false),
+	}
+	r.outputCounts("example.com", &dnsx.ResponseData{DNSData: &retryabledns.DNSData{
+		Host: "example.com",
+		A:    []string{"192.0.2.1", "192.0.2.2"},
+		MX:   []string{"mx.example.com"},
+	}})
+	require.Equal(t, "example.com [A:2] [AAAA:0] [MX:1]", <-r.outputchan, "could not match record counts")
+}
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/projectdiscovery/gologger"
+)
+
+// sinkFilter selects the responses written to a sink, an empty filter matching every response
+type sinkFilter struct {
+	rcodes map[int]struct{}
+	types  []uint16
+}
+
+// outputSink is an additional output file receiving the json lines of the responses matching its filter
+type outputSink struct {
+	path   string
+	filter sinkFilter
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// sinkItem is a json line to write to a sink
+type sinkItem struct {
+	sink *outputSink
+	line string
+}
+
+// outputSinks fans the responses out to the sinks through a dedicated output channel
+type outputSinks struct {
+	sinks    []*outputSink
+	sinkchan chan sinkItem
+	wg       sync.WaitGroup
+}
+
+// parseOutputSink parses a path optionally followed by a filter, made of comma separated conditions
+// all having to match, each listing alternatives separated by pipes (eg. nx.txt:rcode=nxdomain|servfail,type=a)
+func parseOutputSink(value string) (*outputSink, error) {
+	sink := &outputSink{path: value}
+	// the filter is only split off when it holds a condition, so windows paths are left untouched
+	if idx := strings.LastIndex(value, ":"); idx > 0 && strings.Contains(value[idx+1:], "=") {
+		sink.path = value[:idx]
+		for _, condition := range strings.Split(value[idx+1:], ",") {
+			key, values, ok := strings.Cut(condition, "=")
+			if !ok || values == "" {
+				return nil, errors.Errorf("invalid output sink condition: %s", condition)
+			}
+			for _, item := range strings.Split(values, "|") {
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "rcode":
+					rcode, ok := dns.StringToRcode[strings.ToUpper(strings.TrimSpace(item))]
+					if !ok {
+						return nil, errors.Errorf("invalid output sink rcode: %s", item)
+					}
+					if sink.filter.rcodes == nil {
+						sink.filter.rcodes = make(map[int]struct{})
+					}
+					sink.filter.rcodes[rcode] = struct{}{}
+				case "type":
+					questionType, ok := dns.StringToType[strings.ToUpper(strings.TrimSpace(item))]
+					if !ok {
+						return nil, errors.Errorf("invalid output sink type: %s", item)
+					}
+					sink.filter.types = append(sink.filter.types, questionType)
+				default:
+					return nil, errors.Errorf("unsupported output sink filter: %s", key)
+				}
+			}
+		}
+	}
+	if sink.path == "" {
+		return nil, errors.Errorf("missing output sink path: %s", value)
+	}
+	return sink, nil
+}
+
+// match returns true if the response has one of the response codes and records of one of the types
+func (f *sinkFilter) match(dnsData *dnsx.ResponseData) bool {
+	if len(f.rcodes) > 0 {
+		if _, ok := f.rcodes[dnsData.StatusCodeRaw]; !ok {
+			return false
+		}
+	}
+	if len(f.types) > 0 {
+		for _, questionType := range f.types {
+			if dnsData.RecordCount(questionType) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// newOutputSinks opens the files of the sinks and starts the worker writing to them
+func newOutputSinks(values []string) (*outputSinks, error) {
+	s := &outputSinks{sinkchan: make(chan sinkItem)}
+	for _, value := range values {
+		sink, err := parseOutputSink(value)
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		sink.file, err = os.OpenFile(sink.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			s.close()
+			return nil, errors.Wrapf(err, "could not open output sink %s", sink.path)
+		}
+		sink.writer = bufio.NewWriter(sink.file)
+		s.sinks = append(s.sinks, sink)
+	}
+	s.wg.Add(1)
+	go s.handle()
+	return s, nil
+}
+
+// write sends the json line of the response to the sinks it matches, the response being encoded when line is
+// empty
+func (s *outputSinks) write(dnsData *dnsx.ResponseData, line string, marshalOptions ...dnsx.MarshalOption) {
+	for _, sink := range s.sinks {
+		if !sink.filter.match(dnsData) {
+			continue
+		}
+		// the response is only encoded once a sink matches
+		if line == "" {
+			var err error
+			if line, err = dnsData.JSON(marshalOptions...); err != nil {
+				gologger.Warning().Msgf("%s: could not encode output sink record: %s\n", dnsData.Host, err)
+				return
+			}
+		}
+		s.sinkchan <- sinkItem{sink: sink, line: line}
+	}
+}
+
+func (s *outputSinks) handle() {
+	defer s.wg.Done()
+
+	for item := range s.sinkchan {
+		_, _ = item.sink.writer.WriteString(item.line + "\n")
+	}
+}
+
+// close waits for the pending lines and closes the files of the sinks
+func (s *outputSinks) close() {
+	if s.sinkchan != nil {
+		close(s.sinkchan)
+		s.wg.Wait()
+		s.sinkchan = nil
+	}
+	for _, sink := range s.sinks {
+		_ = sink.writer.Flush()
+		sink.file.Close()
+	}
+}
@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	socketDialTimeout  = 5 * time.Second
+	socketWriteTimeout = 5 * time.Second
+	// the reconnections to an unreachable socket are spaced out from socketMinReconnectDelay up to
+	// socketMaxReconnectDelay, the output being dropped in between
+	socketMinReconnectDelay = time.Second
+	socketMaxReconnectDelay = 30 * time.Second
+)
+
+// socketWriter streams the output to a unix socket or tcp address. A write taking longer than the write
+// timeout drops the connection, which is dialed again with a growing delay while the socket is unreachable
+type socketWriter struct {
+	network      string
+	address      string
+	conn         net.Conn
+	writeTimeout time.Duration
+	// nextDial is the earliest time of the next reconnection, delayed by reconnectDelay
+	nextDial       time.Time
+	reconnectDelay time.Duration
+	// dropped counts the writes lost while the socket was unreachable
+	dropped uint64
+	mutex   sync.Mutex
+}
+
+// newSocketWriter parses a unix:///path or tcp://host:port address and dials it
+func newSocketWriter(value string) (*socketWriter, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid output socket")
+	}
+	sw := &socketWriter{network: u.Scheme, writeTimeout: socketWriteTimeout}
+	switch u.Scheme {
+	case "unix":
+		sw.address = u.Path
+	case "tcp":
+		sw.address = u.Host
+	default:
+		return nil, errors.Errorf("unsupported output socket scheme: %s", u.Scheme)
+	}
+	if sw.address == "" {
+		return nil, errors.Errorf("missing output socket address: %s", value)
+	}
+	if err := sw.dial(); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+func (sw *socketWriter) dial() error {
+	conn, err := net.DialTimeout(sw.network, sw.address, socketDialTimeout)
+	if err != nil {
+		return errors.Wrapf(err, "could not connect to output socket %s", sw.address)
+	}
+	sw.conn = conn
+	return nil
+}
+
+// write writes the data on the connection within the write timeout, closing the connection on failure
+func (sw *socketWriter) write(data string) error {
+	_ = sw.conn.SetWriteDeadline(time.Now().Add(sw.writeTimeout))
+	if _, err := sw.conn.Write([]byte(data)); err != nil {
+		sw.conn.Close()
+		sw.conn = nil
+		return errors.Wrapf(err, "could not write to output socket %s", sw.address)
+	}
+	return nil
+}
+
+// WriteString writes the data to the socket, reconnecting on failure unless the last reconnection failed
+// less than the reconnection delay ago
+func (sw *socketWriter) WriteString(data string) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	if sw.conn != nil {
+		err := sw.write(data)
+		if err == nil {
+			return
+		}
+		gologger.Warning().Msgf("%s\n", err)
+	}
+	if time.Now().Before(sw.nextDial) {
+		sw.dropped++
+		return
+	}
+	if err := sw.dial(); err != nil {
+		sw.reconnectDelay = min(max(2*sw.reconnectDelay, socketMinReconnectDelay), socketMaxReconnectDelay)
+		sw.nextDial = time.Now().Add(sw.reconnectDelay)
+		sw.dropped++
+		gologger.Warning().Msgf("%s, next attempt in %s\n", err, sw.reconnectDelay)
+		return
+	}
+	sw.reconnectDelay = 0
+	if err := sw.write(data); err != nil {
+		sw.dropped++
+		gologger.Warning().Msgf("%s\n", err)
+	}
+}
+
+func (sw *socketWriter) Close() {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	if sw.conn != nil {
+		sw.conn.Close()
+		sw.conn = nil
+	}
+	if sw.dropped > 0 {
+		gologger.Warning().Msgf("%d writes to output socket %s were dropped\n", sw.dropped, sw.address)
+	}
+}
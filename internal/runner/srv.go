@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	iputil "github.com/projectdiscovery/utils/ip"
+)
+
+// srvEndpoint is the standard port and transport protocol of a service published with srv records
+type srvEndpoint struct {
+	Port  int
+	Proto string
+}
+
+// srvServices maps the service names to their standard endpoints, a service being queried for each of
+// its protocols (eg. _kerberos._tcp and _kerberos._udp)
+var srvServices = map[string][]srvEndpoint{
+	"autodiscover": {{443, "tcp"}},
+	"caldav":       {{80, "tcp"}},
+	"caldavs":      {{443, "tcp"}},
+	"carddav":      {{80, "tcp"}},
+	"carddavs":     {{443, "tcp"}},
+	"ftp":          {{21, "tcp"}},
+	"gc":           {{3268, "tcp"}},
+	"http":         {{80, "tcp"}},
+	"https":        {{443, "tcp"}},
+	"imap":         {{143, "tcp"}},
+	"imaps":        {{993, "tcp"}},
+	"jabber":       {{5269, "tcp"}},
+	"kerberos":     {{88, "tcp"}, {88, "udp"}},
+	"kpasswd":      {{464, "tcp"}, {464, "udp"}},
+	"ldap":         {{389, "tcp"}},
+	"ldaps":        {{636, "tcp"}},
+	"matrix":       {{8448, "tcp"}},
+	"minecraft":    {{25565, "tcp"}},
+	"ntp":          {{123, "udp"}},
+	"pop3":         {{110, "tcp"}},
+	"pop3s":        {{995, "tcp"}},
+	"sip":          {{5060, "tcp"}, {5060, "udp"}},
+	"sips":         {{5061, "tcp"}},
+	"smtp":         {{25, "tcp"}},
+	"ssh":          {{22, "tcp"}},
+	"stun":         {{3478, "tcp"}, {3478, "udp"}},
+	"stuns":        {{5349, "tcp"}},
+	"submission":   {{587, "tcp"}},
+	"submissions":  {{465, "tcp"}},
+	"turn":         {{3478, "tcp"}, {3478, "udp"}},
+	"turns":        {{5349, "tcp"}},
+	"vlmcs":        {{1688, "tcp"}},
+	"xmpp-client":  {{5222, "tcp"}},
+	"xmpp-server":  {{5269, "tcp"}},
+}
+
+// srvName is the _service._proto prefix of a service endpoint
+type srvName struct {
+	prefix   string
+	service  string
+	endpoint srvEndpoint
+}
+
+// label returns the service with its standard endpoint (eg. ldap 389/tcp)
+func (n srvName) label() string {
+	return fmt.Sprintf("%s %d/%s", n.service, n.endpoint.Port, n.endpoint.Proto)
+}
+
+// parseSRVServiceLine parses a custom service line: the service name followed by its endpoints as
+// port/proto (eg. ldap 389/tcp or kerberos 88/tcp,88/udp)
+func parseSRVServiceLine(line string) (string, []srvEndpoint, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", nil, errors.Errorf("invalid srv service: %s", line)
+	}
+	var endpoints []srvEndpoint
+	for _, field := range fields[1:] {
+		for _, value := range strings.Split(field, ",") {
+			if value == "" {
+				continue
+			}
+			port, proto, ok := strings.Cut(value, "/")
+			portNumber, err := strconv.Atoi(port)
+			if !ok || err != nil || portNumber < 1 || portNumber > 65535 || proto == "" {
+				return "", nil, errors.Errorf("invalid srv service endpoint: %s", value)
+			}
+			endpoints = append(endpoints, srvEndpoint{Port: portNumber, Proto: strings.ToLower(proto)})
+		}
+	}
+	return strings.ToLower(fields[0]), endpoints, nil
+}
+
+// loadSRVServices returns the built-in services extended with the ones of the file, a service of the file
+// replacing the built-in one with the same name
+func loadSRVServices(filename string) (map[string][]srvEndpoint, error) {
+	services := make(map[string][]srvEndpoint, len(srvServices))
+	for service, endpoints := range srvServices {
+		services[service] = endpoints
+	}
+	if filename == "" {
+		return services, nil
+	}
+	lines, err := linesInFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read srv services")
+	}
+	for _, line := range lines {
+		if line = normalize(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		service, endpoints, err := parseSRVServiceLine(line)
+		if err != nil {
+			return nil, err
+		}
+		services[service] = endpoints
+	}
+	return services, nil
+}
+
+// srvNamesFor returns the _service._proto prefixes of the services, sorted by prefix
+func srvNamesFor(services map[string][]srvEndpoint, names []string) ([]srvName, error) {
+	var srvNames []srvName
+	seen := make(map[string]struct{})
+	for _, service := range names {
+		service = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(service), "_"))
+		endpoints, ok := services[service]
+		if !ok {
+			return nil, errors.Errorf("unknown srv service: %s", service)
+		}
+		for _, endpoint := range endpoints {
+			prefix := "_" + service + "._" + endpoint.Proto + "."
+			if _, ok := seen[prefix]; ok {
+				continue
+			}
+			seen[prefix] = struct{}{}
+			srvNames = append(srvNames, srvName{prefix: prefix, service: service, endpoint: endpoint})
+		}
+	}
+	sort.SliceStable(srvNames, func(i, j int) bool {
+		return srvNames[i].prefix < srvNames[j].prefix
+	})
+	return srvNames, nil
+}
+
+// srvHosts returns the srv names of the services for each host, ip addresses being left untouched
+func srvHosts(hosts []string, srvNames []srvName) []string {
+	if len(srvNames) == 0 {
+		return hosts
+	}
+	var names []string
+	for _, host := range hosts {
+		if isURL(host) {
+			host = extractDomain(host)
+		}
+		if host == "" || iputil.IsIP(host) {
+			names = append(names, host)
+			continue
+		}
+		for _, srvName := range srvNames {
+			names = append(names, srvName.prefix+host)
+		}
+	}
+	return names
+}
+
+// srvNameOf returns the service prefix the srv name was built with, nil for other hosts
+func (r *Runner) srvNameOf(host string) *srvName {
+	host = strings.ToLower(host)
+	for i := range r.options.srvNames {
+		if strings.HasPrefix(host, r.options.srvNames[i].prefix) {
+			return &r.options.srvNames[i]
+		}
+	}
+	return nil
+}
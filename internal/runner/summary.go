@@ -0,0 +1,447 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/mapcidr"
+	iputil "github.com/projectdiscovery/utils/ip"
+	"golang.org/x/net/publicsuffix"
+)
+
+// asnSummaryEntry holds the hosts resolved within an autonomous system
+type asnSummaryEntry struct {
+	asn   *dnsx.AsnResponse
+	hosts map[string]struct{}
+}
+
+// asnSummary groups the resolved hosts by autonomous system
+type asnSummary struct {
+	entries map[string]*asnSummaryEntry
+	mutex   sync.Mutex
+}
+
+func newAsnSummary() *asnSummary {
+	return &asnSummary{entries: make(map[string]*asnSummaryEntry)}
+}
+
+// add records the host as part of the autonomous system
+func (s *asnSummary) add(host string, asn *dnsx.AsnResponse) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.entries[asn.AsNumber]
+	if !ok {
+		entry = &asnSummaryEntry{asn: asn, hosts: make(map[string]struct{})}
+		s.entries[asn.AsNumber] = entry
+	}
+	entry.hosts[host] = struct{}{}
+}
+
+// sorted returns the autonomous systems with the most hosts first
+func (s *asnSummary) sorted() []*asnSummaryEntry {
+	entries := make([]*asnSummaryEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if len(entries[i].hosts) != len(entries[j].hosts) {
+			return len(entries[i].hosts) > len(entries[j].hosts)
+		}
+		return entries[i].asn.AsNumber < entries[j].asn.AsNumber
+	})
+	return entries
+}
+
+// print writes the number of hosts per autonomous system, largest first
+func (s *asnSummary) print() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries := s.sorted()
+	if len(entries) == 0 {
+		return
+	}
+	gologger.Print().Msgf("ASN summary (%d autonomous systems)\n", len(entries))
+	for _, entry := range entries {
+		gologger.Print().Msgf("%s: %d hosts\n", entry.asn.String(), len(entry.hosts))
+	}
+}
+
+// nsSummary groups the queried hosts by the nameservers they returned
+type nsSummary struct {
+	hosts map[string]map[string]struct{}
+	mutex sync.Mutex
+}
+
+func newNsSummary() *nsSummary {
+	return &nsSummary{hosts: make(map[string]map[string]struct{})}
+}
+
+// add records the host as served by the nameservers
+func (s *nsSummary) add(host string, nameservers []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, nameserver := range nameservers {
+		nameserver = strings.ToLower(nameserver)
+		hosts, ok := s.hosts[nameserver]
+		if !ok {
+			hosts = make(map[string]struct{})
+			s.hosts[nameserver] = hosts
+		}
+		hosts[host] = struct{}{}
+	}
+}
+
+// sorted returns the nameservers serving the most hosts first
+func (s *nsSummary) sorted() []string {
+	nameservers := make([]string, 0, len(s.hosts))
+	for nameserver := range s.hosts {
+		nameservers = append(nameservers, nameserver)
+	}
+	sort.Slice(nameservers, func(i, j int) bool {
+		if len(s.hosts[nameservers[i]]) != len(s.hosts[nameservers[j]]) {
+			return len(s.hosts[nameservers[i]]) > len(s.hosts[nameservers[j]])
+		}
+		return nameservers[i] < nameservers[j]
+	})
+	return nameservers
+}
+
+// print writes the nameservers with the number of hosts they serve, largest first, and their
+// addresses when lookup is set
+func (s *nsSummary) print(lookup func(string) ([]string, error)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	nameservers := s.sorted()
+	if len(nameservers) == 0 {
+		return
+	}
+	gologger.Print().Msgf("Nameserver summary (%d nameservers)\n", len(nameservers))
+	for _, nameserver := range nameservers {
+		var addresses string
+		if lookup != nil {
+			if ips, err := lookup(nameserver); err == nil && len(ips) > 0 {
+				addresses = fmt.Sprintf(" [%s]", strings.Join(ips, ","))
+			}
+		}
+		gologger.Print().Msgf("%s%s: %d hosts\n", nameserver, addresses, len(s.hosts[nameserver]))
+	}
+}
+
+// hashSummarySampleHosts is the number of hosts listed for each shared response hash
+const hashSummarySampleHosts = 5
+
+// hashSummary groups the hosts by the hash of their response
+type hashSummary struct {
+	hosts map[string]map[string]struct{}
+	mutex sync.Mutex
+}
+
+func newHashSummary() *hashSummary {
+	return &hashSummary{hosts: make(map[string]map[string]struct{})}
+}
+
+// add records the host as answered with the response hash
+func (s *hashSummary) add(host, hash string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hosts, ok := s.hosts[hash]
+	if !ok {
+		hosts = make(map[string]struct{})
+		s.hosts[hash] = hosts
+	}
+	hosts[host] = struct{}{}
+}
+
+// shared returns the hashes of the responses returned to several hosts, largest group first
+func (s *hashSummary) shared() []string {
+	var hashes []string
+	for hash, hosts := range s.hosts {
+		if len(hosts) > 1 {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		if len(s.hosts[hashes[i]]) != len(s.hosts[hashes[j]]) {
+			return len(s.hosts[hashes[i]]) > len(s.hosts[hashes[j]])
+		}
+		return hashes[i] < hashes[j]
+	})
+	return hashes
+}
+
+// print writes the shared response hashes with their number of hosts and the first hosts in name order
+func (s *hashSummary) print() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hashes := s.shared()
+	if len(hashes) == 0 {
+		return
+	}
+	gologger.Print().Msgf("Response hash summary (%d shared responses)\n", len(hashes))
+	for _, hash := range hashes {
+		hosts := make([]string, 0, len(s.hosts[hash]))
+		for host := range s.hosts[hash] {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		sample := strings.Join(hosts, ",")
+		if len(hosts) > hashSummarySampleHosts {
+			sample = strings.Join(hosts[:hashSummarySampleHosts], ",") + ",..."
+		}
+		gologger.Print().Msgf("%s: %d hosts [%s]\n", hash, len(hosts), sample)
+	}
+}
+
+// externalDepsSampleTargets is the number of cname targets listed for each external domain
+const externalDepsSampleTargets = 5
+
+// externalDeps collects the registrable domains of the input hosts and the cname targets of the hosts, to
+// report the targets outside the input domains, the third-party services the hosts depend on. The targets are
+// classified at the end of the run, once every input domain is known
+type externalDeps struct {
+	apexes map[string]struct{}
+	// targets maps the cname targets to the hosts pointing to them
+	targets map[string]map[string]struct{}
+	mutex   sync.Mutex
+}
+
+// externalDep is a registrable domain outside the input with the hosts pointing to it and its targets
+type externalDep struct {
+	apex    string
+	hosts   map[string]struct{}
+	targets []string
+}
+
+func newExternalDeps() *externalDeps {
+	return &externalDeps{apexes: make(map[string]struct{}), targets: make(map[string]map[string]struct{})}
+}
+
+// add records the registrable domain of the input host and the targets of its cname records
+func (e *externalDeps) add(host string, cnames []string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if apex := registeredDomain(host); apex != "" {
+		e.apexes[apex] = struct{}{}
+	}
+	for _, cname := range cnames {
+		cname = strings.ToLower(strings.TrimSuffix(cname, "."))
+		hosts, ok := e.targets[cname]
+		if !ok {
+			hosts = make(map[string]struct{})
+			e.targets[cname] = hosts
+		}
+		hosts[host] = struct{}{}
+	}
+}
+
+// external groups the targets outside the registrable domains of the input by registrable domain, the most
+// depended on first
+func (e *externalDeps) external() []*externalDep {
+	deps := make(map[string]*externalDep)
+	for target, hosts := range e.targets {
+		apex := registeredDomain(target)
+		if apex == "" {
+			continue
+		}
+		if _, ok := e.apexes[apex]; ok {
+			continue
+		}
+		dep, ok := deps[apex]
+		if !ok {
+			dep = &externalDep{apex: apex, hosts: make(map[string]struct{})}
+			deps[apex] = dep
+		}
+		dep.targets = append(dep.targets, target)
+		for host := range hosts {
+			dep.hosts[host] = struct{}{}
+		}
+	}
+	external := make([]*externalDep, 0, len(deps))
+	for _, dep := range deps {
+		sort.Strings(dep.targets)
+		external = append(external, dep)
+	}
+	sort.Slice(external, func(i, j int) bool {
+		if len(external[i].hosts) != len(external[j].hosts) {
+			return len(external[i].hosts) > len(external[j].hosts)
+		}
+		return external[i].apex < external[j].apex
+	})
+	return external
+}
+
+// registeredDomain returns the domain registered below the icann section of the public suffix list, the
+// private section listing the services handing out subdomains to their customers (eg. cloudfront.net) whose
+// targets belong to the service
+func registeredDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || iputil.IsIP(host) {
+		return ""
+	}
+	labels := strings.Split(host, ".")
+	for i := len(labels) - 1; i > 0; i-- {
+		suffix := strings.Join(labels[i-1:], ".")
+		if publicSuffix, icann := publicsuffix.PublicSuffix(suffix); publicSuffix != suffix || !icann {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// print writes the external domains with the number of hosts depending on them and their first targets
+func (e *externalDeps) print() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	external := e.external()
+	if len(external) == 0 {
+		return
+	}
+	gologger.Print().Msgf("External dependencies (%d domains)\n", len(external))
+	for _, dep := range external {
+		sample := strings.Join(dep.targets, ",")
+		if len(dep.targets) > externalDepsSampleTargets {
+			sample = strings.Join(dep.targets[:externalDepsSampleTargets], ",") + ",..."
+		}
+		gologger.Print().Msgf("%s: %d hosts, %d targets [%s]\n", dep.apex, len(dep.hosts), len(dep.targets), sample)
+	}
+}
+
+// printSizeStats writes the totals and averages of the wire sizes, followed by the response sizes per type
+func printSizeStats(sizeStats *dnsx.SizeStats) {
+	requests, responses := sizeStats.Requests(), sizeStats.Responses()
+	if requests.Count == 0 {
+		return
+	}
+	gologger.Print().Msgf("Wire size statistics\n")
+	gologger.Print().Msgf("requests: %d sent, %d bytes total, %.1f bytes avg\n", requests.Count, requests.Total, requests.Average())
+	gologger.Print().Msgf("responses: %d received, %d bytes total, %.1f bytes avg\n", responses.Count, responses.Total, responses.Average())
+
+	byType := sizeStats.ResponsesByType()
+	questionTypes := make([]uint16, 0, len(byType))
+	for questionType := range byType {
+		questionTypes = append(questionTypes, questionType)
+	}
+	sort.Slice(questionTypes, func(i, j int) bool {
+		return questionTypes[i] < questionTypes[j]
+	})
+	for _, questionType := range questionTypes {
+		size := byType[questionType]
+		gologger.Print().Msgf("%s: %d responses, min %d, max %d, avg %.1f bytes\n", dns.TypeToString[questionType], size.Count, size.Min, size.Max, size.Average())
+	}
+}
+
+// cidrCollapser accumulates the resolved addresses to output them as covering cidr blocks
+type cidrCollapser struct {
+	ips   map[string]struct{}
+	mutex sync.Mutex
+}
+
+func newCidrCollapser() *cidrCollapser {
+	return &cidrCollapser{ips: make(map[string]struct{})}
+}
+
+// add records the resolved addresses
+func (c *cidrCollapser) add(ips ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, ip := range ips {
+		c.ips[ip] = struct{}{}
+	}
+}
+
+// collapse returns the ipv4 and ipv6 blocks covering the addresses, exactly (only merging adjacent
+// blocks) or approximately (the smallest block covering all the addresses of each family)
+func (c *cidrCollapser) collapse(approx bool) (ipv4, ipv6 []*net.IPNet) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var networks []*net.IPNet
+	for ip := range c.ips {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			continue
+		}
+		if parsed4 := parsed.To4(); parsed4 != nil {
+			networks = append(networks, &net.IPNet{IP: parsed4, Mask: net.CIDRMask(32, 32)})
+		} else {
+			networks = append(networks, &net.IPNet{IP: parsed, Mask: net.CIDRMask(128, 128)})
+		}
+	}
+	ipv4, ipv6 = mapcidr.CoalesceCIDRs(networks)
+	if approx {
+		ipv4, ipv6 = aggregateApprox(ipv4), aggregateApprox(ipv6)
+	}
+	return ipv4, ipv6
+}
+
+// aggregateApprox merges the blocks of a single family into the smallest block covering them
+func aggregateApprox(networks []*net.IPNet) []*net.IPNet {
+	if len(networks) < 2 {
+		return networks
+	}
+	var first, last net.IP
+	for _, network := range networks {
+		networkFirst, networkLast, err := mapcidr.AddressRange(network)
+		if err != nil {
+			return networks
+		}
+		networkFirst, networkLast = normalizeIPLength(networkFirst), normalizeIPLength(networkLast)
+		if first == nil || bytes.Compare(networkFirst, first) < 0 {
+			first = networkFirst
+		}
+		if last == nil || bytes.Compare(networkLast, last) > 0 {
+			last = networkLast
+		}
+	}
+	bits := len(first) * 8
+	prefixLength := 0
+	for prefixLength < bits && first[prefixLength/8]&(0x80>>(prefixLength%8)) == last[prefixLength/8]&(0x80>>(prefixLength%8)) {
+		prefixLength++
+	}
+	mask := net.CIDRMask(prefixLength, bits)
+	return []*net.IPNet{{IP: first.Mask(mask), Mask: mask}}
+}
+
+// normalizeIPLength returns ipv4 addresses in their 4 bytes form
+func normalizeIPLength(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// printResolverStats writes a line per resolver with the queries it handled, the records it returned and its
+// failed responses
+func printResolverStats(resolverStats *dnsx.ResolverStats) {
+	counts := resolverStats.Resolvers()
+	if len(counts) == 0 {
+		return
+	}
+	width := len("resolver")
+	for _, count := range counts {
+		if len(count.Resolver) > width {
+			width = len(count.Resolver)
+		}
+	}
+	gologger.Print().Msgf("Resolver statistics (%d resolvers)\n", len(counts))
+	gologger.Print().Msgf("%-*s %10s %10s %10s %10s\n", width, "resolver", "queries", "responses", "answers", "failures")
+	for _, count := range counts {
+		gologger.Print().Msgf("%-*s %10d %10d %10d %10d\n", width, count.Resolver, count.Queries, count.Responses, count.Answers, count.Failures)
+	}
+}
@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"gopkg.in/yaml.v3"
+)
+
+// targetGroupConfig are the query settings applied to the targets matching one of the patterns
+type targetGroupConfig struct {
+	Name      string   `yaml:"name"`
+	Match     []string `yaml:"match"`
+	Types     []string `yaml:"types"`
+	Resolvers []string `yaml:"resolvers"`
+	Recursion *bool    `yaml:"recursion"`
+}
+
+// targetGroup is a target group with its query profile
+type targetGroup struct {
+	name     string
+	patterns []string
+	profile  *dnsx.QueryProfile
+}
+
+// targetConfig maps the targets to the query settings of the first group they match, the settings not
+// set by the group being the ones of the command line
+type targetConfig struct {
+	groups []*targetGroup
+}
+
+// loadTargetConfig reads the yaml (or json) target config file
+func loadTargetConfig(filename string, dnsX *dnsx.DNSX) (*targetConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Groups []targetGroupConfig `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, errors.Wrap(err, "invalid target config")
+	}
+
+	targets := &targetConfig{}
+	for i, groupConfig := range config.Groups {
+		name := groupConfig.Name
+		if name == "" {
+			name = "#" + strconv.Itoa(i+1)
+		}
+		if len(groupConfig.Match) == 0 {
+			return nil, errors.Errorf("target group %s has no match pattern", name)
+		}
+		group := &targetGroup{name: name}
+		for _, pattern := range groupConfig.Match {
+			pattern = strings.ToLower(strings.TrimSpace(pattern))
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, errors.Errorf("invalid pattern %s in target group %s", pattern, name)
+			}
+			group.patterns = append(group.patterns, pattern)
+		}
+		var questionTypes []uint16
+		for _, typeName := range groupConfig.Types {
+			questionType, ok := dns.StringToType[strings.ToUpper(strings.TrimSpace(typeName))]
+			if !ok {
+				return nil, errors.Errorf("invalid type %s in target group %s", typeName, name)
+			}
+			questionTypes = append(questionTypes, questionType)
+		}
+		var resolvers []string
+		for _, resolver := range groupConfig.Resolvers {
+			resolvers = append(resolvers, prepareResolver(resolver))
+		}
+		noRecursion := groupConfig.Recursion != nil && !*groupConfig.Recursion
+		group.profile, err = dnsX.NewQueryProfile(questionTypes, resolvers, noRecursion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid resolvers in target group %s", name)
+		}
+		targets.groups = append(targets.groups, group)
+	}
+	return targets, nil
+}
+
+// match returns the first group matching the host
+func (c *targetConfig) match(host string) *targetGroup {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, group := range c.groups {
+		for _, pattern := range group.patterns {
+			if matched, _ := path.Match(pattern, host); matched {
+				return group
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	iputil "github.com/projectdiscovery/utils/ip"
+)
+
+// tlsaDefaultPorts are the ports of the services commonly published with DANE, expanded from the default
+// keyword of -tlsa-ports
+var tlsaDefaultPorts = []srvEndpoint{
+	{25, "tcp"},
+	{110, "tcp"},
+	{143, "tcp"},
+	{443, "tcp"},
+	{465, "tcp"},
+	{587, "tcp"},
+	{853, "tcp"},
+	{993, "tcp"},
+	{995, "tcp"},
+	{5222, "tcp"},
+	{5269, "tcp"},
+}
+
+// tlsaPrefix is the _port._proto prefix of the TLSA records of an endpoint (RFC 6698)
+func tlsaPrefix(endpoint srvEndpoint) string {
+	return fmt.Sprintf("_%d._%s.", endpoint.Port, endpoint.Proto)
+}
+
+// parseTLSAPorts parses the ports as port[/proto], the protocol defaulting to tcp, the default keyword
+// standing for the common DANE ports
+func parseTLSAPorts(values []string) ([]srvEndpoint, error) {
+	var endpoints []srvEndpoint
+	seen := make(map[srvEndpoint]struct{})
+	add := func(endpoint srvEndpoint) {
+		if _, ok := seen[endpoint]; !ok {
+			seen[endpoint] = struct{}{}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		if value == "default" {
+			for _, endpoint := range tlsaDefaultPorts {
+				add(endpoint)
+			}
+			continue
+		}
+		port, proto, ok := strings.Cut(value, "/")
+		if !ok {
+			proto = "tcp"
+		}
+		portNumber, err := strconv.Atoi(port)
+		if err != nil || portNumber < 1 || portNumber > 65535 {
+			return nil, errors.Errorf("invalid tlsa port: %s", value)
+		}
+		if proto != "tcp" && proto != "udp" && proto != "sctp" {
+			return nil, errors.Errorf("invalid tlsa port protocol: %s", value)
+		}
+		add(srvEndpoint{Port: portNumber, Proto: proto})
+	}
+	return endpoints, nil
+}
+
+// tlsaHosts returns the _port._proto names of the ports for each host, ip addresses being left untouched
+func tlsaHosts(hosts []string, ports []srvEndpoint) []string {
+	if len(ports) == 0 {
+		return hosts
+	}
+	var names []string
+	for _, host := range hosts {
+		if isURL(host) {
+			host = extractDomain(host)
+		}
+		if host == "" || iputil.IsIP(host) {
+			names = append(names, host)
+			continue
+		}
+		for _, port := range ports {
+			names = append(names, tlsaPrefix(port)+host)
+		}
+	}
+	return names
+}
+
+// tlsaPortOf returns the port/proto label of the port the TLSA name was built with, empty for other hosts
+func (r *Runner) tlsaPortOf(host string) string {
+	host = strings.ToLower(host)
+	for _, port := range r.options.tlsaPorts {
+		if strings.HasPrefix(host, tlsaPrefix(port)) {
+			return fmt.Sprintf("%d/%s", port.Port, port.Proto)
+		}
+	}
+	return ""
+}
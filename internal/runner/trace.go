@@ -0,0 +1,33 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+)
+
+// DefaultTraceThreads is the default number of concurrent traces
+const DefaultTraceThreads = 10
+
+// addTrace traces the delegations of the host into the response
+func (r *Runner) addTrace(domain string, dnsData *dnsx.ResponseData) {
+	chain, _ := r.dnsx.TraceChainContext(r.ctx, domain)
+	if chain == nil {
+		return
+	}
+	dnsData.TraceData, dnsData.TraceGlueless = chain.TraceData, chain.Glueless
+	for _, data := range dnsData.TraceData.DNSData {
+		if r.options.Raw && data.RawResp != nil {
+			rawRespString := data.RawResp.String()
+			data.Raw = rawRespString
+			// join the whole chain in raw field
+			dnsData.Raw += fmt.Sprintln(rawRespString)
+		}
+		data.RawResp = nil
+	}
+	if r.options.Raw {
+		for _, glueless := range dnsData.TraceGlueless {
+			dnsData.Raw += fmt.Sprintf(";; glueless delegation, nameservers resolved separately: %s\n", glueless)
+		}
+	}
+}
@@ -2,17 +2,23 @@ package runner
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
 	fileutil "github.com/projectdiscovery/utils/file"
+	iputil "github.com/projectdiscovery/utils/ip"
+	sliceutil "github.com/projectdiscovery/utils/slice"
 )
 
 const (
-	stdinMarker = "-"
-	Comma       = ","
-	NewLine     = "\n"
+	stdinMarker       = "-"
+	Comma             = ","
+	NewLine           = "\n"
+	resolverSeparator = "@"
 )
 
 func linesInFile(fileName string) ([]string, error) {
@@ -53,12 +59,82 @@ func extractDomain(URL string) string {
 
 func prepareResolver(resolver string) string {
 	resolver = strings.TrimSpace(resolver)
+	// doh urls are given as is or with the doh: prefix of retryabledns
+	if strings.HasPrefix(strings.ToLower(resolver), "https://") {
+		return "doh:" + resolver
+	}
+	// dot resolvers are given with the tls:// scheme or on the dedicated port, the port defaulting to 853
+	if strings.HasPrefix(strings.ToLower(resolver), "tls://") {
+		resolver = resolver[len("tls://"):]
+		if _, _, err := net.SplitHostPort(resolver); err != nil {
+			resolver = net.JoinHostPort(resolver, "853")
+		}
+		return "dot:" + resolver
+	}
+	if _, port, err := net.SplitHostPort(resolver); err == nil && port == "853" {
+		return "dot:" + resolver
+	}
+	// doq resolvers default to the dedicated port
+	if dnsx.IsDoQResolver(resolver) {
+		if _, _, err := net.SplitHostPort(resolver[len("quic://"):]); err != nil {
+			resolver += ":853"
+		}
+		return resolver
+	}
 	if !strings.Contains(resolver, ":") {
 		resolver += ":53"
 	}
 	return resolver
 }
 
+// splitTargetResolver splits a target in the host@resolver form into the host and the resolver override. The
+// separator is the first @ ending the host, the resolver being free to carry userinfo (https://user@host/dns-query)
+// and an url target to carry its own
+func splitTargetResolver(target string) (string, string) {
+	for offset := 0; ; {
+		idx := strings.Index(target[offset:], resolverSeparator)
+		if idx < 0 {
+			return target, ""
+		}
+		idx += offset
+		if idx > 0 && !inURLUserinfo(target[:idx]) {
+			return target[:idx], strings.TrimSpace(target[idx+1:])
+		}
+		offset = idx + 1
+	}
+}
+
+// inURLUserinfo reports whether the prefix ends inside the userinfo of an url, before its host
+func inURLUserinfo(prefix string) bool {
+	idx := strings.Index(prefix, "://")
+	return idx >= 0 && !strings.ContainsAny(prefix[idx+3:], "/?#")
+}
+
+// joinTargetResolver attaches the resolver override to the host
+func joinTargetResolver(host, resolver string) string {
+	if resolver == "" {
+		return host
+	}
+	return host + resolverSeparator + resolver
+}
+
+// prioritizeQuestionTypes moves the listed query types first in the given order, the others keep their order
+func prioritizeQuestionTypes(questionTypes []uint16, priority []string) []uint16 {
+	prioritized := make([]uint16, 0, len(questionTypes))
+	for _, name := range priority {
+		questionType, ok := dns.StringToType[strings.ToUpper(name)]
+		if ok && sliceutil.Contains(questionTypes, questionType) && !sliceutil.Contains(prioritized, questionType) {
+			prioritized = append(prioritized, questionType)
+		}
+	}
+	for _, questionType := range questionTypes {
+		if !sliceutil.Contains(prioritized, questionType) {
+			prioritized = append(prioritized, questionType)
+		}
+	}
+	return prioritized
+}
+
 func fmtDuration(d time.Duration) string {
 	d = d.Round(time.Second)
 	h := d / time.Hour
@@ -68,3 +144,68 @@ func fmtDuration(d time.Duration) string {
 	s := d / time.Second
 	return fmt.Sprintf("%d:%02d:%02d", h, m, s)
 }
+
+// affixHosts returns the hosts with every combination of the prefixes and suffixes applied, urls being
+// reduced to their host name first and ip addresses left untouched
+func affixHosts(hosts, prefixes, suffixes []string) []string {
+	if len(prefixes) == 0 && len(suffixes) == 0 {
+		return hosts
+	}
+	if len(prefixes) == 0 {
+		prefixes = []string{""}
+	}
+	if len(suffixes) == 0 {
+		suffixes = []string{""}
+	}
+	var affixed []string
+	for _, host := range hosts {
+		if isURL(host) {
+			host = extractDomain(host)
+		}
+		if host == "" || iputil.IsIP(host) {
+			affixed = append(affixed, host)
+			continue
+		}
+		for _, prefix := range prefixes {
+			for _, suffix := range suffixes {
+				affixed = append(affixed, prefix+host+suffix)
+			}
+		}
+	}
+	return affixed
+}
+
+// pairWWW returns the hosts followed by their www/apex counterpart: www.host for a registrable domain
+// and the registrable domain for its www host, other hosts and ip addresses being left unpaired
+func pairWWW(hosts []string) []string {
+	paired := make([]string, 0, len(hosts)*2)
+	paired = append(paired, hosts...)
+	seen := make(map[string]struct{}, len(hosts))
+	for _, host := range hosts {
+		seen[strings.ToLower(host)] = struct{}{}
+	}
+	for _, host := range hosts {
+		if isURL(host) {
+			host = extractDomain(host)
+		}
+		hierarchy := dnsx.ParseHierarchy(host)
+		if hierarchy == nil {
+			continue
+		}
+		var counterpart string
+		switch {
+		case hierarchy.Depth == 0:
+			counterpart = "www." + hierarchy.Apex
+		case hierarchy.Depth == 1 && strings.HasPrefix(strings.ToLower(host), "www."):
+			counterpart = hierarchy.Apex
+		default:
+			continue
+		}
+		if _, ok := seen[counterpart]; ok {
+			continue
+		}
+		seen[counterpart] = struct{}{}
+		paired = append(paired, counterpart)
+	}
+	return paired
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/hmap/store/hybrid"
+	"github.com/projectdiscovery/ratelimit"
+)
+
+const (
+	// DefaultWhoisRateLimit is the default number of whois queries per minute
+	DefaultWhoisRateLimit = 30
+	// whoisConcurrency is the number of whois lookups running at the same time
+	whoisConcurrency = 2
+	// whoisTimeout is the timeout of each whois query
+	whoisTimeout = 10 * time.Second
+)
+
+// whoisLookup looks up the registration of the resolved ips, once per ip. The whois servers ban
+// aggressive clients, so the queries are rate limited, the lookups run a few at a time and are cached (failures
+// included)
+type whoisLookup struct {
+	ctx    context.Context
+	client *dnsx.WhoisClient
+	slots  chan struct{}
+	cache  *hybrid.HybridMap
+
+	mutex   sync.Mutex
+	pending map[string]chan struct{}
+}
+
+func newWhoisLookup(ctx context.Context, rateLimit int) (*whoisLookup, error) {
+	cache, err := hybrid.New(hybrid.DefaultDiskOptions)
+	if err != nil {
+		return nil, err
+	}
+	client := dnsx.NewWhoisClient(whoisTimeout)
+	// each query is rate limited, the first lookup in an iana block querying the iana server and the registry
+	limiter := ratelimit.New(ctx, uint(rateLimit), time.Minute)
+	client.Throttle = func() error {
+		limiter.Take()
+		// the limiter no longer throttles once the run is canceled
+		return ctx.Err()
+	}
+	return &whoisLookup{
+		ctx:     ctx,
+		client:  client,
+		slots:   make(chan struct{}, whoisConcurrency),
+		cache:   cache,
+		pending: make(map[string]chan struct{}),
+	}, nil
+}
+
+// lookup returns the registration of the ip, nil if it could not be retrieved
+func (w *whoisLookup) lookup(ip string) *dnsx.WhoisResponse {
+	for {
+		if whois, ok := w.cached(ip); ok {
+			return whois
+		}
+		// concurrent lookups of the same ip wait for the first one
+		w.mutex.Lock()
+		done, ok := w.pending[ip]
+		if !ok {
+			done = make(chan struct{})
+			w.pending[ip] = done
+			w.mutex.Unlock()
+			break
+		}
+		w.mutex.Unlock()
+		<-done
+	}
+	defer func() {
+		w.mutex.Lock()
+		close(w.pending[ip])
+		delete(w.pending, ip)
+		w.mutex.Unlock()
+	}()
+
+	w.slots <- struct{}{}
+	whois, err := w.client.Lookup(ip)
+	<-w.slots
+	// the lookups aborted by the cancellation are not cached
+	if w.ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		gologger.Verbose().Msgf("%s: whois lookup failed: %s\n", ip, err)
+	}
+	// failed lookups are cached as empty, the servers are not asked again
+	data, _ := json.Marshal(whois)
+	_ = w.cache.Set(ip, data)
+	return whois
+}
+
+// cached returns the cached registration of the ip and whether the ip was looked up already
+func (w *whoisLookup) cached(ip string) (*dnsx.WhoisResponse, bool) {
+	data, ok := w.cache.Get(ip)
+	if !ok {
+		return nil, false
+	}
+	var whois *dnsx.WhoisResponse
+	_ = json.Unmarshal(data, &whois)
+	return whois, true
+}
+
+func (w *whoisLookup) close() {
+	w.cache.Close()
+}
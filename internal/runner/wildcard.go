@@ -1,17 +1,85 @@
 package runner
 
 import (
+	"encoding/json"
 	"strings"
+	"time"
 
+	"github.com/projectdiscovery/hmap/store/hybrid"
 	"github.com/rs/xid"
 )
 
+// wildcardEntry is the answer to the random probe of a parent domain, as kept in the wildcard cache
+type wildcardEntry struct {
+	A        []string  `json:"a"`
+	ProbedAt time.Time `json:"probed_at"`
+}
+
+// wildcardCache keeps the wildcard probe results of the parent domains, in memory or in a directory that
+// outlives the run so that the next runs on the same domains skip the probes
+type wildcardCache struct {
+	entries *hybrid.HybridMap
+	// notBefore is the oldest probe still used, the entries probed earlier being probed again
+	notBefore time.Time
+}
+
+// newWildcardCache opens the cache, persisted in dir when set. The entries older than ttl (when not zero) or
+// all the entries of the previous runs with reprobe are ignored
+func newWildcardCache(dir string, ttl time.Duration, reprobe bool) (*wildcardCache, error) {
+	options := hybrid.DefaultMemoryOptions
+	if dir != "" {
+		options = hybrid.DefaultDiskOptions
+		options.Path = dir
+		options.Cleanup = false
+	}
+	entries, err := hybrid.New(options)
+	if err != nil {
+		return nil, err
+	}
+	cache := &wildcardCache{entries: entries}
+	switch {
+	case reprobe:
+		cache.notBefore = time.Now()
+	case ttl > 0:
+		cache.notBefore = time.Now().Add(-ttl)
+	}
+	return cache, nil
+}
+
+// get returns the probe answer of the parent domain, if probed recently enough
+func (c *wildcardCache) get(host string) ([]string, bool) {
+	data, ok := c.entries.Get(host)
+	if !ok {
+		return nil, false
+	}
+	var entry wildcardEntry
+	if json.Unmarshal(data, &entry) != nil || entry.ProbedAt.Before(c.notBefore) {
+		return nil, false
+	}
+	return entry.A, true
+}
+
+func (c *wildcardCache) set(host string, a []string) {
+	data, _ := json.Marshal(wildcardEntry{A: a, ProbedAt: time.Now()})
+	_ = c.entries.Set(host, data)
+}
+
+func (c *wildcardCache) close() {
+	c.entries.Close()
+}
+
 // IsWildcard checks if a host is wildcard
 func (r *Runner) IsWildcard(host string) bool {
 	orig := make(map[string]struct{})
 	wildcards := make(map[string]struct{})
 
-	in, err := r.dnsx.QueryOne(host)
+	// the probes can be sent over tcp to avoid the udp rate limits of the authoritative servers
+	query := r.dnsx.QueryOne
+	if r.options.WildcardTCP {
+		query = r.dnsx.QueryOneTCP
+	}
+
+	in, err := query(host)
 	if err != nil || in == nil {
 		return false
 	}
@@ -38,18 +106,14 @@ func (r *Runner) IsWildcard(host string) bool {
 
 	// Iterate over all the hosts generated for rand.
 	for _, h := range hosts {
-		r.wildcardscachemutex.Lock()
-		listip, ok := r.wildcardscache[h]
-		r.wildcardscachemutex.Unlock()
+		listip, ok := r.wildcardProbeCache.get(h)
 		if !ok {
-			in, err := r.dnsx.QueryOne(xid.New().String() + "." + h)
+			in, err := query(xid.New().String() + "." + h)
 			if err != nil || in == nil {
 				continue
 			}
 			listip = in.A
-			r.wildcardscachemutex.Lock()
-			r.wildcardscache[h] = in.A
-			r.wildcardscachemutex.Unlock()
+			r.wildcardProbeCache.set(h, in.A)
 		}
 
 		// Get all the records and add them to the wildcard map
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+)
+
+// labels of the resolved subdomains extracted as words
+const (
+	extractWordsLeftmost = "leftmost"
+	extractWordsAll      = "all"
+)
+
+// wordExtractor collects the unique labels of the resolved subdomains to output them as a wordlist
+type wordExtractor struct {
+	all   bool
+	words map[string]struct{}
+	mutex sync.Mutex
+}
+
+func newWordExtractor(mode string) *wordExtractor {
+	return &wordExtractor{all: mode == extractWordsAll, words: make(map[string]struct{})}
+}
+
+// add records the leftmost label of the host, or all its labels below the registrable domain
+func (e *wordExtractor) add(host string) {
+	hierarchy := dnsx.ParseHierarchy(host)
+	if hierarchy == nil || hierarchy.Depth == 0 {
+		return
+	}
+	subdomain := strings.TrimSuffix(strings.ToLower(strings.TrimSuffix(host, ".")), "."+hierarchy.Apex)
+	labels := strings.Split(subdomain, ".")
+	if !e.all {
+		labels = labels[:1]
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, label := range labels {
+		if label == "" || label == "*" {
+			continue
+		}
+		e.words[label] = struct{}{}
+	}
+}
+
+// sorted returns the words in alphabetical order
+func (e *wordExtractor) sorted() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	words := make([]string, 0, len(e.words))
+	for word := range e.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// outputWords writes the extracted words, one per line
+func (r *Runner) outputWords() {
+	r.startOutputWorker()
+	for _, word := range r.wordExtractor.sorted() {
+		r.outputchan <- word
+	}
+	close(r.outputchan)
+	r.wgoutputworker.Wait()
+}
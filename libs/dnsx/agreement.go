@@ -0,0 +1,67 @@
+package dnsx
+
+import (
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+)
+
+// KeepAgreedRecords removes the records not returned by other, which must come from a different resolver
+func (d *ResponseData) KeepAgreedRecords(other *retryabledns.DNSData) {
+	if d.DNSData == nil {
+		return
+	}
+	if other == nil {
+		other = &retryabledns.DNSData{}
+	}
+	d.A = intersect(d.A, other.A, nil)
+	d.AAAA = intersect(d.AAAA, other.AAAA, nil)
+	d.CNAME = intersect(d.CNAME, other.CNAME, nil)
+	d.MX = intersect(d.MX, other.MX, nil)
+	d.PTR = intersect(d.PTR, other.PTR, nil)
+	d.NS = intersect(d.NS, other.NS, nil)
+	d.TXT = intersect(d.TXT, other.TXT, nil)
+	d.SRV = intersect(d.SRV, other.SRV, nil)
+	d.CAA = intersect(d.CAA, other.CAA, nil)
+	// ttls naturally differ across resolvers
+	d.AllRecords = intersect(d.AllRecords, other.AllRecords, withoutTTL)
+
+	seenSOA := make(map[string]struct{}, len(other.SOA))
+	for _, soa := range other.SOA {
+		seenSOA[soa.NS+soa.Mbox] = struct{}{}
+	}
+	var soas []retryabledns.SOA
+	for _, soa := range d.SOA {
+		if _, ok := seenSOA[soa.NS+soa.Mbox]; ok {
+			soas = append(soas, soa)
+		}
+	}
+	d.SOA = soas
+	d.Resolver = append(d.Resolver, other.Resolver...)
+}
+
+// intersect returns the items of a also present in b, comparing the keys returned by key if set
+func intersect(a, b []string, key func(string) string) []string {
+	if key == nil {
+		key = func(s string) string { return s }
+	}
+	seen := make(map[string]struct{}, len(b))
+	for _, item := range b {
+		seen[key(item)] = struct{}{}
+	}
+	var result []string
+	for _, item := range a {
+		if _, ok := seen[key(item)]; ok {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func withoutTTL(record string) string {
+	rr, err := miekgdns.NewRR(record)
+	if err != nil || rr == nil {
+		return record
+	}
+	rr.Header().Ttl = 0
+	return rr.String()
+}
@@ -0,0 +1,29 @@
+package dnsx
+
+import (
+	"testing"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeepAgreedRecords(t *testing.T) {
+	data := &ResponseData{DNSData: &retryabledns.DNSData{
+		A:          []string{"192.0.2.1", "192.0.2.2"},
+		AllRecords: []string{"example.com.\t60\tIN\tA\t192.0.2.1", "example.com.\t60\tIN\tA\t192.0.2.2"},
+		Resolver:   []string{"1.1.1.1:53"},
+	}}
+	other := &retryabledns.DNSData{
+		A:          []string{"192.0.2.1"},
+		AllRecords: []string{"example.com.\t300\tIN\tA\t192.0.2.1"},
+		Resolver:   []string{"8.8.8.8:53"},
+	}
+	data.KeepAgreedRecords(other)
+	require.Equal(t, []string{"192.0.2.1"}, data.A, "could not keep the agreed records")
+	require.Equal(t, []string{"example.com.\t60\tIN\tA\t192.0.2.1"}, data.AllRecords, "ttl compared")
+	require.Equal(t, []string{"1.1.1.1:53", "8.8.8.8:53"}, data.Resolver, "could not add the agreeing resolver")
+
+	// without a second response nothing is agreed
+	data.KeepAgreedRecords(nil)
+	require.Empty(t, data.A, "records kept without agreement")
+}
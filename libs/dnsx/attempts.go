@@ -0,0 +1,57 @@
+package dnsx
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// AttemptCounter counts the attempts sent by the queries run with its context, the ones failing at the
+// transport level included
+type AttemptCounter struct {
+	attempts  atomic.Int64
+	questions atomic.Int64
+}
+
+type attemptCounterKey struct{}
+
+// NewAttemptCounter creates an attempt counter
+func NewAttemptCounter() *AttemptCounter {
+	return &AttemptCounter{}
+}
+
+// Context returns a copy of ctx whose queries are counted
+func (c *AttemptCounter) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attemptCounterKey{}, c)
+}
+
+// Attempts returns the number of attempts sent
+func (c *AttemptCounter) Attempts() int {
+	return int(c.attempts.Load())
+}
+
+// Retries returns the number of attempts beyond the first one of each question
+func (c *AttemptCounter) Retries() int {
+	retries := c.attempts.Load() - c.questions.Load()
+	if retries < 0 {
+		return 0
+	}
+	return int(retries)
+}
+
+// attemptCounterFrom returns the counter of the context, nil when its queries are not counted
+func attemptCounterFrom(ctx context.Context) *AttemptCounter {
+	counter, _ := ctx.Value(attemptCounterKey{}).(*AttemptCounter)
+	return counter
+}
+
+func (c *AttemptCounter) addQuestion() {
+	if c != nil {
+		c.questions.Add(1)
+	}
+}
+
+func (c *AttemptCounter) addAttempt() {
+	if c != nil {
+		c.attempts.Add(1)
+	}
+}
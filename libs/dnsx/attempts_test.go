@@ -0,0 +1,61 @@
+package dnsx
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttemptCounter(t *testing.T) {
+	// the server drops the first two questions, the timeouts being counted as retries
+	var questions atomic.Int32
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		if questions.Add(1) <= 2 {
+			return
+		}
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		// the nodata responses carry the soa of the zone, the empty ones being retried
+		soa, _ := miekgdns.NewRR("example.com. 60 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 60")
+		m.Ns = append(m.Ns, soa)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 3
+	options.Timeout = 100 * time.Millisecond
+	options.QuestionTypes = []uint16{miekgdns.TypeA, miekgdns.TypeAAAA}
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	counter := NewAttemptCounter()
+	data, err := dnsX.QueryMultipleContext(counter.Context(context.Background()), "example.com")
+	require.Nil(t, err, "could not query")
+	require.Len(t, data.Resolver, 1, "could not match resolvers")
+	require.Equal(t, 4, counter.Attempts(), "could not match attempts")
+	require.Equal(t, 2, counter.Retries(), "could not match retries")
+
+	// the queries without the context are not counted
+	_, err = dnsX.QueryMultiple("example.org")
+	require.Nil(t, err, "could not query")
+	require.Equal(t, 4, counter.Attempts(), "query counted without the context")
+
+	// the additional queries are counted as well
+	msg := &miekgdns.Msg{}
+	msg.SetQuestion("example.com.", miekgdns.TypeTXT)
+	_, err = dnsX.client().do(counter.Context(context.Background()), msg, nil)
+	require.Nil(t, err, "could not query")
+	require.Equal(t, 5, counter.Attempts(), "could not match attempts")
+	require.Equal(t, 2, counter.Retries(), "could not match retries")
+}
@@ -0,0 +1,178 @@
+package dnsx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	miekgdns "github.com/miekg/dns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+	"golang.org/x/net/publicsuffix"
+)
+
+// AuthoritativeZone is a zone with the addresses of its authoritative servers
+type AuthoritativeZone struct {
+	Name    string
+	Servers []string
+	profile *QueryProfile
+}
+
+// Status of the authoritative comparisons
+const (
+	AuthStatusMatch = "match"
+	AuthStatusDiff  = "diff"
+	// AuthStatusUnknown is the status of the hosts whose zone has no authoritative servers found
+	AuthStatusUnknown = "unknown"
+	// AuthStatusError is the status of the hosts whose authoritative servers could not be found or queried
+	AuthStatusError = "error"
+)
+
+var errNoAuthoritativeResponse = errors.New("no response from the authoritative servers")
+
+// AuthoritativeComparison holds the records of a host returned by the recursive resolvers and by the
+// authoritative servers of its zone
+type AuthoritativeComparison struct {
+	Status            string   `json:"status" csv:"status"`
+	Error             string   `json:"error,omitempty" csv:"error"`
+	Zone              string   `json:"zone,omitempty" csv:"zone"`
+	Servers           []string `json:"servers,omitempty" csv:"servers"`
+	Recursive         []string `json:"recursive,omitempty" csv:"recursive"`
+	Authoritative     []string `json:"authoritative,omitempty" csv:"authoritative"`
+	RecursiveOnly     []string `json:"recursive_only,omitempty" csv:"recursive_only"`
+	AuthoritativeOnly []string `json:"authoritative_only,omitempty" csv:"authoritative_only"`
+}
+
+// Differs returns true if the recursive and the authoritative records differ
+func (c *AuthoritativeComparison) Differs() bool {
+	return len(c.RecursiveOnly) > 0 || len(c.AuthoritativeOnly) > 0
+}
+
+// AuthoritativeServers finds the authoritative servers of the zones through the recursive resolvers
+// and caches them, the hosts of a zone sharing its servers
+type AuthoritativeServers struct {
+	// Port is the port the authoritative servers are queried on
+	Port string
+
+	dnsx  *DNSX
+	mutex sync.Mutex
+	// zones maps the names looked up to their zone, nil for the names that are not a zone apex
+	zones map[string]*AuthoritativeZone
+}
+
+// NewAuthoritativeServers returns an empty cache of authoritative servers
+func (d *DNSX) NewAuthoritativeServers() *AuthoritativeServers {
+	return &AuthoritativeServers{Port: "53", dnsx: d, zones: make(map[string]*AuthoritativeZone)}
+}
+
+// Find returns the closest enclosing zone of the host having authoritative servers, nil if none was found.
+// The public suffixes are never considered, their servers only return referrals
+func (a *AuthoritativeServers) Find(hostname string) (*AuthoritativeZone, error) {
+	labels := miekgdns.SplitDomainName(normalizeName(hostname))
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+		if suffix, _ := publicsuffix.PublicSuffix(name); suffix == name {
+			break
+		}
+		a.mutex.Lock()
+		zone, ok := a.zones[name]
+		a.mutex.Unlock()
+		if !ok {
+			var err error
+			if zone, err = a.lookup(name); err != nil {
+				return nil, err
+			}
+			a.mutex.Lock()
+			a.zones[name] = zone
+			a.mutex.Unlock()
+		}
+		if zone != nil {
+			return zone, nil
+		}
+	}
+	return nil, nil
+}
+
+// lookup returns the zone if the name owns ns records, with the addresses of the nameservers
+func (a *AuthoritativeServers) lookup(name string) (*AuthoritativeZone, error) {
+	data, err := a.dnsx.client().Query(name, miekgdns.TypeNS)
+	if data == nil || data.RawResp == nil {
+		return nil, err
+	}
+	var nameservers []string
+	for _, record := range data.RawResp.Answer {
+		if ns, ok := record.(*miekgdns.NS); ok && normalizeName(ns.Hdr.Name) == name {
+			nameservers = append(nameservers, ns.Ns)
+		}
+	}
+	if len(nameservers) == 0 {
+		return nil, nil
+	}
+	var servers []string
+	for _, nameserver := range nameservers {
+		ips, _ := a.dnsx.Lookup(trimDot(nameserver))
+		for _, ip := range ips {
+			servers = append(servers, net.JoinHostPort(ip, a.Port))
+		}
+	}
+	servers = sliceutil.Dedupe(servers)
+	if len(servers) == 0 {
+		return nil, nil
+	}
+	profile, err := a.dnsx.NewQueryProfile(nil, servers, true)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthoritativeZone{Name: name, Servers: servers, profile: profile}, nil
+}
+
+// CompareAuthoritative queries the host on the authoritative servers of the zone, without recursion,
+// and compares the records of the question types with the ones returned by the recursive resolvers. The
+// comparison of a nil zone is unknown, and the one of a failed query an error, returned as well
+func (d *DNSX) CompareAuthoritative(hostname string, zone *AuthoritativeZone, recursive *ResponseData, questionTypes []uint16) (*AuthoritativeComparison, error) {
+	comparison := &AuthoritativeComparison{Status: AuthStatusUnknown, Recursive: recursive.Records(questionTypes)}
+	if zone == nil {
+		return comparison, nil
+	}
+	comparison.Zone, comparison.Servers = zone.Name, zone.Servers
+	data, err := d.QueryMultipleWithProfile(hostname, zone.profile)
+	switch {
+	case data == nil || data.Host == "" || data.Timestamp.IsZero():
+		if err == nil {
+			err = errNoAuthoritativeResponse
+		}
+	case data.StatusCodeRaw != miekgdns.RcodeSuccess && data.StatusCodeRaw != miekgdns.RcodeNameError:
+		err = fmt.Errorf("authoritative servers answered %s", miekgdns.RcodeToString[data.StatusCodeRaw])
+	default:
+		err = nil
+	}
+	if err != nil {
+		comparison.Status, comparison.Error = AuthStatusError, err.Error()
+		return comparison, err
+	}
+	authoritative := &ResponseData{DNSData: data}
+	comparison.Authoritative = authoritative.Records(questionTypes)
+	comparison.RecursiveOnly = recordsDifference(comparison.Recursive, comparison.Authoritative)
+	comparison.AuthoritativeOnly = recordsDifference(comparison.Authoritative, comparison.Recursive)
+	comparison.Status = AuthStatusMatch
+	if comparison.Differs() {
+		comparison.Status = AuthStatusDiff
+	}
+	return comparison, nil
+}
+
+// recordsDifference returns the records of a missing from b, compared as the expected values are
+func recordsDifference(a, b []string) []string {
+	present := make(map[string]struct{}, len(b))
+	for _, record := range b {
+		present[normalizeExpectedValue(record)] = struct{}{}
+	}
+	var difference []string
+	for _, record := range a {
+		if _, ok := present[normalizeExpectedValue(record)]; !ok {
+			difference = append(difference, record)
+		}
+	}
+	return difference
+}
@@ -0,0 +1,73 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareAuthoritative(t *testing.T) {
+	// the same server plays the recursive resolver and the authoritative server of example.com, the
+	// answers for www.example.com differing with the recursion desired flag
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		question := r.Question[0]
+		hdr := miekgdns.RR_Header{Name: question.Name, Rrtype: question.Qtype, Class: miekgdns.ClassINET, Ttl: 300}
+		switch {
+		case question.Name == "example.com." && question.Qtype == miekgdns.TypeNS:
+			m.Answer = append(m.Answer, &miekgdns.NS{Hdr: hdr, Ns: "ns1.example.com."})
+		case question.Name == "ns1.example.com." && question.Qtype == miekgdns.TypeA:
+			m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP("127.0.0.1")})
+		case question.Name == "www.example.com." && question.Qtype == miekgdns.TypeA:
+			m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP("192.0.2.1")})
+			if r.RecursionDesired {
+				m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP("198.51.100.1")})
+			} else {
+				m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP("192.0.2.2")})
+			}
+		case question.Name == "broken.example.com." && !r.RecursionDesired:
+			m.Rcode = miekgdns.RcodeServerFailure
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	authServers := dnsX.NewAuthoritativeServers()
+	_, authServers.Port, _ = net.SplitHostPort(conn.LocalAddr().String())
+
+	zone, err := authServers.Find("www.example.com")
+	require.Nil(t, err, "could not find the authoritative servers")
+	require.NotNil(t, zone, "zone not found")
+	require.Equal(t, "example.com", zone.Name, "could not match zone")
+	require.Equal(t, []string{conn.LocalAddr().String()}, zone.Servers, "could not match servers")
+
+	recursive, err := dnsX.QueryOne("www.example.com")
+	require.Nil(t, err, "could not query")
+	comparison, err := dnsX.CompareAuthoritative("www.example.com", zone, &ResponseData{DNSData: recursive}, []uint16{miekgdns.TypeA})
+	require.Nil(t, err, "could not compare")
+	require.True(t, comparison.Differs(), "difference not detected")
+	require.Equal(t, AuthStatusDiff, comparison.Status, "could not match status")
+	require.Equal(t, []string{"198.51.100.1"}, comparison.RecursiveOnly, "could not match recursive only records")
+	require.Equal(t, []string{"192.0.2.2"}, comparison.AuthoritativeOnly, "could not match authoritative only records")
+
+	// a failed authoritative query is an error rather than a match of empty records
+	comparison, err = dnsX.CompareAuthoritative("broken.example.com", zone, &ResponseData{DNSData: recursive}, []uint16{miekgdns.TypeA})
+	require.NotNil(t, err, "failed authoritative query accepted")
+	require.Equal(t, AuthStatusError, comparison.Status, "could not match error status")
+	require.Contains(t, comparison.Error, "SERVFAIL", "could not match error")
+
+	comparison, err = dnsX.CompareAuthoritative("www.example.org", nil, &ResponseData{DNSData: recursive}, []uint16{miekgdns.TypeA})
+	require.Nil(t, err, "could not compare without zone")
+	require.Equal(t, AuthStatusUnknown, comparison.Status, "could not match unknown status")
+	require.Empty(t, comparison.Authoritative, "authoritative records without zone")
+}
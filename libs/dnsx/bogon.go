@@ -0,0 +1,96 @@
+package dnsx
+
+import (
+	"net"
+)
+
+const (
+	BogonTypePrivate = "private"
+	BogonTypeBogon   = "bogon"
+)
+
+// PrivateRanges contains the private and shared address ranges
+var PrivateRanges = []string{
+	"10.0.0.0/8",     // RFC 1918
+	"172.16.0.0/12",  // RFC 1918
+	"192.168.0.0/16", // RFC 1918
+	"100.64.0.0/10",  // RFC 6598 shared address space
+	"fc00::/7",       // RFC 4193 unique local
+}
+
+// BogonRanges contains the reserved address ranges which should never appear on the internet. The ipv4 mapped
+// range is left out, net.ParseIP returning the mapped addresses as ipv4 ones
+var BogonRanges = []string{
+	"0.0.0.0/8",       // RFC 1122 "this" network
+	"127.0.0.0/8",     // RFC 1122 loopback
+	"169.254.0.0/16",  // RFC 3927 link local
+	"192.0.0.0/24",    // RFC 6890 ietf protocol assignments
+	"192.0.2.0/24",    // RFC 5737 test-net-1
+	"198.18.0.0/15",   // RFC 2544 benchmarking
+	"198.51.100.0/24", // RFC 5737 test-net-2
+	"203.0.113.0/24",  // RFC 5737 test-net-3
+	"224.0.0.0/4",     // RFC 5771 multicast
+	"240.0.0.0/4",     // RFC 1112 reserved
+	"::/128",          // RFC 4291 unspecified
+	"::1/128",         // RFC 4291 loopback
+	"100::/64",        // RFC 6666 discard only
+	"2001:10::/28",    // RFC 4843 orchid
+	"2001:db8::/32",   // RFC 3849 documentation
+	"3ffe::/16",       // RFC 3701 6bone
+	"fe80::/10",       // RFC 4291 link local
+	"fec0::/10",       // RFC 3879 site local
+	"ff00::/8",        // RFC 4291 multicast
+}
+
+var (
+	privateNetworks = mustParseCIDRs(PrivateRanges)
+	bogonNetworks   = mustParseCIDRs(BogonRanges)
+)
+
+func mustParseCIDRs(cidrs []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// BogonType returns the kind of non routable range the ip belongs to, if any
+func BogonType(ip string) string {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return ""
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(parsedIP) {
+			return BogonTypePrivate
+		}
+	}
+	for _, network := range bogonNetworks {
+		if network.Contains(parsedIP) {
+			return BogonTypeBogon
+		}
+	}
+	return ""
+}
+
+// AnnotateBogons flags the A and AAAA records belonging to private or bogon ranges
+func (d *ResponseData) AnnotateBogons() {
+	if d.DNSData == nil {
+		return
+	}
+	for _, ip := range append(append([]string{}, d.A...), d.AAAA...) {
+		switch BogonType(ip) {
+		case BogonTypePrivate:
+			d.Private = true
+			d.PrivateIPs = append(d.PrivateIPs, ip)
+		case BogonTypeBogon:
+			d.Bogon = true
+			d.BogonIPs = append(d.BogonIPs, ip)
+		}
+	}
+}
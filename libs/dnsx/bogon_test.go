@@ -0,0 +1,42 @@
+package dnsx
+
+import (
+	"testing"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBogonType(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.1":        BogonTypePrivate,
+		"100.64.0.1":      BogonTypePrivate,
+		"fc00::1":         BogonTypePrivate,
+		"127.0.0.1":       BogonTypeBogon,
+		"192.0.2.1":       BogonTypeBogon,
+		"2001:db8::1":     BogonTypeBogon,
+		"8.8.8.8":         "",
+		"1.1.1.1":         "",
+		"2606:4700::1111": "",
+		"invalid":         "",
+	}
+	for ip, expected := range tests {
+		require.Equal(t, expected, BogonType(ip), "could not get the bogon type of %s", ip)
+	}
+}
+
+func TestAnnotateBogons(t *testing.T) {
+	data := &ResponseData{DNSData: &retryabledns.DNSData{
+		A:    []string{"10.0.0.1", "8.8.8.8"},
+		AAAA: []string{"2001:db8::1"},
+	}}
+	data.AnnotateBogons()
+	require.True(t, data.Private, "could not flag the private address")
+	require.Equal(t, []string{"10.0.0.1"}, data.PrivateIPs, "could not list the private addresses")
+	require.True(t, data.Bogon, "could not flag the bogon address")
+	require.Equal(t, []string{"2001:db8::1"}, data.BogonIPs, "could not list the bogon addresses")
+
+	public := &ResponseData{DNSData: &retryabledns.DNSData{A: []string{"8.8.8.8"}}}
+	public.AnnotateBogons()
+	require.False(t, public.Private || public.Bogon, "public address flagged")
+}
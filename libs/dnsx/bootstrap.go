@@ -0,0 +1,30 @@
+package dnsx
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// newBootstrapResolver returns the resolver of the doh, dot and doq server hostnames querying the given ip[:port]
+// instead of the system dns, nil without bootstrap resolver. It is only used by the dialers of the dnsx transports
+func newBootstrapResolver(resolver string) (*net.Resolver, error) {
+	if resolver == "" {
+		return nil, nil
+	}
+	host, port, err := net.SplitHostPort(resolver)
+	if err != nil {
+		host, port = resolver, "53"
+	}
+	if net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("bootstrap resolver must be an ip address: %s", resolver)
+	}
+	address := net.JoinHostPort(host, port)
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}, nil
+}
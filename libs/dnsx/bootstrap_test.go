@@ -0,0 +1,62 @@
+package dnsx
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootstrapResolver(t *testing.T) {
+	// the bootstrap server resolves the doh server hostname to the loopback
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	bootstrap := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		resp := &miekgdns.Msg{}
+		resp.SetReply(r)
+		if r.Question[0].Qtype == miekgdns.TypeA {
+			a, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN A 127.0.0.1")
+			resp.Answer = append(resp.Answer, a)
+		}
+		_ = w.WriteMsg(resp)
+	})}
+	go func() { _ = bootstrap.ActivateAndServe() }()
+	defer func() { _ = bootstrap.Shutdown() }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		msg := &miekgdns.Msg{}
+		_ = msg.Unpack(body)
+		resp := &miekgdns.Msg{}
+		resp.SetReply(msg)
+		a, _ := miekgdns.NewRR(msg.Question[0].Name + " 60 IN A 192.0.2.1")
+		resp.Answer = append(resp.Answer, a)
+		packed, _ := resp.Pack()
+		w.Header().Set("Content-Type", dohMediaType)
+		_, _ = w.Write(packed)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	defaultResolver := net.DefaultResolver
+	options := DefaultOptions
+	options.BaseResolvers = []string{"doh:http://doh.dnsx.test:" + port + "/dns-query"}
+	options.MaxRetries = 1
+	options.Timeout = 2 * time.Second
+	options.BootstrapResolver = conn.LocalAddr().String()
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	ips, err := dnsX.Lookup("example.com")
+	require.Nil(t, err, "could not query the doh resolver through the bootstrap resolver")
+	require.Equal(t, []string{"192.0.2.1"}, ips)
+	require.Same(t, defaultResolver, net.DefaultResolver, "default resolver replaced")
+
+	options.BootstrapResolver = "dns.example.com"
+	_, err = New(options)
+	require.NotNil(t, err, "bootstrap hostname accepted")
+}
@@ -32,3 +32,20 @@ func (d *DNSX) CdnCheck(domain string) (bool, string, error) {
 	}
 	return d.cdn.CheckCDN(net.ParseIP((ipAddr)))
 }
+
+// CdnCheckIPs returns the cdn of the first of the ips part of the cdn ranges
+func (d *DNSX) CdnCheckIPs(ips []string) (bool, string, error) {
+	if d.cdn == nil {
+		return false, "", errorutil.New("cdn client not initialized")
+	}
+	for _, ip := range ips {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			continue
+		}
+		if isCDN, name, err := d.cdn.CheckCDN(parsed); err == nil && isCDN {
+			return isCDN, name, nil
+		}
+	}
+	return false, "", nil
+}
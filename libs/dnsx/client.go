@@ -0,0 +1,390 @@
+package dnsx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+const (
+	// defaultEncryptedTimeout is the per attempt timeout of the doh, dot and doq resolvers when none is set, the
+	// first attempt including the handshake
+	defaultEncryptedTimeout = 5 * time.Second
+)
+
+var (
+	errMaxRetries          = errors.New("could not resolve, max retries exceeded")
+	errInvalidMaxRetries   = errors.New("max retries must be at least 1")
+	errTransferUnsupported = errors.New("zone transfers are only supported over tcp and dot")
+)
+
+// client sends the queries to the resolvers over their protocol (udp, tcp, dot, doh and doq), the resolvers
+// being queried in turn at each attempt. Its methods follow the ones of the retryabledns client, the queries
+// ending with their context
+type client struct {
+	transport  *transport
+	resolvers  []retryabledns.Resolver
+	timeout    time.Duration
+	maxTimeout time.Duration
+	escalation bool
+	maxRetries int
+	index      uint32
+	knownHosts map[string][]string
+	queryLog   *QueryLog
+	stats      *ResolverStats
+	sizeStats  *SizeStats
+	edns       ednsSettings
+}
+
+// newClient creates the client querying the resolvers
+func newClient(options *Options, resolvers []string) (*client, error) {
+	if len(resolvers) == 0 {
+		return nil, errEmptyResolvers
+	}
+	if options.MaxRetries < 1 {
+		return nil, errInvalidMaxRetries
+	}
+	transport, err := newTransport(options)
+	if err != nil {
+		return nil, err
+	}
+	c := &client{
+		transport:  transport,
+		timeout:    options.Timeout,
+		maxTimeout: options.MaxTimeout,
+		escalation: options.TimeoutEscalation,
+		maxRetries: options.MaxRetries,
+		queryLog:   options.QueryLog,
+		stats:      options.ResolverStats,
+		sizeStats:  options.SizeStats,
+		edns:       newEDNSSettings(options),
+	}
+	for _, resolver := range resolvers {
+		c.resolvers = append(c.resolvers, parseResolver(resolver))
+	}
+	if options.Hostsfile {
+		c.knownHosts, _ = loadHostsFile()
+	}
+	return c, nil
+}
+
+// close closes the connections kept open to the resolvers
+func (c *client) close() {
+	c.transport.close()
+}
+
+func (c *client) nextResolver() retryabledns.Resolver {
+	index := atomic.AddUint32(&c.index, 1)
+	return c.resolvers[index%uint32(len(c.resolvers))]
+}
+
+// attemptTimeout returns the timeout of an attempt sent to the resolver, 0 being the first attempt of a question
+func (c *client) attemptTimeout(resolver retryabledns.Resolver, attempt int) time.Duration {
+	if c.escalation {
+		return AttemptTimeout(c.timeout, c.maxTimeout, attempt)
+	}
+	if c.timeout > 0 {
+		return c.timeout
+	}
+	if networkResolver, ok := resolver.(*retryabledns.NetworkResolver); ok && networkResolver.Protocol != retryabledns.DOT {
+		return defaultAttemptTimeout
+	}
+	return defaultEncryptedTimeout
+}
+
+// exchange sends the message about the host to the resolver within the timeout of the attempt, which is
+// written to the query log
+func (c *client) exchange(ctx context.Context, host string, attempt int, msg *miekgdns.Msg, resolver retryabledns.Resolver) (*miekgdns.Msg, error) {
+	start := time.Now()
+	attemptCtx, cancel := context.WithTimeout(ctx, c.attemptTimeout(resolver, attempt))
+	resp, size, err := c.transport.exchange(attemptCtx, msg, resolver)
+	cancel()
+	if c.queryLog != nil {
+		c.queryLog.record(host, resolver.String(), attempt, start, msg, resp, err)
+	}
+	if c.stats != nil {
+		c.stats.record(resolver.String(), resp)
+	}
+	if c.sizeStats != nil {
+		c.sizeStats.record(msg, size)
+	}
+	return resp, err
+}
+
+// Do sends the message to the resolvers in turn until a successful response
+func (c *client) Do(msg *miekgdns.Msg) (*miekgdns.Msg, error) {
+	return c.do(context.Background(), msg, nil)
+}
+
+// do sends the message to the resolver, or to the configured resolvers in turn when nil, until it is answered
+// with NOERROR
+func (c *client) do(ctx context.Context, msg *miekgdns.Msg, resolver retryabledns.Resolver) (*miekgdns.Msg, error) {
+	c.edns.prepare(msg)
+	var (
+		resp    *miekgdns.Msg
+		err     error
+		counter = attemptCounterFrom(ctx)
+	)
+	counter.addQuestion()
+	for i := 0; i < c.maxRetries; i++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		counter.addAttempt()
+		server := resolver
+		if server == nil {
+			server = c.nextResolver()
+		}
+		msg.Id = c.transport.ids.next()
+		resp, err = c.exchange(ctx, questionHost(msg), i, msg, server)
+		if err == nil && resp.Rcode == miekgdns.RcodeSuccess {
+			return resp, nil
+		}
+	}
+	if err == nil {
+		err = errMaxRetries
+	}
+	return resp, err
+}
+
+// questionHost returns the name asked by the message
+func questionHost(msg *miekgdns.Msg) string {
+	if len(msg.Question) == 0 {
+		return ""
+	}
+	return trimDot(msg.Question[0].Name)
+}
+
+func (c *client) Query(host string, requestType uint16) (*retryabledns.DNSData, error) {
+	return c.QueryMultiple(host, []uint16{requestType})
+}
+
+func (c *client) Resolve(host string) (*retryabledns.DNSData, error) {
+	return c.QueryMultiple(host, []uint16{miekgdns.TypeA, miekgdns.TypeAAAA})
+}
+
+func (c *client) QueryMultiple(host string, requestTypes []uint16) (*retryabledns.DNSData, error) {
+	return c.queryMultiple(context.Background(), host, requestTypes, nil)
+}
+
+// QueryMultipleWithResolver queries the given resolver only
+func (c *client) QueryMultipleWithResolver(host string, requestTypes []uint16, resolver retryabledns.Resolver) (*retryabledns.DNSData, error) {
+	return c.queryMultiple(context.Background(), host, requestTypes, resolver)
+}
+
+// newQuestion returns the message asking the question type for the host, the reverse name being asked for the
+// PTR of an ip
+func newQuestion(host string, requestType uint16) (*miekgdns.Msg, error) {
+	name := miekgdns.Fqdn(host)
+	if requestType == miekgdns.TypePTR && net.ParseIP(host) != nil {
+		var err error
+		if name, err = miekgdns.ReverseAddr(host); err != nil {
+			return nil, err
+		}
+	}
+	msg := &miekgdns.Msg{}
+	msg.SetQuestion(name, requestType)
+	msg.SetEdns0(4096, false)
+	return msg, nil
+}
+
+// queryMultiple sends each question type to the resolver, or to the resolvers in turn when nil, until a
+// successful response, a NOERROR response without any record being retried as by retryabledns. Only the last response of each question type is kept, the records of the question
+// types being merged and the resolvers of all the attempts that got a response reported
+func (c *client) queryMultiple(ctx context.Context, host string, requestTypes []uint16, resolver retryabledns.Resolver) (*retryabledns.DNSData, error) {
+	return c.queryPrepared(ctx, host, requestTypes, resolver, nil)
+}
+
+// queryPrepared queries like queryMultiple, the questions being adjusted by prepare when set (eg. to clear the
+// recursion desired flag)
+func (c *client) queryPrepared(ctx context.Context, host string, requestTypes []uint16, resolver retryabledns.Resolver, prepare func(*miekgdns.Msg)) (*retryabledns.DNSData, error) {
+	var (
+		dnsdata   = &retryabledns.DNSData{Host: host}
+		resolvers []string
+		err       error
+		counter   = attemptCounterFrom(ctx)
+	)
+	c.addKnownHosts(dnsdata, host, requestTypes)
+	for _, requestType := range requestTypes {
+		msg, questionErr := newQuestion(host, requestType)
+		if questionErr != nil {
+			return nil, questionErr
+		}
+		if prepare != nil {
+			prepare(msg)
+		}
+		c.edns.prepare(msg)
+
+		var resp *miekgdns.Msg
+		counter.addQuestion()
+		for i := 0; i < c.maxRetries; i++ {
+			if ctx.Err() != nil {
+				return dnsdata, ctx.Err()
+			}
+			counter.addAttempt()
+			server := resolver
+			if server == nil {
+				server = c.nextResolver()
+			}
+			var attemptResp *miekgdns.Msg
+			msg.Id = c.transport.ids.next()
+			attemptResp, err = c.exchange(ctx, host, i, msg, server)
+			if attemptResp == nil {
+				continue
+			}
+			resp = attemptResp
+			resolvers = append(resolvers, server.String())
+			if err == nil && resp.Rcode == miekgdns.RcodeSuccess && len(resp.Answer)+len(resp.Ns) > 0 {
+				break
+			}
+		}
+		if resp == nil {
+			continue
+		}
+		typed := &retryabledns.DNSData{Host: host}
+		_ = typed.ParseFromMsg(resp)
+		typed.StatusCode = miekgdns.RcodeToString[resp.Rcode]
+		typed.StatusCodeRaw = resp.Rcode
+		typed.Raw = resp.String()
+		typed.RawResp = resp
+		typed.Timestamp = time.Now()
+		mergeDNSData(dnsdata, typed)
+	}
+	dnsdata.Resolver = sliceutil.Dedupe(resolvers)
+	return dnsdata, err
+}
+
+// addKnownHosts adds the addresses of the host found in the hosts file for the question types
+func (c *client) addKnownHosts(dnsdata *retryabledns.DNSData, host string, requestTypes []uint16) {
+	addHostsFileAddresses(dnsdata, c.knownHosts[normalizeName(host)], requestTypes)
+}
+
+func (c *client) QueryParallel(host string, requestType uint16, resolvers []string) ([]*retryabledns.DNSData, error) {
+	return c.queryParallel(context.Background(), host, requestType, resolvers)
+}
+
+// queryParallel sends the question to each of the ip[:port] servers at the same time
+func (c *client) queryParallel(ctx context.Context, host string, requestType uint16, resolvers []string) ([]*retryabledns.DNSData, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	results := make([]*retryabledns.DNSData, len(resolvers))
+	done := make(chan struct{}, len(resolvers))
+	for i, resolver := range resolvers {
+		go func(i int, resolver string) {
+			defer func() { done <- struct{}{} }()
+
+			msg := &miekgdns.Msg{}
+			msg.SetQuestion(miekgdns.Fqdn(host), requestType)
+			c.edns.prepare(msg)
+			msg.Id = c.transport.ids.next()
+			server := parseResolver(resolver)
+			resp, err := c.exchange(ctx, host, 0, msg, server)
+			if err != nil || resp == nil {
+				return
+			}
+			dnsdata := &retryabledns.DNSData{Host: host, Resolver: []string{server.String()}}
+			_ = dnsdata.ParseFromMsg(resp)
+			dnsdata.StatusCode = miekgdns.RcodeToString[resp.Rcode]
+			dnsdata.StatusCodeRaw = resp.Rcode
+			dnsdata.Raw = resp.String()
+			dnsdata.RawResp = resp
+			dnsdata.Timestamp = time.Now()
+			results[i] = dnsdata
+		}(i, resolver)
+	}
+	for range resolvers {
+		<-done
+	}
+
+	var dnsdatas []*retryabledns.DNSData
+	for _, dnsdata := range results {
+		if dnsdata != nil {
+			dnsdatas = append(dnsdatas, dnsdata)
+		}
+	}
+	return dnsdatas, nil
+}
+
+func (c *client) AXFR(host string) (*retryabledns.AXFRData, error) {
+	return c.axfr(context.Background(), host)
+}
+
+// axfr transfers the zone from its name servers then from the resolvers, the failed transfers being skipped
+func (c *client) axfr(ctx context.Context, host string) (*retryabledns.AXFRData, error) {
+	nsdata, err := c.queryMultiple(ctx, host, []uint16{miekgdns.TypeNS}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var servers []retryabledns.Resolver
+	for _, ns := range nsdata.NS {
+		adata, err := c.queryMultiple(ctx, ns, []uint16{miekgdns.TypeA}, nil)
+		if err != nil {
+			continue
+		}
+		for _, a := range adata.A {
+			servers = append(servers, &retryabledns.NetworkResolver{Protocol: retryabledns.TCP, Host: a, Port: "53"})
+		}
+	}
+	servers = append(servers, c.resolvers...)
+
+	axfrdata := &retryabledns.AXFRData{Host: host}
+	for _, server := range servers {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		dnsdata, err := c.transfer(ctx, host, server)
+		if err != nil {
+			continue
+		}
+		axfrdata.DNSData = append(axfrdata.DNSData, dnsdata)
+	}
+	return axfrdata, nil
+}
+
+// transfer transfers the zone from the server, over tcp for the plain servers. The attempt timeout applies to
+// the connection and to each message of the transfer, not to the whole transfer
+func (c *client) transfer(ctx context.Context, host string, server retryabledns.Resolver) (*retryabledns.DNSData, error) {
+	networkResolver, ok := server.(*retryabledns.NetworkResolver)
+	if !ok {
+		return nil, errTransferUnsupported
+	}
+	network := "tcp"
+	if networkResolver.Protocol == retryabledns.DOT {
+		network = "tcp-tls"
+	}
+	timeout := c.attemptTimeout(server, 0)
+	address := net.JoinHostPort(networkResolver.Host, networkResolver.Port)
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	conn, err := c.transport.dial(dialCtx, network, address)
+	cancel()
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	defer conn.Close()
+	// unblocks the transfer when the context is canceled
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	msg := &miekgdns.Msg{}
+	msg.SetAxfr(miekgdns.Fqdn(host))
+	c.edns.prepare(msg)
+	msg.Id = c.transport.ids.next()
+	transfer := &miekgdns.Transfer{Conn: conn, ReadTimeout: timeout, WriteTimeout: timeout}
+	envelopes, err := transfer.In(msg, address)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	dnsdata := &retryabledns.DNSData{Host: host, Resolver: []string{server.String()}}
+	if err := dnsdata.ParseFromEnvelopeChan(envelopes); err != nil {
+		return nil, contextError(ctx, err)
+	}
+	dnsdata.Timestamp = time.Now()
+	return dnsdata, nil
+}
@@ -0,0 +1,67 @@
+package dnsx
+
+import (
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// MaxCNAMEChainDepth is the length above which a cname chain is reported as too deep, resolvers
+// usually giving up on longer chains
+const MaxCNAMEChainDepth = 16
+
+// OrderCNAMEChain sorts the cname records following the chain starting from the queried host and
+// reports the chains looping back or longer than the maximum depth
+func (d *ResponseData) OrderCNAMEChain() {
+	if d.DNSData == nil || len(d.CNAME) == 0 {
+		return
+	}
+	chain, loop := cnameChain(d.Host, d.AllRecords)
+	d.CNAMELoop = loop
+	d.CNAMETooDeep = loop == nil && len(chain) > MaxCNAMEChainDepth
+	// records not belonging to the chain are kept at the end
+	d.CNAME = sliceutil.Dedupe(append(chain, d.CNAME...))
+}
+
+// cnameChain follows the cname records from host and returns the ordered targets, along with the
+// names forming the cycle when the chain loops back on itself
+func cnameChain(host string, records []string) (chain []string, loop []string) {
+	targets := make(map[string]string)
+	for _, record := range records {
+		rr, err := miekgdns.NewRR(record)
+		if err != nil || rr == nil {
+			continue
+		}
+		if cname, ok := rr.(*miekgdns.CNAME); ok {
+			targets[normalizeName(cname.Hdr.Name)] = trimDot(cname.Target)
+		}
+	}
+
+	var path []string
+	seen := make(map[string]int)
+	current := normalizeName(host)
+	for {
+		target, ok := targets[current]
+		if !ok {
+			break
+		}
+		if idx, ok := seen[current]; ok {
+			loop = append(path[idx:], current)
+			break
+		}
+		seen[current] = len(path)
+		path = append(path, current)
+		chain = append(chain, target)
+		current = normalizeName(target)
+	}
+	return chain, loop
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(trimDot(name))
+}
+
+func trimDot(name string) string {
+	return strings.TrimRight(name, ".")
+}
@@ -0,0 +1,44 @@
+package dnsx
+
+import (
+	"fmt"
+	"testing"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func cnameResponse(host string, links ...string) *ResponseData {
+	data := &ResponseData{DNSData: &retryabledns.DNSData{Host: host}}
+	for i := 0; i+1 < len(links); i += 2 {
+		data.CNAME = append(data.CNAME, links[i+1])
+		data.AllRecords = append(data.AllRecords, fmt.Sprintf("%s.\t300\tIN\tCNAME\t%s.", links[i], links[i+1]))
+	}
+	return data
+}
+
+func TestOrderCNAMEChain(t *testing.T) {
+	data := cnameResponse("a.example.com", "b.example.com", "c.example.com", "a.example.com", "b.example.com")
+	data.OrderCNAMEChain()
+	require.Equal(t, []string{"b.example.com", "c.example.com"}, data.CNAME, "invalid chain order")
+	require.Nil(t, data.CNAMELoop, "chain reported as loop")
+	require.False(t, data.CNAMETooDeep, "chain reported as too deep")
+
+	data = cnameResponse("a.example.com", "a.example.com", "b.example.com", "b.example.com", "c.example.com", "c.example.com", "b.example.com")
+	data.OrderCNAMEChain()
+	require.Equal(t, []string{"b.example.com", "c.example.com", "b.example.com"}, data.CNAMELoop, "invalid loop members")
+	require.False(t, data.CNAMETooDeep, "loop reported as too deep")
+
+	data = cnameResponse("a.example.com", "a.example.com", "a.example.com")
+	data.OrderCNAMEChain()
+	require.Equal(t, []string{"a.example.com", "a.example.com"}, data.CNAMELoop, "invalid self loop")
+
+	var links []string
+	for i := 0; i <= MaxCNAMEChainDepth; i++ {
+		links = append(links, fmt.Sprintf("%d.example.com", i), fmt.Sprintf("%d.example.com", i+1))
+	}
+	data = cnameResponse("0.example.com", links...)
+	data.OrderCNAMEChain()
+	require.Nil(t, data.CNAMELoop, "deep chain reported as loop")
+	require.True(t, data.CNAMETooDeep, "deep chain not reported")
+}
@@ -0,0 +1,121 @@
+package dnsx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// WithCompactJSON drops the null and empty fields (empty strings, arrays and objects) from the json record and
+// leaves the html characters unescaped, for the smallest lines. The records no longer share a fixed set of keys
+func WithCompactJSON() MarshalOption {
+	return func(d *ResponseData) {
+		d.compactJSON = true
+	}
+}
+
+// compactJSON encodes the value without html escaping and without the empty fields of its objects, keeping
+// the order of the fields and the elements of the arrays
+func compactJSON(value interface{}) ([]byte, error) {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(&encoded)
+	decoder.UseNumber()
+	var compacted bytes.Buffer
+	if _, err := compactValue(decoder, &compacted); err != nil {
+		return nil, err
+	}
+	return compacted.Bytes(), nil
+}
+
+// compactValue writes the next value of the decoder to buffer, returning whether it is empty
+func compactValue(decoder *json.Decoder, buffer *bytes.Buffer) (bool, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return false, err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '[' {
+			return compactArray(decoder, buffer)
+		}
+		return compactObject(decoder, buffer)
+	case nil:
+		buffer.WriteString("null")
+		return true, nil
+	case string:
+		writeString(buffer, token)
+		return token == "", nil
+	case json.Number:
+		buffer.WriteString(token.String())
+	case bool:
+		fmt.Fprint(buffer, token)
+	}
+	return false, nil
+}
+
+// compactObject writes the non-empty fields of an object, the opening delimiter being consumed. Each field is
+// written in place and cut off when its value turns out empty, the record being compacted in a single pass
+func compactObject(decoder *json.Decoder, buffer *bytes.Buffer) (bool, error) {
+	buffer.WriteByte('{')
+	fields := 0
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return false, err
+		}
+		mark := buffer.Len()
+		if fields > 0 {
+			buffer.WriteByte(',')
+		}
+		writeString(buffer, key.(string))
+		buffer.WriteByte(':')
+		empty, err := compactValue(decoder, buffer)
+		if err != nil {
+			return false, err
+		}
+		if empty {
+			buffer.Truncate(mark)
+			continue
+		}
+		fields++
+	}
+	if _, err := decoder.Token(); err != nil {
+		return false, err
+	}
+	buffer.WriteByte('}')
+	return fields == 0, nil
+}
+
+// compactArray writes all the elements of an array, compacted, the opening delimiter being consumed
+func compactArray(decoder *json.Decoder, buffer *bytes.Buffer) (bool, error) {
+	buffer.WriteByte('[')
+	elements := 0
+	for decoder.More() {
+		if elements > 0 {
+			buffer.WriteByte(',')
+		}
+		if _, err := compactValue(decoder, buffer); err != nil {
+			return false, err
+		}
+		elements++
+	}
+	if _, err := decoder.Token(); err != nil {
+		return false, err
+	}
+	buffer.WriteByte(']')
+	return elements == 0, nil
+}
+
+// writeString writes the json string without html escaping
+func writeString(buffer *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	// the encoder terminates the value with a newline
+	buffer.Truncate(buffer.Len() - 1)
+}
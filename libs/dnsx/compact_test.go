@@ -0,0 +1,39 @@
+package dnsx
+
+import (
+	"testing"
+
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactJSON(t *testing.T) {
+	d := &ResponseData{
+		DNSData:  &retryabledns.DNSData{Host: "example.com", A: []string{"192.0.2.1"}, TXT: []string{"<v=spf1 -all>", ""}, StatusCode: "NOERROR"},
+		Sections: &SectionCounts{Answer: 1},
+	}
+	compact, err := d.JSON(WithCompactJSON())
+	require.Nil(t, err, "could not marshal compact json")
+	require.Equal(t, `{"host":"example.com","a":["192.0.2.1"],"txt":["<v=spf1 -all>",""],"status_code":"NOERROR","timestamp":"0001-01-01T00:00:00Z","sections":{"answer":1,"authority":0,"additional":0}}`, compact, "could not match compact json")
+
+	// the plain records keep their null fields and html escaping
+	plain, err := d.JSON()
+	require.Nil(t, err, "could not marshal json")
+	require.Contains(t, plain, `"\u003cv=spf1 -all\u003e"`, "could not match escaped txt")
+	require.Contains(t, plain, `"edns":null`, "could not match null field")
+	require.False(t, d.compactJSON, "compact option applied to the response")
+
+	// the objects left without fields are dropped from their parent, in the order of the fields
+	type inner struct {
+		Name  string   `json:"name"`
+		Items []string `json:"items"`
+	}
+	nested, err := compactJSON(struct {
+		Empty inner             `json:"empty"`
+		Kept  inner             `json:"kept"`
+		Map   map[string]*inner `json:"map"`
+		Last  string            `json:"last"`
+	}{Kept: inner{Items: []string{"a"}}, Map: map[string]*inner{"x": nil}, Last: "z"})
+	require.Nil(t, err, "could not compact nested json")
+	require.Equal(t, `{"kept":{"items":["a"]},"last":"z"}`, string(nested), "could not match nested compact json")
+}
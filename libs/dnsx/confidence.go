@@ -0,0 +1,87 @@
+package dnsx
+
+import (
+	retryabledns "github.com/projectdiscovery/retryabledns"
+)
+
+// Confidence levels of the records
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// DefaultConfidenceResolvers is the default number of distinct resolvers that must return a record for it to be trusted
+const DefaultConfidenceResolvers = 2
+
+// DefaultConfidenceMaxRetries is the default number of retries above which the records are not trusted
+const DefaultConfidenceMaxRetries = 1
+
+var confidenceRanks = map[string]int{ConfidenceLow: 0, ConfidenceMedium: 1, ConfidenceHigh: 2}
+
+// ComputeConfidence sets the confidence level of each record of the question types from the resolver that
+// answered the host, the responses of other resolvers (confirmations) and the number of retries the host required:
+//   - high: returned by at least minResolvers distinct resolvers, without retry
+//   - medium: returned by at least minResolvers distinct resolvers, with up to maxRetries retries
+//   - low: returned by fewer resolvers or after more than maxRetries retries
+//
+// The records of a confirmation are attributed to the resolver of its last attempt, so that several responses
+// of the same resolver count once
+func (d *ResponseData) ComputeConfidence(resolver string, confirmations []*retryabledns.DNSData, questionTypes []uint16, minResolvers, maxRetries int) {
+	if d.DNSData == nil {
+		return
+	}
+	records := d.Records(questionTypes)
+	if len(records) == 0 {
+		return
+	}
+	resolvers := make(map[string]map[string]struct{}, len(records))
+	add := func(record, resolver string) {
+		key := normalizeExpectedValue(record)
+		if resolvers[key] == nil {
+			resolvers[key] = make(map[string]struct{})
+		}
+		resolvers[key][resolver] = struct{}{}
+	}
+	for _, record := range records {
+		add(record, resolver)
+	}
+	for _, confirmation := range confirmations {
+		if confirmation == nil || len(confirmation.Resolver) == 0 {
+			continue
+		}
+		confirmed := &ResponseData{DNSData: confirmation}
+		for _, record := range confirmed.Records(questionTypes) {
+			add(record, confirmation.Resolver[len(confirmation.Resolver)-1])
+		}
+	}
+	d.Confidence = make(map[string]string, len(records))
+	for _, record := range records {
+		key := normalizeExpectedValue(record)
+		switch count := len(resolvers[key]); {
+		case count < minResolvers || d.Retries > maxRetries:
+			d.Confidence[key] = ConfidenceLow
+		case d.Retries > 0:
+			d.Confidence[key] = ConfidenceMedium
+		default:
+			d.Confidence[key] = ConfidenceHigh
+		}
+	}
+}
+
+// RecordConfidence returns the confidence level of the record, empty if it was not computed
+func (d *ResponseData) RecordConfidence(record string) string {
+	return d.Confidence[normalizeExpectedValue(record)]
+}
+
+// LowestConfidence returns the lowest confidence level of the records, empty if none was computed
+func (d *ResponseData) LowestConfidence(records []string) string {
+	var lowest string
+	for _, record := range records {
+		level := d.RecordConfidence(record)
+		if level != "" && (lowest == "" || confidenceRanks[level] < confidenceRanks[lowest]) {
+			lowest = level
+		}
+	}
+	return lowest
+}
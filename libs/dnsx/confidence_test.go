@@ -0,0 +1,43 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeConfidence(t *testing.T) {
+	questionTypes := []uint16{miekgdns.TypeA}
+	confirmation := &retryabledns.DNSData{A: []string{"1.2.3.4"}, Resolver: []string{"8.8.8.8:53"}}
+
+	data := &ResponseData{DNSData: &retryabledns.DNSData{A: []string{"1.2.3.4", "5.6.7.8"}}}
+	data.ComputeConfidence("1.1.1.1:53", []*retryabledns.DNSData{confirmation}, questionTypes, DefaultConfidenceResolvers, DefaultConfidenceMaxRetries)
+	require.Equal(t, ConfidenceHigh, data.RecordConfidence("1.2.3.4"), "confirmed record without retry")
+	require.Equal(t, ConfidenceLow, data.RecordConfidence("5.6.7.8"), "record returned by a single resolver")
+	require.Equal(t, ConfidenceLow, data.LowestConfidence(data.A), "could not match lowest confidence")
+
+	data.Retries = 1
+	data.ComputeConfidence("1.1.1.1:53", []*retryabledns.DNSData{confirmation}, questionTypes, DefaultConfidenceResolvers, DefaultConfidenceMaxRetries)
+	require.Equal(t, ConfidenceMedium, data.RecordConfidence("1.2.3.4"), "confirmed record with retry")
+
+	data.Retries = 2
+	data.ComputeConfidence("1.1.1.1:53", []*retryabledns.DNSData{confirmation}, questionTypes, DefaultConfidenceResolvers, DefaultConfidenceMaxRetries)
+	require.Equal(t, ConfidenceLow, data.RecordConfidence("1.2.3.4"), "confirmed record with too many retries")
+
+	// a single resolver is enough when no confirmation is required
+	data.Retries = 0
+	data.ComputeConfidence("1.1.1.1:53", nil, questionTypes, 1, DefaultConfidenceMaxRetries)
+	require.Equal(t, ConfidenceHigh, data.RecordConfidence("5.6.7.8"), "unconfirmed record with one resolver required")
+	require.Empty(t, data.RecordConfidence("9.9.9.9"), "confidence of a missing record")
+
+	// the confirmations count once per distinct resolver
+	again := &retryabledns.DNSData{A: []string{"1.2.3.4"}, Resolver: []string{"8.8.8.8:53"}}
+	same := &retryabledns.DNSData{A: []string{"1.2.3.4"}, Resolver: []string{"1.1.1.1:53"}}
+	data.ComputeConfidence("1.1.1.1:53", []*retryabledns.DNSData{confirmation, again, same}, questionTypes, 3, DefaultConfidenceMaxRetries)
+	require.Equal(t, ConfidenceLow, data.RecordConfidence("1.2.3.4"), "record confirmed twice by the same resolver")
+	other := &retryabledns.DNSData{A: []string{"1.2.3.4"}, Resolver: []string{"192.0.2.1:53", "9.9.9.9:53"}}
+	data.ComputeConfidence("1.1.1.1:53", []*retryabledns.DNSData{confirmation, again, other}, questionTypes, 3, DefaultConfidenceMaxRetries)
+	require.Equal(t, ConfidenceHigh, data.RecordConfidence("1.2.3.4"), "record confirmed by three resolvers")
+}
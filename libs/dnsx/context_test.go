@@ -0,0 +1,34 @@
+package dnsx
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryMultipleContextCancel(t *testing.T) {
+	// the resolver never answers
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	defer conn.Close()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.MaxRetries = 5
+	options.Timeout = 5 * time.Second
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = dnsX.QueryMultipleContext(ctx, "example.com")
+	require.ErrorIs(t, err, context.Canceled, "query not canceled")
+	require.Less(t, time.Since(start), time.Second, "query not aborted promptly")
+
+	_, err = dnsX.AXFRContext(ctx, "example.com")
+	require.ErrorIs(t, err, context.Canceled, "query started on a canceled context")
+}
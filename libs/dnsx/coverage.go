@@ -0,0 +1,71 @@
+package dnsx
+
+import (
+	"fmt"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// QuestionTypesFor returns the question types sent for the host
+func (d *DNSX) QuestionTypesFor(hostname string) []uint16 {
+	return d.questionTypes(normalizeIP(hostname))
+}
+
+// ComputeCoverage sets the number of question types that returned records out of the queried ones (eg. 3/5)
+func (d *ResponseData) ComputeCoverage(questionTypes []uint16) {
+	if d.DNSData == nil || len(questionTypes) == 0 {
+		return
+	}
+	var answered int
+	for _, questionType := range questionTypes {
+		if d.RecordCount(questionType) > 0 {
+			answered++
+		}
+	}
+	d.Coverage = fmt.Sprintf("%d/%d", answered, len(questionTypes))
+}
+
+// RecordCount returns the number of records of the question type
+func (d *ResponseData) RecordCount(questionType uint16) int {
+	switch questionType {
+	case miekgdns.TypeA:
+		return len(d.A)
+	case miekgdns.TypeAAAA:
+		return len(d.AAAA)
+	case miekgdns.TypeCNAME:
+		return len(d.CNAME)
+	case miekgdns.TypeMX:
+		return len(d.MX)
+	case miekgdns.TypeNS:
+		return len(d.NS)
+	case miekgdns.TypePTR:
+		return len(d.PTR)
+	case miekgdns.TypeSOA:
+		return len(d.SOA)
+	case miekgdns.TypeTXT:
+		return len(d.TXT)
+	case miekgdns.TypeSRV:
+		return len(d.SRV)
+	case miekgdns.TypeCAA:
+		return len(d.CAA)
+	case miekgdns.TypeTLSA:
+		return len(d.TLSA)
+	case miekgdns.TypeDNSKEY:
+		return len(d.DNSKEY)
+	case miekgdns.TypeDS:
+		return len(d.DS)
+	case miekgdns.TypeHTTPS:
+		return len(d.HTTPS)
+	case miekgdns.TypeSVCB:
+		return len(d.SVCB)
+	case miekgdns.TypeNAPTR:
+		return len(d.NAPTR)
+	case miekgdns.TypeSSHFP:
+		return len(d.SSHFP)
+	case miekgdns.TypeDNAME:
+		return len(d.DNAME)
+	case miekgdns.TypeANY:
+		return len(d.AllRecords)
+	}
+	return 0
+}
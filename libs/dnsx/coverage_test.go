@@ -0,0 +1,29 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeCoverage(t *testing.T) {
+	data := &ResponseData{DNSData: &retryabledns.DNSData{
+		Host: "example.com",
+		A:    []string{"192.0.2.1"},
+		MX:   []string{"mx.example.com"},
+		SOA:  []retryabledns.SOA{{NS: "ns1.example.com"}},
+	}}
+	data.ComputeCoverage([]uint16{miekgdns.TypeA, miekgdns.TypeAAAA, miekgdns.TypeMX, miekgdns.TypeSOA, miekgdns.TypeTXT})
+	require.Equal(t, "3/5", data.Coverage, "could not compute coverage")
+
+	empty := &ResponseData{DNSData: &retryabledns.DNSData{Host: "example.com"}}
+	empty.ComputeCoverage([]uint16{miekgdns.TypeA})
+	require.Equal(t, "0/1", empty.Coverage, "could not compute coverage without records")
+
+	// no question types leaves the coverage unset
+	empty.Coverage = ""
+	empty.ComputeCoverage(nil)
+	require.Empty(t, empty.Coverage, "coverage set without question types")
+}
@@ -0,0 +1,53 @@
+package dnsx
+
+import (
+	"fmt"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// DNAME is a redirection of the names below Name to the same names below Target (RFC 6672), the resolvers
+// returning it with the cname synthesized for the queried name
+type DNAME struct {
+	Name   string `json:"name" csv:"name"`
+	Target string `json:"target" csv:"target"`
+}
+
+func (d DNAME) String() string {
+	return fmt.Sprintf("%s -> %s", d.Name, d.Target)
+}
+
+// newDNAME returns the redirection of the DNAME record
+func newDNAME(dname *miekgdns.DNAME) DNAME {
+	return DNAME{Name: normalizeName(dname.Hdr.Name), Target: normalizeName(dname.Target)}
+}
+
+// dnameTargets returns the targets of the redirections
+func dnameTargets(redirections []DNAME) []string {
+	targets := make([]string, 0, len(redirections))
+	for _, redirection := range redirections {
+		targets = append(targets, redirection.Target)
+	}
+	return targets
+}
+
+// Redirections returns the DNAME records redirecting the host or a name of its cname chain, the ones of the
+// names above them
+func (d *ResponseData) Redirections() []DNAME {
+	if len(d.DNAME) == 0 {
+		return nil
+	}
+	chain, _ := cnameChain(d.Host, d.AllRecords)
+	names := append([]string{d.Host}, chain...)
+	var redirections []DNAME
+	for _, redirection := range d.DNAME {
+		for _, name := range names {
+			name = normalizeName(name)
+			if name != redirection.Name && miekgdns.IsSubDomain(redirection.Name, name) {
+				redirections = append(redirections, redirection)
+				break
+			}
+		}
+	}
+	return redirections
+}
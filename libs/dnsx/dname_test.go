@@ -0,0 +1,44 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDNAME(t *testing.T) {
+	d := &ResponseData{DNSData: &retryabledns.DNSData{
+		Host: "www.old.example.com",
+		AllRecords: []string{
+			"old.example.com.\t300\tIN\tDNAME\texample.net.",
+			"www.old.example.com.\t300\tIN\tCNAME\twww.example.net.",
+			"www.example.net.\t300\tIN\tA\t192.0.2.1",
+		},
+		RawResp: &miekgdns.Msg{},
+	}}
+	d.ParseRawResp()
+	require.Equal(t, []DNAME{{Name: "old.example.com", Target: "example.net"}}, d.DNAME, "could not parse dname records")
+	require.Equal(t, []string{"example.net"}, d.Records([]uint16{miekgdns.TypeDNAME}))
+	require.Equal(t, d.DNAME, d.Redirections(), "could not match the redirection of the host")
+
+	// the owner of the dname is not redirected itself
+	d.Host = "old.example.com"
+	require.Empty(t, d.Redirections())
+
+	// the redirection of a name further down the cname chain
+	d = &ResponseData{DNSData: &retryabledns.DNSData{
+		Host: "shop.example.org",
+		AllRecords: []string{
+			"shop.example.org.\t300\tIN\tCNAME\tshop.old.example.com.",
+			"old.example.com.\t300\tIN\tDNAME\texample.net.",
+			"shop.old.example.com.\t300\tIN\tCNAME\tshop.example.net.",
+			"shop.example.net.\t300\tIN\tA\t192.0.2.1",
+			"other.example.com.\t300\tIN\tDNAME\texample.net.",
+		},
+		RawResp: &miekgdns.Msg{},
+	}}
+	d.ParseRawResp()
+	require.Equal(t, []DNAME{{Name: "old.example.com", Target: "example.net"}}, d.Redirections(), "could not match the redirection of the cname chain")
+}
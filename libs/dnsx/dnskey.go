@@ -0,0 +1,50 @@
+package dnsx
+
+import (
+	"fmt"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// DNSKEY is a public key of a zone with the key material, the role telling the key signing keys (with the
+// secure entry point flag) from the zone signing keys
+type DNSKEY struct {
+	Flags         uint16 `json:"flags" csv:"flags"`
+	Protocol      uint8  `json:"protocol" csv:"protocol"`
+	Algorithm     uint8  `json:"algorithm" csv:"algorithm"`
+	AlgorithmName string `json:"algorithm_name,omitempty" csv:"algorithm_name"`
+	KeyTag        uint16 `json:"key_tag" csv:"key_tag"`
+	Role          string `json:"role" csv:"role"`
+	PublicKey     string `json:"public_key" csv:"public_key"`
+}
+
+// String returns the role, flags, algorithm and key tag of the key (eg. ksk 257 ECDSAP256SHA256 (13) tag 2371)
+func (k DNSKEY) String() string {
+	return fmt.Sprintf("%s %d %s (%d) tag %d", k.Role, k.Flags, k.AlgorithmName, k.Algorithm, k.KeyTag)
+}
+
+// newDNSKEY returns the key of the DNSKEY record
+func newDNSKEY(dnskey *miekgdns.DNSKEY) DNSKEY {
+	role := "zsk"
+	if dnskey.Flags&miekgdns.SEP != 0 {
+		role = "ksk"
+	}
+	return DNSKEY{
+		Flags:         dnskey.Flags,
+		Protocol:      dnskey.Protocol,
+		Algorithm:     dnskey.Algorithm,
+		AlgorithmName: miekgdns.AlgorithmToString[dnskey.Algorithm],
+		KeyTag:        dnskey.KeyTag(),
+		Role:          role,
+		PublicKey:     dnskey.PublicKey,
+	}
+}
+
+// formatDNSKEY returns the keys as strings
+func formatDNSKEY(keys []DNSKEY) []string {
+	records := make([]string, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, key.String())
+	}
+	return records
+}
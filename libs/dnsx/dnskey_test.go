@@ -0,0 +1,30 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDNSKEY(t *testing.T) {
+	d := &ResponseData{DNSData: &retryabledns.DNSData{
+		// the a record comes from the response to an other question type
+		AllRecords: []string{
+			"example.com.\t300\tIN\tA\t192.0.2.1",
+			"example.com.\t3600\tIN\tDNSKEY\t257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==",
+			"example.com.\t3599\tIN\tDNSKEY\t257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==",
+			"example.com.\t3600\tIN\tDNSKEY\t256 3 13 oJMRESz5E4gYzS/q6XDrvU1qMPYIjCWzJaOau8XNEZeqCYKD5ar0IRd8KqXXFJkqmVfRvMGPmM1x8fGAa2XhSA==",
+		},
+		RawResp: &miekgdns.Msg{},
+	}}
+	d.ParseRawResp()
+	require.Len(t, d.DNSKEY, 2, "could not parse dnskey records")
+	require.Equal(t, "ksk", d.DNSKEY[0].Role)
+	require.Equal(t, "zsk", d.DNSKEY[1].Role)
+	require.Equal(t, "ECDSAP256SHA256", d.DNSKEY[0].AlgorithmName)
+	require.NotEmpty(t, d.DNSKEY[0].PublicKey, "key material not kept")
+	require.Equal(t, []string{d.DNSKEY[0].String(), d.DNSKEY[1].String()}, d.Records([]uint16{miekgdns.TypeDNSKEY}))
+	require.Equal(t, 2, d.RecordCount(miekgdns.TypeDNSKEY), "could not count dnskey records")
+}
@@ -0,0 +1,93 @@
+package dnsx
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// DNSSECAlgorithm is a signing algorithm found in the DNSKEY or RRSIG records of a zone
+type DNSSECAlgorithm struct {
+	Record    string `json:"record" csv:"record"`
+	Algorithm uint8  `json:"algorithm" csv:"algorithm"`
+	Name      string `json:"name" csv:"name"`
+}
+
+func (a DNSSECAlgorithm) String() string {
+	return fmt.Sprintf("%s %s (%d)", a.Record, a.Name, a.Algorithm)
+}
+
+// ParseDNSSECAlgorithm parses an algorithm number or mnemonic (eg. 8 or RSASHA256)
+func ParseDNSSECAlgorithm(value string) (uint8, error) {
+	value = strings.TrimSpace(value)
+	if number, err := strconv.ParseUint(value, 10, 8); err == nil {
+		return uint8(number), nil
+	}
+	if algorithm, ok := miekgdns.StringToAlgorithm[strings.ToUpper(value)]; ok {
+		return algorithm, nil
+	}
+	return 0, fmt.Errorf("invalid dnssec algorithm: %s", value)
+}
+
+// QueryDNSSEC queries the DNSKEY records of the host with the DO bit set, so that their signatures are returned
+// as well, or the signed NSEC/NSEC3 proofs of the zone when the host is not a zone apex
+func (d *DNSX) QueryDNSSEC(hostname string) (*miekgdns.Msg, error) {
+	return d.QueryDNSSECContext(context.Background(), hostname, "", nil)
+}
+
+// QueryDNSSECContext queries like QueryDNSSEC with the resolver override or the profile of the host, the
+// configured resolvers being used when both are empty. The query ends with the context
+func (d *DNSX) QueryDNSSECContext(ctx context.Context, hostname, resolver string, profile *QueryProfile) (*miekgdns.Msg, error) {
+	msg := &miekgdns.Msg{}
+	msg.SetQuestion(miekgdns.Fqdn(hostname), miekgdns.TypeDNSKEY)
+	msg.SetEdns0(4096, true)
+	return d.doWith(ctx, msg, resolver, profile)
+}
+
+// DNSSECAlgorithms returns the distinct algorithms of the DNSKEY and RRSIG records of the response
+func DNSSECAlgorithms(msg *miekgdns.Msg) []DNSSECAlgorithm {
+	if msg == nil {
+		return nil
+	}
+	var algorithms []DNSSECAlgorithm
+	seen := make(map[DNSSECAlgorithm]struct{})
+	add := func(record string, algorithm uint8) {
+		name, ok := miekgdns.AlgorithmToString[algorithm]
+		if !ok {
+			name = strconv.Itoa(int(algorithm))
+		}
+		item := DNSSECAlgorithm{Record: record, Algorithm: algorithm, Name: name}
+		if _, ok := seen[item]; ok {
+			return
+		}
+		seen[item] = struct{}{}
+		algorithms = append(algorithms, item)
+	}
+	for _, section := range [][]miekgdns.RR{msg.Answer, msg.Ns} {
+		for _, record := range section {
+			switch rr := record.(type) {
+			case *miekgdns.DNSKEY:
+				add("DNSKEY", rr.Algorithm)
+			case *miekgdns.RRSIG:
+				add("RRSIG", rr.Algorithm)
+			}
+		}
+	}
+	return algorithms
+}
+
+// CheckDNSSECAlgorithms sets the algorithms of the DNSKEY and RRSIG records of the response numbered below
+// minAlgorithm as weak
+func (d *ResponseData) CheckDNSSECAlgorithms(msg *miekgdns.Msg, minAlgorithm uint8) {
+	if d.DNSData == nil {
+		return
+	}
+	for _, algorithm := range DNSSECAlgorithms(msg) {
+		if algorithm.Algorithm < minAlgorithm {
+			d.WeakDNSSEC = append(d.WeakDNSSEC, algorithm)
+		}
+	}
+}
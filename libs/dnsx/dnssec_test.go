@@ -0,0 +1,90 @@
+package dnsx
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDNSSECAlgorithm(t *testing.T) {
+	algorithm, err := ParseDNSSECAlgorithm("8")
+	require.Nil(t, err, "could not parse algorithm number")
+	require.Equal(t, miekgdns.RSASHA256, algorithm, "could not match algorithm number")
+	algorithm, err = ParseDNSSECAlgorithm("ecdsap256sha256")
+	require.Nil(t, err, "could not parse algorithm name")
+	require.Equal(t, miekgdns.ECDSAP256SHA256, algorithm, "could not match algorithm name")
+	_, err = ParseDNSSECAlgorithm("rot13")
+	require.NotNil(t, err, "unknown algorithm accepted")
+}
+
+func TestCheckDNSSECAlgorithms(t *testing.T) {
+	// a zone with a legacy rsa/sha-1 key signed by both keys
+	key5, _ := miekgdns.NewRR("example.com. 3600 IN DNSKEY 257 3 5 AwEAAQ==")
+	key13, _ := miekgdns.NewRR("example.com. 3600 IN DNSKEY 256 3 13 AwEAAQ==")
+	sig5, _ := miekgdns.NewRR("example.com. 3600 IN RRSIG DNSKEY 5 2 3600 20300101000000 20200101000000 1 example.com. AwEAAQ==")
+	sig5again, _ := miekgdns.NewRR("example.com. 3600 IN RRSIG DNSKEY 5 2 3600 20300101000000 20200101000000 2 example.com. AwEAAQ==")
+	sig13, _ := miekgdns.NewRR("example.com. 3600 IN RRSIG DNSKEY 13 2 3600 20300101000000 20200101000000 3 example.com. AwEAAQ==")
+	msg := &miekgdns.Msg{Answer: []miekgdns.RR{key5, key13, sig5, sig5again, sig13}}
+	require.Len(t, DNSSECAlgorithms(msg), 4, "could not match distinct algorithms")
+
+	d := &ResponseData{DNSData: &retryabledns.DNSData{}}
+	d.CheckDNSSECAlgorithms(msg, miekgdns.RSASHA256)
+	expected := []DNSSECAlgorithm{
+		{Record: "DNSKEY", Algorithm: miekgdns.RSASHA1, Name: "RSASHA1"},
+		{Record: "RRSIG", Algorithm: miekgdns.RSASHA1, Name: "RSASHA1"},
+	}
+	require.Equal(t, expected, d.WeakDNSSEC, "could not match weak algorithms")
+	require.Equal(t, "RRSIG RSASHA1 (5)", d.WeakDNSSEC[1].String(), "could not match algorithm label")
+
+	d = &ResponseData{DNSData: &retryabledns.DNSData{}}
+	d.CheckDNSSECAlgorithms(msg, miekgdns.RSASHA1)
+	require.Empty(t, d.WeakDNSSEC, "algorithm at the threshold flagged")
+}
+
+func TestQueryDNSSECContext(t *testing.T) {
+	// each server signs with its own algorithm
+	startServer := func(algorithm string) string {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.Nil(t, err, "could not listen")
+		server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+			m := &miekgdns.Msg{}
+			m.SetReply(r)
+			if r.Question[0].Qtype == miekgdns.TypeDNSKEY {
+				rr, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN DNSKEY 257 3 " + algorithm + " AwEAAQ==")
+				m.Answer = append(m.Answer, rr)
+			}
+			_ = w.WriteMsg(m)
+		})}
+		go func() { _ = server.ActivateAndServe() }()
+		t.Cleanup(func() { _ = server.Shutdown() })
+		return conn.LocalAddr().String()
+	}
+	configured, override := startServer("8"), startServer("13")
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{configured}
+	options.Hostsfile = false
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	algorithm := func(msg *miekgdns.Msg, err error) uint8 {
+		require.Nil(t, err, "could not query")
+		algorithms := DNSSECAlgorithms(msg)
+		require.Len(t, algorithms, 1, "could not match algorithms")
+		return algorithms[0].Algorithm
+	}
+
+	require.Equal(t, miekgdns.RSASHA256, algorithm(dnsX.QueryDNSSEC("example.com")), "configured resolver not queried")
+	require.Equal(t, miekgdns.ECDSAP256SHA256, algorithm(dnsX.QueryDNSSECContext(context.Background(), "example.com", override, nil)), "resolver override not queried")
+	profile, err := dnsX.NewQueryProfile(nil, []string{override}, false)
+	require.Nil(t, err, "could not create profile")
+	require.Equal(t, miekgdns.ECDSAP256SHA256, algorithm(dnsX.QueryDNSSECContext(context.Background(), "example.com", "", profile)), "profile resolvers not queried")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = dnsX.QueryDNSSECContext(ctx, "example.com", "", nil)
+	require.ErrorIs(t, err, context.Canceled, "query not ended with the context")
+}
@@ -1,10 +1,13 @@
 package dnsx
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"sync"
+	"time"
 
 	miekgdns "github.com/miekg/dns"
 	"github.com/projectdiscovery/cdncheck"
@@ -15,29 +18,111 @@ import (
 
 // DNSX is structure to perform dns lookups
 type DNSX struct {
-	dnsClient *retryabledns.Client
-	Options   *Options
-	cdn       *cdncheck.Client
+	dnsClient   *client
+	clientMutex sync.RWMutex
+	Options     *Options
+	cdn         *cdncheck.Client
+	knownHosts  map[string][]string
+	tcpClient   *client
+	// profileClients are the clients of the query profiles, closed with the instance
+	profileClients []*client
+	// excludingIndex rotates the resolvers picked by QueryMultipleExcluding
+	excludingIndex uint32
 }
 
 // Options contains configuration options
 type Options struct {
 	BaseResolvers     []string
 	MaxRetries        int
+	Timeout           time.Duration
 	QuestionTypes     []uint16
 	Trace             bool
 	TraceMaxRecursion int
 	Hostsfile         bool
 	OutputCDN         bool
 	QueryAll          bool
+	Offline           bool
+	SizeStats         *SizeStats
+	EDNSVersion       uint8
+	BootstrapResolver string
+	// Padding pads the queries with the EDNS(0) padding option to a multiple of this block size, 0 disables it
+	Padding int
+	// NSID requests the identifier of the servers with the EDNS(0) nsid option
+	NSID bool
+	// ResolverHash sends the questions of a host to the resolver picked by hashing its name
+	ResolverHash bool
+	// DoHUserAgent is the User-Agent of the doh requests (nil keeps the http client default, empty omits it)
+	DoHUserAgent *string
+	// DoTInsecure skips the verification of the certificates of the dot servers
+	DoTInsecure bool
+	// TimeoutEscalation doubles the timeout at each retry, starting from Timeout and up to MaxTimeout
+	TimeoutEscalation bool
+	MaxTimeout        time.Duration
+	// QueryLog receives every attempt of the queries of the instance
+	QueryLog *QueryLog
+	// ResolverStats counts the attempts of all the queries handled by each resolver and the records they returned
+	ResolverStats *ResolverStats
+	// QueryIDMode sets how the query message ids of the instance are generated (random, fixed or sequential from
+	// QueryID), the non-random modes being meant for lab testing
+	QueryIDMode string
+	QueryID     uint16
+	// NoCompression packs the queries without name compression and flags the responses whose names were
+	// compressed by setting their Compress field, to test how the servers and middleboxes handle them
+	NoCompression bool
+	// ids generates the query ids of the instance, set by New
+	ids *queryIDs
 }
 
 // ResponseData to show output result
 type ResponseData struct {
 	*retryabledns.DNSData
-	IsCDNIP bool         `json:"cdn,omitempty" csv:"cdn"`
-	CDNName string       `json:"cdn-name,omitempty" csv:"cdn-name"`
-	ASN     *AsnResponse `json:"asn,omitempty" csv:"asn"`
+	IsCDNIP              bool                     `json:"cdn,omitempty" csv:"cdn"`
+	CDNName              string                   `json:"cdn-name,omitempty" csv:"cdn-name"`
+	ASN                  *AsnResponse             `json:"asn,omitempty" csv:"asn"`
+	EDE                  []ExtendedError          `json:"ede,omitempty" csv:"ede"`
+	Private              bool                     `json:"private,omitempty" csv:"private"`
+	PrivateIPs           []string                 `json:"private_ips,omitempty" csv:"private_ips"`
+	Bogon                bool                     `json:"bogon,omitempty" csv:"bogon"`
+	BogonIPs             []string                 `json:"bogon_ips,omitempty" csv:"bogon_ips"`
+	Retries              int                      `json:"retries,omitempty" csv:"retries"`
+	Live                 *bool                    `json:"live,omitempty" csv:"live"`
+	SupportedEDNSVersion *uint8                   `json:"supported_edns_version,omitempty" csv:"supported_edns_version"`
+	Anomalies            []string                 `json:"anomalies,omitempty" csv:"anomalies"`
+	MultiASN             bool                     `json:"multi_asn,omitempty" csv:"multi_asn"`
+	ASNs                 []string                 `json:"asns,omitempty" csv:"asns"`
+	SOAWarnings          []SOAWarning             `json:"soa_warnings,omitempty" csv:"soa_warnings"`
+	Hierarchy            *Hierarchy               `json:"hierarchy,omitempty" csv:"hierarchy"`
+	Coverage             string                   `json:"coverage,omitempty" csv:"coverage"`
+	QueryName            string                   `json:"query_name,omitempty" csv:"query_name"`
+	CNAMELoop            []string                 `json:"cname_loop,omitempty" csv:"cname_loop"`
+	CNAMETooDeep         bool                     `json:"cname_too_deep,omitempty" csv:"cname_too_deep"`
+	Sections             *SectionCounts           `json:"sections,omitempty" csv:"sections"`
+	Whois                []WhoisResponse          `json:"whois,omitempty" csv:"whois"`
+	Expect               *Expectation             `json:"expect,omitempty" csv:"expect"`
+	EDNS                 *EDNS                    `json:"edns" csv:"edns"`
+	CompareAuth          *AuthoritativeComparison `json:"compare_auth,omitempty" csv:"compare_auth"`
+	Confidence           map[string]string        `json:"confidence,omitempty" csv:"confidence"`
+	NXHijackIPs          []string                 `json:"nxhijack_ips,omitempty" csv:"nxhijack_ips"`
+	SRVService           string                   `json:"srv_service,omitempty" csv:"srv_service"`
+	TLSA                 []string                 `json:"tlsa,omitempty" csv:"tlsa"`
+	TLSAPort             string                   `json:"tlsa_port,omitempty" csv:"tlsa_port"`
+	DNSKEY               []DNSKEY                 `json:"dnskey,omitempty" csv:"dnskey"`
+	DS                   []DS                     `json:"ds,omitempty" csv:"ds"`
+	HTTPS                []ServiceBinding         `json:"https,omitempty" csv:"https"`
+	SVCB                 []ServiceBinding         `json:"svcb,omitempty" csv:"svcb"`
+	NAPTR                []NAPTR                  `json:"naptr,omitempty" csv:"naptr"`
+	SSHFP                []SSHFP                  `json:"sshfp,omitempty" csv:"sshfp"`
+	DNAME                []DNAME                  `json:"dname,omitempty" csv:"dname"`
+	ResponseHash         string                   `json:"response_hash,omitempty" csv:"response_hash"`
+	WeakDNSSEC           []DNSSECAlgorithm        `json:"weak_dnssec,omitempty" csv:"weak_dnssec"`
+	NSInventory          []Nameserver             `json:"ns_inventory,omitempty" csv:"ns_inventory"`
+	DualStack            *DualStack               `json:"dualstack,omitempty" csv:"dualstack"`
+	ECH                  []ECHConfig              `json:"ech,omitempty" csv:"ech"`
+	OutOfRange           []string                 `json:"out_of_range,omitempty" csv:"out_of_range"`
+	TraceGlueless        []GluelessDelegation     `json:"trace_glueless,omitempty" csv:"trace_glueless"`
+	BulkTimestamp        string                   `json:"@timestamp,omitempty" csv:"@timestamp"`
+	// compactJSON drops the empty fields of the json record, set by WithCompactJSON
+	compactJSON bool
 }
 type AsnResponse struct {
 	AsNumber  string   `json:"as-number,omitempty" csv:"as_number"`
@@ -50,6 +135,46 @@ func (o *AsnResponse) String() string {
 	return fmt.Sprintf("[%v, %v, %v]", o.AsNumber, o.AsName, o.AsCountry)
 }
 
+// IsNoData returns true if the response is successful but carries no answer
+func (d *ResponseData) IsNoData() bool {
+	if d.DNSData == nil || d.HostsFile || d.StatusCodeRaw != miekgdns.RcodeSuccess {
+		return false
+	}
+	if d.RawResp != nil && len(d.RawResp.Answer) > 0 {
+		return false
+	}
+	// soa and ns records might come from the authority section
+	return len(d.A)+len(d.AAAA)+len(d.CNAME)+len(d.MX)+len(d.PTR)+len(d.TXT)+len(d.SRV)+len(d.CAA)+len(d.TLSA)+len(d.DNSKEY)+len(d.DS)+len(d.HTTPS)+len(d.SVCB)+len(d.NAPTR)+len(d.SSHFP)+len(d.DNAME) == 0
+}
+
+// HasRecords returns true if any of the queried types returned records
+func (d *ResponseData) HasRecords() bool {
+	if d.DNSData == nil {
+		return false
+	}
+	if d.HostsFile || (d.RawResp != nil && len(d.RawResp.Answer) > 0) {
+		return true
+	}
+	return len(d.A)+len(d.AAAA)+len(d.CNAME)+len(d.MX)+len(d.PTR)+len(d.NS)+len(d.SOA)+len(d.TXT)+len(d.SRV)+len(d.CAA)+len(d.TLSA)+len(d.DNSKEY)+len(d.DS)+len(d.HTTPS)+len(d.SVCB)+len(d.NAPTR)+len(d.SSHFP)+len(d.DNAME) > 0
+}
+
+// ParseRawResp populates the fields derived from the raw dns response, the response of the last question type
+// for the edns, extended errors and section counts
+func (d *ResponseData) ParseRawResp() {
+	if d.DNSData == nil || d.RawResp == nil {
+		return
+	}
+	d.EDE = parseExtendedErrors(d.RawResp)
+	d.EDNS = parseEDNS(d.RawResp)
+	d.Sections = CountSections(d.RawResp)
+	// the records of the other question types are in the earlier responses
+	d.parseRecords()
+	d.SupportedEDNSVersion = parseSupportedEDNSVersion(d.RawResp)
+	if d.SupportedEDNSVersion != nil {
+		d.StatusCode = RcodeBadVersName
+	}
+}
+
 type MarshalOption func(d *ResponseData)
 
 func WithoutAllRecords() MarshalOption {
@@ -58,10 +183,27 @@ func WithoutAllRecords() MarshalOption {
 	}
 }
 
+// bulkTimestampLayout is the format of the @timestamp field, parsed by the default elasticsearch date mapping
+const bulkTimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// WithBulkTimestamp adds the @timestamp field expected by the elasticsearch/opensearch tooling, the time of the
+// response in utc with millisecond precision
+func WithBulkTimestamp() MarshalOption {
+	return func(d *ResponseData) {
+		if d.DNSData != nil && !d.Timestamp.IsZero() {
+			d.BulkTimestamp = d.Timestamp.UTC().Format(bulkTimestampLayout)
+		}
+	}
+}
+
 func (d *ResponseData) JSON(options ...MarshalOption) (string, error) {
 	dataToMarshal := *d
 	for _, option := range options {
-		option(d)
+		option(&dataToMarshal)
+	}
+	if dataToMarshal.compactJSON {
+		b, err := compactJSON(dataToMarshal)
+		return string(b), err
 	}
 	b, err := json.Marshal(dataToMarshal)
 	return string(b), err
@@ -90,21 +232,26 @@ var DefaultResolvers = []string{
 
 // New creates a dns resolver
 func New(options Options) (*DNSX, error) {
-	retryablednsOptions := retryabledns.Options{
-		BaseResolvers: options.BaseResolvers,
-		MaxRetries:    options.MaxRetries,
-		Hostsfile:     options.Hostsfile,
+	ids, err := newQueryIDs(options.QueryIDMode, options.QueryID)
+	if err != nil {
+		return nil, err
 	}
+	options.ids = ids
 
-	dnsClient, err := retryabledns.NewWithOptions(retryablednsOptions)
+	dnsClient, err := newClient(&options, options.BaseResolvers)
 	if err != nil {
 		return nil, err
 	}
-	dnsClient.TCPFallback = true
 	dnsx := &DNSX{dnsClient: dnsClient, Options: &options}
 	if options.OutputCDN {
 		dnsx.cdn = cdncheck.New()
 	}
+	if options.Offline {
+		dnsx.knownHosts, err = loadHostsFile()
+		if err != nil {
+			return nil, err
+		}
+	}
 	return dnsx, nil
 }
 
@@ -114,7 +261,7 @@ func (d *DNSX) Lookup(hostname string) ([]string, error) {
 		return []string{hostname}, nil
 	}
 
-	dnsdata, err := d.dnsClient.Resolve(hostname)
+	dnsdata, err := d.client().Resolve(hostname)
 	if err != nil {
 		return nil, err
 	}
@@ -128,11 +275,26 @@ func (d *DNSX) Lookup(hostname string) ([]string, error) {
 
 // QueryOne performs a DNS question of a specified type and returns raw responses
 func (d *DNSX) QueryOne(hostname string) (*retryabledns.DNSData, error) {
-	return d.dnsClient.Query(hostname, d.Options.QuestionTypes[0])
+	hostname = normalizeIP(hostname)
+	return d.client().Query(hostname, d.Options.QuestionTypes[0])
 }
 
 // QueryMultiple performs a DNS question of the specified types and returns raw responses
 func (d *DNSX) QueryMultiple(hostname string) (*retryabledns.DNSData, error) {
+	return d.QueryMultipleContext(context.Background(), hostname)
+}
+
+// QueryMultipleContext performs the dns questions like QueryMultiple, the queries ending with the context
+func (d *DNSX) QueryMultipleContext(ctx context.Context, hostname string) (*retryabledns.DNSData, error) {
+	hostname = normalizeIP(hostname)
+	if d.Options.ResolverHash {
+		return d.client().queryMultiple(ctx, hostname, d.questionTypes(hostname), parseResolver(d.hashedResolver(hostname)))
+	}
+	return d.client().queryMultiple(ctx, hostname, d.questionTypes(hostname), nil)
+}
+
+// questionTypes returns the question types to use for the hostname
+func (d *DNSX) questionTypes(hostname string) []uint16 {
 	// Omit PTR queries unless the input is an IP address to decrease execution time, as PTR queries can lead to timeouts.
 	filteredQuestionTypes := d.Options.QuestionTypes
 	if d.Options.QueryAll {
@@ -143,15 +305,15 @@ func (d *DNSX) QueryMultiple(hostname string) (*retryabledns.DNSData, error) {
 			filteredQuestionTypes = []uint16{miekgdns.TypePTR}
 		}
 	}
-	return d.dnsClient.QueryMultiple(hostname, filteredQuestionTypes)
+	return filteredQuestionTypes
 }
 
-// Trace performs a DNS trace of the specified types and returns raw responses
-func (d *DNSX) Trace(hostname string) (*retryabledns.TraceData, error) {
-	return d.dnsClient.Trace(hostname, d.Options.QuestionTypes[0], d.Options.TraceMaxRecursion)
+// AXFR performs a zone transfer of the hostname from its name servers and the resolvers
+func (d *DNSX) AXFR(hostname string) (*retryabledns.AXFRData, error) {
+	return d.AXFRContext(context.Background(), hostname)
 }
 
-// Trace performs a DNS trace of the specified types and returns raw responses
-func (d *DNSX) AXFR(hostname string) (*retryabledns.AXFRData, error) {
-	return d.dnsClient.AXFR(hostname)
+// AXFRContext performs the zone transfer like AXFR, the transfers ending with the context
+func (d *DNSX) AXFRContext(ctx context.Context, hostname string) (*retryabledns.AXFRData, error) {
+	return d.client().axfr(ctx, hostname)
 }
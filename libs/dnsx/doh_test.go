@@ -0,0 +1,81 @@
+package dnsx
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoHUserAgent(t *testing.T) {
+	userAgents := make(chan []string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents <- r.Header["User-Agent"]
+		body, _ := io.ReadAll(r.Body)
+		msg := &miekgdns.Msg{}
+		_ = msg.Unpack(body)
+		resp := &miekgdns.Msg{}
+		resp.SetReply(msg)
+		packed, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(packed)
+	}))
+	defer server.Close()
+
+	tests := map[string][]string{
+		"dnsx/test": {"dnsx/test"},
+		"":          nil,
+	}
+	for userAgent, expected := range tests {
+		options := DefaultOptions
+		options.BaseResolvers = []string{"doh:" + server.URL}
+		options.MaxRetries = 1
+		options.DoHUserAgent = &userAgent
+		dnsX, err := New(options)
+		require.Nil(t, err, "could not create dnsx")
+		_, _ = dnsX.QueryOne("example.com")
+		require.Equal(t, expected, <-userAgents, "invalid user agent")
+	}
+}
+
+func TestDoHQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		msg := &miekgdns.Msg{}
+		_ = msg.Unpack(body)
+		resp := &miekgdns.Msg{}
+		resp.SetReply(msg)
+		if msg.Question[0].Qtype == miekgdns.TypeA {
+			a, _ := miekgdns.NewRR(msg.Question[0].Name + " 60 IN A 192.0.2.1")
+			resp.Answer = append(resp.Answer, a)
+		}
+		packed, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(packed)
+	}))
+	defer server.Close()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{"doh:" + server.URL}
+	options.MaxRetries = 1
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	ips, err := dnsX.Lookup("example.com")
+	require.Nil(t, err, "could not query the doh resolver")
+	require.Equal(t, []string{"192.0.2.1"}, ips)
+}
+
+func TestDoHProxy(t *testing.T) {
+	// the doh requests go through the proxy of the environment, with the user agent set
+	userAgent := "dnsx/test"
+	options := DefaultOptions
+	options.DoHUserAgent = &userAgent
+	transport, err := newTransport(&options)
+	require.Nil(t, err, "could not create transport")
+	wrapped, ok := transport.httpClient.Transport.(*userAgentTransport)
+	require.True(t, ok, "could not set the user agent on the http client")
+	require.NotNil(t, wrapped.transport.Proxy, "proxy of the environment not used")
+}
@@ -0,0 +1,232 @@
+package dnsx
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	// doqScheme prefixes the DNS over QUIC resolvers (RFC 9250), eg. quic://dns.adguard-dns.com:853
+	doqScheme = "quic://"
+	// doqALPN is the application protocol of DNS over QUIC
+	doqALPN = "doq"
+	// tlsAlertNoApplicationProtocol is the tls alert sent by the servers not supporting any offered alpn
+	tlsAlertNoApplicationProtocol = 120
+)
+
+// DoQResolver is a DNS over QUIC resolver
+type DoQResolver struct {
+	Host string
+	Port string
+}
+
+func (r *DoQResolver) String() string {
+	return doqScheme + net.JoinHostPort(r.Host, r.Port)
+}
+
+// IsDoQResolver returns true if the resolver is a DNS over QUIC one
+func IsDoQResolver(resolver string) bool {
+	return strings.HasPrefix(strings.ToLower(resolver), doqScheme)
+}
+
+// parseDoQResolver parses a quic://host[:port] resolver, the port defaulting to 853
+func parseDoQResolver(resolver string) *DoQResolver {
+	address := resolver[len(doqScheme):]
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		return &DoQResolver{Host: host, Port: port}
+	}
+	return &DoQResolver{Host: address, Port: "853"}
+}
+
+// doqTransport exchanges the dns messages with the DNS over QUIC servers, a stream per query on a connection
+// kept per server. The server hostnames are resolved by the bootstrap resolver when set
+type doqTransport struct {
+	tlsConfig *tls.Config
+	resolver  *net.Resolver
+
+	mutex sync.Mutex
+	conns map[string]quic.Connection
+	// dials are the handshakes in progress, shared by the queries to the same server
+	dials  map[string]*doqDial
+	closed bool
+}
+
+// doqDial is a handshake in progress with a server
+type doqDial struct {
+	done chan struct{}
+	conn quic.Connection
+	err  error
+}
+
+var errDoQClosed = errors.New("doq transport closed")
+
+func newDoQTransport(resolver *net.Resolver) *doqTransport {
+	return &doqTransport{
+		tlsConfig: &tls.Config{},
+		resolver:  resolver,
+		conns:     make(map[string]quic.Connection),
+		dials:     make(map[string]*doqDial),
+	}
+}
+
+// exchange sends the message to the server and returns its response
+func (t *doqTransport) exchange(ctx context.Context, msg *miekgdns.Msg, resolver *DoQResolver) (*miekgdns.Msg, int, error) {
+	conn, err := t.connection(ctx, resolver)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, size, err := exchangeStream(ctx, conn, msg)
+	// the server may have closed the idle connection, a new one is dialed once
+	if err != nil && conn.Context().Err() != nil && ctx.Err() == nil {
+		if conn, err = t.connection(ctx, resolver); err != nil {
+			return nil, 0, err
+		}
+		resp, size, err = exchangeStream(ctx, conn, msg)
+	}
+	return resp, size, err
+}
+
+// connection returns the open connection to the server, dialing it if needed. The handshake runs without the
+// lock, the queries to the same server waiting for it while the ones to the other servers go on
+func (t *doqTransport) connection(ctx context.Context, resolver *DoQResolver) (quic.Connection, error) {
+	address := net.JoinHostPort(resolver.Host, resolver.Port)
+	t.mutex.Lock()
+	if t.closed {
+		t.mutex.Unlock()
+		return nil, errDoQClosed
+	}
+	if conn, ok := t.conns[address]; ok && conn.Context().Err() == nil {
+		t.mutex.Unlock()
+		return conn, nil
+	}
+	if dial, ok := t.dials[address]; ok {
+		t.mutex.Unlock()
+		select {
+		case <-dial.done:
+			return dial.conn, dial.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	dial := &doqDial{done: make(chan struct{})}
+	t.dials[address] = dial
+	t.mutex.Unlock()
+
+	dial.conn, dial.err = t.dial(ctx, resolver)
+
+	t.mutex.Lock()
+	delete(t.dials, address)
+	if dial.err == nil {
+		if t.closed {
+			_ = dial.conn.CloseWithError(0, "")
+			dial.conn, dial.err = nil, errDoQClosed
+		} else {
+			t.conns[address] = dial.conn
+		}
+	}
+	t.mutex.Unlock()
+	close(dial.done)
+	return dial.conn, dial.err
+}
+
+// dial performs the handshake with the server, checking that the doq alpn was negotiated
+func (t *doqTransport) dial(ctx context.Context, resolver *DoQResolver) (quic.Connection, error) {
+	tlsConfig := t.tlsConfig.Clone()
+	tlsConfig.ServerName = resolver.Host
+	tlsConfig.NextProtos = []string{doqALPN}
+	dialAddress, err := t.resolve(ctx, resolver)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := quic.DialAddr(ctx, dialAddress, tlsConfig, nil)
+	if err != nil {
+		var transportErr *quic.TransportError
+		if errors.As(err, &transportErr) && transportErr.ErrorCode == quic.TransportErrorCode(0x100+tlsAlertNoApplicationProtocol) {
+			return nil, fmt.Errorf("doq handshake with %s failed: the server does not support the %s alpn", resolver, doqALPN)
+		}
+		return nil, fmt.Errorf("doq handshake with %s failed: %w", resolver, err)
+	}
+	if protocol := conn.ConnectionState().TLS.NegotiatedProtocol; protocol != doqALPN {
+		_ = conn.CloseWithError(0, "")
+		return nil, fmt.Errorf("doq handshake with %s failed: the server negotiated the %q alpn", resolver, protocol)
+	}
+	return conn, nil
+}
+
+// close closes the connections to the servers, the following queries failing
+func (t *doqTransport) close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.closed = true
+	for address, conn := range t.conns {
+		_ = conn.CloseWithError(0, "")
+		delete(t.conns, address)
+	}
+}
+
+// resolve returns the address of the server, its hostname being resolved by the bootstrap resolver when set
+func (t *doqTransport) resolve(ctx context.Context, resolver *DoQResolver) (string, error) {
+	if t.resolver == nil || net.ParseIP(resolver.Host) != nil {
+		return net.JoinHostPort(resolver.Host, resolver.Port), nil
+	}
+	addrs, err := t.resolver.LookupNetIP(ctx, "ip", resolver.Host)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("no address found for %s", resolver.Host)
+	}
+	return net.JoinHostPort(addrs[0].String(), resolver.Port), nil
+}
+
+// exchangeStream sends the query on a new stream of the connection. The message id is 0 and the messages are
+// prefixed with their 2 bytes length, the end of the query being signaled by closing the stream (RFC 9250 4.2)
+func exchangeStream(ctx context.Context, conn quic.Connection, msg *miekgdns.Msg) (*miekgdns.Msg, int, error) {
+	query := msg.Copy()
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+	data := make([]byte, 2+len(packed))
+	binary.BigEndian.PutUint16(data, uint16(len(packed)))
+	copy(data[2:], packed)
+	if _, err := stream.Write(data); err != nil {
+		stream.CancelRead(0)
+		return nil, 0, err
+	}
+	_ = stream.Close()
+
+	var length uint16
+	if err := binary.Read(stream, binary.BigEndian, &length); err != nil {
+		return nil, 0, err
+	}
+	data = make([]byte, length)
+	if _, err := io.ReadFull(stream, data); err != nil {
+		return nil, 0, err
+	}
+	resp := &miekgdns.Msg{}
+	if err := resp.Unpack(data); err != nil {
+		return nil, 0, err
+	}
+	resp.Id = msg.Id
+	return resp, len(data), nil
+}
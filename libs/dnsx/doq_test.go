@@ -0,0 +1,170 @@
+package dnsx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/require"
+)
+
+// startDoQServer serves a single A record over doq with the given alpn, returning its address and certificate
+func startDoQServer(t *testing.T, alpn string) (string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err, "could not generate key")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err, "could not create certificate")
+	certificate, _ := x509.ParseCertificate(der)
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{alpn},
+	}
+	listener, err := quic.ListenAddr("127.0.0.1:0", tlsConfig, nil)
+	require.Nil(t, err, "could not listen")
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					stream, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					var length uint16
+					_ = binary.Read(stream, binary.BigEndian, &length)
+					data := make([]byte, length)
+					_, _ = io.ReadFull(stream, data)
+					query := &miekgdns.Msg{}
+					if query.Unpack(data) != nil || query.Id != 0 {
+						stream.CancelWrite(0)
+						continue
+					}
+					resp := &miekgdns.Msg{}
+					resp.SetReply(query)
+					resp.Answer = append(resp.Answer, &miekgdns.A{
+						Hdr: miekgdns.RR_Header{Name: query.Question[0].Name, Rrtype: miekgdns.TypeA, Class: miekgdns.ClassINET, Ttl: 300},
+						A:   net.ParseIP("192.0.2.1"),
+					})
+					packed, _ := resp.Pack()
+					_ = binary.Write(stream, binary.BigEndian, uint16(len(packed)))
+					_, _ = stream.Write(packed)
+					_ = stream.Close()
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), certificate
+}
+
+func TestDoQClient(t *testing.T) {
+	address, certificate := startDoQServer(t, doqALPN)
+	options := DefaultOptions
+	options.Timeout = 2 * time.Second
+	options.MaxRetries = 2
+	client, err := newClient(&options, []string{"quic://" + address, "udp:127.0.0.1:1"})
+	require.Nil(t, err, "could not create doq client")
+	client.transport.doq.tlsConfig.RootCAs = x509.NewCertPool()
+	client.transport.doq.tlsConfig.RootCAs.AddCert(certificate)
+	doqResolver := parseResolver("quic://" + address)
+
+	dnsdata, err := client.QueryMultipleWithResolver("example.com", []uint16{miekgdns.TypeA}, doqResolver)
+	require.Nil(t, err, "could not query over doq")
+	require.Equal(t, []string{"192.0.2.1"}, dnsdata.A, "could not match answer")
+	require.Equal(t, []string{"quic://" + address}, dnsdata.Resolver, "could not match resolver")
+	require.Equal(t, "NOERROR", dnsdata.StatusCode, "could not match rcode")
+	// the following queries reuse the connection
+	_, err = client.QueryMultipleWithResolver("www.example.com", []uint16{miekgdns.TypeA}, doqResolver)
+	require.Nil(t, err, "could not query again over doq")
+	require.Len(t, client.transport.doq.conns, 1, "connection not reused")
+
+	// the doq resolver can be mixed with resolvers of other protocols, the closed udp port being retried on the doq one
+	dnsdata, err = client.QueryMultiple("mixed.example.com", []uint16{miekgdns.TypeA})
+	require.Nil(t, err, "could not query the mixed resolvers")
+	require.Equal(t, []string{"192.0.2.1"}, dnsdata.A, "could not match answer of the mixed resolvers")
+
+	client.close()
+	require.Empty(t, client.transport.doq.conns, "connection not closed")
+	_, err = client.QueryMultipleWithResolver("closed.example.com", []uint16{miekgdns.TypeA}, doqResolver)
+	require.NotNil(t, err, "query sent on a closed transport")
+}
+
+func TestDoQConcurrentDial(t *testing.T) {
+	address, certificate := startDoQServer(t, doqALPN)
+	transport := newDoQTransport(nil)
+	transport.tlsConfig.RootCAs = x509.NewCertPool()
+	transport.tlsConfig.RootCAs.AddCert(certificate)
+	resolver := parseDoQResolver("quic://" + address)
+
+	// the queries started at the same time share the handshake
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conns := make(chan quic.Connection, 10)
+	for i := 0; i < cap(conns); i++ {
+		go func() {
+			conn, _ := transport.connection(ctx, resolver)
+			conns <- conn
+		}()
+	}
+	first := <-conns
+	require.NotNil(t, first, "could not connect")
+	for i := 1; i < cap(conns); i++ {
+		require.Equal(t, first, <-conns, "handshake not shared")
+	}
+	transport.close()
+	require.NotNil(t, first.Context().Err(), "connection not closed")
+}
+
+func TestDoQOverride(t *testing.T) {
+	address, certificate := startDoQServer(t, doqALPN)
+	options := DefaultOptions
+	options.BaseResolvers = []string{"udp:127.0.0.1:1"}
+	options.MaxRetries = 1
+	options.Timeout = 2 * time.Second
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	defer dnsX.Close()
+	dnsX.client().transport.doq.tlsConfig.RootCAs = x509.NewCertPool()
+	dnsX.client().transport.doq.tlsConfig.RootCAs.AddCert(certificate)
+
+	// the doq override of a host goes to the doq server, the pool having plain resolvers only
+	dnsdata, err := dnsX.QueryMultipleWithResolver("example.com", "quic://"+address)
+	require.Nil(t, err, "could not query the doq override")
+	require.Equal(t, []string{"192.0.2.1"}, dnsdata.A, "could not match answer of the doq override")
+}
+
+func TestDoQALPN(t *testing.T) {
+	address, certificate := startDoQServer(t, "h3")
+	transport := newDoQTransport(nil)
+	transport.tlsConfig.RootCAs = x509.NewCertPool()
+	transport.tlsConfig.RootCAs.AddCert(certificate)
+	msg := &miekgdns.Msg{}
+	msg.SetQuestion("example.com.", miekgdns.TypeA)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, _, err := transport.exchange(ctx, msg, parseDoQResolver("quic://"+address))
+	require.ErrorContains(t, err, "does not support the doq alpn", "could not match alpn error")
+
+	require.Equal(t, &DoQResolver{Host: "dns.example.com", Port: "853"}, parseResolver("quic://dns.example.com"), "could not parse default port")
+}
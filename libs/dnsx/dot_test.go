@@ -0,0 +1,66 @@
+package dnsx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// startDoTServer serves a single A record over dot with a self-signed certificate, returning its address
+func startDoTServer(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err, "could not generate key")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err, "could not create certificate")
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{Listener: listener, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, query *miekgdns.Msg) {
+		resp := &miekgdns.Msg{}
+		resp.SetReply(query)
+		resp.Answer = append(resp.Answer, &miekgdns.A{
+			Hdr: miekgdns.RR_Header{Name: query.Question[0].Name, Rrtype: miekgdns.TypeA, Class: miekgdns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+		_ = w.WriteMsg(resp)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return listener.Addr().String()
+}
+
+func TestDoTInsecure(t *testing.T) {
+	address := startDoTServer(t)
+	options := DefaultOptions
+	options.BaseResolvers = []string{"dot:" + address}
+	options.MaxRetries = 1
+	options.Timeout = 2 * time.Second
+
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	_, err = dnsX.Lookup("example.com")
+	require.NotNil(t, err, "self-signed certificate accepted")
+
+	options.DoTInsecure = true
+	dnsX, err = New(options)
+	require.Nil(t, err, "could not create dnsx")
+	ips, err := dnsX.Lookup("example.com")
+	require.Nil(t, err, "could not query the dot resolver")
+	require.Equal(t, []string{"192.0.2.1"}, ips)
+}
@@ -0,0 +1,45 @@
+package dnsx
+
+import (
+	"fmt"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// DS is a delegation signer record published by the parent zone, the digest of a key signing key of the child
+type DS struct {
+	KeyTag         uint16 `json:"key_tag" csv:"key_tag"`
+	Algorithm      uint8  `json:"algorithm" csv:"algorithm"`
+	AlgorithmName  string `json:"algorithm_name,omitempty" csv:"algorithm_name"`
+	DigestType     uint8  `json:"digest_type" csv:"digest_type"`
+	DigestTypeName string `json:"digest_type_name,omitempty" csv:"digest_type_name"`
+	// Digest is the hex encoded digest of the key
+	Digest string `json:"digest" csv:"digest"`
+}
+
+// String returns the key tag, algorithm and digest type of the record (eg. tag 370 ECDSAP256SHA256 (13) SHA256 (2))
+func (d DS) String() string {
+	return fmt.Sprintf("tag %d %s (%d) %s (%d)", d.KeyTag, d.AlgorithmName, d.Algorithm, d.DigestTypeName, d.DigestType)
+}
+
+// newDS returns the delegation signer of the DS record
+func newDS(ds *miekgdns.DS) DS {
+	return DS{
+		KeyTag:         ds.KeyTag,
+		Algorithm:      ds.Algorithm,
+		AlgorithmName:  miekgdns.AlgorithmToString[ds.Algorithm],
+		DigestType:     ds.DigestType,
+		DigestTypeName: miekgdns.HashToString[ds.DigestType],
+		Digest:         strings.ToLower(ds.Digest),
+	}
+}
+
+// formatDS returns the records as strings
+func formatDS(signers []DS) []string {
+	records := make([]string, 0, len(signers))
+	for _, signer := range signers {
+		records = append(records, signer.String())
+	}
+	return records
+}
@@ -0,0 +1,31 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDS(t *testing.T) {
+	d := &ResponseData{DNSData: &retryabledns.DNSData{
+		AllRecords: []string{
+			"example.com.\t300\tIN\tNS\ta.iana-servers.net.",
+			"example.com.\t86400\tIN\tDS\t370 13 2 BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C",
+			"example.com.\t86399\tIN\tDS\t370 13 2 BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C",
+		},
+		RawResp: &miekgdns.Msg{},
+	}}
+	d.ParseRawResp()
+	require.Equal(t, []DS{{
+		KeyTag:         370,
+		Algorithm:      13,
+		AlgorithmName:  "ECDSAP256SHA256",
+		DigestType:     2,
+		DigestTypeName: "SHA256",
+		Digest:         "be74359954660069d5c63d200c39f5603827d7dd02b56f120ee9f3a86764247c",
+	}}, d.DS, "could not parse ds records")
+	require.Equal(t, []string{"tag 370 ECDSAP256SHA256 (13) SHA256 (2)"}, d.Records([]uint16{miekgdns.TypeDS}))
+	require.True(t, d.HasRecords(), "ds records not counted")
+}
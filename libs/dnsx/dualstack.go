@@ -0,0 +1,83 @@
+package dnsx
+
+import (
+	"net"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// ReverseCheck is an address of a host with its PTR names and whether one of them resolves back to the
+// address (forward-confirmed reverse DNS)
+type ReverseCheck struct {
+	IP        string   `json:"ip" csv:"ip"`
+	PTR       []string `json:"ptr,omitempty" csv:"ptr"`
+	Confirmed bool     `json:"confirmed" csv:"confirmed"`
+}
+
+// DualStack is the reverse DNS of the IPv4 and IPv6 addresses of a host, with the inconsistencies found
+// between and within the two address families
+type DualStack struct {
+	IPv4   []ReverseCheck `json:"ipv4" csv:"ipv4"`
+	IPv6   []ReverseCheck `json:"ipv6" csv:"ipv6"`
+	Issues []string       `json:"issues,omitempty" csv:"issues"`
+}
+
+// CheckReverse queries the PTR names of the address and the A or AAAA records of these names, matching the
+// family of the address
+func (d *DNSX) CheckReverse(ip string) ReverseCheck {
+	return checkReverse(ip, d.client().QueryMultiple)
+}
+
+func checkReverse(ip string, query func(host string, questionTypes []uint16) (*retryabledns.DNSData, error)) ReverseCheck {
+	check := ReverseCheck{IP: ip}
+	address := net.ParseIP(ip)
+	dnsdata, err := query(ip, []uint16{miekgdns.TypePTR})
+	if err != nil || dnsdata == nil || address == nil {
+		return check
+	}
+	forwardType := miekgdns.TypeAAAA
+	if address.To4() != nil {
+		forwardType = miekgdns.TypeA
+	}
+	for _, name := range dnsdata.PTR {
+		check.PTR = append(check.PTR, strings.ToLower(strings.TrimSuffix(name, ".")))
+	}
+	check.PTR = sliceutil.Dedupe(check.PTR)
+	for _, name := range check.PTR {
+		forward, err := query(name, []uint16{forwardType})
+		if err != nil || forward == nil {
+			continue
+		}
+		for _, value := range append(forward.A, forward.AAAA...) {
+			if address.Equal(net.ParseIP(value)) {
+				check.Confirmed = true
+				return check
+			}
+		}
+	}
+	return check
+}
+
+// NewDualStack gathers the reverse checks of the two address families and flags the host missing one of
+// them, and the addresses without PTR record or whose PTR names don't resolve back to them
+func NewDualStack(ipv4, ipv6 []ReverseCheck) *DualStack {
+	dualStack := &DualStack{IPv4: ipv4, IPv6: ipv6}
+	if len(ipv4) == 0 {
+		dualStack.Issues = append(dualStack.Issues, "no-ipv4")
+	}
+	if len(ipv6) == 0 {
+		dualStack.Issues = append(dualStack.Issues, "no-ipv6")
+	}
+	for _, check := range append(append([]ReverseCheck{}, ipv4...), ipv6...) {
+		switch {
+		case len(check.PTR) == 0:
+			dualStack.Issues = append(dualStack.Issues, "no-ptr "+check.IP)
+		case !check.Confirmed:
+			dualStack.Issues = append(dualStack.Issues, "fcrdns-fail "+check.IP)
+		}
+	}
+	return dualStack
+}
@@ -0,0 +1,39 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReverse(t *testing.T) {
+	records := map[string]*retryabledns.DNSData{
+		"192.0.2.1":         {PTR: []string{"Host.example.com."}},
+		"host.example.com":  {A: []string{"192.0.2.1"}, AAAA: []string{"2001:db8::1"}},
+		"2001:db8::1":       {PTR: []string{"host.example.com"}},
+		"2001:db8::2":       {PTR: []string{"other.example.com"}},
+		"other.example.com": {AAAA: []string{"2001:db8::3"}},
+		"192.0.2.2":         {},
+	}
+	var queried []uint16
+	query := func(host string, questionTypes []uint16) (*retryabledns.DNSData, error) {
+		queried = append(queried, questionTypes...)
+		if dnsdata, ok := records[host]; ok {
+			return dnsdata, nil
+		}
+		return &retryabledns.DNSData{}, nil
+	}
+
+	ipv4 := []ReverseCheck{checkReverse("192.0.2.1", query), checkReverse("192.0.2.2", query)}
+	require.Equal(t, ReverseCheck{IP: "192.0.2.1", PTR: []string{"host.example.com"}, Confirmed: true}, ipv4[0], "could not confirm ipv4")
+	require.Equal(t, []uint16{miekgdns.TypePTR, miekgdns.TypeA}, queried[:2], "forward query of the wrong family")
+	ipv6 := []ReverseCheck{checkReverse("2001:db8::1", query), checkReverse("2001:db8::2", query)}
+	require.True(t, ipv6[0].Confirmed, "could not confirm ipv6")
+	require.False(t, ipv6[1].Confirmed, "unconfirmed ptr accepted")
+
+	dualStack := NewDualStack(ipv4, ipv6)
+	require.Equal(t, []string{"no-ptr 192.0.2.2", "fcrdns-fail 2001:db8::2"}, dualStack.Issues, "could not match issues")
+	require.Equal(t, []string{"no-ipv6"}, NewDualStack(ipv4[:1], nil).Issues, "missing family not flagged")
+}
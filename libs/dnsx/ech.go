@@ -0,0 +1,89 @@
+package dnsx
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// ECHConfig is the Encrypted Client Hello configuration advertised in the ech parameter of an HTTPS or SVCB record
+type ECHConfig struct {
+	Record   string `json:"record" csv:"record"`
+	Priority uint16 `json:"priority" csv:"priority"`
+	Target   string `json:"target" csv:"target"`
+	// ConfigList is the raw ECHConfigList, base64 encoded like in the presentation format of the record
+	ConfigList string `json:"config_list" csv:"config_list"`
+}
+
+func (c ECHConfig) String() string {
+	return fmt.Sprintf("%s %d %s %s", c.Record, c.Priority, c.Target, c.ConfigList)
+}
+
+// QueryHTTPS queries the HTTPS records of the host, with a large EDNS buffer as the ECH configurations
+// hardly fit in a plain udp response
+func (d *DNSX) QueryHTTPS(hostname string) (*miekgdns.Msg, error) {
+	return d.QueryHTTPSContext(context.Background(), hostname, "", nil)
+}
+
+// QueryHTTPSContext queries like QueryHTTPS with the resolver override or the profile of the host, the
+// configured resolvers being used when both are empty. The query ends with the context
+func (d *DNSX) QueryHTTPSContext(ctx context.Context, hostname, resolver string, profile *QueryProfile) (*miekgdns.Msg, error) {
+	msg := &miekgdns.Msg{}
+	msg.SetQuestion(miekgdns.Fqdn(hostname), miekgdns.TypeHTTPS)
+	msg.SetEdns0(4096, false)
+	return d.doWith(ctx, msg, resolver, profile)
+}
+
+// ECHConfigs returns the ECH configurations of the HTTPS and SVCB records in the answer of the response
+func ECHConfigs(msg *miekgdns.Msg) []ECHConfig {
+	if msg == nil {
+		return nil
+	}
+	var configs []ECHConfig
+	add := func(record string, svcb *miekgdns.SVCB) {
+		for _, value := range svcb.Value {
+			if ech, ok := value.(*miekgdns.SVCBECHConfig); ok {
+				configs = append(configs, ECHConfig{
+					Record:     record,
+					Priority:   svcb.Priority,
+					Target:     svcb.Target,
+					ConfigList: base64.StdEncoding.EncodeToString(ech.ECH),
+				})
+			}
+		}
+	}
+	for _, record := range msg.Answer {
+		switch rr := record.(type) {
+		case *miekgdns.HTTPS:
+			add("HTTPS", &rr.SVCB)
+		case *miekgdns.SVCB:
+			add("SVCB", rr)
+		}
+	}
+	return configs
+}
+
+// ParseECH sets the ECH configurations advertised in the HTTPS and SVCB records of the response
+func (d *ResponseData) ParseECH(msg *miekgdns.Msg) {
+	if d.DNSData == nil {
+		return
+	}
+	d.ECH = ECHConfigs(msg)
+}
+
+// ParseECHRecords sets the ECH configurations advertised in the HTTPS and SVCB records already returned, when
+// these types were queried with the others
+func (d *ResponseData) ParseECHRecords() {
+	if d.DNSData == nil {
+		return
+	}
+	msg := &miekgdns.Msg{}
+	for _, record := range d.AllRecords {
+		if rr, err := miekgdns.NewRR(record); err == nil && rr != nil {
+			msg.Answer = append(msg.Answer, rr)
+		}
+	}
+	d.ParseECH(msg)
+}
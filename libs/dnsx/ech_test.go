@@ -0,0 +1,82 @@
+package dnsx
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseECH(t *testing.T) {
+	https, err := miekgdns.NewRR(`example.com. 300 IN HTTPS 1 . alpn="h2,h3" ech="AEX+DQBBpQAgACB/e2u2"`)
+	require.Nil(t, err, "could not parse https record")
+	plain, _ := miekgdns.NewRR(`example.com. 300 IN HTTPS 2 cdn.example.net. alpn="h2"`)
+	svcb, _ := miekgdns.NewRR(`_dns.example.com. 300 IN SVCB 1 dns.example.com. alpn="dot" ech="AEX+DQBB"`)
+	msg := &miekgdns.Msg{Answer: []miekgdns.RR{https, plain, svcb}}
+
+	d := &ResponseData{DNSData: &retryabledns.DNSData{}}
+	d.ParseECH(msg)
+	expected := []ECHConfig{
+		{Record: "HTTPS", Priority: 1, Target: ".", ConfigList: "AEX+DQBBpQAgACB/e2u2"},
+		{Record: "SVCB", Priority: 1, Target: "dns.example.com.", ConfigList: "AEX+DQBB"},
+	}
+	require.Equal(t, expected, d.ECH, "could not match ech configs")
+	require.Equal(t, "HTTPS 1 . AEX+DQBBpQAgACB/e2u2", d.ECH[0].String(), "could not match ech label")
+	require.Empty(t, ECHConfigs(&miekgdns.Msg{Answer: []miekgdns.RR{plain}}), "ech reported without ech parameter")
+}
+
+func TestParseECHRecords(t *testing.T) {
+	d := &ResponseData{DNSData: &retryabledns.DNSData{AllRecords: []string{
+		"example.com.\t300\tIN\tA\t192.0.2.1",
+		`example.com.	300	IN	HTTPS	1 . alpn="h2" ech="AEX+DQBB"`,
+	}}}
+	d.ParseECHRecords()
+	require.Equal(t, []ECHConfig{{Record: "HTTPS", Priority: 1, Target: ".", ConfigList: "AEX+DQBB"}}, d.ECH, "could not match ech configs of the records")
+}
+
+func TestQueryHTTPSContext(t *testing.T) {
+	// each server advertises its own ech config
+	startServer := func(ech string) string {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.Nil(t, err, "could not listen")
+		server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+			m := &miekgdns.Msg{}
+			m.SetReply(r)
+			if r.Question[0].Qtype == miekgdns.TypeHTTPS {
+				rr, _ := miekgdns.NewRR(r.Question[0].Name + ` 60 IN HTTPS 1 . ech="` + ech + `"`)
+				m.Answer = append(m.Answer, rr)
+			}
+			_ = w.WriteMsg(m)
+		})}
+		go func() { _ = server.ActivateAndServe() }()
+		t.Cleanup(func() { _ = server.Shutdown() })
+		return conn.LocalAddr().String()
+	}
+	configured, override := startServer("AAAA"), startServer("BBBB")
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{configured}
+	options.Hostsfile = false
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	configList := func(msg *miekgdns.Msg, err error) string {
+		require.Nil(t, err, "could not query")
+		configs := ECHConfigs(msg)
+		require.Len(t, configs, 1, "could not match ech configs")
+		return configs[0].ConfigList
+	}
+
+	require.Equal(t, "AAAA", configList(dnsX.QueryHTTPS("example.com")), "configured resolver not queried")
+	require.Equal(t, "BBBB", configList(dnsX.QueryHTTPSContext(context.Background(), "example.com", override, nil)), "resolver override not queried")
+	profile, err := dnsX.NewQueryProfile(nil, []string{override}, false)
+	require.Nil(t, err, "could not create profile")
+	require.Equal(t, "BBBB", configList(dnsX.QueryHTTPSContext(context.Background(), "example.com", "", profile)), "profile resolvers not queried")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = dnsX.QueryHTTPSContext(ctx, "example.com", "", nil)
+	require.ErrorIs(t, err, context.Canceled, "query not ended with the context")
+}
@@ -0,0 +1,189 @@
+package dnsx
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// RcodeBadVersName is the name of the BADVERS rcode, which shares its value with BADSIG
+const RcodeBadVersName = "BADVERS"
+
+// ExtendedError is an extended dns error (RFC 8914) carried in the OPT record
+type ExtendedError struct {
+	Code uint16 `json:"code"`
+	Name string `json:"name,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+func (e ExtendedError) String() string {
+	if e.Text != "" {
+		return fmt.Sprintf("%d (%s): %s", e.Code, e.Name, e.Text)
+	}
+	return fmt.Sprintf("%d (%s)", e.Code, e.Name)
+}
+
+// EDNS holds the edns fields of the OPT record of a response, the extended rcode being the upper 8 bits
+// of the rcode. It is null in the json output when the response carries no OPT record
+type EDNS struct {
+	Version       uint8  `json:"version"`
+	DO            bool   `json:"do"`
+	ExtendedRcode uint8  `json:"extended_rcode"`
+	UDPSize       uint16 `json:"udp_size"`
+	// NSID is the identifier of the server (RFC 5001), returned to the queries requesting it
+	NSID string `json:"nsid,omitempty"`
+	// Cookie is the hex encoded client and server cookie (RFC 7873)
+	Cookie string `json:"cookie,omitempty"`
+	// ClientSubnet is the client subnet echoed back with its scope (RFC 7871), eg. 192.0.2.0/24/0
+	ClientSubnet string       `json:"client_subnet,omitempty"`
+	Options      []EDNSOption `json:"options,omitempty"`
+}
+
+func (e *EDNS) String() string {
+	s := fmt.Sprintf("version %d, do %t, extended rcode %d, udp size %d", e.Version, e.DO, e.ExtendedRcode, e.UDPSize)
+	if e.NSID != "" {
+		s += ", nsid " + e.NSID
+	}
+	return s
+}
+
+// EDNSOption is an option of the OPT record of a response
+type EDNSOption struct {
+	Code uint16 `json:"code"`
+	Name string `json:"name,omitempty"`
+	// Data is the content of the option in presentation format, mostly hex encoded
+	Data string `json:"data,omitempty"`
+}
+
+// ednsOptionNames are the names of the EDNS(0) option codes
+var ednsOptionNames = map[uint16]string{
+	miekgdns.EDNS0LLQ:          "LLQ",
+	miekgdns.EDNS0UL:           "UL",
+	miekgdns.EDNS0NSID:         "NSID",
+	miekgdns.EDNS0ESU:          "ESU",
+	miekgdns.EDNS0DAU:          "DAU",
+	miekgdns.EDNS0DHU:          "DHU",
+	miekgdns.EDNS0N3U:          "N3U",
+	miekgdns.EDNS0SUBNET:       "ECS",
+	miekgdns.EDNS0EXPIRE:       "EXPIRE",
+	miekgdns.EDNS0COOKIE:       "COOKIE",
+	miekgdns.EDNS0TCPKEEPALIVE: "TCP-KEEPALIVE",
+	miekgdns.EDNS0PADDING:      "PADDING",
+	miekgdns.EDNS0EDE:          "EDE",
+}
+
+// parseEDNS returns the edns fields of the message, nil without OPT record
+func parseEDNS(msg *miekgdns.Msg) *EDNS {
+	opt := msg.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	edns := &EDNS{
+		Version:       opt.Version(),
+		DO:            opt.Do(),
+		ExtendedRcode: uint8(opt.Hdr.Ttl >> 24),
+		UDPSize:       opt.UDPSize(),
+	}
+	for _, option := range opt.Option {
+		parsed := EDNSOption{Code: option.Option(), Name: ednsOptionNames[option.Option()], Data: option.String()}
+		switch option := option.(type) {
+		case *miekgdns.EDNS0_NSID:
+			edns.NSID = nsidText(option.Nsid)
+		case *miekgdns.EDNS0_COOKIE:
+			edns.Cookie = option.Cookie
+		case *miekgdns.EDNS0_SUBNET:
+			edns.ClientSubnet = option.String()
+		case *miekgdns.EDNS0_PADDING:
+			// the padding only carries zeros
+			parsed.Data = fmt.Sprintf("%d bytes", len(option.Padding))
+		}
+		edns.Options = append(edns.Options, parsed)
+	}
+	return edns
+}
+
+// nsidText returns the hex encoded nsid as text when printable, most servers setting their hostname
+func nsidText(nsid string) string {
+	decoded, err := hex.DecodeString(nsid)
+	if err != nil {
+		return nsid
+	}
+	for _, c := range decoded {
+		if c < 0x20 || c > 0x7e {
+			return nsid
+		}
+	}
+	return string(decoded)
+}
+
+// parseExtendedErrors returns the extended dns errors found in the message
+func parseExtendedErrors(msg *miekgdns.Msg) []ExtendedError {
+	opt := msg.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	var extendedErrors []ExtendedError
+	for _, option := range opt.Option {
+		if ede, ok := option.(*miekgdns.EDNS0_EDE); ok {
+			extendedErrors = append(extendedErrors, ExtendedError{
+				Code: ede.InfoCode,
+				Name: miekgdns.ExtendedErrorCodeToString[ede.InfoCode],
+				Text: ede.ExtraText,
+			})
+		}
+	}
+	return extendedErrors
+}
+
+// ednsSettings are the edns version, nsid and padding set on every query of a client
+type ednsSettings struct {
+	version uint8
+	nsid    bool
+	padding int
+}
+
+func newEDNSSettings(options *Options) ednsSettings {
+	return ednsSettings{version: options.EDNSVersion, nsid: options.NSID, padding: options.Padding}
+}
+
+// prepare sets the edns version, nsid and padding on the message, reusing its OPT record when it has one (eg.
+// with the DO bit set). The padding comes last as it depends on the size of the other options
+func (e ednsSettings) prepare(msg *miekgdns.Msg) {
+	if e.version == 0 && !e.nsid && e.padding == 0 {
+		return
+	}
+	opt := msg.IsEdns0()
+	if opt == nil {
+		msg.SetEdns0(4096, false)
+		opt = msg.IsEdns0()
+	}
+	if e.version > 0 {
+		opt.SetVersion(e.version)
+	}
+	if e.nsid && !hasEDNSOption(opt, miekgdns.EDNS0NSID) {
+		opt.Option = append(opt.Option, &miekgdns.EDNS0_NSID{Code: miekgdns.EDNS0NSID})
+	}
+	if e.padding > 0 {
+		PadQuery(msg, e.padding)
+	}
+}
+
+func hasEDNSOption(opt *miekgdns.OPT, code uint16) bool {
+	for _, option := range opt.Option {
+		if option.Option() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSupportedEDNSVersion returns the highest edns version supported by the server on BADVERS responses
+func parseSupportedEDNSVersion(msg *miekgdns.Msg) *uint8 {
+	opt := msg.IsEdns0()
+	if opt == nil || msg.Rcode != miekgdns.RcodeBadVers {
+		return nil
+	}
+	version := opt.Version()
+	return &version
+}
@@ -0,0 +1,235 @@
+package dnsx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEDNS(t *testing.T) {
+	msg := &miekgdns.Msg{}
+	require.Nil(t, parseEDNS(msg), "edns without OPT record")
+
+	msg.SetEdns0(1232, true)
+	msg.IsEdns0().SetVersion(1)
+	msg.IsEdns0().SetExtendedRcode(miekgdns.RcodeBadVers)
+	require.Equal(t, &EDNS{Version: 1, DO: true, ExtendedRcode: 1, UDPSize: 1232}, parseEDNS(msg), "could not match edns")
+
+	// a response without OPT record is told apart from zero flags
+	data := &ResponseData{DNSData: &retryabledns.DNSData{Host: "example.com"}}
+	encoded, err := json.Marshal(data)
+	require.Nil(t, err, "could not marshal response")
+	require.Contains(t, string(encoded), `"edns":null`, "missing edns not null")
+}
+
+func TestParseEDNSOptions(t *testing.T) {
+	msg := &miekgdns.Msg{}
+	msg.SetEdns0(1232, false)
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option,
+		&miekgdns.EDNS0_NSID{Code: miekgdns.EDNS0NSID, Nsid: hex.EncodeToString([]byte("res1.example"))},
+		&miekgdns.EDNS0_COOKIE{Code: miekgdns.EDNS0COOKIE, Cookie: "24a5ac8a1d2e4f6b"},
+		&miekgdns.EDNS0_SUBNET{Code: miekgdns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 0, Address: net.ParseIP("192.0.2.0").To4()},
+		&miekgdns.EDNS0_PADDING{Padding: make([]byte, 10)},
+	)
+	edns := parseEDNS(msg)
+	require.Equal(t, "res1.example", edns.NSID, "could not decode nsid")
+	require.Equal(t, "24a5ac8a1d2e4f6b", edns.Cookie)
+	require.Equal(t, "192.0.2.0/24/0", edns.ClientSubnet)
+	require.Equal(t, []EDNSOption{
+		{Code: miekgdns.EDNS0NSID, Name: "NSID", Data: hex.EncodeToString([]byte("res1.example"))},
+		{Code: miekgdns.EDNS0COOKIE, Name: "COOKIE", Data: "24a5ac8a1d2e4f6b"},
+		{Code: miekgdns.EDNS0SUBNET, Name: "ECS", Data: "192.0.2.0/24/0"},
+		{Code: miekgdns.EDNS0PADDING, Name: "PADDING", Data: "10 bytes"},
+	}, edns.Options)
+
+	// a binary nsid is kept hex encoded
+	require.Equal(t, "00ff", nsidText("00ff"))
+}
+
+func TestParseExtendedErrors(t *testing.T) {
+	msg := &miekgdns.Msg{}
+	require.Nil(t, parseExtendedErrors(msg), "extended errors without OPT record")
+
+	msg.SetEdns0(1232, false)
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option,
+		&miekgdns.EDNS0_EDE{InfoCode: miekgdns.ExtendedErrorCodeDNSBogus},
+		&miekgdns.EDNS0_EDE{InfoCode: miekgdns.ExtendedErrorCodeBlocked, ExtraText: "blocked by policy"},
+	)
+	data := &ResponseData{DNSData: &retryabledns.DNSData{Host: "example.com", RawResp: msg}}
+	data.ParseRawResp()
+	require.Equal(t, []ExtendedError{
+		{Code: miekgdns.ExtendedErrorCodeDNSBogus, Name: "DNSSEC Bogus"},
+		{Code: miekgdns.ExtendedErrorCodeBlocked, Name: "Blocked", Text: "blocked by policy"},
+	}, data.EDE, "could not match extended errors")
+	require.Equal(t, "6 (DNSSEC Bogus)", data.EDE[0].String(), "could not match label")
+	require.Equal(t, "15 (Blocked): blocked by policy", data.EDE[1].String(), "could not match label with text")
+}
+
+func TestPrepareEDNSNSID(t *testing.T) {
+	edns := newEDNSSettings(&Options{EDNSVersion: 1, NSID: true, Padding: DefaultPaddingBlockSize})
+	msg := &miekgdns.Msg{}
+	msg.SetQuestion("example.com.", miekgdns.TypeA)
+	msg.SetEdns0(4096, true)
+	edns.prepare(msg)
+	// preparing again leaves the message unchanged
+	edns.prepare(msg)
+
+	var opts int
+	for _, rr := range msg.Extra {
+		if _, ok := rr.(*miekgdns.OPT); ok {
+			opts++
+		}
+	}
+	require.Equal(t, 1, opts, "OPT record added twice")
+	opt := msg.IsEdns0()
+	require.True(t, opt.Do(), "DO bit dropped")
+	require.Equal(t, uint8(1), opt.Version(), "could not set the edns version")
+	require.Len(t, opt.Option, 2, "could not add the nsid and padding options")
+	require.Equal(t, uint16(miekgdns.EDNS0NSID), opt.Option[0].Option())
+	require.Equal(t, 0, msg.Len()%DefaultPaddingBlockSize, "padding not computed last")
+}
+
+func TestEDNSQueries(t *testing.T) {
+	// the server returns its nsid and checks the queries are padded
+	var unpadded atomic.Int32
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		if r.Len()%DefaultPaddingBlockSize != 0 {
+			unpadded.Add(1)
+		}
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		m.SetEdns0(1232, false)
+		opt := m.IsEdns0()
+		opt.Option = append(opt.Option, &miekgdns.EDNS0_NSID{Code: miekgdns.EDNS0NSID, Nsid: hex.EncodeToString([]byte("res1"))})
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	var buffer bytes.Buffer
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.Timeout = time.Second
+	options.QuestionTypes = []uint16{miekgdns.TypeA}
+	options.NSID = true
+	options.Padding = DefaultPaddingBlockSize
+	options.QueryLog = NewQueryLog(&buffer)
+	options.ResolverStats = NewResolverStats()
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	data, err := dnsX.QueryMultiple("example.com")
+	require.Nil(t, err, "could not query")
+	require.Equal(t, []string{conn.LocalAddr().String()}, data.Resolver, "could not match resolver")
+	response := &ResponseData{DNSData: data}
+	response.ParseRawResp()
+	require.Equal(t, "res1", response.EDNS.NSID, "could not match nsid")
+
+	// the profiles clearing the recursion desired flag get the edns options and the resolver as well
+	profile, err := dnsX.NewQueryProfile(nil, nil, true)
+	require.Nil(t, err, "could not create profile")
+	data, err = dnsX.QueryMultipleWithProfile("example.org", profile)
+	require.Nil(t, err, "could not query with the profile")
+	require.Equal(t, []string{conn.LocalAddr().String()}, data.Resolver, "could not match profile resolver")
+	require.False(t, data.RawResp.RecursionDesired, "recursion desired with the profile")
+
+	require.Nil(t, options.QueryLog.Close(), "could not close query log")
+	require.Equal(t, 2, bytes.Count(buffer.Bytes(), []byte("\n")), "edns queries not logged")
+	require.Equal(t, 2, options.ResolverStats.Resolvers()[0].Queries, "edns queries not counted")
+	require.Zero(t, unpadded.Load(), "queries not padded")
+}
+
+func TestNSIDResolver(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		m.SetEdns0(1232, false)
+		opt := m.IsEdns0()
+		opt.Option = append(opt.Option, &miekgdns.EDNS0_NSID{Code: miekgdns.EDNS0NSID, Nsid: hex.EncodeToString([]byte("res1"))})
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.Timeout = time.Second
+	options.QuestionTypes = []uint16{miekgdns.TypeA, miekgdns.TypeAAAA}
+	options.NSID = true
+	options.ResolverHash = true
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	// the resolver answering the nsid queries is reported, for the pinned hosts as well
+	data, err := dnsX.QueryMultiple("example.com")
+	require.Nil(t, err, "could not query")
+	require.Equal(t, []string{conn.LocalAddr().String()}, data.Resolver, "could not match resolver")
+	data, err = dnsX.QueryMultipleWithResolver("example.org", conn.LocalAddr().String())
+	require.Nil(t, err, "could not query the override")
+	require.Equal(t, []string{conn.LocalAddr().String()}, data.Resolver, "could not match override resolver")
+	response := &ResponseData{DNSData: data}
+	response.ParseRawResp()
+	require.Equal(t, "res1", response.EDNS.NSID, "could not match nsid")
+}
+
+func TestBadVersQuery(t *testing.T) {
+	// the server only supports edns version 0
+	var version atomic.Int32
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		m.SetEdns0(1232, false)
+		if opt := r.IsEdns0(); opt != nil && opt.Version() > 0 {
+			version.Store(int32(opt.Version()))
+			m.Rcode = miekgdns.RcodeBadVers
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.Timeout = time.Second
+	options.QuestionTypes = []uint16{miekgdns.TypeA}
+	options.EDNSVersion = 1
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	data, _ := dnsX.QueryMultiple("example.com")
+	require.NotNil(t, data, "could not keep the badvers response")
+	require.Equal(t, int32(1), version.Load(), "could not advertise the edns version")
+	response := &ResponseData{DNSData: data}
+	response.ParseRawResp()
+	require.Equal(t, RcodeBadVersName, response.StatusCode, "could not report badvers")
+	require.NotNil(t, response.SupportedEDNSVersion, "could not report the supported version")
+	require.Equal(t, uint8(0), *response.SupportedEDNSVersion, "could not match the supported version")
+
+	// the responses to other rcodes carry no supported version
+	msg := &miekgdns.Msg{}
+	msg.SetEdns0(1232, false)
+	msg.Rcode = miekgdns.RcodeServerFailure
+	require.Nil(t, parseSupportedEDNSVersion(msg), "supported version without badvers")
+}
@@ -0,0 +1,28 @@
+package dnsx
+
+import "time"
+
+// DefaultMaxTimeout is the default cap of the escalating per attempt timeout
+const DefaultMaxTimeout = 10 * time.Second
+
+// defaultAttemptTimeout is the per attempt timeout applied by the dns client when none is set
+const defaultAttemptTimeout = 2 * time.Second
+
+// AttemptTimeout returns the timeout of an attempt (0 for the first one) when the timeout escalates:
+// the base timeout doubled at each retry, capped to max (eg. 1s, 2s, 4s)
+func AttemptTimeout(base, max time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = defaultAttemptTimeout
+	}
+	timeout := base
+	for i := 0; i < attempt; i++ {
+		timeout *= 2
+		if max > 0 && timeout >= max {
+			break
+		}
+	}
+	if max > 0 && timeout > max {
+		timeout = max
+	}
+	return timeout
+}
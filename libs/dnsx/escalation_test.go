@@ -0,0 +1,72 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttemptTimeout(t *testing.T) {
+	require.Equal(t, time.Second, AttemptTimeout(time.Second, 10*time.Second, 0), "could not match first attempt timeout")
+	require.Equal(t, 4*time.Second, AttemptTimeout(time.Second, 10*time.Second, 2), "could not match escalated timeout")
+	require.Equal(t, 10*time.Second, AttemptTimeout(time.Second, 10*time.Second, 5), "timeout not capped")
+	require.Equal(t, 8*time.Second, AttemptTimeout(0, 0, 2), "could not match escalated default timeout")
+}
+
+func TestQueryEscalating(t *testing.T) {
+	// the server answers after the first attempt timeout but within the second one
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		time.Sleep(150 * time.Millisecond)
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		hdr := miekgdns.RR_Header{Name: r.Question[0].Name, Rrtype: miekgdns.TypeA, Class: miekgdns.ClassINET, Ttl: 300}
+		m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP("192.0.2.1")})
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 2
+	options.Timeout = 100 * time.Millisecond
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	data, _ := dnsX.QueryMultiple("example.com")
+	require.Empty(t, data.A, "answer received with a constant timeout")
+
+	options.TimeoutEscalation = true
+	options.MaxTimeout = time.Second
+	dnsX, err = New(options)
+	require.Nil(t, err, "could not create dnsx")
+	data, err = dnsX.QueryMultiple("example.com")
+	require.Nil(t, err, "could not query with escalating timeout")
+	require.Equal(t, []string{"192.0.2.1"}, data.A, "could not match records")
+}
+
+func TestQueryTimeout(t *testing.T) {
+	// the server never answers, each attempt ends with the query timeout
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	defer conn.Close()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 2
+	options.Timeout = 100 * time.Millisecond
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	start := time.Now()
+	_, err = dnsX.QueryOne("example.com")
+	require.NotNil(t, err, "unanswered query succeeded")
+	elapsed := time.Since(start)
+	require.GreaterOrEqual(t, elapsed, 200*time.Millisecond, "attempts ended before the timeout")
+	require.Less(t, elapsed, time.Second, "attempts not ended by the timeout")
+}
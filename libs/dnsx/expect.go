@@ -0,0 +1,92 @@
+package dnsx
+
+import (
+	"net"
+
+	miekgdns "github.com/miekg/dns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// Expectation is the outcome of the comparison of the records of a host with the expected values
+type Expectation struct {
+	Expected []string `json:"expected,omitempty" csv:"expected"`
+	Actual   []string `json:"actual,omitempty" csv:"actual"`
+	Pass     bool     `json:"pass" csv:"pass"`
+}
+
+// Records returns the values of the records of the question types
+func (d *ResponseData) Records(questionTypes []uint16) []string {
+	if d.DNSData == nil {
+		return nil
+	}
+	var records []string
+	for _, questionType := range questionTypes {
+		switch questionType {
+		case miekgdns.TypeA:
+			records = append(records, d.A...)
+		case miekgdns.TypeAAAA:
+			records = append(records, d.AAAA...)
+		case miekgdns.TypeCNAME:
+			records = append(records, d.CNAME...)
+		case miekgdns.TypeMX:
+			records = append(records, d.MX...)
+		case miekgdns.TypeNS:
+			records = append(records, d.NS...)
+		case miekgdns.TypePTR:
+			records = append(records, d.PTR...)
+		case miekgdns.TypeSOA:
+			for _, soa := range d.SOA {
+				records = append(records, soa.NS, soa.Mbox)
+			}
+		case miekgdns.TypeTXT:
+			records = append(records, d.TXT...)
+		case miekgdns.TypeSRV:
+			records = append(records, d.SRV...)
+		case miekgdns.TypeCAA:
+			records = append(records, d.CAA...)
+		case miekgdns.TypeTLSA:
+			records = append(records, d.TLSA...)
+		case miekgdns.TypeDNSKEY:
+			records = append(records, formatDNSKEY(d.DNSKEY)...)
+		case miekgdns.TypeDS:
+			records = append(records, formatDS(d.DS)...)
+		case miekgdns.TypeHTTPS:
+			records = append(records, formatServiceBindings(d.HTTPS)...)
+		case miekgdns.TypeSVCB:
+			records = append(records, formatServiceBindings(d.SVCB)...)
+		case miekgdns.TypeNAPTR:
+			records = append(records, formatNAPTR(d.NAPTR)...)
+		case miekgdns.TypeSSHFP:
+			records = append(records, formatSSHFP(d.SSHFP)...)
+		case miekgdns.TypeDNAME:
+			records = append(records, dnameTargets(d.DNAME)...)
+		}
+	}
+	return sliceutil.Dedupe(records)
+}
+
+// CheckExpectation compares the records of the question types with the expected values. The check
+// passes when every expected value was returned, or when any record was returned if none is expected
+func (d *ResponseData) CheckExpectation(expected []string, questionTypes []uint16) {
+	actual := d.Records(questionTypes)
+	d.Expect = &Expectation{Expected: expected, Actual: actual, Pass: len(actual) > 0}
+	returned := make(map[string]struct{}, len(actual))
+	for _, record := range actual {
+		returned[normalizeExpectedValue(record)] = struct{}{}
+	}
+	for _, value := range expected {
+		if _, ok := returned[normalizeExpectedValue(value)]; !ok {
+			d.Expect.Pass = false
+			return
+		}
+	}
+}
+
+// normalizeExpectedValue returns the comparable form of a record value, ignoring the case, the trailing
+// dot of names and the notation of ip addresses
+func normalizeExpectedValue(value string) string {
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+	return normalizeName(value)
+}
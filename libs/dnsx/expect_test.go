@@ -0,0 +1,41 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckExpectation(t *testing.T) {
+	data := &ResponseData{DNSData: &retryabledns.DNSData{
+		A:     []string{"192.0.2.1", "192.0.2.2"},
+		AAAA:  []string{"2001:db8::1"},
+		CNAME: []string{"edge.example.net"},
+	}}
+	questionTypes := []uint16{miekgdns.TypeA, miekgdns.TypeAAAA, miekgdns.TypeCNAME}
+
+	tests := []struct {
+		expected []string
+		pass     bool
+	}{
+		{expected: []string{"192.0.2.1"}, pass: true},
+		{expected: []string{"192.0.2.2", "2001:0db8:0000::1", "Edge.Example.net."}, pass: true},
+		{expected: []string{"192.0.2.1", "192.0.2.3"}, pass: false},
+		{expected: nil, pass: true},
+	}
+	for _, test := range tests {
+		data.CheckExpectation(test.expected, questionTypes)
+		require.Equal(t, test.pass, data.Expect.Pass, "could not match expectation %v", test.expected)
+		require.Equal(t, []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "edge.example.net"}, data.Expect.Actual, "could not match actual records")
+	}
+
+	// the records of the types that were not queried are ignored
+	data.CheckExpectation([]string{"2001:db8::1"}, []uint16{miekgdns.TypeA})
+	require.False(t, data.Expect.Pass, "record of a type not queried matched")
+
+	empty := &ResponseData{DNSData: &retryabledns.DNSData{}}
+	empty.CheckExpectation(nil, questionTypes)
+	require.False(t, empty.Expect.Pass, "host without records passed")
+}
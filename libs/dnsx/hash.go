@@ -0,0 +1,61 @@
+package dnsx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// responseHashSize is the number of bytes of the sha256 digest kept in the response hash
+const responseHashSize = 8
+
+// ResponseHash returns a hash of the wire form of the response, identical for the servers returning the same
+// canned answer to different names. The parts changing with each query are left out of the hash: the message
+// id, the question, the queried name as owner of the records, the ttls and the edns OPT record (cookies, padding)
+func ResponseHash(msg *miekgdns.Msg) string {
+	if msg == nil {
+		return ""
+	}
+	normalized := msg.Copy()
+	normalized.Id = 0
+	normalized.Compress = false
+	var name string
+	if len(normalized.Question) > 0 {
+		name = normalized.Question[0].Name
+	}
+	normalized.Question = nil
+	normalize := func(records []miekgdns.RR) []miekgdns.RR {
+		var kept []miekgdns.RR
+		for _, record := range records {
+			header := record.Header()
+			if header.Rrtype == miekgdns.TypeOPT {
+				continue
+			}
+			if name != "" && strings.EqualFold(header.Name, name) {
+				header.Name = "."
+			}
+			header.Ttl = 0
+			kept = append(kept, record)
+		}
+		return kept
+	}
+	normalized.Answer = normalize(normalized.Answer)
+	normalized.Ns = normalize(normalized.Ns)
+	normalized.Extra = normalize(normalized.Extra)
+	data, err := normalized.Pack()
+	if err != nil {
+		return ""
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:responseHashSize])
+}
+
+// HashResponse sets the hash of the raw response, the last one when several types were queried
+func (d *ResponseData) HashResponse() {
+	if d.DNSData == nil || d.RawResp == nil {
+		return
+	}
+	d.ResponseHash = ResponseHash(d.RawResp)
+}
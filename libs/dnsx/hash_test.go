@@ -0,0 +1,33 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseHash(t *testing.T) {
+	require.Empty(t, ResponseHash(nil), "hash of a missing response")
+
+	// the same parking answer to two names, with different ids, ttls and edns cookies
+	parked := func(name string, id uint16, ttl uint32, cookie string) *miekgdns.Msg {
+		msg := new(miekgdns.Msg)
+		msg.SetQuestion(name, miekgdns.TypeA)
+		msg.Id = id
+		msg.Response = true
+		answer, _ := miekgdns.NewRR(name + " 300 IN A 192.0.2.80")
+		answer.Header().Ttl = ttl
+		msg.Answer = []miekgdns.RR{answer}
+		msg.SetEdns0(1232, false)
+		msg.IsEdns0().Option = append(msg.IsEdns0().Option, &miekgdns.EDNS0_COOKIE{Code: miekgdns.EDNS0COOKIE, Cookie: cookie})
+		return msg
+	}
+	first := ResponseHash(parked("one.example.", 1, 300, "0102030405060708"))
+	require.Len(t, first, 2*responseHashSize, "could not match hash size")
+	require.Equal(t, first, ResponseHash(parked("TWO.example.net.", 2, 120, "0807060504030201")), "identical answers hashed differently")
+
+	other := parked("one.example.", 1, 300, "0102030405060708")
+	other.Answer[0].(*miekgdns.A).A[3] = 81
+	require.NotEqual(t, first, ResponseHash(other), "different answers hashed identically")
+}
@@ -0,0 +1,44 @@
+package dnsx
+
+import (
+	"strings"
+
+	iputil "github.com/projectdiscovery/utils/ip"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Hierarchy places a host within its registrable domain
+type Hierarchy struct {
+	Apex   string `json:"apex" csv:"apex"`
+	Parent string `json:"parent,omitempty" csv:"parent"`
+	Depth  int    `json:"depth" csv:"depth"`
+}
+
+// ParseHierarchy returns the registrable domain (according to the public suffix list), the parent
+// domain and the number of labels below the registrable domain of the host
+func ParseHierarchy(host string) *Hierarchy {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || iputil.IsIP(host) {
+		return nil
+	}
+	apex, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return nil
+	}
+	hierarchy := &Hierarchy{Apex: apex}
+	if host == apex {
+		return hierarchy
+	}
+	subdomain := strings.TrimSuffix(host, "."+apex)
+	hierarchy.Depth = strings.Count(subdomain, ".") + 1
+	hierarchy.Parent = host[strings.Index(host, ".")+1:]
+	return hierarchy
+}
+
+// ParseHierarchy populates the position of the host within its registrable domain
+func (d *ResponseData) ParseHierarchy() {
+	if d.DNSData == nil {
+		return
+	}
+	d.Hierarchy = ParseHierarchy(d.Host)
+}
@@ -0,0 +1,21 @@
+package dnsx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHierarchy(t *testing.T) {
+	tests := map[string]*Hierarchy{
+		"example.com":             {Apex: "example.com"},
+		"www.example.com":         {Apex: "example.com", Parent: "example.com", Depth: 1},
+		"a.b.example.co.uk.":      {Apex: "example.co.uk", Parent: "b.example.co.uk", Depth: 2},
+		"API.Staging.Example.COM": {Apex: "example.com", Parent: "staging.example.com", Depth: 2},
+	}
+	for host, expected := range tests {
+		require.Equal(t, expected, ParseHierarchy(host), "invalid hierarchy for %s", host)
+	}
+	require.Nil(t, ParseHierarchy("192.0.2.1"), "ip with hierarchy")
+	require.Nil(t, ParseHierarchy("co.uk"), "public suffix with hierarchy")
+}
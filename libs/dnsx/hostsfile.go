@@ -0,0 +1,84 @@
+package dnsx
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/projectdiscovery/retryabledns/hostsfile"
+	iputil "github.com/projectdiscovery/utils/ip"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// QueryHostsFile answers the question types exclusively from the hosts file without any network activity, a
+// host of the file without address of the question types getting an empty answer
+func (d *DNSX) QueryHostsFile(hostname string) (*retryabledns.DNSData, error) {
+	dnsdata := &retryabledns.DNSData{
+		Host:      hostname,
+		Timestamp: time.Now(),
+	}
+	ips, known := d.knownHosts[normalizeName(hostname)]
+	addHostsFileAddresses(dnsdata, ips, d.questionTypes(hostname))
+	if known {
+		dnsdata.StatusCodeRaw = miekgdns.RcodeSuccess
+	} else {
+		dnsdata.StatusCodeRaw = miekgdns.RcodeNameError
+	}
+	dnsdata.StatusCode = miekgdns.RcodeToString[dnsdata.StatusCodeRaw]
+	return dnsdata, nil
+}
+
+// addHostsFileAddresses adds the addresses of the hosts file matching the question types
+func addHostsFileAddresses(dnsdata *retryabledns.DNSData, ips []string, questionTypes []uint16) {
+	ipv4, ipv6 := sliceutil.Contains(questionTypes, miekgdns.TypeA), sliceutil.Contains(questionTypes, miekgdns.TypeAAAA)
+	for _, ip := range ips {
+		if ipv4 && iputil.IsIPv4(ip) {
+			dnsdata.A = append(dnsdata.A, ip)
+		} else if ipv6 && iputil.IsIPv6(ip) {
+			dnsdata.AAAA = append(dnsdata.AAAA, ip)
+		}
+	}
+	dnsdata.HostsFile = len(dnsdata.A)+len(dnsdata.AAAA) > 0
+}
+
+// loadHostsFile reads the hosts file of the system in full, unlike the parser of retryabledns which stops after
+// its first lines
+func loadHostsFile() (map[string][]string, error) {
+	return parseHostsFile(hostsfile.Path())
+}
+
+// parseHostsFile maps the lowercase names of the hosts file to their addresses, the comments being dropped
+func parseHostsFile(path string) (map[string][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hosts := make(map[string][]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip := normalizeIP(fields[0])
+		if !iputil.IsIP(ip) {
+			continue
+		}
+		for _, name := range fields[1:] {
+			name = normalizeName(name)
+			if !sliceutil.Contains(hosts[name], ip) {
+				hosts[name] = append(hosts[name], ip)
+			}
+		}
+	}
+	return hosts, scanner.Err()
+}
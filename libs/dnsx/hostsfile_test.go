@@ -0,0 +1,61 @@
+package dnsx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHostsFile(t *testing.T) {
+	// the entries beyond the first lines are read as well
+	lines := []string{"# static entries", "192.0.2.1 www.example.com WWW.Example.org. # web", "2001:db8::1 www.example.com", "invalid www.example.net"}
+	for i := 0; i < 5000; i++ {
+		lines = append(lines, "198.51.100.1 filler.example.com")
+	}
+	lines = append(lines, "198.51.100.2 last.example.com")
+	path := filepath.Join(t.TempDir(), "hosts")
+	require.Nil(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644), "could not write hosts file")
+
+	hosts, err := parseHostsFile(path)
+	require.Nil(t, err, "could not parse hosts file")
+	require.Equal(t, []string{"192.0.2.1", "2001:db8::1"}, hosts["www.example.com"], "could not match addresses")
+	require.Equal(t, []string{"192.0.2.1"}, hosts["www.example.org"], "name not normalized")
+	require.Equal(t, []string{"198.51.100.1"}, hosts["filler.example.com"], "duplicate addresses kept")
+	require.Equal(t, []string{"198.51.100.2"}, hosts["last.example.com"], "last entry not read")
+	require.NotContains(t, hosts, "www.example.net", "entry without ip kept")
+}
+
+func TestQueryHostsFile(t *testing.T) {
+	knownHosts := map[string][]string{"www.example.com": {"192.0.2.1", "2001:db8::1"}}
+	query := func(hostname string, questionTypes ...uint16) *retryabledns.DNSData {
+		dnsX := &DNSX{Options: &Options{QuestionTypes: questionTypes}, knownHosts: knownHosts}
+		dnsData, err := dnsX.QueryHostsFile(hostname)
+		require.Nil(t, err, "could not query hosts file")
+		return dnsData
+	}
+
+	dnsData := query("WWW.example.com", miekgdns.TypeAAAA)
+	require.Empty(t, dnsData.A, "a record returned for an aaaa question")
+	require.Equal(t, []string{"2001:db8::1"}, dnsData.AAAA, "could not match aaaa record")
+	require.True(t, dnsData.HostsFile, "hosts file answer not flagged")
+
+	dnsData = query("www.example.com", miekgdns.TypeMX)
+	require.Empty(t, dnsData.A, "address returned for an mx question")
+	require.False(t, dnsData.HostsFile, "empty answer flagged")
+	require.Equal(t, miekgdns.RcodeSuccess, dnsData.StatusCodeRaw, "known host without address not noerror")
+
+	dnsData = query("unknown.example.com", miekgdns.TypeA)
+	require.Equal(t, miekgdns.RcodeNameError, dnsData.StatusCodeRaw, "unknown host not nxdomain")
+
+	// the hosts file answers of the engine are filtered the same way
+	c := &client{knownHosts: knownHosts}
+	dnsData = &retryabledns.DNSData{}
+	c.addKnownHosts(dnsData, "www.example.com", []uint16{miekgdns.TypeA})
+	require.Equal(t, []string{"192.0.2.1"}, dnsData.A, "could not match a record")
+	require.Empty(t, dnsData.AAAA, "aaaa record returned for an a question")
+}
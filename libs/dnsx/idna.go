@@ -0,0 +1,44 @@
+package dnsx
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+const acePrefix = "xn--"
+
+// aceNameRegex matches the domain names having at least a punycode label
+var aceNameRegex = regexp.MustCompile(`(?i)[a-z0-9._-]*xn--[a-z0-9._-]*`)
+
+// DecodePunycode returns the text with the punycode labels of the domain names it contains converted
+// to unicode, invalid labels being kept in their ace form
+func DecodePunycode(text string) string {
+	if !strings.Contains(strings.ToLower(text), acePrefix) {
+		return text
+	}
+	return aceNameRegex.ReplaceAllStringFunc(text, func(name string) string {
+		labels := strings.Split(name, ".")
+		for i, label := range labels {
+			if !strings.HasPrefix(strings.ToLower(label), acePrefix) {
+				continue
+			}
+			// a label decoding to plain ascii is not a valid idna label
+			if decoded, err := idna.Lookup.ToUnicode(label); err == nil && !isASCII(decoded) {
+				labels[i] = decoded
+			}
+		}
+		return strings.Join(labels, ".")
+	})
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
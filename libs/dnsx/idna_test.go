@@ -0,0 +1,22 @@
+package dnsx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePunycode(t *testing.T) {
+	tests := map[string]string{
+		"xn--mnchen-3ya.de":                    "münchen.de",
+		"www.xn--mnchen-3ya.de.":               "www.münchen.de.",
+		"XN--MNCHEN-3YA.de":                    "münchen.de",
+		"v=spf1 include:xn--bcher-kva.ch ~all": "v=spf1 include:bücher.ch ~all",
+		"xn--invalid-.example.com":             "xn--invalid-.example.com",
+		"xn--99999999.example.com":             "xn--99999999.example.com",
+		"example.com":                          "example.com",
+	}
+	for text, expected := range tests {
+		require.Equal(t, expected, DecodePunycode(text), "invalid decoding of %s", text)
+	}
+}
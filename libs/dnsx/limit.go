@@ -0,0 +1,47 @@
+package dnsx
+
+// DefaultMaxAnswers is the default maximum number of records kept per record type
+const DefaultMaxAnswers = 1000
+
+// LimitAnswers caps the number of records stored for each record type and returns true if any was truncated.
+// The response is parsed in full before, the cap bounding the records kept and output, not the parsing work: a
+// dns message is at most 64KB on the wire, which bounds the memory of a single response anyway
+func (d *ResponseData) LimitAnswers(max int) bool {
+	if d.DNSData == nil || max <= 0 {
+		return false
+	}
+	var truncated bool
+	d.A = limitRecords(d.A, max, &truncated)
+	d.AAAA = limitRecords(d.AAAA, max, &truncated)
+	d.CNAME = limitRecords(d.CNAME, max, &truncated)
+	d.MX = limitRecords(d.MX, max, &truncated)
+	d.PTR = limitRecords(d.PTR, max, &truncated)
+	d.NS = limitRecords(d.NS, max, &truncated)
+	d.TXT = limitRecords(d.TXT, max, &truncated)
+	d.SRV = limitRecords(d.SRV, max, &truncated)
+	d.CAA = limitRecords(d.CAA, max, &truncated)
+	d.SOA = limitRecords(d.SOA, max, &truncated)
+	// the records parsed from the raw response
+	d.TLSA = limitRecords(d.TLSA, max, &truncated)
+	d.DNSKEY = limitRecords(d.DNSKEY, max, &truncated)
+	d.DS = limitRecords(d.DS, max, &truncated)
+	d.HTTPS = limitRecords(d.HTTPS, max, &truncated)
+	d.SVCB = limitRecords(d.SVCB, max, &truncated)
+	d.NAPTR = limitRecords(d.NAPTR, max, &truncated)
+	d.SSHFP = limitRecords(d.SSHFP, max, &truncated)
+	d.DNAME = limitRecords(d.DNAME, max, &truncated)
+	d.AllRecords = limitRecords(d.AllRecords, max, &truncated)
+	if d.RawResp != nil {
+		d.RawResp.Answer = limitRecords(d.RawResp.Answer, max, &truncated)
+	}
+	return truncated
+}
+
+// limitRecords returns the first max records, flagging the truncation
+func limitRecords[T any](records []T, max int, truncated *bool) []T {
+	if len(records) > max {
+		*truncated = true
+		return records[:max]
+	}
+	return records
+}
@@ -0,0 +1,32 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitAnswers(t *testing.T) {
+	d := &ResponseData{DNSData: &retryabledns.DNSData{
+		A: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"},
+		AllRecords: []string{
+			"host.example.com.\t300\tIN\tSSHFP\t1 1 DD465C09CFA51FB45020CC83316FFF21B9EC74AC",
+			"host.example.com.\t300\tIN\tSSHFP\t1 2 5A7B9C1D2E3F40516273849506A7B8C9D0E1F2031425364758697A8B9CADBECF",
+			"host.example.com.\t300\tIN\tSSHFP\t4 2 5A7B9C1D2E3F40516273849506A7B8C9D0E1F2031425364758697A8B9CADBECF",
+		},
+		RawResp: &miekgdns.Msg{},
+	}}
+	d.ParseRawResp()
+	require.Len(t, d.SSHFP, 3, "could not parse sshfp records")
+
+	require.True(t, d.LimitAnswers(2), "truncation not reported")
+	require.Len(t, d.A, 2, "could not limit the a records")
+	// the records parsed from the raw response are limited as well
+	require.Len(t, d.SSHFP, 2, "could not limit the sshfp records")
+	require.Equal(t, uint8(1), d.SSHFP[1].Algorithm, "could not keep the first records")
+
+	require.False(t, d.LimitAnswers(2), "truncation reported under the limit")
+	require.False(t, d.LimitAnswers(0), "truncation reported without limit")
+}
@@ -0,0 +1,59 @@
+package dnsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgPack returns the response as a messagepack record prefixed by its big endian uint32 length.
+// The record is built from the json representation so that both formats carry the same fields.
+func (d *ResponseData) MsgPack(options ...MarshalOption) ([]byte, error) {
+	jsonData, err := d.JSON(options...)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(jsonData)))
+	decoder.UseNumber()
+	var fields interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	buffer.Write(make([]byte, 4))
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetSortMapKeys(true)
+	if err := encoder.Encode(msgpackValue(fields)); err != nil {
+		return nil, err
+	}
+	record := buffer.Bytes()
+	binary.BigEndian.PutUint32(record, uint32(len(record)-4))
+	return record, nil
+}
+
+// msgpackValue converts the json numbers to integers when possible to keep the native types
+func msgpackValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = msgpackValue(item)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = msgpackValue(item)
+		}
+		return value
+	default:
+		return value
+	}
+}
@@ -0,0 +1,38 @@
+package dnsx
+
+import (
+	"encoding/binary"
+	"testing"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMsgPack(t *testing.T) {
+	data := &ResponseData{DNSData: &retryabledns.DNSData{
+		Host: "example.com",
+		TTL:  300,
+		A:    []string{"192.0.2.1", "192.0.2.2"},
+	}}
+	record, err := data.MsgPack()
+	require.Nil(t, err, "could not encode record")
+	require.Equal(t, uint32(len(record)-4), binary.BigEndian.Uint32(record), "could not match length prefix")
+
+	var fields map[string]interface{}
+	require.Nil(t, msgpack.Unmarshal(record[4:], &fields), "could not decode record")
+	require.Equal(t, "example.com", fields["host"], "could not match host")
+	require.Equal(t, []interface{}{"192.0.2.1", "192.0.2.2"}, fields["a"], "could not match records")
+	// the json numbers are kept as integers
+	ttl, ok := fields["ttl"].(int64)
+	require.True(t, ok, "ttl not encoded as integer: %T", fields["ttl"])
+	require.Equal(t, int64(300), ttl, "could not match ttl")
+
+	// the marshal options apply as in json
+	data.AllRecords = []string{"example.com.\t300\tIN\tA\t192.0.2.1"}
+	record, err = data.MsgPack(WithoutAllRecords())
+	require.Nil(t, err, "could not encode record without raw records")
+	fields = nil
+	require.Nil(t, msgpack.Unmarshal(record[4:], &fields), "could not decode record")
+	require.NotContains(t, fields, "all", "raw records not omitted")
+}
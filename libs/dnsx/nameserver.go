@@ -0,0 +1,43 @@
+package dnsx
+
+import (
+	"fmt"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// Nameserver is a nameserver of a domain with its addresses and, when looked up, their autonomous system
+type Nameserver struct {
+	Name string       `json:"name" csv:"name"`
+	IPs  []string     `json:"ips,omitempty" csv:"ips"`
+	ASN  *AsnResponse `json:"asn,omitempty" csv:"asn"`
+}
+
+func (n *Nameserver) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(n.Name)
+	if len(n.IPs) > 0 {
+		fmt.Fprintf(&builder, " [%s]", strings.Join(n.IPs, ","))
+	}
+	if n.ASN != nil {
+		builder.WriteString(" " + n.ASN.String())
+	}
+	return builder.String()
+}
+
+// ResolveNameserver queries the A and AAAA records of the nameserver hostname
+func (d *DNSX) ResolveNameserver(name string) (*Nameserver, error) {
+	nameserver := &Nameserver{Name: NormalizeNameserver(name)}
+	dnsdata, err := d.client().QueryMultiple(nameserver.Name, []uint16{miekgdns.TypeA, miekgdns.TypeAAAA})
+	if dnsdata != nil {
+		nameserver.IPs = append(append(nameserver.IPs, dnsdata.A...), dnsdata.AAAA...)
+	}
+	return nameserver, err
+}
+
+// NormalizeNameserver returns the nameserver hostname lowercased and without trailing dot, as
+// the NS records of the domains sharing it may spell it differently
+func NormalizeNameserver(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
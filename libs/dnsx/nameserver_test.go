@@ -0,0 +1,44 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveNameserver(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		hdr := miekgdns.RR_Header{Name: r.Question[0].Name, Rrtype: r.Question[0].Qtype, Class: miekgdns.ClassINET, Ttl: 300}
+		switch r.Question[0].Qtype {
+		case miekgdns.TypeA:
+			m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP("192.0.2.53")})
+		case miekgdns.TypeAAAA:
+			m.Answer = append(m.Answer, &miekgdns.AAAA{Hdr: hdr, AAAA: net.ParseIP("2001:db8::53")})
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 2
+	options.Timeout = time.Second
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	nameserver, err := dnsX.ResolveNameserver("NS1.Example.com.")
+	require.Nil(t, err, "could not resolve nameserver")
+	require.Equal(t, "ns1.example.com", nameserver.Name, "could not match normalized name")
+	require.Equal(t, []string{"192.0.2.53", "2001:db8::53"}, nameserver.IPs, "could not match addresses")
+
+	nameserver.ASN = &AsnResponse{AsNumber: "AS64496", AsName: "example", AsCountry: "US"}
+	require.Equal(t, "ns1.example.com [192.0.2.53,2001:db8::53] [AS64496, example, US]", nameserver.String(), "could not match text form")
+}
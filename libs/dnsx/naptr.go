@@ -0,0 +1,50 @@
+package dnsx
+
+import (
+	"fmt"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// NAPTR is a naming authority pointer record (RFC 3403), used by SIP (RFC 3263) and ENUM (RFC 6116) to map a
+// domain or a telephone number to the services and their endpoints
+type NAPTR struct {
+	Order      uint16 `json:"order" csv:"order"`
+	Preference uint16 `json:"preference" csv:"preference"`
+	Flags      string `json:"flags" csv:"flags"`
+	Service    string `json:"service" csv:"service"`
+	Regexp     string `json:"regexp" csv:"regexp"`
+	// Replacement is the next name to query, "." when the regexp gives the result
+	Replacement string `json:"replacement" csv:"replacement"`
+}
+
+// String returns the record in presentation format (eg. 100 10 "S" "SIP+D2U" "" _sip._udp.example.com)
+func (n NAPTR) String() string {
+	return fmt.Sprintf("%d %d %q %q %q %s", n.Order, n.Preference, n.Flags, n.Service, n.Regexp, n.Replacement)
+}
+
+// newNAPTR returns the pointer of the NAPTR record
+func newNAPTR(naptr *miekgdns.NAPTR) NAPTR {
+	pointer := NAPTR{
+		Order:       naptr.Order,
+		Preference:  naptr.Preference,
+		Flags:       naptr.Flags,
+		Service:     naptr.Service,
+		Regexp:      naptr.Regexp,
+		Replacement: strings.ToLower(naptr.Replacement),
+	}
+	if pointer.Replacement != "." {
+		pointer.Replacement = trimDot(pointer.Replacement)
+	}
+	return pointer
+}
+
+// formatNAPTR returns the records as strings
+func formatNAPTR(pointers []NAPTR) []string {
+	records := make([]string, 0, len(pointers))
+	for _, pointer := range pointers {
+		records = append(records, pointer.String())
+	}
+	return records
+}
@@ -0,0 +1,27 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNAPTR(t *testing.T) {
+	d := &ResponseData{DNSData: &retryabledns.DNSData{
+		AllRecords: []string{
+			"example.com.\t300\tIN\tNAPTR\t100 10 \"S\" \"SIP+D2U\" \"\" _sip._udp.Example.com.",
+			"example.com.\t299\tIN\tNAPTR\t100 10 \"S\" \"SIP+D2U\" \"\" _sip._udp.Example.com.",
+			"4.3.2.1.5.5.5.0.0.8.1.e164.arpa.\t300\tIN\tNAPTR\t100 10 \"u\" \"E2U+sip\" \"!^.*$!sip:info@example.com!\" .",
+		},
+		RawResp: &miekgdns.Msg{},
+	}}
+	d.ParseRawResp()
+	require.Equal(t, []NAPTR{
+		{Order: 100, Preference: 10, Flags: "S", Service: "SIP+D2U", Replacement: "_sip._udp.example.com"},
+		{Order: 100, Preference: 10, Flags: "u", Service: "E2U+sip", Regexp: "!^.*$!sip:info@example.com!", Replacement: "."},
+	}, d.NAPTR, "could not parse naptr records")
+	require.Equal(t, `100 10 "S" "SIP+D2U" "" _sip._udp.example.com`, d.NAPTR[0].String())
+	require.Equal(t, 2, d.RecordCount(miekgdns.TypeNAPTR), "could not count naptr records")
+}
@@ -0,0 +1,146 @@
+package dnsx
+
+import (
+	"sync"
+
+	miekgdns "github.com/miekg/dns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+	"github.com/rs/xid"
+)
+
+// nxHijackProbeSuffix is appended to the random labels of the calibration probes, a name under a
+// delegated tld being what the redirecting resolvers rewrite
+const nxHijackProbeSuffix = ".com"
+
+// nxHijackProbeThreads is the number of resolvers probed at the same time
+const nxHijackProbeThreads = 10
+
+// nxHijackProbes is the number of non-existent names queried on each resolver, the redirecting resolvers
+// rotating between several addresses
+const nxHijackProbes = 3
+
+// NXHijackSignature holds the addresses returned by the resolvers redirecting NXDOMAIN responses
+type NXHijackSignature struct {
+	// Resolvers maps the hijacking resolvers to the addresses they return for non-existent names
+	Resolvers map[string][]string
+	// ips maps the hijacking resolvers, as reported in the responses, to their addresses
+	ips map[string]map[string]struct{}
+}
+
+// Detected returns true if any resolver redirects NXDOMAIN responses
+func (s *NXHijackSignature) Detected() bool {
+	return len(s.ips) > 0
+}
+
+// Contains returns true if the address is returned for non-existent names by one of the resolvers, or by any
+// hijacking resolver when the resolvers of the response are unknown
+func (s *NXHijackSignature) Contains(resolvers []string, ip string) bool {
+	ip = normalizeExpectedValue(ip)
+	if len(resolvers) == 0 {
+		for _, ips := range s.ips {
+			if _, ok := ips[ip]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	for _, resolver := range resolvers {
+		if _, ok := s.ips[resolver][ip]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ProbeNXHijack queries random names that can't exist on each resolver, the ones answering them with addresses
+// instead of NXDOMAIN being flagged along with the addresses. The addresses are kept per resolver, the answers
+// of the other resolvers being left untouched
+func (d *DNSX) ProbeNXHijack() *NXHijackSignature {
+	signature := &NXHijackSignature{Resolvers: make(map[string][]string), ips: make(map[string]map[string]struct{})}
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		tokens = make(chan struct{}, nxHijackProbeThreads)
+	)
+	for _, resolver := range d.resolvers() {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(resolver string) {
+			defer func() {
+				<-tokens
+				wg.Done()
+			}()
+
+			server := parseResolver(resolver)
+			var hijacked []string
+			for i := 0; i < nxHijackProbes; i++ {
+				name := xid.New().String() + nxHijackProbeSuffix
+				data, _ := d.client().QueryMultipleWithResolver(name, []uint16{miekgdns.TypeA, miekgdns.TypeAAAA}, server)
+				if data != nil {
+					hijacked = append(append(hijacked, data.A...), data.AAAA...)
+				}
+			}
+			if len(hijacked) == 0 {
+				return
+			}
+			ips := make(map[string]struct{})
+			for _, ip := range hijacked {
+				ips[normalizeExpectedValue(ip)] = struct{}{}
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			signature.Resolvers[resolver] = sliceutil.Dedupe(hijacked)
+			signature.ips[server.String()] = ips
+		}(resolver)
+	}
+	wg.Wait()
+	return signature
+}
+
+// RemoveHijacked removes the A and AAAA records matching the hijack signature, reporting them in
+// NXHijackIPs. A response left without any record is turned back into the NXDOMAIN it replaced
+func (d *ResponseData) RemoveHijacked(signature *NXHijackSignature) {
+	if d.DNSData == nil || d.HostsFile || !signature.Detected() {
+		return
+	}
+	filter := func(ips []string) (kept, removed []string) {
+		for _, ip := range ips {
+			if signature.Contains(d.Resolver, ip) {
+				removed = append(removed, ip)
+				continue
+			}
+			kept = append(kept, ip)
+		}
+		return kept, removed
+	}
+	var removedA, removedAAAA []string
+	d.A, removedA = filter(d.A)
+	d.AAAA, removedAAAA = filter(d.AAAA)
+	d.NXHijackIPs = append(removedA, removedAAAA...)
+	if len(d.NXHijackIPs) == 0 {
+		return
+	}
+	d.InternalIPs, _ = filter(d.InternalIPs)
+	d.HasInternalIPs = len(d.InternalIPs) > 0
+	if d.RawResp != nil {
+		var answer []miekgdns.RR
+		for _, record := range d.RawResp.Answer {
+			switch rr := record.(type) {
+			case *miekgdns.A:
+				if signature.Contains(d.Resolver, rr.A.String()) {
+					continue
+				}
+			case *miekgdns.AAAA:
+				if signature.Contains(d.Resolver, rr.AAAA.String()) {
+					continue
+				}
+			}
+			answer = append(answer, record)
+		}
+		d.RawResp.Answer = answer
+	}
+	if len(d.A)+len(d.AAAA)+len(d.CNAME)+len(d.MX)+len(d.PTR)+len(d.TXT)+len(d.SRV)+len(d.CAA) == 0 {
+		d.StatusCode = miekgdns.RcodeToString[miekgdns.RcodeNameError]
+		d.StatusCodeRaw = miekgdns.RcodeNameError
+	}
+}
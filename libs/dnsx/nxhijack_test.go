@@ -0,0 +1,95 @@
+package dnsx
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// startNXHijackServer starts a server answering the names other than www.example.com and shop.example.com with
+// the addresses of hijack in turn, NXDOMAIN when hijack is empty
+func startNXHijackServer(t *testing.T, hijack ...string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	var served atomic.Int32
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		question := r.Question[0]
+		hdr := miekgdns.RR_Header{Name: question.Name, Rrtype: question.Qtype, Class: miekgdns.ClassINET, Ttl: 300}
+		var ip string
+		switch question.Name {
+		case "www.example.com.":
+			ip = "192.0.2.1"
+		case "shop.example.com.":
+			// a legitimate address shared with the redirection of the other resolver
+			ip = "192.0.2.99"
+		default:
+			if len(hijack) > 0 && question.Qtype == miekgdns.TypeA {
+				ip = hijack[int(served.Add(1))%len(hijack)]
+			}
+		}
+		switch {
+		case ip == "" && len(hijack) == 0:
+			m.Rcode = miekgdns.RcodeNameError
+		case ip != "" && question.Qtype == miekgdns.TypeA:
+			m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP(ip)})
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestNXHijack(t *testing.T) {
+	// the hijacking resolver rotates between two ad servers
+	hijacking := startNXHijackServer(t, "192.0.2.99", "192.0.2.98")
+	clean := startNXHijackServer(t)
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{hijacking, clean}
+	options.Hostsfile = false
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	signature := dnsX.ProbeNXHijack()
+	require.True(t, signature.Detected(), "hijacking not detected")
+	require.Equal(t, []string{hijacking}, keys(signature.Resolvers), "could not match hijacking resolvers")
+	require.ElementsMatch(t, []string{"192.0.2.99", "192.0.2.98"}, signature.Resolvers[hijacking], "rotating addresses not probed")
+
+	data, err := dnsX.QueryMultipleWithResolver("missing.example.com", hijacking)
+	require.Nil(t, err, "could not query")
+	response := &ResponseData{DNSData: data}
+	response.RemoveHijacked(signature)
+	require.Empty(t, response.A, "hijacked answer kept")
+	require.Len(t, response.NXHijackIPs, 1, "could not match hijacked answers")
+	require.Equal(t, miekgdns.RcodeNameError, response.StatusCodeRaw, "nxdomain not restored")
+	require.False(t, response.HasRecords(), "hijacked answer kept in the raw response")
+
+	data, err = dnsX.QueryMultipleWithResolver("www.example.com", hijacking)
+	require.Nil(t, err, "could not query")
+	response = &ResponseData{DNSData: data}
+	response.RemoveHijacked(signature)
+	require.Equal(t, []string{"192.0.2.1"}, response.A, "could not match legitimate answer")
+	require.Empty(t, response.NXHijackIPs, "legitimate answer flagged")
+
+	// the clean resolver answering with an address of the other resolver's signature is left untouched
+	data, err = dnsX.QueryMultipleWithResolver("shop.example.com", clean)
+	require.Nil(t, err, "could not query")
+	response = &ResponseData{DNSData: data}
+	response.RemoveHijacked(signature)
+	require.Equal(t, []string{"192.0.2.99"}, response.A, "answer of the clean resolver dropped")
+	require.Empty(t, response.NXHijackIPs, "answer of the clean resolver flagged")
+}
+
+func keys(m map[string][]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
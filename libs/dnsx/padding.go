@@ -0,0 +1,41 @@
+package dnsx
+
+import (
+	miekgdns "github.com/miekg/dns"
+)
+
+// DefaultPaddingBlockSize is the block size the queries are padded to as recommended by RFC 8467
+const DefaultPaddingBlockSize = 128
+
+// PadQuery adds the EDNS(0) padding option (RFC 7830) to the message, creating its OPT record if needed, so
+// that its wire size is a multiple of blockSize, and returns the padded size
+func PadQuery(msg *miekgdns.Msg, blockSize int) int {
+	opt := msg.IsEdns0()
+	if opt == nil {
+		msg.SetEdns0(4096, false)
+		opt = msg.IsEdns0()
+	}
+	options := opt.Option[:0]
+	for _, option := range opt.Option {
+		if _, ok := option.(*miekgdns.EDNS0_PADDING); !ok {
+			options = append(options, option)
+		}
+	}
+	padding := &miekgdns.EDNS0_PADDING{}
+	opt.Option = append(options, padding)
+	if remainder := msg.Len() % blockSize; remainder != 0 {
+		padding.Padding = make([]byte, blockSize-remainder)
+	}
+	return msg.Len()
+}
+
+// QuerySize returns the wire size of the query of the question type sent for the host, with the configured edns
+// version, nsid and padding
+func (d *DNSX) QuerySize(hostname string, questionType uint16) int {
+	msg, err := newQuestion(normalizeIP(hostname), questionType)
+	if err != nil {
+		return 0
+	}
+	d.client().edns.prepare(msg)
+	return msg.Len()
+}
@@ -0,0 +1,42 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPadQuery(t *testing.T) {
+	for _, hostname := range []string{"a.io", "a-much-longer-name.subdomain.example.com"} {
+		msg := &miekgdns.Msg{}
+		msg.SetQuestion(miekgdns.Fqdn(hostname), miekgdns.TypeA)
+		size := PadQuery(msg, DefaultPaddingBlockSize)
+		require.Equal(t, 0, size%DefaultPaddingBlockSize, "%s not padded to the block size", hostname)
+		packed, err := msg.Pack()
+		require.Nil(t, err, "could not pack padded query")
+		require.Len(t, packed, size, "padded size does not match the wire size")
+
+		// padding again replaces the option
+		require.Equal(t, size, PadQuery(msg, DefaultPaddingBlockSize), "padding added twice")
+		require.Len(t, msg.IsEdns0().Option, 1, "padding options kept")
+	}
+}
+
+func TestQuerySize(t *testing.T) {
+	options := DefaultOptions
+	options.Hostsfile = false
+	options.Padding = 468
+	options.NSID = true
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	require.Equal(t, 468, dnsX.QuerySize("example.com", miekgdns.TypeAAAA), "could not pad to a large block")
+
+	// the nsid option is part of the size
+	options.Padding = 0
+	dnsX, err = New(options)
+	require.Nil(t, err, "could not create dnsx")
+	msg, err := newQuestion("example.com", miekgdns.TypeAAAA)
+	require.Nil(t, err, "could not create question")
+	require.Equal(t, msg.Len()+4, dnsX.QuerySize("example.com", miekgdns.TypeAAAA), "could not count the nsid option")
+}
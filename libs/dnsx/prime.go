@@ -0,0 +1,99 @@
+package dnsx
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// PrimingResponse is the answer of a root server to the priming query
+type PrimingResponse struct {
+	Server      retryabledns.RootDNS
+	Latency     time.Duration
+	Hints       []string
+	Glue        []string
+	Missing     []string
+	Unknown     []string
+	UnknownGlue []string
+	Err         error
+}
+
+// Prime sends the priming query (. NS) to each known root server and compares
+// the returned root hints with the built-in root server list (RootServers)
+func (d *DNSX) Prime() []*PrimingResponse {
+	responses := make([]*PrimingResponse, len(RootServers))
+	var wg sync.WaitGroup
+	for i, root := range RootServers {
+		wg.Add(1)
+		go func(i int, root retryabledns.RootDNS) {
+			defer wg.Done()
+			responses[i] = d.prime(root)
+		}(i, root)
+	}
+	wg.Wait()
+	return responses
+}
+
+func (d *DNSX) prime(root retryabledns.RootDNS) *PrimingResponse {
+	response := &PrimingResponse{Server: root}
+	start := time.Now()
+	dnsData, err := d.client().QueryMultipleWithResolver(".", []uint16{dns.TypeNS}, parseResolver(root.IPv4))
+	response.Latency = time.Since(start)
+	if err != nil {
+		response.Err = err
+		return response
+	}
+	compareRootHints(response, dnsData)
+	return response
+}
+
+// compareRootHints sets the root hints and glue of the priming response, with the known root servers missing
+// from them and the unknown ones
+func compareRootHints(response *PrimingResponse, dnsData *retryabledns.DNSData) {
+	if len(dnsData.NS) == 0 {
+		response.Err = fmt.Errorf("no root hints in the response (%s)", dnsData.StatusCode)
+		return
+	}
+	response.Hints = dnsData.NS
+	response.Glue = dnsData.A
+
+	for _, known := range RootServers {
+		if !sliceutil.Contains(dnsData.NS, known.Host) {
+			response.Missing = append(response.Missing, known.Host)
+		}
+	}
+	for _, hint := range dnsData.NS {
+		if !isRootServer(hint) {
+			response.Unknown = append(response.Unknown, hint)
+		}
+	}
+	// the glue records are not bound to their owner, so they are only
+	// checked against the whole set of known addresses
+	for _, glue := range dnsData.A {
+		if !isRootServerIP(glue) {
+			response.UnknownGlue = append(response.UnknownGlue, glue)
+		}
+	}
+}
+
+func isRootServer(host string) bool {
+	for _, root := range RootServers {
+		if root.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+func isRootServerIP(ip string) bool {
+	for _, root := range RootServers {
+		if root.IPv4 == ip {
+			return true
+		}
+	}
+	return false
+}
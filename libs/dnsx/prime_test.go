@@ -0,0 +1,47 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootServers(t *testing.T) {
+	require.Len(t, RootServers, 13, "could not match root servers")
+	hosts, ips := make(map[string]struct{}), make(map[string]struct{})
+	for _, root := range RootServers {
+		require.NotNil(t, net.ParseIP(root.IPv4).To4(), "invalid ipv4 of %s", root.Host)
+		require.NotNil(t, net.ParseIP(root.IPv6), "invalid ipv6 of %s", root.Host)
+		hosts[root.Host] = struct{}{}
+		ips[root.IPv4] = struct{}{}
+	}
+	require.Len(t, hosts, 13, "duplicate root server")
+	require.Len(t, ips, 13, "duplicate root server address")
+	// b-root was renumbered in 2023
+	require.True(t, isRootServerIP("170.247.170.2"), "could not match the b-root address")
+	require.False(t, isRootServerIP("199.9.14.201"), "former b-root address known")
+	require.Contains(t, rootServerAddresses(), "170.247.170.2:53", "could not match the trace roots")
+}
+
+func TestCompareRootHints(t *testing.T) {
+	var hints, glue []string
+	for _, root := range RootServers[1:] {
+		hints = append(hints, root.Host)
+		glue = append(glue, root.IPv4)
+	}
+	response := &PrimingResponse{}
+	compareRootHints(response, &retryabledns.DNSData{
+		NS: append(hints, "z.root-servers.net"),
+		A:  append(glue, "199.9.14.201"),
+	})
+	require.Nil(t, response.Err, "could not compare root hints")
+	require.Equal(t, []string{"a.root-servers.net"}, response.Missing, "could not match missing hints")
+	require.Equal(t, []string{"z.root-servers.net"}, response.Unknown, "could not match unknown hints")
+	require.Equal(t, []string{"199.9.14.201"}, response.UnknownGlue, "could not match unknown glue")
+
+	response = &PrimingResponse{}
+	compareRootHints(response, &retryabledns.DNSData{StatusCode: "REFUSED"})
+	require.ErrorContains(t, response.Err, "no root hints in the response (REFUSED)", "could not match error without hints")
+}
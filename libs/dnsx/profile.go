@@ -0,0 +1,83 @@
+package dnsx
+
+import (
+	"context"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+)
+
+// QueryProfile overrides the query settings of the dnsx instance for a group of hosts
+type QueryProfile struct {
+	// QuestionTypes replaces the configured question types when set
+	QuestionTypes []uint16
+	// NoRecursion clears the recursion desired flag of the questions
+	NoRecursion bool
+	// client queries the profile resolvers, nil for the configured ones
+	client *client
+}
+
+// NewQueryProfile creates a profile querying the question types with the resolvers, the configured
+// settings being kept for the empty ones
+func (d *DNSX) NewQueryProfile(questionTypes []uint16, resolvers []string, noRecursion bool) (*QueryProfile, error) {
+	profile := &QueryProfile{QuestionTypes: questionTypes, NoRecursion: noRecursion}
+	if len(resolvers) > 0 {
+		client, err := newClient(d.Options, resolvers)
+		if err != nil {
+			return nil, err
+		}
+		profile.client = client
+		d.clientMutex.Lock()
+		d.profileClients = append(d.profileClients, client)
+		d.clientMutex.Unlock()
+	}
+	return profile, nil
+}
+
+// QuestionTypesWithProfile returns the question types sent for the host with the profile
+func (d *DNSX) QuestionTypesWithProfile(hostname string, profile *QueryProfile) []uint16 {
+	if len(profile.QuestionTypes) > 0 {
+		return profile.QuestionTypes
+	}
+	return d.QuestionTypesFor(hostname)
+}
+
+// QueryMultipleWithProfile performs the dns questions with the settings of the profile
+func (d *DNSX) QueryMultipleWithProfile(hostname string, profile *QueryProfile) (*retryabledns.DNSData, error) {
+	return d.QueryMultipleWithProfileContext(context.Background(), hostname, profile)
+}
+
+// QueryMultipleWithProfileContext performs the dns questions like QueryMultipleWithProfile, the queries ending
+// with the context
+func (d *DNSX) QueryMultipleWithProfileContext(ctx context.Context, hostname string, profile *QueryProfile) (*retryabledns.DNSData, error) {
+	hostname = normalizeIP(hostname)
+	client := profile.client
+	if client == nil {
+		client = d.client()
+	}
+	questionTypes := d.QuestionTypesWithProfile(hostname, profile)
+	if profile.NoRecursion {
+		return client.queryPrepared(ctx, hostname, questionTypes, nil, func(msg *miekgdns.Msg) {
+			msg.RecursionDesired = false
+		})
+	}
+	return client.queryMultiple(ctx, hostname, questionTypes, nil)
+}
+
+// doWith sends the prepared message to the resolver override when set, else to the resolvers of the profile
+// when set, else to the configured ones, as the questions of the host are
+func (d *DNSX) doWith(ctx context.Context, msg *miekgdns.Msg, resolver string, profile *QueryProfile) (*miekgdns.Msg, error) {
+	client := d.client()
+	if resolver != "" {
+		return client.do(ctx, msg, parseResolver(resolver))
+	}
+	if profile != nil {
+		if profile.client != nil {
+			client = profile.client
+		}
+		if profile.NoRecursion {
+			msg.RecursionDesired = false
+		}
+	}
+	return client.do(ctx, msg, nil)
+}
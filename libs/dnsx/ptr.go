@@ -0,0 +1,34 @@
+package dnsx
+
+import (
+	"net"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// normalizeIP returns the canonical form of bracketed or zoned ipv6 addresses (eg. [2001:db8::1], fe80::1%eth0)
+// so that ptr questions are built on the nibble-reversed ip6.arpa name instead of the literal input
+func normalizeIP(host string) string {
+	candidate := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if idx := strings.LastIndex(candidate, "%"); idx > 0 {
+		candidate = candidate[:idx]
+	}
+	ip := net.ParseIP(candidate)
+	if ip == nil {
+		return host
+	}
+	return ip.String()
+}
+
+// QueryName returns the name the questions for the host are sent for, the in-addr.arpa or ip6.arpa name of the
+// ptr questions for an ip
+func QueryName(host string) string {
+	host = normalizeIP(host)
+	if net.ParseIP(host) != nil {
+		if name, err := miekgdns.ReverseAddr(host); err == nil {
+			return strings.TrimSuffix(name, ".")
+		}
+	}
+	return host
+}
@@ -0,0 +1,40 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseAddrIPv6(t *testing.T) {
+	const expected = "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."
+	tests := []string{
+		"2001:db8::1",
+		"2001:0db8:0000:0000:0000:0000:0000:0001",
+		"2001:DB8::1",
+		"[2001:db8::1]",
+		"2001:db8::1%eth0",
+	}
+	for _, ip := range tests {
+		name, err := miekgdns.ReverseAddr(normalizeIP(ip))
+		require.Nil(t, err, "could not reverse %s", ip)
+		require.Equal(t, expected, name, "invalid ptr name for %s", ip)
+	}
+}
+
+func TestReverseAddrIPv4Mapped(t *testing.T) {
+	name, err := miekgdns.ReverseAddr(normalizeIP("::ffff:192.0.2.1"))
+	require.Nil(t, err, "could not reverse ipv4 mapped address")
+	require.Equal(t, "1.2.0.192.in-addr.arpa.", name)
+}
+
+func TestNormalizeIPHostname(t *testing.T) {
+	require.Equal(t, "example.com", normalizeIP("example.com"))
+}
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "www.example.com", QueryName("www.example.com"), "could not match hostname")
+	require.Equal(t, "1.2.0.192.in-addr.arpa", QueryName("192.0.2.1"), "could not match ipv4 reverse name")
+	require.Equal(t, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa", QueryName("[2001:db8::1]"), "could not match ipv6 reverse name")
+}
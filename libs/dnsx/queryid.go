@@ -0,0 +1,43 @@
+package dnsx
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// Generation modes of the query message ids
+const (
+	QueryIDRandom     = "random"
+	QueryIDFixed      = "fixed"
+	QueryIDSequential = "sequential"
+)
+
+// queryIDs generates the message ids of the queries of a dnsx instance, shared by all its clients, and counts
+// the responses whose id differed from the query one
+type queryIDs struct {
+	next       func() uint16
+	mismatches atomic.Uint64
+}
+
+// newQueryIDs returns the generator of the ids of the mode: random draws them from the cryptographically random
+// generator of the dns library, fixed always returns id and sequential counts up from id
+func newQueryIDs(mode string, id uint16) (*queryIDs, error) {
+	switch mode {
+	case "", QueryIDRandom:
+		return &queryIDs{next: miekgdns.Id}, nil
+	case QueryIDFixed:
+		return &queryIDs{next: func() uint16 { return id }}, nil
+	case QueryIDSequential:
+		next := uint32(id) - 1
+		return &queryIDs{next: func() uint16 { return uint16(atomic.AddUint32(&next, 1)) }}, nil
+	}
+	return nil, fmt.Errorf("invalid query id mode: %s", mode)
+}
+
+// IDMismatches returns the number of responses whose id differed from the query one. Over tcp and tls the
+// attempt fails, over udp the response is skipped and the attempt keeps waiting for the right one
+func (d *DNSX) IDMismatches() uint64 {
+	return d.Options.ids.mismatches.Load()
+}
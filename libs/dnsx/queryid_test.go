@@ -0,0 +1,117 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryIDs(t *testing.T) {
+	ids, err := newQueryIDs(QueryIDFixed, 4242)
+	require.Nil(t, err, "could not create fixed ids")
+	require.Equal(t, uint16(4242), ids.next(), "could not match fixed id")
+	require.Equal(t, uint16(4242), ids.next(), "fixed id changed")
+
+	ids, err = newQueryIDs(QueryIDSequential, 65535)
+	require.Nil(t, err, "could not create sequential ids")
+	require.Equal(t, uint16(65535), ids.next(), "could not match first sequential id")
+	require.Equal(t, uint16(0), ids.next(), "sequential id did not wrap")
+
+	_, err = newQueryIDs("guessable", 0)
+	require.NotNil(t, err, "invalid mode accepted")
+}
+
+func TestQueryIDsPerInstance(t *testing.T) {
+	// the server records the ids of the queries
+	received := make(chan uint16, 10)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		received <- r.Id
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.QueryIDMode = QueryIDFixed
+	options.QueryID = 4242
+	fixed, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	options.QueryIDMode = QueryIDSequential
+	options.QueryID = 10
+	sequential, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	_, _ = sequential.QueryOne("example.com")
+	require.Equal(t, uint16(10), <-received, "could not match sequential id")
+	_, _ = fixed.QueryOne("example.com")
+	require.Equal(t, uint16(4242), <-received, "fixed id replaced by the other instance")
+	_, _ = sequential.QueryOne("example.com")
+	require.Equal(t, uint16(11), <-received, "could not match next sequential id")
+}
+
+func TestIDMismatches(t *testing.T) {
+	// the server echoes a different id over tcp
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{Listener: listener, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		m.Id = r.Id + 1
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{"tcp:" + listener.Addr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 2
+	options.Timeout = time.Second
+	options.QueryIDMode = QueryIDFixed
+	options.QueryID = 1000
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	_, _ = dnsX.QueryMultiple("example.com")
+	require.Equal(t, uint64(2), dnsX.IDMismatches(), "could not match id mismatches")
+}
+
+func TestIDMismatchesUDP(t *testing.T) {
+	// the server sends a response with another id before the right one
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		m.Id = r.Id + 1
+		_ = w.WriteMsg(m)
+		m.Id = r.Id
+		a, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN A 192.0.2.1")
+		m.Answer = append(m.Answer, a)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.Timeout = time.Second
+	options.QueryIDMode = QueryIDSequential
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	dnsdata, err := dnsX.QueryMultiple("example.com")
+	require.Nil(t, err, "mismatching datagram not skipped")
+	require.Equal(t, []string{"192.0.2.1"}, dnsdata.A, "could not match answer")
+	require.Equal(t, uint64(1), dnsX.IDMismatches(), "could not match id mismatches")
+}
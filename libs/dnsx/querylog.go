@@ -0,0 +1,94 @@
+package dnsx
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// QueryAttempt is a single attempt of a dns question, as written to the query log
+type QueryAttempt struct {
+	Timestamp time.Time `json:"timestamp"`
+	Host      string    `json:"host"`
+	Type      string    `json:"type"`
+	Resolver  string    `json:"resolver"`
+	// Attempt starts at 1 for the first attempt of the question
+	Attempt int `json:"attempt"`
+	// Rcode is empty when no response was received
+	Rcode     string  `json:"rcode,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// QueryLog writes a json line for every query attempt, through a dedicated channel so that the queries are
+// not slowed down by the writes. The attempts recorded once it is closed are dropped
+type QueryLog struct {
+	logchan chan QueryAttempt
+	writer  *bufio.Writer
+	wg      sync.WaitGroup
+	// mutex guards the channel against the attempts recorded while closing
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// NewQueryLog starts writing the query attempts to writer
+func NewQueryLog(writer io.Writer) *QueryLog {
+	l := &QueryLog{logchan: make(chan QueryAttempt), writer: bufio.NewWriter(writer)}
+	l.wg.Add(1)
+	go l.handle()
+	return l
+}
+
+// record sends the attempt of the question of the request started at start to the writer
+func (l *QueryLog) record(hostname, resolver string, attempt int, start time.Time, request, response *miekgdns.Msg, err error) {
+	item := QueryAttempt{
+		Timestamp: start,
+		Host:      hostname,
+		Resolver:  resolver,
+		Attempt:   attempt + 1,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if len(request.Question) > 0 {
+		item.Type = miekgdns.TypeToString[request.Question[0].Qtype]
+	}
+	if response != nil {
+		item.Rcode = miekgdns.RcodeToString[response.Rcode]
+	}
+	if err != nil {
+		item.Error = err.Error()
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if !l.closed {
+		l.logchan <- item
+	}
+}
+
+func (l *QueryLog) handle() {
+	defer l.wg.Done()
+
+	encoder := json.NewEncoder(l.writer)
+	for item := range l.logchan {
+		_ = encoder.Encode(item)
+	}
+}
+
+// Close writes the pending attempts, the attempts of the queries still running being dropped
+func (l *QueryLog) Close() error {
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.logchan)
+	l.mutex.Unlock()
+
+	l.wg.Wait()
+	return l.writer.Flush()
+}
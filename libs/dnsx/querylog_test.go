@@ -0,0 +1,84 @@
+package dnsx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLog(t *testing.T) {
+	// the server drops the first question
+	var questions atomic.Int32
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		if questions.Add(1) == 1 {
+			return
+		}
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		if r.Question[0].Qtype == miekgdns.TypeA {
+			hdr := miekgdns.RR_Header{Name: r.Question[0].Name, Rrtype: miekgdns.TypeA, Class: miekgdns.ClassINET, Ttl: 300}
+			m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP("192.0.2.1")})
+		} else {
+			// the nodata responses carry the soa of the zone, the empty ones being retried
+			soa, _ := miekgdns.NewRR("example.com. 60 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 60")
+			m.Ns = append(m.Ns, soa)
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	var buffer bytes.Buffer
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 2
+	options.Timeout = 100 * time.Millisecond
+	options.QuestionTypes = []uint16{miekgdns.TypeA, miekgdns.TypeAAAA}
+	options.QueryLog = NewQueryLog(&buffer)
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	data, err := dnsX.QueryMultiple("example.com")
+	require.Nil(t, err, "could not query")
+	require.Equal(t, []string{"192.0.2.1"}, data.A, "could not match records")
+	// the queries of the other paths are logged as well
+	_, err = dnsX.QueryMultipleWithResolver("override.example.com", conn.LocalAddr().String())
+	require.Nil(t, err, "could not query the override")
+	_, err = dnsX.QueryDNSSEC("example.com")
+	require.Nil(t, err, "could not query dnssec")
+	require.Nil(t, options.QueryLog.Close(), "could not close query log")
+	// the queries still running once closed are dropped
+	_, err = dnsX.QueryMultiple("closed.example.com")
+	require.Nil(t, err, "could not query once the log is closed")
+
+	var attempts []QueryAttempt
+	scanner := bufio.NewScanner(&buffer)
+	for scanner.Scan() {
+		var attempt QueryAttempt
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &attempt), "could not decode attempt")
+		attempts = append(attempts, attempt)
+	}
+	// each question type is logged on its own
+	require.Len(t, attempts, 6, "could not match attempts")
+	require.Equal(t, "A", attempts[0].Type, "could not match type")
+	require.Equal(t, 1, attempts[0].Attempt, "could not match first attempt")
+	require.Empty(t, attempts[0].Rcode, "rcode of a dropped question")
+	require.NotEmpty(t, attempts[0].Error, "missing error of a dropped question")
+	require.Equal(t, 2, attempts[1].Attempt, "could not match retry")
+	require.Equal(t, "NOERROR", attempts[1].Rcode, "could not match rcode")
+	require.Equal(t, "AAAA", attempts[2].Type, "could not match type")
+	require.Equal(t, conn.LocalAddr().String(), attempts[2].Resolver, "could not match resolver")
+	require.Equal(t, "override.example.com", attempts[3].Host, "override not logged")
+	require.Equal(t, "AAAA", attempts[4].Type, "could not match type of the override")
+	require.Equal(t, "DNSKEY", attempts[5].Type, "dnssec query not logged")
+	require.Equal(t, "example.com", attempts[5].Host, "could not match host of the dnssec query")
+}
@@ -0,0 +1,30 @@
+package dnsx
+
+import (
+	"net"
+)
+
+// CheckAllowedRanges sets the A and AAAA records falling outside all the allowed networks as out of range
+func (d *ResponseData) CheckAllowedRanges(allowed []*net.IPNet) {
+	if d.DNSData == nil {
+		return
+	}
+	for _, record := range append(append([]string{}, d.A...), d.AAAA...) {
+		ip := net.ParseIP(record)
+		if ip == nil {
+			continue
+		}
+		if !ipInNetworks(ip, allowed) {
+			d.OutOfRange = append(d.OutOfRange, record)
+		}
+	}
+}
+
+func ipInNetworks(ip net.IP, networks []*net.IPNet) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
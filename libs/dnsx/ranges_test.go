@@ -0,0 +1,21 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAllowedRanges(t *testing.T) {
+	_, ipv4, _ := net.ParseCIDR("192.0.2.0/24")
+	_, ipv6, _ := net.ParseCIDR("2001:db8::/32")
+	d := &ResponseData{DNSData: &retryabledns.DNSData{A: []string{"192.0.2.10", "203.0.113.5"}, AAAA: []string{"2001:db8::1", "2001:db9::1"}}}
+	d.CheckAllowedRanges([]*net.IPNet{ipv4, ipv6})
+	require.Equal(t, []string{"203.0.113.5", "2001:db9::1"}, d.OutOfRange, "could not match out of range records")
+
+	d = &ResponseData{DNSData: &retryabledns.DNSData{A: []string{"192.0.2.10"}}}
+	d.CheckAllowedRanges([]*net.IPNet{ipv4})
+	require.Empty(t, d.OutOfRange, "allowed record reported")
+}
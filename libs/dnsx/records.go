@@ -0,0 +1,74 @@
+package dnsx
+
+import (
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// parsedTypes are the types of the records parsed into fields of their own
+var parsedTypes = map[string]struct{}{
+	"TLSA":   {},
+	"DNSKEY": {},
+	"DS":     {},
+	"HTTPS":  {},
+	"SVCB":   {},
+	"NAPTR":  {},
+	"SSHFP":  {},
+	"DNAME":  {},
+}
+
+// parseRecords sets the fields of the parsed types from the answer records of all the question types, each record
+// being parsed once and the others skipped. The records repeated by the retried queries are kept once
+func (d *ResponseData) parseRecords() {
+	d.TLSA, d.DNSKEY, d.DS, d.HTTPS, d.SVCB, d.NAPTR, d.SSHFP, d.DNAME = nil, nil, nil, nil, nil, nil, nil, nil
+	seen := make(map[string]struct{})
+	for _, record := range d.AllRecords {
+		if !isParsedType(record) {
+			continue
+		}
+		rr, err := miekgdns.NewRR(record)
+		if err != nil || rr == nil {
+			continue
+		}
+		// the ttls differ across the responses, the owner name only matters for the redirections
+		key := miekgdns.TypeToString[rr.Header().Rrtype] + " " + strings.TrimPrefix(rr.String(), rr.Header().String())
+		if dname, ok := rr.(*miekgdns.DNAME); ok {
+			key = normalizeName(dname.Hdr.Name) + " " + key
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		switch rr := rr.(type) {
+		case *miekgdns.TLSA:
+			d.TLSA = append(d.TLSA, formatTLSA(rr))
+		case *miekgdns.DNSKEY:
+			d.DNSKEY = append(d.DNSKEY, newDNSKEY(rr))
+		case *miekgdns.DS:
+			d.DS = append(d.DS, newDS(rr))
+		case *miekgdns.HTTPS:
+			d.HTTPS = append(d.HTTPS, newServiceBinding(&rr.SVCB))
+		case *miekgdns.SVCB:
+			d.SVCB = append(d.SVCB, newServiceBinding(rr))
+		case *miekgdns.NAPTR:
+			d.NAPTR = append(d.NAPTR, newNAPTR(rr))
+		case *miekgdns.SSHFP:
+			d.SSHFP = append(d.SSHFP, newSSHFP(rr))
+		case *miekgdns.DNAME:
+			d.DNAME = append(d.DNAME, newDNAME(rr))
+		}
+	}
+}
+
+// isParsedType returns true if the record in presentation format (name, ttl, class, type and data separated by
+// tabs) is of a parsed type, the records in another format being parsed to find out
+func isParsedType(record string) bool {
+	fields := strings.SplitN(record, "\t", 5)
+	if len(fields) < 5 {
+		return true
+	}
+	_, ok := parsedTypes[fields[3]]
+	return ok
+}
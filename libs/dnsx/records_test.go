@@ -0,0 +1,40 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecords(t *testing.T) {
+	var records []string
+	for _, record := range []string{
+		"example.com. 60 IN A 192.0.2.1",
+		"_443._tcp.example.com. 60 IN TLSA 3 1 1 0C72AC70",
+		// repeated by a retried query with another ttl
+		"_443._tcp.example.com. 30 IN TLSA 3 1 1 0C72AC70",
+		"example.com. 60 IN HTTPS 1 . alpn=h2",
+		"example.com. 60 IN SSHFP 4 2 5A7B",
+		"a.example.com. 60 IN DNAME b.example.net.",
+		// another owner redirected to the same target
+		"c.example.com. 60 IN DNAME b.example.net.",
+	} {
+		rr, err := miekgdns.NewRR(record)
+		require.Nil(t, err, "could not parse %s", record)
+		records = append(records, rr.String())
+	}
+	data := &ResponseData{DNSData: &retryabledns.DNSData{AllRecords: records}}
+	data.parseRecords()
+	require.Equal(t, []string{"3 1 1 0c72ac70"}, data.TLSA, "could not match tlsa")
+	require.Len(t, data.HTTPS, 1, "could not match https")
+	require.Equal(t, []string{"h2"}, data.HTTPS[0].ALPN, "could not match alpn")
+	require.Empty(t, data.SVCB, "svcb parsed from https")
+	require.Equal(t, []SSHFP{{Algorithm: 4, AlgorithmName: "Ed25519", Type: 2, TypeName: "SHA-256", Fingerprint: "5a7b"}}, data.SSHFP, "could not match sshfp")
+	require.Equal(t, []DNAME{{Name: "a.example.com", Target: "b.example.net"}, {Name: "c.example.com", Target: "b.example.net"}}, data.DNAME, "could not match dname")
+
+	require.False(t, isParsedType(records[0]), "A record parsed")
+	require.True(t, isParsedType(records[1]), "TLSA record skipped")
+	require.True(t, isParsedType("example.com. 60 IN TLSA 3 1 1 0c72ac70"), "record without tabs skipped")
+}
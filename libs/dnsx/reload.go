@@ -0,0 +1,58 @@
+package dnsx
+
+import (
+	"errors"
+)
+
+var errEmptyResolvers = errors.New("resolvers list must not be empty")
+
+// client returns the active client
+func (d *DNSX) client() *client {
+	d.clientMutex.RLock()
+	defer d.clientMutex.RUnlock()
+	return d.dnsClient
+}
+
+// resolvers returns the active resolvers
+func (d *DNSX) resolvers() []string {
+	d.clientMutex.RLock()
+	defer d.clientMutex.RUnlock()
+	return d.Options.BaseResolvers
+}
+
+// SetResolvers swaps the resolvers used by the following queries, the current ones are kept on error. The idle
+// udp connections of the replaced clients are closed, their other connections being left to the in-flight queries
+// and closed by the servers once idle
+func (d *DNSX) SetResolvers(resolvers []string) error {
+	if len(resolvers) == 0 {
+		return errEmptyResolvers
+	}
+	dnsClient, err := newClient(d.Options, resolvers)
+	if err != nil {
+		return err
+	}
+	d.clientMutex.Lock()
+	defer d.clientMutex.Unlock()
+	for _, replaced := range []*client{d.dnsClient, d.tcpClient} {
+		if replaced != nil {
+			replaced.transport.udp.close()
+		}
+	}
+	d.dnsClient = dnsClient
+	d.tcpClient = nil
+	d.Options.BaseResolvers = resolvers
+	return nil
+}
+
+// Close closes the connections kept open to the doh and doq resolvers, by the clients of the instance and of its
+// query profiles. The queries must be over
+func (d *DNSX) Close() {
+	d.clientMutex.Lock()
+	defer d.clientMutex.Unlock()
+
+	for _, client := range append([]*client{d.dnsClient, d.tcpClient}, d.profileClients...) {
+		if client != nil {
+			client.close()
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package dnsx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetResolvers(t *testing.T) {
+	first := startUDPServer(t, "192.0.2.1")
+	second := startUDPServer(t, "192.0.2.2")
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{first}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.Timeout = time.Second
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	data, err := dnsX.QueryOne("example.com")
+	require.Nil(t, err, "could not query")
+	require.Equal(t, []string{"192.0.2.1"}, data.A, "could not match first resolver records")
+	// the tcp client is created for the current resolvers and replaced with them
+	_, err = dnsX.tcpQueryClient()
+	require.Nil(t, err, "could not create tcp client")
+
+	require.Nil(t, dnsX.SetResolvers([]string{second}), "could not set resolvers")
+	require.Nil(t, dnsX.tcpClient, "tcp client of the previous resolvers kept")
+	data, err = dnsX.QueryOne("example.com")
+	require.Nil(t, err, "could not query")
+	require.Equal(t, []string{"192.0.2.2"}, data.A, "could not match reloaded resolver records")
+	require.Equal(t, []string{second}, dnsX.resolvers(), "could not match active resolvers")
+
+	// an empty list keeps the current resolvers
+	require.ErrorIs(t, dnsX.SetResolvers(nil), errEmptyResolvers, "empty resolvers accepted")
+	require.Equal(t, []string{second}, dnsX.resolvers(), "resolvers replaced on error")
+}
@@ -0,0 +1,106 @@
+package dnsx
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+var errNoResolverAvailable = errors.New("no resolver available")
+
+// parseResolver converts a resolver in the [protocol:]host[:port] or quic://host[:port] form into a resolver
+func parseResolver(resolver string) retryabledns.Resolver {
+	if IsDoQResolver(resolver) {
+		return parseDoQResolver(resolver)
+	}
+	protocol := retryabledns.UDP
+	if len(resolver) > 4 && resolver[3] == ':' {
+		switch retryabledns.Protocol(resolver[:3]) {
+		case retryabledns.UDP:
+			resolver = resolver[4:]
+		case retryabledns.TCP:
+			protocol = retryabledns.TCP
+			resolver = resolver[4:]
+		case retryabledns.DOT:
+			protocol = retryabledns.DOT
+			resolver = resolver[4:]
+		case retryabledns.DOH:
+			return parseDohResolver(resolver[4:])
+		}
+	}
+
+	networkResolver := &retryabledns.NetworkResolver{Protocol: protocol}
+	if host, port, err := net.SplitHostPort(resolver); err == nil {
+		networkResolver.Host, networkResolver.Port = host, port
+	} else {
+		networkResolver.Host = resolver
+		networkResolver.Port = "53"
+		if protocol == retryabledns.DOT {
+			networkResolver.Port = "853"
+		}
+	}
+	return networkResolver
+}
+
+// parseDohResolver parses a doh url with an optional :get, :post or :jsonapi method suffix
+func parseDohResolver(resolver string) *retryabledns.DohResolver {
+	dohResolver := &retryabledns.DohResolver{URL: resolver, Protocol: retryabledns.POST}
+	for _, method := range []retryabledns.DohProtocol{retryabledns.GET, retryabledns.POST, retryabledns.JsonAPI} {
+		if strings.HasSuffix(resolver, method.StringWithSemicolon()) {
+			dohResolver.URL = strings.TrimSuffix(resolver, method.StringWithSemicolon())
+			dohResolver.Protocol = method
+			break
+		}
+	}
+	return dohResolver
+}
+
+// QueryMultipleExcluding performs the dns questions with a configured resolver not present in exclude, the
+// resolvers being picked in turn
+func (d *DNSX) QueryMultipleExcluding(hostname string, exclude []string) (*retryabledns.DNSData, error) {
+	return d.QueryMultipleExcludingContext(context.Background(), hostname, exclude)
+}
+
+// QueryMultipleExcludingContext performs the dns questions like QueryMultipleExcluding, the queries ending with
+// the context
+func (d *DNSX) QueryMultipleExcludingContext(ctx context.Context, hostname string, exclude []string) (*retryabledns.DNSData, error) {
+	hostname = normalizeIP(hostname)
+	resolvers := d.resolvers()
+	start := int(atomic.AddUint32(&d.excludingIndex, 1))
+	for i := range resolvers {
+		resolver := parseResolver(resolvers[(start+i)%len(resolvers)])
+		if sliceutil.Contains(exclude, resolver.String()) {
+			continue
+		}
+		return d.client().queryMultiple(ctx, hostname, d.questionTypes(hostname), resolver)
+	}
+	return nil, errNoResolverAvailable
+}
+
+// QueryMultipleWithResolver performs the dns questions using exclusively the given resolver
+func (d *DNSX) QueryMultipleWithResolver(hostname, resolver string) (*retryabledns.DNSData, error) {
+	return d.QueryMultipleWithResolverContext(context.Background(), hostname, resolver)
+}
+
+// QueryMultipleWithResolverContext performs the dns questions like QueryMultipleWithResolver, the queries ending
+// with the context
+func (d *DNSX) QueryMultipleWithResolverContext(ctx context.Context, hostname, resolver string) (*retryabledns.DNSData, error) {
+	hostname = normalizeIP(hostname)
+	networkResolver := parseResolver(resolver)
+	return d.client().queryMultiple(ctx, hostname, d.questionTypes(hostname), networkResolver)
+}
+
+// hashedResolver returns the resolver of the pool picked by hashing the host name, so that a host is
+// always sent to the same resolver for a given pool
+func (d *DNSX) hashedResolver(hostname string) string {
+	resolvers := d.resolvers()
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(strings.ToLower(trimDot(hostname))))
+	return resolvers[hash.Sum32()%uint32(len(resolvers))]
+}
@@ -0,0 +1,76 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// startUDPServer starts a dns server on a local udp port, answering the A questions with the ip when set and with
+// an empty answer otherwise
+func startUDPServer(t *testing.T, ip string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		if ip != "" && r.Question[0].Qtype == miekgdns.TypeA {
+			a, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN A " + ip)
+			m.Answer = append(m.Answer, a)
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestHashedResolver(t *testing.T) {
+	options := DefaultOptions
+	options.BaseResolvers = []string{"127.0.0.1:5301", "127.0.0.1:5302", "127.0.0.1:5303"}
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	resolver := dnsX.hashedResolver("www.example.com")
+	require.Contains(t, options.BaseResolvers, resolver, "unknown resolver")
+	for _, host := range []string{"www.example.com", "WWW.example.com", "www.example.com."} {
+		require.Equal(t, resolver, dnsX.hashedResolver(host), "different resolver for %s", host)
+	}
+
+	picked := make(map[string]struct{})
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com", "f.example.com"} {
+		picked[dnsX.hashedResolver(host)] = struct{}{}
+	}
+	require.Greater(t, len(picked), 1, "hosts not spread over the resolvers")
+}
+
+func TestQueryMultipleExcluding(t *testing.T) {
+	// the first resolver returns an empty answer, the others a record of their own
+	nodata := startUDPServer(t, "")
+	options := DefaultOptions
+	options.BaseResolvers = []string{nodata, startUDPServer(t, "192.0.2.1"), startUDPServer(t, "192.0.2.2")}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.Timeout = time.Second
+	options.QuestionTypes = []uint16{miekgdns.TypeA}
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	// the retries of the empty answers are spread over the other resolvers
+	picked := make(map[string]struct{})
+	for i := 0; i < 4; i++ {
+		data, err := dnsX.QueryMultipleExcluding("example.com", []string{nodata})
+		require.Nil(t, err, "could not query")
+		require.Len(t, data.Resolver, 1, "could not match resolvers")
+		require.NotEqual(t, nodata, data.Resolver[0], "excluded resolver queried")
+		require.Len(t, data.A, 1, "could not match records")
+		picked[data.Resolver[0]] = struct{}{}
+	}
+	require.Len(t, picked, 2, "resolvers not rotated")
+
+	_, err = dnsX.QueryMultipleExcluding("example.com", options.BaseResolvers)
+	require.ErrorIs(t, err, errNoResolverAvailable, "could not match error with every resolver excluded")
+}
@@ -0,0 +1,71 @@
+package dnsx
+
+import (
+	"sort"
+	"sync"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// ResolverCount is what a resolver did during the run
+type ResolverCount struct {
+	Resolver string `json:"resolver"`
+	// Queries is the number of attempts sent to the resolver
+	Queries   int `json:"queries"`
+	Responses int `json:"responses"`
+	// Answers is the number of records in the answer section of the responses
+	Answers int `json:"answers"`
+	// Failures is the number of responses with an error rcode other than NXDOMAIN (eg. SERVFAIL, REFUSED)
+	Failures int `json:"failures"`
+}
+
+// ResolverStats counts the attempts handled by each resolver and the records they returned, for all the
+// queries of the instance. Its memory grows with the number of resolvers only
+type ResolverStats struct {
+	mutex     sync.Mutex
+	resolvers map[string]*ResolverCount
+}
+
+// NewResolverStats creates an empty resolver statistics collector
+func NewResolverStats() *ResolverStats {
+	return &ResolverStats{resolvers: make(map[string]*ResolverCount)}
+}
+
+// record counts an attempt sent to resolver and its response, nil when none was received
+func (s *ResolverStats) record(resolver string, response *miekgdns.Msg) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count, ok := s.resolvers[resolver]
+	if !ok {
+		count = &ResolverCount{Resolver: resolver}
+		s.resolvers[resolver] = count
+	}
+	count.Queries++
+	if response == nil {
+		return
+	}
+	count.Responses++
+	count.Answers += len(response.Answer)
+	if response.Rcode != miekgdns.RcodeSuccess && response.Rcode != miekgdns.RcodeNameError {
+		count.Failures++
+	}
+}
+
+// Resolvers returns the counts of the resolvers, the most queried first
+func (s *ResolverStats) Resolvers() []ResolverCount {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make([]ResolverCount, 0, len(s.resolvers))
+	for _, count := range s.resolvers {
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Queries != counts[j].Queries {
+			return counts[i].Queries > counts[j].Queries
+		}
+		return counts[i].Resolver < counts[j].Resolver
+	})
+	return counts
+}
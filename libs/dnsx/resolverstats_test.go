@@ -0,0 +1,72 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolverStats(t *testing.T) {
+	stats := NewResolverStats()
+	answer := func(rcode int, records ...string) *miekgdns.Msg {
+		msg := &miekgdns.Msg{}
+		msg.Rcode = rcode
+		for _, record := range records {
+			rr, _ := miekgdns.NewRR(record)
+			msg.Answer = append(msg.Answer, rr)
+		}
+		return msg
+	}
+	stats.record("udp:1.1.1.1:53", answer(miekgdns.RcodeSuccess, "example.com. 60 IN A 192.0.2.1", "example.com. 60 IN A 192.0.2.2"))
+	stats.record("udp:8.8.8.8:53", answer(miekgdns.RcodeSuccess, "example.com. 60 IN A 192.0.2.1"))
+	stats.record("udp:8.8.8.8:53", answer(miekgdns.RcodeNameError))
+	stats.record("udp:9.9.9.9:53", answer(miekgdns.RcodeServerFailure))
+	stats.record("udp:9.9.9.9:53", nil)
+
+	expected := []ResolverCount{
+		{Resolver: "udp:8.8.8.8:53", Queries: 2, Responses: 2, Answers: 1},
+		{Resolver: "udp:9.9.9.9:53", Queries: 2, Responses: 1, Failures: 1},
+		{Resolver: "udp:1.1.1.1:53", Queries: 1, Responses: 1, Answers: 2},
+	}
+	require.Equal(t, expected, stats.Resolvers(), "could not match resolver counts")
+}
+
+func TestResolverStatsQueries(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		if r.Question[0].Qtype == miekgdns.TypeA {
+			a, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, a)
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.Timeout = time.Second
+	options.QuestionTypes = []uint16{miekgdns.TypeA, miekgdns.TypeAAAA}
+	options.ResolverStats = NewResolverStats()
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	_, err = dnsX.QueryMultiple("example.com")
+	require.Nil(t, err, "could not query")
+	// the overrides are counted as well
+	_, err = dnsX.QueryMultipleWithResolver("example.org", "127.0.0.1:1")
+	require.NotNil(t, err, "query answered by a closed port")
+
+	expected := []ResolverCount{
+		{Resolver: "127.0.0.1:1", Queries: 2},
+		{Resolver: conn.LocalAddr().String(), Queries: 2, Responses: 2, Answers: 1},
+	}
+	require.ElementsMatch(t, expected, options.ResolverStats.Resolvers(), "could not match resolver counts")
+}
@@ -0,0 +1,34 @@
+package dnsx
+
+import (
+	"net"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+)
+
+// RootServers are the root servers of the IANA root hints (https://www.internic.net/domain/named.root), b-root
+// having been renumbered in 2023
+var RootServers = []retryabledns.RootDNS{
+	{Host: "a.root-servers.net", IPv4: "198.41.0.4", IPv6: "2001:503:ba3e::2:30", Operator: "Verisign, Inc."},
+	{Host: "b.root-servers.net", IPv4: "170.247.170.2", IPv6: "2801:1b8:10::b", Operator: "University of Southern California, Information Sciences Institute"},
+	{Host: "c.root-servers.net", IPv4: "192.33.4.12", IPv6: "2001:500:2::c", Operator: "Cogent Communications"},
+	{Host: "d.root-servers.net", IPv4: "199.7.91.13", IPv6: "2001:500:2d::d", Operator: "University of Maryland"},
+	{Host: "e.root-servers.net", IPv4: "192.203.230.10", IPv6: "2001:500:a8::e", Operator: "NASA (Ames Research Center)"},
+	{Host: "f.root-servers.net", IPv4: "192.5.5.241", IPv6: "2001:500:2f::f", Operator: "Internet Systems Consortium, Inc."},
+	{Host: "g.root-servers.net", IPv4: "192.112.36.4", IPv6: "2001:500:12::d0d", Operator: "US Department of Defense (NIC)"},
+	{Host: "h.root-servers.net", IPv4: "198.97.190.53", IPv6: "2001:500:1::53", Operator: "US Army (Research Lab)"},
+	{Host: "i.root-servers.net", IPv4: "192.36.148.17", IPv6: "2001:7fe::53", Operator: "Netnod"},
+	{Host: "j.root-servers.net", IPv4: "192.58.128.30", IPv6: "2001:503:c27::2:30", Operator: "Verisign, Inc."},
+	{Host: "k.root-servers.net", IPv4: "193.0.14.129", IPv6: "2001:7fd::1", Operator: "RIPE NCC"},
+	{Host: "l.root-servers.net", IPv4: "199.7.83.42", IPv6: "2001:500:9f::42", Operator: "ICANN"},
+	{Host: "m.root-servers.net", IPv4: "202.12.27.33", IPv6: "2001:dc3::35", Operator: "WIDE Project"},
+}
+
+// rootServerAddresses returns the ipv4 ip:port of the root servers
+func rootServerAddresses() []string {
+	addresses := make([]string, 0, len(RootServers))
+	for _, root := range RootServers {
+		addresses = append(addresses, net.JoinHostPort(root.IPv4, "53"))
+	}
+	return addresses
+}
@@ -0,0 +1,21 @@
+package dnsx
+
+import miekgdns "github.com/miekg/dns"
+
+// SectionCounts is the number of records in each section of a dns response, as announced in its header
+// (the additional section includes the edns OPT pseudo-record, as reported by dig). The counts are the ones of
+// a single response: a host queried for several types only keeps the raw response of its last type, whose
+// counts are reported, the sections of the other responses being neither summed nor kept
+type SectionCounts struct {
+	Answer     int `json:"answer" csv:"answer"`
+	Authority  int `json:"authority" csv:"authority"`
+	Additional int `json:"additional" csv:"additional"`
+}
+
+// CountSections returns the number of records in the answer, authority and additional sections of the message
+func CountSections(msg *miekgdns.Msg) *SectionCounts {
+	if msg == nil {
+		return nil
+	}
+	return &SectionCounts{Answer: len(msg.Answer), Authority: len(msg.Ns), Additional: len(msg.Extra)}
+}
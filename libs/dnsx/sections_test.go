@@ -0,0 +1,19 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountSections(t *testing.T) {
+	require.Nil(t, CountSections(nil), "counts for a missing response")
+
+	// referral: no answer, the delegation in the authority section and the glue in the additional section
+	ns, _ := miekgdns.NewRR("example.com. 3600 IN NS ns1.example.com.")
+	glue, _ := miekgdns.NewRR("ns1.example.com. 3600 IN A 192.0.2.53")
+	msg := &miekgdns.Msg{Ns: []miekgdns.RR{ns}, Extra: []miekgdns.RR{glue}}
+	msg.SetEdns0(1232, false)
+	require.Equal(t, &SectionCounts{Answer: 0, Authority: 1, Additional: 2}, CountSections(msg), "could not match section counts")
+}
@@ -0,0 +1,97 @@
+package dnsx
+
+import (
+	"sync"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// WireSize accumulates the wire sizes of dns messages
+type WireSize struct {
+	Count int
+	Total int
+	Min   int
+	Max   int
+}
+
+// Average returns the average message size
+func (w WireSize) Average() float64 {
+	if w.Count == 0 {
+		return 0
+	}
+	return float64(w.Total) / float64(w.Count)
+}
+
+func (w *WireSize) add(size int) {
+	if w.Count == 0 || size < w.Min {
+		w.Min = size
+	}
+	if size > w.Max {
+		w.Max = size
+	}
+	w.Count++
+	w.Total += size
+}
+
+// SizeStats collects the wire sizes of the requests sent by every attempt of the queries and of the responses
+// received
+type SizeStats struct {
+	mutex     sync.Mutex
+	requests  WireSize
+	responses WireSize
+	byType    map[uint16]*WireSize
+}
+
+// NewSizeStats creates an empty wire size collector
+func NewSizeStats() *SizeStats {
+	return &SizeStats{byType: make(map[uint16]*WireSize)}
+}
+
+// Requests returns the sizes of all the requests sent
+func (s *SizeStats) Requests() WireSize {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests
+}
+
+// Responses returns the sizes of all the responses received
+func (s *SizeStats) Responses() WireSize {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.responses
+}
+
+// ResponsesByType returns the sizes of the responses received for each question type
+func (s *SizeStats) ResponsesByType() map[uint16]WireSize {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	byType := make(map[uint16]WireSize, len(s.byType))
+	for questionType, size := range s.byType {
+		byType[questionType] = *size
+	}
+	return byType
+}
+
+// record accounts the request sent by an attempt and the size of its response, 0 when none was received
+func (s *SizeStats) record(request *miekgdns.Msg, responseSize int) {
+	requestSize := request.Len()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests.add(requestSize)
+	if responseSize == 0 {
+		return
+	}
+	s.responses.add(responseSize)
+	if len(request.Question) == 0 {
+		return
+	}
+	questionType := request.Question[0].Qtype
+	size, ok := s.byType[questionType]
+	if !ok {
+		size = &WireSize{}
+		s.byType[questionType] = size
+	}
+	size.add(responseSize)
+}
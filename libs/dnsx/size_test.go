@@ -0,0 +1,65 @@
+package dnsx
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeStats(t *testing.T) {
+	// the server drops the first question and returns a compressed answer
+	var questions, responseSize atomic.Int32
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		if questions.Add(1) == 1 {
+			return
+		}
+		m := &miekgdns.Msg{Compress: true}
+		m.SetReply(r)
+		if r.Question[0].Qtype == miekgdns.TypeA {
+			for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+				a, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN A " + ip)
+				m.Answer = append(m.Answer, a)
+			}
+			packed, _ := m.Pack()
+			responseSize.Store(int32(len(packed)))
+		} else {
+			// the nodata responses carry the soa of the zone, the empty ones being retried
+			soa, _ := miekgdns.NewRR("example.com. 60 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 60")
+			m.Ns = append(m.Ns, soa)
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 2
+	options.Timeout = 100 * time.Millisecond
+	options.QuestionTypes = []uint16{miekgdns.TypeA, miekgdns.TypeAAAA}
+	options.Padding = DefaultPaddingBlockSize
+	options.SizeStats = NewSizeStats()
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	_, err = dnsX.QueryMultiple("example.com")
+	require.Nil(t, err, "could not query")
+
+	// the request of the failed attempt is counted, with its padded size
+	requests := options.SizeStats.Requests()
+	require.Equal(t, 3, requests.Count, "could not match requests")
+	require.Equal(t, DefaultPaddingBlockSize, requests.Min, "could not match the size of the padded requests")
+	require.Equal(t, DefaultPaddingBlockSize, requests.Max, "could not match the size of the padded requests")
+	responses := options.SizeStats.Responses()
+	require.Equal(t, 2, responses.Count, "could not match responses")
+	// the size of the compressed answer as received
+	a := options.SizeStats.ResponsesByType()[miekgdns.TypeA]
+	require.Equal(t, WireSize{Count: 1, Total: int(responseSize.Load()), Min: int(responseSize.Load()), Max: int(responseSize.Load())}, a, "could not match the size of the A response")
+	require.Equal(t, 1, options.SizeStats.ResponsesByType()[miekgdns.TypeAAAA].Count, "could not match the AAAA responses")
+}
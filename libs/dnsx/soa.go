@@ -0,0 +1,66 @@
+package dnsx
+
+import (
+	"fmt"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+)
+
+// recommended soa timer ranges in seconds (RFC 1912 section 2.2 and RFC 2308 section 5)
+const (
+	soaMinRefresh     = 20 * 60
+	soaMaxRefresh     = 12 * 60 * 60
+	soaMinRetry       = 2 * 60
+	soaMinExpire      = 7 * 24 * 60 * 60
+	soaMaxNegativeTTL = 24 * 60 * 60
+)
+
+// SOAWarning is a risky timer configuration found in the soa record of a zone
+type SOAWarning struct {
+	Zone    string `json:"zone,omitempty" csv:"zone"`
+	Warning string `json:"warning,omitempty" csv:"warning"`
+}
+
+// CheckSOATimers returns the warnings for the soa timers deviating from the recommended values
+func CheckSOATimers(soa retryabledns.SOA) []string {
+	var warnings []string
+	if soa.Expire < soaMinExpire {
+		warnings = append(warnings, fmt.Sprintf("expire %ds is shorter than one week (%ds), secondaries drop the zone quickly when the primary is unreachable", soa.Expire, soaMinExpire))
+	}
+	if soa.Refresh >= soa.Expire {
+		warnings = append(warnings, fmt.Sprintf("refresh %ds is not shorter than expire %ds, the zone expires on the secondaries before being refreshed", soa.Refresh, soa.Expire))
+	} else if uint64(soa.Refresh)+uint64(soa.Retry) >= uint64(soa.Expire) {
+		warnings = append(warnings, fmt.Sprintf("refresh %ds plus retry %ds is not shorter than expire %ds, a single failed refresh expires the zone", soa.Refresh, soa.Retry, soa.Expire))
+	}
+	if soa.Refresh < soaMinRefresh {
+		warnings = append(warnings, fmt.Sprintf("refresh %ds is shorter than %ds, secondaries poll the primary too often", soa.Refresh, soaMinRefresh))
+	} else if soa.Refresh > soaMaxRefresh {
+		warnings = append(warnings, fmt.Sprintf("refresh %ds is longer than %ds, changes reach the secondaries slowly", soa.Refresh, soaMaxRefresh))
+	}
+	if soa.Retry >= soa.Refresh {
+		warnings = append(warnings, fmt.Sprintf("retry %ds is not shorter than refresh %ds", soa.Retry, soa.Refresh))
+	} else if soa.Retry < soaMinRetry {
+		warnings = append(warnings, fmt.Sprintf("retry %ds is shorter than %ds, failed refreshes are retried too aggressively", soa.Retry, soaMinRetry))
+	}
+	if soa.Minttl > soaMaxNegativeTTL {
+		warnings = append(warnings, fmt.Sprintf("negative caching ttl %ds is longer than one day (%ds), new names stay unresolvable for too long", soa.Minttl, soaMaxNegativeTTL))
+	}
+	return warnings
+}
+
+// CheckSOAHealth populates the warnings for the soa records of the response, once per zone
+func (d *ResponseData) CheckSOAHealth() {
+	if d.DNSData == nil {
+		return
+	}
+	seen := make(map[string]struct{})
+	for _, soa := range d.SOA {
+		if _, ok := seen[soa.Name]; ok {
+			continue
+		}
+		seen[soa.Name] = struct{}{}
+		for _, warning := range CheckSOATimers(soa) {
+			d.SOAWarnings = append(d.SOAWarnings, SOAWarning{Zone: soa.Name, Warning: warning})
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package dnsx
+
+import (
+	"testing"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSOATimers(t *testing.T) {
+	healthy := retryabledns.SOA{Refresh: 7200, Retry: 900, Expire: 1209600, Minttl: 3600}
+	require.Empty(t, CheckSOATimers(healthy), "healthy soa reported as risky")
+
+	tests := map[string]retryabledns.SOA{
+		"short expire":            {Refresh: 3600, Retry: 600, Expire: 86400, Minttl: 3600},
+		"refresh beyond expire":   {Refresh: 43200, Retry: 600, Expire: 3600, Minttl: 3600},
+		"retry beyond refresh":    {Refresh: 3600, Retry: 7200, Expire: 1209600, Minttl: 3600},
+		"long negative cache ttl": {Refresh: 7200, Retry: 900, Expire: 1209600, Minttl: 604800},
+	}
+	for name, soa := range tests {
+		require.NotEmpty(t, CheckSOATimers(soa), "%s not reported", name)
+	}
+}
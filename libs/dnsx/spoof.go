@@ -0,0 +1,205 @@
+package dnsx
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// Response anomalies which might indicate on-path tampering
+const (
+	AnomalyCaseMismatch       = "0x20-case-mismatch"
+	AnomalyQuestionMismatch   = "question-mismatch"
+	AnomalyUnexpectedSource   = "unexpected-source"
+	AnomalyIDMismatch         = "id-mismatch"
+	AnomalyMalformedResponse  = "malformed-response"
+	AnomalyMultipleResponses  = "multiple-responses"
+	AnomalyConflictingAnswers = "conflicting-answers"
+)
+
+// spoofListenWindow is how long responses are still collected after the first one,
+// as injected answers usually win the race against the legitimate one
+const spoofListenWindow = 500 * time.Millisecond
+
+// SpoofDetector collects the anomalies of the udp responses received by the queries run with its context. These
+// queries are sent with a 0x20 encoded name from an unconnected socket, receiving the datagrams of any source,
+// and the responses are still read for a short window after the first one
+type SpoofDetector struct {
+	anomalies map[string]struct{}
+	mutex     sync.Mutex
+}
+
+type spoofDetectorKey struct{}
+
+// NewSpoofDetector creates a spoof detector
+func NewSpoofDetector() *SpoofDetector {
+	return &SpoofDetector{anomalies: make(map[string]struct{})}
+}
+
+// Context returns a copy of ctx whose udp responses are checked
+func (s *SpoofDetector) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, spoofDetectorKey{}, s)
+}
+
+// Anomalies returns the sorted anomalies observed on the responses
+func (s *SpoofDetector) Anomalies() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var labels []string
+	for anomaly := range s.anomalies {
+		labels = append(labels, anomaly)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func (s *SpoofDetector) add(anomaly string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.anomalies[anomaly] = struct{}{}
+}
+
+// spoofDetectorFrom returns the spoof detector of the context, nil when its responses are not checked
+func spoofDetectorFrom(ctx context.Context) *SpoofDetector {
+	detector, _ := ctx.Value(spoofDetectorKey{}).(*SpoofDetector)
+	return detector
+}
+
+// exchangeUDPChecked exchanges the message with the udp server like exchangeConn while reporting the anomalies
+// of the datagrams received to the detector. The first valid response is returned once the listen window is
+// over, with the case of its names restored
+func (t *transport) exchangeUDPChecked(ctx context.Context, msg *miekgdns.Msg, address string, detector *SpoofDetector) (*miekgdns.Msg, int, error) {
+	serverAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, 0, err
+	}
+	// the socket is not connected so that responses from any source are received
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// unblocks the exchange when the context is canceled
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	query := msg.Copy()
+	name := randomizeCase(query.Question[0].Name)
+	query.Question[0].Name = name
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := conn.WriteToUDP(packed, serverAddr); err != nil {
+		return nil, 0, contextError(ctx, err)
+	}
+
+	var (
+		first *miekgdns.Msg
+		size  int
+	)
+	buffer := make([]byte, miekgdns.MaxMsgSize)
+	for {
+		n, source, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if first != nil {
+				break
+			}
+			return nil, 0, contextError(ctx, err)
+		}
+		resp := &miekgdns.Msg{}
+		if err := resp.Unpack(buffer[:n]); err != nil {
+			detector.add(AnomalyMalformedResponse)
+			continue
+		}
+		if !source.IP.Equal(serverAddr.IP) || source.Port != serverAddr.Port {
+			detector.add(AnomalyUnexpectedSource)
+			continue
+		}
+		if resp.Id != query.Id {
+			t.ids.mismatches.Add(1)
+			detector.add(AnomalyIDMismatch)
+			continue
+		}
+		if len(resp.Question) == 0 || !strings.EqualFold(resp.Question[0].Name, name) {
+			detector.add(AnomalyQuestionMismatch)
+			continue
+		}
+		if resp.Question[0].Name != name {
+			detector.add(AnomalyCaseMismatch)
+		}
+		if first == nil {
+			first, size = resp, n
+			window := time.Now().Add(spoofListenWindow)
+			if deadline, ok := ctx.Deadline(); ok && deadline.Before(window) {
+				window = deadline
+			}
+			_ = conn.SetReadDeadline(window)
+			continue
+		}
+		detector.add(AnomalyMultipleResponses)
+		if !sameAnswers(first, resp) {
+			detector.add(AnomalyConflictingAnswers)
+		}
+	}
+	restoreNameCase(first, msg.Question[0].Name)
+	return first, size, nil
+}
+
+// restoreNameCase sets back the name of the question to its original case in the question and the records
+// of the response
+func restoreNameCase(resp *miekgdns.Msg, name string) {
+	for i := range resp.Question {
+		if strings.EqualFold(resp.Question[i].Name, name) {
+			resp.Question[i].Name = name
+		}
+	}
+	for _, section := range [][]miekgdns.RR{resp.Answer, resp.Ns, resp.Extra} {
+		for _, rr := range section {
+			if strings.EqualFold(rr.Header().Name, name) {
+				rr.Header().Name = name
+			}
+		}
+	}
+}
+
+// randomizeCase randomly flips the case of the letters of name (draft-vixie-dnsext-dns0x20)
+func randomizeCase(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if rand.Intn(2) == 0 {
+			runes[i] = unicode.ToUpper(r)
+		} else {
+			runes[i] = unicode.ToLower(r)
+		}
+	}
+	return string(runes)
+}
+
+// sameAnswers checks if both responses carry the same answer records regardless of their ttl
+func sameAnswers(a, b *miekgdns.Msg) bool {
+	if a.Rcode != b.Rcode || len(a.Answer) != len(b.Answer) {
+		return false
+	}
+	var aRecords, bRecords []string
+	for _, rr := range a.Answer {
+		aRecords = append(aRecords, withoutTTL(rr.String()))
+	}
+	for _, rr := range b.Answer {
+		bRecords = append(bRecords, withoutTTL(rr.String()))
+	}
+	return len(intersect(aRecords, bRecords, nil)) == len(aRecords)
+}
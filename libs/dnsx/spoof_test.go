@@ -0,0 +1,71 @@
+package dnsx
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpoofDetector(t *testing.T) {
+	const host = "spoof-detection-test.example.com"
+	// the handler answers the query with the lowercase name, then the extra responses
+	startServer := func(extra func(w miekgdns.ResponseWriter, r *miekgdns.Msg)) string {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.Nil(t, err, "could not listen")
+		server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+			m := &miekgdns.Msg{}
+			m.SetReply(r)
+			a, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, a)
+			_ = w.WriteMsg(m)
+			if extra != nil {
+				extra(w, r)
+			}
+		})}
+		go func() { _ = server.ActivateAndServe() }()
+		t.Cleanup(func() { _ = server.Shutdown() })
+		return conn.LocalAddr().String()
+	}
+	query := func(resolver string) (*retryabledns.DNSData, []string) {
+		options := DefaultOptions
+		options.BaseResolvers = []string{resolver}
+		options.QuestionTypes = []uint16{miekgdns.TypeA}
+		options.MaxRetries = 1
+		dnsX, err := New(options)
+		require.Nil(t, err, "could not create dnsx")
+		detector := NewSpoofDetector()
+		dnsData, err := dnsX.QueryMultipleContext(detector.Context(context.Background()), host)
+		require.Nil(t, err, "could not query")
+		return dnsData, detector.Anomalies()
+	}
+
+	dnsData, anomalies := query(startServer(nil))
+	require.Empty(t, anomalies, "anomalies of a clean response")
+	require.Equal(t, []string{"192.0.2.1"}, dnsData.A, "could not match answer")
+	require.Equal(t, host, dnsData.Host, "could not match host")
+
+	_, anomalies = query(startServer(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		m.Question[0].Name = miekgdns.Fqdn(host)
+		a, _ := miekgdns.NewRR(host + ". 60 IN A 198.51.100.1")
+		m.Answer = append(m.Answer, a)
+		_ = w.WriteMsg(m)
+	}))
+	require.Equal(t, []string{AnomalyCaseMismatch, AnomalyConflictingAnswers, AnomalyMultipleResponses}, anomalies, "could not match anomalies of an injected response")
+
+	_, anomalies = query(startServer(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.Nil(t, err, "could not listen")
+		defer conn.Close()
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		packed, _ := m.Pack()
+		_, _ = conn.WriteTo(packed, w.RemoteAddr())
+	}))
+	require.Equal(t, []string{AnomalyUnexpectedSource}, anomalies, "could not match anomalies of an other source")
+}
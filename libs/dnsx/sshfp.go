@@ -0,0 +1,49 @@
+package dnsx
+
+import (
+	"fmt"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// sshfpAlgorithms are the names of the ssh key algorithms of the SSHFP records (RFC 4255, 6594, 7479 and 8709)
+var sshfpAlgorithms = map[uint8]string{1: "RSA", 2: "DSA", 3: "ECDSA", 4: "Ed25519", 6: "Ed448"}
+
+// sshfpTypes are the names of the fingerprint types of the SSHFP records
+var sshfpTypes = map[uint8]string{1: "SHA-1", 2: "SHA-256"}
+
+// SSHFP is the fingerprint of an ssh host key
+type SSHFP struct {
+	Algorithm     uint8  `json:"algorithm" csv:"algorithm"`
+	AlgorithmName string `json:"algorithm_name,omitempty" csv:"algorithm_name"`
+	Type          uint8  `json:"type" csv:"type"`
+	TypeName      string `json:"type_name,omitempty" csv:"type_name"`
+	// Fingerprint is the hex encoded fingerprint of the key
+	Fingerprint string `json:"fingerprint" csv:"fingerprint"`
+}
+
+// String returns the record in presentation format: algorithm, fingerprint type and fingerprint (eg. 4 2 5a7b...)
+func (s SSHFP) String() string {
+	return fmt.Sprintf("%d %d %s", s.Algorithm, s.Type, s.Fingerprint)
+}
+
+// newSSHFP returns the fingerprint of the SSHFP record
+func newSSHFP(sshfp *miekgdns.SSHFP) SSHFP {
+	return SSHFP{
+		Algorithm:     sshfp.Algorithm,
+		AlgorithmName: sshfpAlgorithms[sshfp.Algorithm],
+		Type:          sshfp.Type,
+		TypeName:      sshfpTypes[sshfp.Type],
+		Fingerprint:   strings.ToLower(sshfp.FingerPrint),
+	}
+}
+
+// formatSSHFP returns the records as strings
+func formatSSHFP(fingerprints []SSHFP) []string {
+	records := make([]string, 0, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		records = append(records, fingerprint.String())
+	}
+	return records
+}
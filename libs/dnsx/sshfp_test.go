@@ -0,0 +1,26 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSSHFP(t *testing.T) {
+	d := &ResponseData{DNSData: &retryabledns.DNSData{
+		AllRecords: []string{
+			"host.example.com.\t300\tIN\tSSHFP\t4 2 5A7B9C1D2E3F40516273849506A7B8C9D0E1F2031425364758697A8B9CADBECF",
+			"host.example.com.\t300\tIN\tSSHFP\t4 2 5A7B9C1D2E3F40516273849506A7B8C9D0E1F2031425364758697A8B9CADBECF",
+			"host.example.com.\t300\tIN\tSSHFP\t1 1 DD465C09CFA51FB45020CC83316FFF21B9EC74AC",
+		},
+		RawResp: &miekgdns.Msg{},
+	}}
+	d.ParseRawResp()
+	require.Equal(t, []SSHFP{
+		{Algorithm: 4, AlgorithmName: "Ed25519", Type: 2, TypeName: "SHA-256", Fingerprint: "5a7b9c1d2e3f40516273849506a7b8c9d0e1f2031425364758697a8b9cadbecf"},
+		{Algorithm: 1, AlgorithmName: "RSA", Type: 1, TypeName: "SHA-1", Fingerprint: "dd465c09cfa51fb45020cc83316fff21b9ec74ac"},
+	}, d.SSHFP, "could not parse sshfp records")
+	require.Equal(t, []string{"4 2 5a7b9c1d2e3f40516273849506a7b8c9d0e1f2031425364758697a8b9cadbecf", "1 1 dd465c09cfa51fb45020cc83316fff21b9ec74ac"}, d.Records([]uint16{miekgdns.TypeSSHFP}))
+}
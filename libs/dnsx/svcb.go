@@ -0,0 +1,79 @@
+package dnsx
+
+import (
+	"fmt"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// ServiceBinding is an HTTPS or SVCB record (RFC 9460), the alias form having a priority of 0 and no parameter
+type ServiceBinding struct {
+	Priority uint16 `json:"priority" csv:"priority"`
+	// Target is the name of the alternative endpoint, "." standing for the owner name of the record
+	Target   string   `json:"target" csv:"target"`
+	ALPN     []string `json:"alpn,omitempty" csv:"alpn"`
+	Port     uint16   `json:"port,omitempty" csv:"port"`
+	IPv4Hint []string `json:"ipv4hint,omitempty" csv:"ipv4hint"`
+	IPv6Hint []string `json:"ipv6hint,omitempty" csv:"ipv6hint"`
+	// Params are all the parameters of the record in presentation format, the ones above included
+	Params map[string]string `json:"params,omitempty" csv:"params"`
+	// params keeps the parameters in the order of the record for display
+	params []string
+}
+
+// String returns the record in presentation format (eg. 1 . alpn=h3,h2 ipv4hint=192.0.2.1)
+func (s ServiceBinding) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%d %s %s", s.Priority, s.Target, strings.Join(s.params, " ")))
+}
+
+// newServiceBinding returns the priority, target and parameters of the record
+func newServiceBinding(svcb *miekgdns.SVCB) ServiceBinding {
+	binding := ServiceBinding{Priority: svcb.Priority, Target: strings.ToLower(svcb.Target)}
+	if binding.Target != "." {
+		binding.Target = trimDot(binding.Target)
+	}
+	for _, value := range svcb.Value {
+		key, param := value.Key().String(), value.String()
+		if binding.Params == nil {
+			binding.Params = make(map[string]string)
+		}
+		binding.Params[key] = param
+		binding.params = append(binding.params, key+"="+param)
+		switch value := value.(type) {
+		case *miekgdns.SVCBAlpn:
+			binding.ALPN = value.Alpn
+		case *miekgdns.SVCBPort:
+			binding.Port = value.Port
+		case *miekgdns.SVCBIPv4Hint:
+			for _, ip := range value.Hint {
+				binding.IPv4Hint = append(binding.IPv4Hint, ip.String())
+			}
+		case *miekgdns.SVCBIPv6Hint:
+			for _, ip := range value.Hint {
+				binding.IPv6Hint = append(binding.IPv6Hint, ip.String())
+			}
+		}
+	}
+	return binding
+}
+
+// formatServiceBindings returns the records as strings
+func formatServiceBindings(bindings []ServiceBinding) []string {
+	records := make([]string, 0, len(bindings))
+	for _, binding := range bindings {
+		records = append(records, binding.String())
+	}
+	return records
+}
+
+// HintIPs returns the distinct addresses of the ipv4hint and ipv6hint parameters of the HTTPS and SVCB records
+func (d *ResponseData) HintIPs() []string {
+	var ips []string
+	for _, binding := range append(append([]ServiceBinding{}, d.HTTPS...), d.SVCB...) {
+		ips = append(ips, binding.IPv4Hint...)
+		ips = append(ips, binding.IPv6Hint...)
+	}
+	return sliceutil.Dedupe(ips)
+}
@@ -0,0 +1,33 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServiceBindings(t *testing.T) {
+	d := &ResponseData{DNSData: &retryabledns.DNSData{
+		AllRecords: []string{
+			"example.com.\t300\tIN\tHTTPS\t1 . alpn=\"h3,h2\" ipv4hint=\"192.0.2.1\" ipv6hint=\"2001:db8::1\"",
+			"example.com.\t300\tIN\tHTTPS\t1 . alpn=\"h3,h2\" ipv4hint=\"192.0.2.1\" ipv6hint=\"2001:db8::1\"",
+			"_dns.example.com.\t300\tIN\tSVCB\t1 DNS.example.net. alpn=\"dot\" port=\"853\" ipv4hint=\"192.0.2.53\"",
+		},
+		RawResp: &miekgdns.Msg{},
+	}}
+	d.ParseRawResp()
+	require.Len(t, d.HTTPS, 1, "could not parse https records")
+	require.Equal(t, uint16(1), d.HTTPS[0].Priority)
+	require.Equal(t, ".", d.HTTPS[0].Target)
+	require.Equal(t, []string{"h3", "h2"}, d.HTTPS[0].ALPN)
+	require.Equal(t, "192.0.2.1", d.HTTPS[0].Params["ipv4hint"])
+
+	require.Len(t, d.SVCB, 1, "could not parse svcb records")
+	require.Equal(t, "dns.example.net", d.SVCB[0].Target)
+	require.Equal(t, uint16(853), d.SVCB[0].Port)
+	require.Equal(t, []string{"1 dns.example.net alpn=dot port=853 ipv4hint=192.0.2.53"}, d.Records([]uint16{miekgdns.TypeSVCB}))
+
+	require.Equal(t, []string{"192.0.2.1", "2001:db8::1", "192.0.2.53"}, d.HintIPs())
+}
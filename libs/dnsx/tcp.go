@@ -0,0 +1,73 @@
+package dnsx
+
+import (
+	"context"
+
+	retryabledns "github.com/projectdiscovery/retryabledns"
+)
+
+// tcpResolvers returns the resolvers with the plain udp ones switched to tcp on the same port
+func tcpResolvers(resolvers []string) []string {
+	var converted []string
+	for _, resolver := range resolvers {
+		if networkResolver, ok := parseResolver(resolver).(*retryabledns.NetworkResolver); ok && networkResolver.Protocol == retryabledns.UDP {
+			resolver = retryabledns.TCP.StringWithSemicolon() + networkResolver.String()
+		}
+		converted = append(converted, resolver)
+	}
+	return converted
+}
+
+// tcpQueryClient returns the client querying the active resolvers over tcp, creating it on first use
+func (d *DNSX) tcpQueryClient() (*client, error) {
+	d.clientMutex.RLock()
+	tcpClient := d.tcpClient
+	d.clientMutex.RUnlock()
+	if tcpClient != nil {
+		return tcpClient, nil
+	}
+
+	d.clientMutex.Lock()
+	defer d.clientMutex.Unlock()
+	if d.tcpClient == nil {
+		tcpClient, err := newClient(d.Options, tcpResolvers(d.Options.BaseResolvers))
+		if err != nil {
+			return nil, err
+		}
+		d.tcpClient = tcpClient
+	}
+	return d.tcpClient, nil
+}
+
+// QueryOneTCP performs a DNS question of the first specified type over tcp, regardless of the resolvers protocol
+func (d *DNSX) QueryOneTCP(hostname string) (*retryabledns.DNSData, error) {
+	tcpClient, err := d.tcpQueryClient()
+	if err != nil {
+		return nil, err
+	}
+	hostname = normalizeIP(hostname)
+	return tcpClient.Query(hostname, d.Options.QuestionTypes[0])
+}
+
+// QueryMultipleTCP performs a DNS question of the specified types over tcp, regardless of the resolvers protocol
+func (d *DNSX) QueryMultipleTCP(hostname string) (*retryabledns.DNSData, error) {
+	return d.QueryMultipleTCPContext(context.Background(), hostname)
+}
+
+// QueryMultipleTCPContext performs the dns questions like QueryMultipleTCP, the queries ending with the context
+func (d *DNSX) QueryMultipleTCPContext(ctx context.Context, hostname string) (*retryabledns.DNSData, error) {
+	tcpClient, err := d.tcpQueryClient()
+	if err != nil {
+		return nil, err
+	}
+	hostname = normalizeIP(hostname)
+	return tcpClient.queryMultiple(ctx, hostname, d.Options.QuestionTypes, nil)
+}
+
+// QueryMultipleTCPWithResolverContext performs the dns questions over tcp with the resolver only, a udp one being
+// queried on the same port, the queries ending with the context
+func (d *DNSX) QueryMultipleTCPWithResolverContext(ctx context.Context, hostname, resolver string) (*retryabledns.DNSData, error) {
+	hostname = normalizeIP(hostname)
+	tcpResolver := parseResolver(tcpResolvers([]string{resolver})[0])
+	return d.client().queryMultiple(ctx, hostname, d.questionTypes(hostname), tcpResolver)
+}
@@ -0,0 +1,77 @@
+package dnsx
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryMultipleTCP(t *testing.T) {
+	// the server fails over udp and answers over tcp on the same port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	conn, err := net.ListenPacket("udp", listener.Addr().String())
+	require.Nil(t, err, "could not listen")
+	handler := func(tcp bool) miekgdns.HandlerFunc {
+		return func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+			m := &miekgdns.Msg{}
+			m.SetReply(r)
+			if !tcp {
+				m.Rcode = miekgdns.RcodeServerFailure
+			} else if r.Question[0].Qtype == miekgdns.TypeA {
+				hdr := miekgdns.RR_Header{Name: r.Question[0].Name, Rrtype: miekgdns.TypeA, Class: miekgdns.ClassINET, Ttl: 300}
+				m.Answer = append(m.Answer, &miekgdns.A{Hdr: hdr, A: net.ParseIP("192.0.2.1")})
+			}
+			_ = w.WriteMsg(m)
+		}
+	}
+	tcpServer := &miekgdns.Server{Listener: listener, Handler: handler(true)}
+	udpServer := &miekgdns.Server{PacketConn: conn, Handler: handler(false)}
+	go func() { _ = tcpServer.ActivateAndServe() }()
+	go func() { _ = udpServer.ActivateAndServe() }()
+	defer func() { _ = tcpServer.Shutdown() }()
+	defer func() { _ = udpServer.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.MaxRetries = 1
+	options.Timeout = time.Second
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	data, _ := dnsX.QueryMultiple("example.com")
+	require.Equal(t, miekgdns.RcodeServerFailure, data.StatusCodeRaw, "could not match udp rcode")
+	data, err = dnsX.QueryMultipleTCP("example.com")
+	require.Nil(t, err, "could not query over tcp")
+	require.Equal(t, []string{"192.0.2.1"}, data.A, "could not match tcp records")
+	// the wildcard probes query the first type over tcp
+	data, err = dnsX.QueryOneTCP("example.com")
+	require.Nil(t, err, "could not query one type over tcp")
+	require.Equal(t, []string{"192.0.2.1"}, data.A, "could not match tcp record")
+
+	// the resolver that answered over udp is asked again over tcp, whatever the pool
+	options.BaseResolvers = []string{"127.0.0.1:1", conn.LocalAddr().String()}
+	dnsX, err = New(options)
+	require.Nil(t, err, "could not create dnsx")
+	for i := 0; i < 2; i++ {
+		data, err = dnsX.QueryMultipleTCPWithResolverContext(context.Background(), "example.com", conn.LocalAddr().String())
+		require.Nil(t, err, "could not query the resolver over tcp")
+		require.Equal(t, []string{"192.0.2.1"}, data.A, "could not match tcp records")
+		require.Equal(t, []string{conn.LocalAddr().String()}, data.Resolver, "could not match tcp resolver")
+	}
+}
+
+func TestTCPResolvers(t *testing.T) {
+	resolvers := []string{"192.0.2.53", "udp:192.0.2.53:5353", "tcp:192.0.2.53:53", "dot:192.0.2.53:853", "doh:https://dns.example/dns-query"}
+	require.Equal(t, []string{
+		"tcp:192.0.2.53:53",
+		"tcp:192.0.2.53:5353",
+		"tcp:192.0.2.53:53",
+		"dot:192.0.2.53:853",
+		"doh:https://dns.example/dns-query",
+	}, tcpResolvers(resolvers), "could not switch the udp resolvers to tcp")
+}
@@ -0,0 +1,14 @@
+package dnsx
+
+import (
+	"fmt"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// formatTLSA returns the TLSA record in presentation format: certificate usage, selector, matching type and hex
+// encoded certificate association data (eg. 3 1 1 0c72ac70...)
+func formatTLSA(tlsa *miekgdns.TLSA) string {
+	return fmt.Sprintf("%d %d %d %s", tlsa.Usage, tlsa.Selector, tlsa.MatchingType, strings.ToLower(tlsa.Certificate))
+}
@@ -0,0 +1,24 @@
+package dnsx
+
+import (
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTLSA(t *testing.T) {
+	tlsa, err := miekgdns.NewRR("_443._tcp.example.com. 300 IN TLSA 3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6")
+	require.Nil(t, err, "could not parse tlsa record")
+	cname, _ := miekgdns.NewRR("_25._tcp.example.com. 300 IN CNAME _dane.example.net.")
+	msg := &miekgdns.Msg{Answer: []miekgdns.RR{cname, tlsa}}
+	d := &ResponseData{DNSData: &retryabledns.DNSData{RawResp: msg}}
+	require.Nil(t, d.ParseFromMsg(msg), "could not parse response")
+	// the tlsa record of a retried query is repeated
+	require.Nil(t, d.ParseFromMsg(msg), "could not parse response")
+	d.ParseRawResp()
+	require.Equal(t, []string{"3 1 1 0c72ac70b745ac19998811b131d662c9ac69dbdbe7cb23e5b514b56664c5d3d6"}, d.TLSA, "could not match tlsa records")
+	require.Equal(t, 1, d.RecordCount(miekgdns.TypeTLSA), "could not count tlsa records")
+	require.True(t, d.HasRecords(), "tlsa records not counted")
+}
@@ -0,0 +1,271 @@
+package dnsx
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// maxGluelessDepth bounds the nested resolutions of the nameservers delegated without glue, as the zones of
+// these nameservers may be delegated without glue as well
+const maxGluelessDepth = 4
+
+// GluelessDelegation is a delegation met during a trace whose nameservers came without glue, resolved
+// separately to continue the trace
+type GluelessDelegation struct {
+	Zone        string   `json:"zone" csv:"zone"`
+	Nameservers []string `json:"nameservers" csv:"nameservers"`
+	// IPs are the addresses found for the nameservers, empty when none could be resolved
+	IPs []string `json:"ips,omitempty" csv:"ips"`
+}
+
+func (g GluelessDelegation) String() string {
+	s := fmt.Sprintf("%s %s", g.Zone, strings.Join(g.Nameservers, ","))
+	if len(g.IPs) > 0 {
+		s += " [" + strings.Join(g.IPs, ",") + "]"
+	}
+	return s
+}
+
+// TraceChain is the result of a trace, the responses along the delegation chain and the delegations whose
+// nameservers came without glue
+type TraceChain struct {
+	*retryabledns.TraceData
+	Glueless []GluelessDelegation
+}
+
+// tracer follows the delegations from the root servers using the glue of the referrals, keeping the servers of
+// the zones met on the way to resolve the nameservers delegated without glue from their closest known parent
+type tracer struct {
+	query        func(host string, questionType uint16, servers []string) ([]*retryabledns.DNSData, error)
+	roots        []string
+	maxRecursion int
+	zones        map[string][]string
+}
+
+// Trace performs a DNS trace of the first question type from the root servers and returns raw responses
+func (d *DNSX) Trace(hostname string) (*retryabledns.TraceData, error) {
+	return d.TraceContext(context.Background(), hostname)
+}
+
+// TraceContext performs the dns trace like Trace, the queries ending with the context
+func (d *DNSX) TraceContext(ctx context.Context, hostname string) (*retryabledns.TraceData, error) {
+	chain, err := d.TraceChainContext(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	return chain.TraceData, nil
+}
+
+// TraceChain performs the dns trace like Trace, returning as well the delegations without glue whose
+// nameservers were resolved through the zones already traced
+func (d *DNSX) TraceChain(hostname string) (*TraceChain, error) {
+	return d.TraceChainContext(context.Background(), hostname)
+}
+
+// TraceChainContext performs the dns trace like TraceChain, the queries ending with the context
+func (d *DNSX) TraceChainContext(ctx context.Context, hostname string) (*TraceChain, error) {
+	client := d.client()
+	t := &tracer{
+		query: func(host string, questionType uint16, servers []string) ([]*retryabledns.DNSData, error) {
+			return client.queryParallel(ctx, host, questionType, servers)
+		},
+		roots:        rootServerAddresses(),
+		maxRecursion: d.Options.TraceMaxRecursion,
+		zones:        make(map[string][]string),
+	}
+	return t.trace(hostname, d.Options.QuestionTypes[0])
+}
+
+func (t *tracer) trace(host string, questionType uint16) (*TraceChain, error) {
+	chain := &TraceChain{TraceData: &retryabledns.TraceData{}}
+	host = miekgdns.CanonicalName(host)
+	servers := t.roots
+	seenNS := make(map[string]struct{})
+	followed := make(map[string]struct{})
+	for i := 1; i < t.maxRecursion; i++ {
+		dnsdatas, err := t.query(host, questionType, servers)
+		if err != nil {
+			return nil, err
+		}
+		for _, server := range servers {
+			seenNS[server] = struct{}{}
+		}
+
+		var (
+			newNSResolvers []string
+			nextCname      string
+		)
+		for _, dnsdata := range dnsdatas {
+			if dnsdata == nil || len(dnsdata.Resolver) == 0 {
+				continue
+			}
+			chain.DNSData = append(chain.DNSData, dnsdata)
+			zone, nameservers := referral(dnsdata.RawResp)
+			resolvers, glueless := t.delegationServers(zone, nameservers, dnsdata.RawResp, 0)
+			if glueless != nil {
+				chain.Glueless = append(chain.Glueless, *glueless)
+			}
+			newNSResolvers = append(newNSResolvers, resolvers...)
+			// follow CNAME - should happen at the final step of the trace, once per target against loops
+			if nextCname == "" && len(dnsdata.CNAME) > 0 {
+				if _, ok := followed[dnsdata.CNAME[0]]; !ok {
+					nextCname = dnsdata.CNAME[0]
+				}
+			}
+		}
+		newNSResolvers = sliceutil.Dedupe(newNSResolvers)
+
+		if len(newNSResolvers) == 0 {
+			if nextCname == "" {
+				break
+			}
+			// the cname target is traced again from the closest zone known
+			_, newNSResolvers = t.closestServers(miekgdns.CanonicalName(nextCname))
+		}
+		randomServer := newNSResolvers[rand.Intn(len(newNSResolvers))]
+		// the same resolver without any new cname to follow ends the trace
+		if _, ok := seenNS[randomServer]; ok && nextCname == "" {
+			break
+		}
+		servers = []string{randomServer}
+		if nextCname != "" {
+			followed[nextCname] = struct{}{}
+			host = miekgdns.CanonicalName(nextCname)
+		}
+	}
+	return chain, nil
+}
+
+// delegationServers returns the addresses of the nameservers of the delegated zone, from the glue of the
+// referral or else resolved separately, in which case the delegation is returned as glueless
+func (t *tracer) delegationServers(zone string, nameservers []string, msg *miekgdns.Msg, depth int) ([]string, *GluelessDelegation) {
+	if len(nameservers) == 0 {
+		return nil, nil
+	}
+	glue := glueAddresses(msg)
+	var servers []string
+	for _, nameserver := range nameservers {
+		for _, ip := range glue[nameserver] {
+			servers = append(servers, net.JoinHostPort(ip, "53"))
+		}
+	}
+	if len(servers) > 0 {
+		t.zones[zone] = servers
+		return servers, nil
+	}
+
+	glueless := &GluelessDelegation{Zone: NormalizeNameserver(zone)}
+	for _, nameserver := range nameservers {
+		glueless.Nameservers = append(glueless.Nameservers, NormalizeNameserver(nameserver))
+		for _, ip := range t.resolve(nameserver, depth+1) {
+			glueless.IPs = append(glueless.IPs, ip)
+			servers = append(servers, net.JoinHostPort(ip, "53"))
+		}
+	}
+	glueless.IPs = sliceutil.Dedupe(glueless.IPs)
+	if len(servers) > 0 {
+		t.zones[zone] = servers
+	}
+	return servers, glueless
+}
+
+// resolve finds the addresses of the nameserver iteratively, starting from the closest zone already traced
+func (t *tracer) resolve(name string, depth int) []string {
+	if depth > maxGluelessDepth {
+		return nil
+	}
+	host := miekgdns.CanonicalName(name)
+	zone, servers := t.closestServers(host)
+	for i := 1; i < t.maxRecursion && len(servers) > 0; i++ {
+		dnsdatas, err := t.query(host, miekgdns.TypeA, servers)
+		if err != nil {
+			return nil
+		}
+		var (
+			next     []string
+			nextZone string
+		)
+		for _, dnsdata := range dnsdatas {
+			if dnsdata == nil || dnsdata.RawResp == nil {
+				continue
+			}
+			if ips := answerAddresses(dnsdata.RawResp); len(ips) > 0 {
+				return ips
+			}
+			delegated, nameservers := referral(dnsdata.RawResp)
+			// only the referrals down the tree lead closer to the nameserver
+			if delegated == zone || !miekgdns.IsSubDomain(zone, delegated) {
+				continue
+			}
+			resolvers, _ := t.delegationServers(delegated, nameservers, dnsdata.RawResp, depth)
+			if len(resolvers) > 0 {
+				next, nextZone = append(next, resolvers...), delegated
+			}
+		}
+		zone, servers = nextZone, sliceutil.Dedupe(next)
+	}
+	return nil
+}
+
+// closestServers returns the deepest zone already traced enclosing the host with its servers, the root zone
+// and the root servers if none
+func (t *tracer) closestServers(host string) (string, []string) {
+	zone, servers := ".", t.roots
+	for known, knownServers := range t.zones {
+		if miekgdns.IsSubDomain(known, host) && miekgdns.CountLabel(known) > miekgdns.CountLabel(zone) {
+			zone, servers = known, knownServers
+		}
+	}
+	return zone, servers
+}
+
+// referral returns the zone and the nameservers delegated in the authority section of a response without answer
+func referral(msg *miekgdns.Msg) (string, []string) {
+	if msg == nil || len(msg.Answer) > 0 {
+		return "", nil
+	}
+	var (
+		zone        string
+		nameservers []string
+	)
+	for _, record := range msg.Ns {
+		if ns, ok := record.(*miekgdns.NS); ok {
+			zone = strings.ToLower(ns.Hdr.Name)
+			nameservers = append(nameservers, strings.ToLower(miekgdns.CanonicalName(ns.Ns)))
+		}
+	}
+	return zone, sliceutil.Dedupe(nameservers)
+}
+
+// glueAddresses returns the IPv4 glue of the additional section by nameserver
+func glueAddresses(msg *miekgdns.Msg) map[string][]string {
+	glue := make(map[string][]string)
+	if msg == nil {
+		return glue
+	}
+	for _, record := range msg.Extra {
+		if a, ok := record.(*miekgdns.A); ok {
+			name := strings.ToLower(a.Hdr.Name)
+			glue[name] = append(glue[name], a.A.String())
+		}
+	}
+	return glue
+}
+
+// answerAddresses returns the IPv4 addresses of the answer section
+func answerAddresses(msg *miekgdns.Msg) []string {
+	var ips []string
+	for _, record := range msg.Answer {
+		if a, ok := record.(*miekgdns.A); ok {
+			ips = append(ips, a.A.String())
+		}
+	}
+	return ips
+}
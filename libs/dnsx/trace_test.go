@@ -0,0 +1,68 @@
+package dnsx
+
+import (
+	"strings"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/projectdiscovery/retryabledns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceGlueless(t *testing.T) {
+	rr := func(s string) miekgdns.RR {
+		record, err := miekgdns.NewRR(s)
+		require.Nil(t, err, "could not parse record")
+		return record
+	}
+	// the example.com nameserver lives in dns.net, delegated without glue by com
+	servers := map[string]func(host string) *miekgdns.Msg{
+		"192.0.2.1:53": func(host string) *miekgdns.Msg {
+			if strings.HasSuffix(host, ".net.") {
+				return &miekgdns.Msg{Ns: []miekgdns.RR{rr("net. 300 IN NS b.nic.net.")}, Extra: []miekgdns.RR{rr("b.nic.net. 300 IN A 192.0.2.4")}}
+			}
+			return &miekgdns.Msg{Ns: []miekgdns.RR{rr("com. 300 IN NS a.gtld.com.")}, Extra: []miekgdns.RR{rr("a.gtld.com. 300 IN A 192.0.2.2")}}
+		},
+		"192.0.2.2:53": func(host string) *miekgdns.Msg {
+			return &miekgdns.Msg{Ns: []miekgdns.RR{rr("example.com. 300 IN NS ns1.dns.net.")}}
+		},
+		"192.0.2.4:53": func(host string) *miekgdns.Msg {
+			return &miekgdns.Msg{Ns: []miekgdns.RR{rr("dns.net. 300 IN NS ns.dns.net.")}, Extra: []miekgdns.RR{rr("ns.dns.net. 300 IN A 192.0.2.5")}}
+		},
+		"192.0.2.5:53": func(host string) *miekgdns.Msg {
+			return &miekgdns.Msg{Answer: []miekgdns.RR{rr(host + " 300 IN A 192.0.2.3")}}
+		},
+		"192.0.2.3:53": func(host string) *miekgdns.Msg {
+			return &miekgdns.Msg{Answer: []miekgdns.RR{rr(host + " 300 IN A 198.51.100.1")}}
+		},
+	}
+	var queried []string
+	query := func(host string, questionType uint16, resolvers []string) ([]*retryabledns.DNSData, error) {
+		var dnsdatas []*retryabledns.DNSData
+		for _, resolver := range resolvers {
+			queried = append(queried, host+"@"+resolver)
+			msg := servers[resolver](host)
+			dnsdata := &retryabledns.DNSData{Host: host, Resolver: []string{resolver}, RawResp: msg}
+			require.Nil(t, dnsdata.ParseFromMsg(msg), "could not parse response")
+			dnsdatas = append(dnsdatas, dnsdata)
+		}
+		return dnsdatas, nil
+	}
+
+	tracer := &tracer{query: query, roots: []string{"192.0.2.1:53"}, maxRecursion: 10, zones: make(map[string][]string)}
+	chain, err := tracer.trace("www.example.com", miekgdns.TypeA)
+	require.Nil(t, err, "could not trace")
+	require.Len(t, chain.DNSData, 3, "could not match chain length")
+	require.Equal(t, []string{"198.51.100.1"}, chain.DNSData[2].A, "could not reach the authoritative server")
+	require.Equal(t, []GluelessDelegation{{Zone: "example.com", Nameservers: []string{"ns1.dns.net"}, IPs: []string{"192.0.2.3"}}}, chain.Glueless, "could not match glueless delegations")
+	require.Equal(t, "example.com ns1.dns.net [192.0.2.3]", chain.Glueless[0].String(), "could not match glueless label")
+	// the nameserver is resolved from the root, already known as the parent of net
+	require.Equal(t, []string{
+		"www.example.com.@192.0.2.1:53",
+		"www.example.com.@192.0.2.2:53",
+		"ns1.dns.net.@192.0.2.1:53",
+		"ns1.dns.net.@192.0.2.4:53",
+		"ns1.dns.net.@192.0.2.5:53",
+		"www.example.com.@192.0.2.3:53",
+	}, queried, "could not match queries")
+}
@@ -0,0 +1,272 @@
+package dnsx
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+)
+
+// dohMediaType is the content type of the DNS over HTTPS messages (RFC 8484)
+const dohMediaType = "application/dns-message"
+
+// transport exchanges the dns messages with the resolvers over their protocol: udp (falling back to tcp when
+// truncated), tcp, dns over tls, https and quic. The exchanges end with the context. The hostnames of the servers
+// are resolved by the bootstrap resolver when set, the other transports of the process being left untouched
+type transport struct {
+	dialer     *net.Dialer
+	dotConfig  *tls.Config
+	httpClient *http.Client
+	doq        *doqTransport
+	udp        *udpPool
+	ids        *queryIDs
+	// noCompression packs the queries without name compression, the compression of the responses being
+	// reported in their Compress field
+	noCompression bool
+}
+
+func newTransport(options *Options) (*transport, error) {
+	bootstrap, err := newBootstrapResolver(options.BootstrapResolver)
+	if err != nil {
+		return nil, err
+	}
+	ids := options.ids
+	if ids == nil {
+		ids, _ = newQueryIDs(QueryIDRandom, 0)
+	}
+	t := &transport{
+		ids:           ids,
+		noCompression: options.NoCompression,
+		dialer:        &net.Dialer{Resolver: bootstrap},
+		dotConfig:     &tls.Config{InsecureSkipVerify: options.DoTInsecure},
+		doq:           newDoQTransport(bootstrap),
+		udp:           newUDPPool(),
+	}
+	// the proxy of the environment (HTTPS_PROXY) is used as by the default transport, the hostnames being
+	// resolved by the bootstrap resolver when dialed directly
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	httpTransport.DialContext = t.dialer.DialContext
+	t.httpClient = &http.Client{Transport: httpTransport}
+	if options.DoHUserAgent != nil {
+		t.httpClient.Transport = &userAgentTransport{userAgent: *options.DoHUserAgent, transport: httpTransport}
+	}
+	return t, nil
+}
+
+// close closes the connections kept open to the udp, doh and doq servers
+func (t *transport) close() {
+	t.udp.close()
+	t.httpClient.CloseIdleConnections()
+	t.doq.close()
+}
+
+// exchange sends the message to the resolver and returns its response with its wire size
+func (t *transport) exchange(ctx context.Context, msg *miekgdns.Msg, resolver retryabledns.Resolver) (*miekgdns.Msg, int, error) {
+	if !t.noCompression {
+		return t.exchangeResolver(ctx, msg, resolver)
+	}
+	msg.Compress = false
+	resp, size, err := t.exchangeResolver(ctx, msg, resolver)
+	if resp != nil {
+		// the unpacked names take their full length, the wire being shorter with compression pointers
+		resp.Compress = size < resp.Len()
+	}
+	return resp, size, err
+}
+
+// exchangeResolver sends the message over the protocol of the resolver
+func (t *transport) exchangeResolver(ctx context.Context, msg *miekgdns.Msg, resolver retryabledns.Resolver) (*miekgdns.Msg, int, error) {
+	switch resolver := resolver.(type) {
+	case *retryabledns.NetworkResolver:
+		address := net.JoinHostPort(resolver.Host, resolver.Port)
+		switch resolver.Protocol {
+		case retryabledns.TCP:
+			return t.exchangeConn(ctx, msg, "tcp", address)
+		case retryabledns.DOT:
+			return t.exchangeConn(ctx, msg, "tcp-tls", address)
+		}
+		var (
+			resp *miekgdns.Msg
+			size int
+			err  error
+		)
+		if detector := spoofDetectorFrom(ctx); detector != nil {
+			resp, size, err = t.exchangeUDPChecked(ctx, msg, address, detector)
+		} else {
+			resp, size, err = t.exchangeConn(ctx, msg, "udp", address)
+		}
+		if err == nil && resp.Truncated {
+			return t.exchangeConn(ctx, msg, "tcp", address)
+		}
+		return resp, size, err
+	case *retryabledns.DohResolver:
+		return t.exchangeHTTPS(ctx, msg, resolver)
+	case *DoQResolver:
+		return t.doq.exchange(ctx, msg, resolver)
+	}
+	return nil, 0, fmt.Errorf("unsupported resolver: %s", resolver)
+}
+
+// dial connects to the server, over tls for the tcp-tls network
+func (t *transport) dial(ctx context.Context, network, address string) (*miekgdns.Conn, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if network == "tcp-tls" {
+		dialer := &tls.Dialer{NetDialer: t.dialer, Config: t.dotConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = t.dialer.DialContext(ctx, network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &miekgdns.Conn{Conn: conn}, nil
+}
+
+// exchangeConn sends the message to the server, on an idle udp connection of the server when there is one and on
+// a new connection otherwise. The udp connections are kept for the next queries once answered
+func (t *transport) exchangeConn(ctx context.Context, msg *miekgdns.Msg, network, address string) (*miekgdns.Msg, int, error) {
+	var conn *miekgdns.Conn
+	if network == "udp" {
+		conn = t.udp.get(address)
+	}
+	if conn == nil {
+		var err error
+		if conn, err = t.dial(ctx, network, address); err != nil {
+			return nil, 0, contextError(ctx, err)
+		}
+	}
+	resp, size, err := t.exchangeOn(ctx, conn, msg, network)
+	// a canceled exchange may have left the deadline of the connection in the past
+	if network == "udp" && err == nil && ctx.Err() == nil {
+		t.udp.put(address, conn)
+	} else {
+		conn.Close()
+	}
+	return resp, size, err
+}
+
+// exchangeOn sends the message on the connection. A response with another id is skipped over udp, where it may be
+// a late or spoofed datagram, and returned with miekgdns.ErrId over a stream
+func (t *transport) exchangeOn(ctx context.Context, conn *miekgdns.Conn, msg *miekgdns.Msg, network string) (*miekgdns.Msg, int, error) {
+	// the zero deadline clears the one of the previous exchange on a reused connection
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	// unblocks the exchange when the context is canceled
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if network == "udp" {
+		conn.UDPSize = 0
+		if opt := msg.IsEdns0(); opt != nil {
+			conn.UDPSize = opt.UDPSize()
+		}
+	}
+	if err := conn.WriteMsg(msg); err != nil {
+		return nil, 0, contextError(ctx, err)
+	}
+	for {
+		data, err := conn.ReadMsgHeader(nil)
+		if err != nil {
+			return nil, 0, contextError(ctx, err)
+		}
+		resp := &miekgdns.Msg{}
+		if err := resp.Unpack(data); err != nil {
+			return nil, 0, err
+		}
+		if resp.Id == msg.Id {
+			return resp, len(data), nil
+		}
+		t.ids.mismatches.Add(1)
+		if network != "udp" {
+			return resp, len(data), miekgdns.ErrId
+		}
+	}
+}
+
+// contextError returns the cancellation of the context over the error it caused
+func contextError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	return err
+}
+
+// exchangeHTTPS sends the message to the doh server, in the body of a POST request or in the dns parameter of a
+// GET one. The message id is 0 to be cache friendly (RFC 8484 4.1)
+func (t *transport) exchangeHTTPS(ctx context.Context, msg *miekgdns.Msg, resolver *retryabledns.DohResolver) (*miekgdns.Msg, int, error) {
+	query := msg.Copy()
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var req *http.Request
+	if resolver.Protocol == retryabledns.GET {
+		dohURL, err := url.Parse(resolver.URL)
+		if err != nil {
+			return nil, 0, err
+		}
+		values := dohURL.Query()
+		values.Set("dns", base64.RawURLEncoding.EncodeToString(packed))
+		dohURL.RawQuery = values.Encode()
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, dohURL.String(), nil); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		if req, err = http.NewRequestWithContext(ctx, http.MethodPost, resolver.URL, bytes.NewReader(packed)); err != nil {
+			return nil, 0, err
+		}
+		req.Header.Set("Content-Type", dohMediaType)
+	}
+	req.Header.Set("Accept", dohMediaType)
+
+	httpResp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("doh server %s returned %s", resolver.URL, httpResp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, miekgdns.MaxMsgSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	resp := &miekgdns.Msg{}
+	if err := resp.Unpack(body); err != nil {
+		return nil, 0, err
+	}
+	resp.Id = msg.Id
+	return resp, len(body), nil
+}
+
+// userAgentTransport sets the User-Agent of the doh requests, an empty value omits the header
+type userAgentTransport struct {
+	userAgent string
+	transport *http.Transport
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *userAgentTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
@@ -0,0 +1,90 @@
+package dnsx
+
+import (
+	"net"
+	"testing"
+
+	miekgdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoCompression(t *testing.T) {
+	// the cname target and the answer records repeat the names of the question, compressed for the
+	// compressed host only
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		m.Compress = r.Question[0].Name == "compressed.example.com."
+		cname, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN CNAME www.example.com.")
+		a, _ := miekgdns.NewRR("www.example.com. 60 IN A 192.0.2.1")
+		m.Answer = append(m.Answer, cname, a)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.QuestionTypes = []uint16{miekgdns.TypeA}
+	options.NoCompression = true
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+
+	compressed, err := dnsX.QueryOne("compressed.example.com")
+	require.Nil(t, err, "could not query")
+	require.True(t, compressed.RawResp.Compress, "compressed response not flagged")
+	require.Equal(t, []string{"192.0.2.1"}, compressed.A, "could not match answer")
+
+	uncompressed, err := dnsX.QueryOne("uncompressed.example.com")
+	require.Nil(t, err, "could not query")
+	require.False(t, uncompressed.RawResp.Compress, "uncompressed response flagged")
+}
+
+func TestUDPConnReuse(t *testing.T) {
+	// the server reports the source address of each question
+	sources := make(chan string, 2)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	server := &miekgdns.Server{PacketConn: conn, Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+		sources <- w.RemoteAddr().String()
+		m := &miekgdns.Msg{}
+		m.SetReply(r)
+		a, _ := miekgdns.NewRR(r.Question[0].Name + " 60 IN A 192.0.2.1")
+		m.Answer = append(m.Answer, a)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	options := DefaultOptions
+	options.BaseResolvers = []string{conn.LocalAddr().String()}
+	options.Hostsfile = false
+	options.QuestionTypes = []uint16{miekgdns.TypeA}
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	defer dnsX.Close()
+
+	for _, host := range []string{"a.example.com", "b.example.com"} {
+		_, err := dnsX.QueryOne(host)
+		require.Nil(t, err, "could not query %s", host)
+	}
+	require.Equal(t, <-sources, <-sources, "could not reuse the udp connection")
+}
+
+func TestEmptyResponseRetry(t *testing.T) {
+	// the empty NOERROR responses of a resolver are retried on the next one, whatever the resolver queried first
+	options := DefaultOptions
+	options.BaseResolvers = []string{startUDPServer(t, ""), startUDPServer(t, "192.0.2.1")}
+	options.Hostsfile = false
+	options.MaxRetries = 2
+	options.QuestionTypes = []uint16{miekgdns.TypeA}
+	dnsX, err := New(options)
+	require.Nil(t, err, "could not create dnsx")
+	for _, host := range []string{"a.example.com", "b.example.com"} {
+		data, err := dnsX.QueryOne(host)
+		require.Nil(t, err, "could not query %s", host)
+		require.Equal(t, []string{"192.0.2.1"}, data.A, "could not retry the empty response for %s", host)
+	}
+}
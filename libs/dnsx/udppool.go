@@ -0,0 +1,61 @@
+package dnsx
+
+import (
+	"sync"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// udpIdleConns is the number of idle udp connections kept per server, the connections beyond being closed once
+// answered
+const udpIdleConns = 16
+
+// udpPool keeps the udp connections of the answered queries to send the next queries of the server on them
+// instead of dialing a new connection for each query
+type udpPool struct {
+	mutex sync.Mutex
+	conns map[string][]*miekgdns.Conn
+}
+
+func newUDPPool() *udpPool {
+	return &udpPool{conns: make(map[string][]*miekgdns.Conn)}
+}
+
+// get returns an idle connection to the server, nil when there is none
+func (p *udpPool) get(address string) *miekgdns.Conn {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	conns := p.conns[address]
+	if len(conns) == 0 {
+		return nil
+	}
+	conn := conns[len(conns)-1]
+	p.conns[address] = conns[:len(conns)-1]
+	return conn
+}
+
+// put keeps the connection for the next queries of the server, or closes it when enough are kept
+func (p *udpPool) put(address string, conn *miekgdns.Conn) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.conns == nil || len(p.conns[address]) >= udpIdleConns {
+		conn.Close()
+		return
+	}
+	p.conns[address] = append(p.conns[address], conn)
+}
+
+// close closes the idle connections, the connections of the in-flight queries being closed once answered
+func (p *udpPool) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, conns := range p.conns {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	p.conns = nil
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/miekg/dns"
+	retryabledns "github.com/projectdiscovery/retryabledns"
+	sliceutil "github.com/projectdiscovery/utils/slice"
 )
 
 // StringToRequestType conversion helper
@@ -38,3 +40,34 @@ func StringToRequestType(tp string) (rt uint16, err error) {
 
 	return
 }
+
+// mergeDNSData merges the results of src into dst, the status of the latest response prevails as in retryabledns
+func mergeDNSData(dst, src *retryabledns.DNSData) {
+	dst.Resolver = append(dst.Resolver, src.Resolver...)
+	// hosts file entries are added to every query
+	dst.A = sliceutil.Dedupe(append(dst.A, src.A...))
+	dst.AAAA = sliceutil.Dedupe(append(dst.AAAA, src.AAAA...))
+	dst.CNAME = sliceutil.Dedupe(append(dst.CNAME, src.CNAME...))
+	dst.MX = sliceutil.Dedupe(append(dst.MX, src.MX...))
+	dst.PTR = sliceutil.Dedupe(append(dst.PTR, src.PTR...))
+	dst.SOA = append(dst.SOA, src.SOA...)
+	dst.NS = sliceutil.Dedupe(append(dst.NS, src.NS...))
+	dst.TXT = sliceutil.Dedupe(append(dst.TXT, src.TXT...))
+	dst.SRV = sliceutil.Dedupe(append(dst.SRV, src.SRV...))
+	dst.CAA = sliceutil.Dedupe(append(dst.CAA, src.CAA...))
+	dst.AllRecords = sliceutil.Dedupe(append(dst.AllRecords, src.AllRecords...))
+	dst.InternalIPs = sliceutil.Dedupe(append(dst.InternalIPs, src.InternalIPs...))
+	dst.HasInternalIPs = dst.HasInternalIPs || src.HasInternalIPs
+	dst.HostsFile = dst.HostsFile || src.HostsFile
+	dst.Raw += src.Raw
+	if dst.TTL == 0 {
+		dst.TTL = src.TTL
+	}
+	if src.Host != "" {
+		dst.Host = src.Host
+		dst.StatusCode = src.StatusCode
+		dst.StatusCodeRaw = src.StatusCodeRaw
+		dst.RawResp = src.RawResp
+		dst.Timestamp = src.Timestamp
+	}
+}
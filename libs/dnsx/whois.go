@@ -0,0 +1,187 @@
+package dnsx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWhoisServer is the whois server queried first, referring to the registry holding the address
+const DefaultWhoisServer = "whois.iana.org"
+
+// maxWhoisResponseSize caps the size of a whois response read from a server
+const maxWhoisResponseSize = 1024 * 1024
+
+// WhoisResponse is the registration of the network holding an ip address
+type WhoisResponse struct {
+	IP      string `json:"ip,omitempty" csv:"ip"`
+	NetName string `json:"netname,omitempty" csv:"netname"`
+	Org     string `json:"org,omitempty" csv:"org"`
+	Country string `json:"country,omitempty" csv:"country"`
+}
+
+func (o *WhoisResponse) String() string {
+	return fmt.Sprintf("[%v: %v, %v, %v]", o.IP, o.NetName, o.Org, o.Country)
+}
+
+// WhoisClient looks up the registration of ip addresses, following the referral of the iana server
+// to the regional registry. The referrals are cached per iana allocation block (/8 for ipv4, /23 for ipv6)
+type WhoisClient struct {
+	Server  string
+	Timeout time.Duration
+	// Throttle, when set, is called before each query sent to a whois server, a lookup sending up to two of
+	// them. An error aborts the lookup
+	Throttle func() error
+
+	mutex     sync.Mutex
+	referrals map[string]string
+}
+
+// NewWhoisClient returns a whois client starting the lookups from the iana server
+func NewWhoisClient(timeout time.Duration) *WhoisClient {
+	return &WhoisClient{Server: DefaultWhoisServer, Timeout: timeout, referrals: make(map[string]string)}
+}
+
+// Lookup returns the netname, organization and country of the network holding the ip address
+func (c *WhoisClient) Lookup(ip string) (*WhoisResponse, error) {
+	addr := net.ParseIP(normalizeIP(ip))
+	if addr == nil {
+		return nil, fmt.Errorf("invalid ip address %s", ip)
+	}
+	block := whoisBlock(addr)
+	c.mutex.Lock()
+	server, ok := c.referrals[block]
+	c.mutex.Unlock()
+	if !ok {
+		response, err := c.query(c.Server, addr.String())
+		if err != nil {
+			return nil, err
+		}
+		server = parseWhoisReferral(response)
+		if server == "" {
+			return nil, fmt.Errorf("no whois server referred for %s", ip)
+		}
+		c.mutex.Lock()
+		c.referrals[block] = server
+		c.mutex.Unlock()
+	}
+
+	query := addr.String()
+	// arin returns a list of the matching networks unless the network details are requested
+	if strings.EqualFold(server, "whois.arin.net") {
+		query = "n + " + query
+	}
+	response, err := c.query(server, query)
+	if err != nil {
+		return nil, err
+	}
+	whois := ParseWhois(response)
+	whois.IP = addr.String()
+	return whois, nil
+}
+
+// query sends the query to the whois server and returns the whole response
+func (c *WhoisClient) query(server, query string) (string, error) {
+	if c.Throttle != nil {
+		if err := c.Throttle(); err != nil {
+			return "", err
+		}
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "43")
+	}
+	conn, err := net.DialTimeout("tcp", server, c.Timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if c.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(c.Timeout))
+	}
+	if _, err := conn.Write([]byte(query + "\r\n")); err != nil {
+		return "", err
+	}
+	response, err := io.ReadAll(io.LimitReader(conn, maxWhoisResponseSize))
+	if err != nil {
+		return "", err
+	}
+	return string(response), nil
+}
+
+// whoisBlock returns the iana allocation block of the ip address
+func whoisBlock(ip net.IP) string {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(8, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(23, 128)).String()
+}
+
+// parseWhoisReferral returns the whois server referred by the iana response
+func parseWhoisReferral(response string) string {
+	var server string
+	parseWhoisFields(response, func(key, value string) {
+		if key == "refer" || key == "whois" {
+			server = value
+		}
+	})
+	return server
+}
+
+// ParseWhois extracts the netname, organization and country from a registry whois response. The
+// first value of each field is kept, arin responses listing several networks keep the most specific one
+func ParseWhois(response string) *WhoisResponse {
+	whois := &WhoisResponse{}
+	var description string
+	parseWhoisFields(response, func(key, value string) {
+		switch key {
+		case "netrange":
+			// a new network starts, arin lists the most specific one last
+			if whois.NetName != "" {
+				*whois = WhoisResponse{}
+				description = ""
+			}
+		case "netname":
+			if whois.NetName == "" {
+				whois.NetName = value
+			}
+		case "orgname", "org-name", "owner":
+			if whois.Org == "" {
+				whois.Org = value
+			}
+		case "descr":
+			if description == "" {
+				description = value
+			}
+		case "country":
+			if whois.Country == "" {
+				whois.Country = strings.ToUpper(value)
+			}
+		}
+	})
+	// networks without organization object are only described
+	if whois.Org == "" {
+		whois.Org = description
+	}
+	return whois
+}
+
+// parseWhoisFields calls fn with the lowercase key and the value of each field of the response
+func parseWhoisFields(response string, fn func(key, value string)) {
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "%") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			continue
+		}
+		fn(strings.ToLower(strings.TrimSpace(key)), value)
+	}
+}
@@ -0,0 +1,102 @@
+package dnsx
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const arinWhoisResponse = `
+NetRange:       8.0.0.0 - 8.127.255.255
+CIDR:           8.0.0.0/9
+NetName:        LVLT-ORG-8-8
+Organization:   Level 3 Parent, LLC (LPL-141)
+OrgName:        Level 3 Parent, LLC
+Country:        US
+
+NetRange:       8.8.8.0 - 8.8.8.255
+CIDR:           8.8.8.0/24
+NetName:        GOGL
+Organization:   Google LLC (GOGL)
+OrgName:        Google LLC
+Country:        US
+`
+
+const ripeWhoisResponse = `
+% This is the RIPE Database query service.
+
+inetnum:        193.0.0.0 - 193.0.7.255
+netname:        RIPE-NCC
+descr:          RIPE Network Coordination Centre
+org:            ORG-RIEN1-RIPE
+country:        nl
+
+organisation:   ORG-RIEN1-RIPE
+org-name:       Reseaux IP Europeens Network Coordination Centre (RIPE NCC)
+country:        NL
+`
+
+func TestParseWhois(t *testing.T) {
+	require.Equal(t, &WhoisResponse{NetName: "GOGL", Org: "Google LLC", Country: "US"}, ParseWhois(arinWhoisResponse), "could not parse arin response")
+	require.Equal(t, &WhoisResponse{NetName: "RIPE-NCC", Org: "Reseaux IP Europeens Network Coordination Centre (RIPE NCC)", Country: "NL"}, ParseWhois(ripeWhoisResponse), "could not parse ripe response")
+
+	described := ParseWhois("inetnum: 10.0.0.0 - 10.0.0.255\nnetname: EXAMPLE\ndescr: Example Network\ncountry: DE\n")
+	require.Equal(t, "Example Network", described.Org, "description not used as organization")
+}
+
+func TestWhoisClient(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "could not listen")
+	defer listener.Close()
+	queries := make(chan string, 10)
+	go func() {
+		// the same server plays the iana role on the first connection and the registry role afterwards
+		for served := 0; ; served++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			query, _ := bufio.NewReader(conn).ReadString('\n')
+			queries <- strings.TrimSpace(query)
+			if served == 0 {
+				_, _ = conn.Write([]byte("refer: " + listener.Addr().String() + "\n"))
+			} else {
+				_, _ = conn.Write([]byte(ripeWhoisResponse))
+			}
+			conn.Close()
+		}
+	}()
+
+	client := NewWhoisClient(time.Second)
+	client.Server = listener.Addr().String()
+	var throttled int
+	client.Throttle = func() error {
+		throttled++
+		return nil
+	}
+	whois, err := client.Lookup("192.0.2.1")
+	require.Nil(t, err, "could not lookup")
+	require.Equal(t, "192.0.2.1", whois.IP, "could not match ip")
+	require.Equal(t, "RIPE-NCC", whois.NetName, "could not match netname")
+	require.Equal(t, "192.0.2.1", <-queries, "iana not queried first")
+	require.Equal(t, "192.0.2.1", <-queries, "registry not queried")
+	require.Equal(t, 2, throttled, "could not match throttled queries")
+
+	// the referral of the block is cached, only the registry is queried
+	_, err = client.Lookup("192.0.2.2")
+	require.Nil(t, err, "could not lookup")
+	require.Equal(t, "192.0.2.2", <-queries, "registry not queried")
+	require.Empty(t, queries, "iana queried again")
+	require.Equal(t, 3, throttled, "could not match throttled queries")
+
+	// a throttle error aborts the lookup before the query
+	client.Throttle = func() error { return context.Canceled }
+	_, err = client.Lookup("192.0.2.3")
+	require.ErrorIs(t, err, context.Canceled, "lookup not aborted")
+	require.Empty(t, queries, "registry queried")
+}